@@ -11,36 +11,304 @@
  * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
  */
 
+// Package alert implements deduplication and rate limiting for alerts,
+// before they are written as audit records by the alert decoder.
 package alert
 
 import (
-	"github.com/dreadl0ck/netcap/types"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	streamalert "github.com/dreadl0ck/netcap/decoder/stream/alert"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	alertLog = zap.NewNop()
+
+	// Instance is the manager used at runtime
+	// if it is nil, alerts passed to Emit are written without deduplication.
+	Instance *Manager
+
+	errAlertDecoderNotLoaded = errors.New("alert decoder not loaded")
+	errInvalidKeyField       = errors.New("invalid alert key field")
 )
 
+// SetLogger sets the logger for the alert package.
+func SetLogger(l *zap.Logger) {
+	alertLog = l
+}
+
+// DefaultKeyFields are used to identify duplicate alerts.
+var DefaultKeyFields = []string{"Name", "SrcIP", "DstIP", "Protocol"}
+
+// KeyFunc returns the key used to identify duplicate alerts.
+type KeyFunc func(a *types.Alert) string
+
+// NewKeyFunc returns a KeyFunc that joins the values of the given alert fields.
+func NewKeyFunc(fields ...string) (KeyFunc, error) {
+	getters := make([]func(a *types.Alert) string, len(fields))
+
+	for i, f := range fields {
+		switch strings.TrimSpace(f) {
+		case "Name":
+			getters[i] = func(a *types.Alert) string { return a.Name }
+		case "Description":
+			getters[i] = func(a *types.Alert) string { return a.Description }
+		case "SrcIP":
+			getters[i] = func(a *types.Alert) string { return a.SrcIP }
+		case "SrcPort":
+			getters[i] = func(a *types.Alert) string { return a.SrcPort }
+		case "DstIP":
+			getters[i] = func(a *types.Alert) string { return a.DstIP }
+		case "DstPort":
+			getters[i] = func(a *types.Alert) string { return a.DstPort }
+		case "MITRE":
+			getters[i] = func(a *types.Alert) string { return a.MITRE }
+		case "Domain":
+			getters[i] = func(a *types.Alert) string { return a.Domain }
+		case "Protocol":
+			getters[i] = func(a *types.Alert) string { return a.Protocol }
+		default:
+			return nil, fmt.Errorf("%w: %q", errInvalidKeyField, f)
+		}
+	}
+
+	return func(a *types.Alert) string {
+		var b strings.Builder
+
+		for _, g := range getters {
+			b.WriteString(g(a))
+			b.WriteByte('|')
+		}
+
+		return b.String()
+	}, nil
+}
+
+// Config for the alert manager.
+type Config struct {
+
+	// Key identifies duplicate alerts, defaults to the DefaultKeyFields
+	Key KeyFunc
+
+	// FlushInterval for writing aggregated alerts, disabled if zero
+	FlushInterval time.Duration
+
+	// SuppressionWindow is the minimum amount of time between writing the same alert again.
+	// The window is based on the timestamps of the alerts, so it works for live capture and offline analysis.
+	SuppressionWindow time.Duration
+
+	// Write is called for each aggregated alert, defaults to writing it with the alert decoder
+	Write func(a *types.Alert) error
+}
+
+// entry tracks the occurrences of an alert.
+type entry struct {
+
+	// aggregated alert, Timestamp is the first time the alert was seen
+	alert *types.Alert
+
+	// occurrences since the alert was written last
+	pending int64
+
+	// timestamp of the last occurrence at the time the alert was written
+	lastWritten int64
+}
+
 // Manager keeps track of generated alerts to perform deduplication,
 // and provides an interface to fetch alerts in a configurable interval.
 type Manager struct {
+	conf Config
 
 	// current alerts hashmap
-	alerts map[string]*types.Alert
+	alerts map[string]*entry
+
+	// timestamp of the latest alert, entries are pruned once they have been idle for longer than the suppression window
+	latest int64
+
+	// signal to stop the flush loop
+	stop chan struct{}
+	done chan struct{}
 
 	// allow thread safe access
 	sync.Mutex
 }
 
-// AddAlert will add an alert
+// NewManager returns a new alert manager.
+func NewManager(conf Config) *Manager {
+	if conf.Key == nil {
+		conf.Key, _ = NewKeyFunc(DefaultKeyFields...)
+	}
+
+	if conf.Write == nil {
+		conf.Write = writeAlert
+	}
+
+	return &Manager{
+		conf:   conf,
+		alerts: make(map[string]*entry),
+	}
+}
+
+// AddAlert will add an alert, or increment the counter if the same alert has been seen before.
 func (a *Manager) AddAlert(alert *types.Alert) {
+	key := a.conf.Key(alert)
 
 	a.Lock()
 	defer a.Unlock()
 
-	// TODO: deduplicate and keep track of the number of times an alert was fired.
-	a.alerts[alert.Name] = alert
+	if alert.Timestamp > a.latest {
+		a.latest = alert.Timestamp
+	}
+
+	e, ok := a.alerts[key]
+	if !ok {
+		c := *alert
+		c.Count = 1
+		c.LastSeen = alert.Timestamp
+
+		a.alerts[key] = &entry{
+			alert:   &c,
+			pending: 1,
+		}
+
+		return
+	}
+
+	e.pending++
+	e.alert.Count++
+
+	if alert.Timestamp > e.alert.LastSeen {
+		e.alert.LastSeen = alert.Timestamp
+	}
+
+	if alert.Timestamp < e.alert.Timestamp {
+		e.alert.Timestamp = alert.Timestamp
+	}
+}
+
+// FetchAlerts returns a copy of all aggregated alerts.
+func (a *Manager) FetchAlerts() []*types.Alert {
+	a.Lock()
+	defer a.Unlock()
+
+	alerts := make([]*types.Alert, 0, len(a.alerts))
+	for _, e := range a.alerts {
+		c := *e.alert
+		alerts = append(alerts, &c)
+	}
+
+	return alerts
 }
 
-// FetchAlerts fetches all alerts from the manager
-// make configurable, add timer etc
-func (a *Manager) FetchAlerts() map[string]*types.Alert {
-	return a.alerts
+// Flush writes all alerts with new occurrences that are not suppressed,
+// and returns the number of written alerts.
+// Alerts without new occurrences are removed once they have been idle for longer than the suppression window,
+// so a later occurrence starts a new aggregate.
+func (a *Manager) Flush() int {
+	return a.flush(false)
+}
+
+func (a *Manager) flush(force bool) int {
+	var alerts []*types.Alert
+
+	a.Lock()
+	for key, e := range a.alerts {
+		if e.pending == 0 {
+			if a.latest-e.alert.LastSeen > int64(a.conf.SuppressionWindow) {
+				delete(a.alerts, key)
+			}
+
+			continue
+		}
+
+		if !force && e.lastWritten != 0 && e.alert.LastSeen-e.lastWritten < int64(a.conf.SuppressionWindow) {
+			continue
+		}
+
+		e.pending = 0
+		e.lastWritten = e.alert.LastSeen
+
+		c := *e.alert
+		alerts = append(alerts, &c)
+	}
+	a.Unlock()
+
+	for _, alert := range alerts {
+		if err := a.conf.Write(alert); err != nil {
+			alertLog.Error("failed to write alert", zap.String("name", alert.Name), zap.Error(err))
+		}
+	}
+
+	return len(alerts)
+}
+
+// Start flushes the alerts periodically, until Stop is called.
+func (a *Manager) Start() {
+	if a.conf.FlushInterval == 0 || a.stop != nil {
+		return
+	}
+
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(a.conf.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if n := a.Flush(); n > 0 {
+					alertLog.Debug("flushed alerts", zap.Int("num", n))
+				}
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop and writes all pending alerts, ignoring the suppression window.
+func (a *Manager) Stop() {
+	if a.stop != nil {
+		close(a.stop)
+		<-a.done
+
+		a.stop = nil
+	}
+
+	n := a.flush(true)
+	alertLog.Info("stopped alert manager", zap.Int("flushed", n))
+}
+
+// Emit passes the alert to the manager instance for deduplication,
+// or writes it directly if no manager is active.
+func Emit(a *types.Alert) error {
+	if Instance != nil {
+		Instance.AddAlert(a)
+
+		return nil
+	}
+
+	return writeAlert(a)
+}
+
+// writeAlert writes the alert with the alert decoder.
+func writeAlert(a *types.Alert) error {
+	if streamalert.Decoder.Writer == nil {
+		return errAlertDecoderNotLoaded
+	}
+
+	streamalert.WriteAlert(a)
+
+	return nil
 }
@@ -0,0 +1,187 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestManagerDeduplication(t *testing.T) {
+	var (
+		written []*types.Alert
+		ts      = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	m := NewManager(Config{
+		SuppressionWindow: time.Minute,
+		Write: func(a *types.Alert) error {
+			written = append(written, a)
+
+			return nil
+		},
+	})
+
+	add := func(name, src string, offset time.Duration) {
+		m.AddAlert(&types.Alert{
+			Timestamp: ts.Add(offset).UnixNano(),
+			Name:      name,
+			SrcIP:     src,
+			DstIP:     "10.0.0.1",
+			Protocol:  "HTTP",
+		})
+	}
+
+	for i := 0; i < 10; i++ {
+		add("a", "192.168.1.1", time.Duration(i)*time.Second)
+	}
+
+	add("a", "192.168.1.2", 0)
+	add("b", "192.168.1.1", 0)
+
+	alerts := m.FetchAlerts()
+	if len(alerts) != 3 {
+		t.Fatal("expected 3 alerts, got", len(alerts))
+	}
+
+	if n := m.Flush(); n != 3 {
+		t.Fatal("expected 3 written alerts, got", n)
+	}
+
+	for _, a := range written {
+		if a.Name == "a" && a.SrcIP == "192.168.1.1" {
+			if a.Count != 10 {
+				t.Fatal("expected count 10, got", a.Count)
+			}
+
+			if a.Timestamp != ts.UnixNano() || a.LastSeen != ts.Add(9*time.Second).UnixNano() {
+				t.Fatal("unexpected first and last seen timestamps", a.Timestamp, a.LastSeen)
+			}
+		}
+	}
+
+	// no new occurrences
+	if n := m.Flush(); n != 0 {
+		t.Fatal("expected no written alerts, got", n)
+	}
+
+	// suppressed
+	add("a", "192.168.1.1", 30*time.Second)
+
+	if n := m.Flush(); n != 0 {
+		t.Fatal("expected suppressed alert, got", n)
+	}
+
+	// suppression window has passed
+	add("a", "192.168.1.1", 2*time.Minute)
+
+	if n := m.Flush(); n != 1 {
+		t.Fatal("expected 1 written alert, got", n)
+	}
+
+	if written[len(written)-1].Count != 12 {
+		t.Fatal("expected count 12, got", written[len(written)-1].Count)
+	}
+
+	// pending alerts are written on stop, regardless of the suppression window
+	add("b", "192.168.1.1", 10*time.Second)
+	m.Stop()
+
+	if len(written) != 5 {
+		t.Fatal("expected 5 written alerts, got", len(written))
+	}
+}
+
+func TestManagerPruning(t *testing.T) {
+	var (
+		written int
+		ts      = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	m := NewManager(Config{
+		SuppressionWindow: time.Minute,
+		Write: func(a *types.Alert) error {
+			written++
+
+			return nil
+		},
+	})
+
+	add := func(name string, offset time.Duration) {
+		m.AddAlert(&types.Alert{
+			Timestamp: ts.Add(offset).UnixNano(),
+			Name:      name,
+		})
+	}
+
+	add("a", 0)
+	add("b", 0)
+
+	if n := m.Flush(); n != 2 {
+		t.Fatal("expected 2 written alerts, got", n)
+	}
+
+	// within the suppression window: entries are kept
+	add("b", 30*time.Second)
+	m.Flush()
+
+	if n := len(m.FetchAlerts()); n != 2 {
+		t.Fatal("expected 2 alerts, got", n)
+	}
+
+	// a has been idle for longer than the suppression window, b has a suppressed occurrence pending
+	add("c", 90*time.Second)
+	m.Flush()
+
+	alerts := m.FetchAlerts()
+	if len(alerts) != 2 {
+		t.Fatal("expected 2 alerts, got", len(alerts))
+	}
+
+	for _, a := range alerts {
+		if a.Name == "a" {
+			t.Fatal("expected idle alert to be removed")
+		}
+	}
+
+	// a new occurrence of a pruned alert starts a new aggregate and is written
+	add("a", 100*time.Second)
+
+	if n := m.Flush(); n != 1 {
+		t.Fatal("expected 1 written alert, got", n)
+	}
+
+	for _, a := range m.FetchAlerts() {
+		if a.Name == "a" && a.Count != 1 {
+			t.Fatal("expected count 1, got", a.Count)
+		}
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	key, err := NewKeyFunc("Name", "DstPort")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key(&types.Alert{Name: "a", SrcIP: "1.1.1.1", DstPort: "80"}) != key(&types.Alert{Name: "a", SrcIP: "2.2.2.2", DstPort: "80"}) {
+		t.Fatal("expected equal keys")
+	}
+
+	if _, err = NewKeyFunc("Name", "Invalid"); err == nil {
+		t.Fatal("expected an error for invalid field")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package analyze
+
+import (
+	"math"
+	"sync"
+)
+
+// baseline tracks a rolling mean and variance for a series of observations,
+// using exponentially weighted moving averages.
+type baseline struct {
+	mean     float64
+	variance float64
+	count    int
+}
+
+// score returns the number of standard deviations the value is above the mean.
+func (b *baseline) score(v float64) float64 {
+	stddev := math.Sqrt(b.variance)
+	if stddev == 0 {
+		if v > b.mean {
+			return math.Inf(1)
+		}
+
+		return 0
+	}
+
+	return (v - b.mean) / stddev
+}
+
+// update adds a new observation to the baseline.
+func (b *baseline) update(v float64, alpha float64) {
+	b.count++
+
+	if b.count == 1 {
+		b.mean = v
+
+		return
+	}
+
+	diff := v - b.mean
+	incr := alpha * diff
+
+	b.mean += incr
+	b.variance = (1 - alpha) * (b.variance + diff*incr)
+}
+
+// BaselineConfig controls the sensitivity of baseline based detectors.
+type BaselineConfig struct {
+
+	// Alpha is the smoothing factor for the moving averages, between 0 and 1
+	// higher values give more weight to recent observations.
+	Alpha float64
+
+	// Threshold is the number of standard deviations above the mean
+	// that an observation must exceed to be reported.
+	Threshold float64
+
+	// Warmup is the number of observations required for a host before alerts are generated.
+	Warmup int
+
+	// MaxHosts limits the number of tracked hosts, to bound memory usage.
+	MaxHosts int
+}
+
+// DefaultBaselineConfig is used for the builtin detectors.
+var DefaultBaselineConfig = BaselineConfig{
+	Alpha:     0.1,
+	Threshold: 4,
+	Warmup:    20,
+	MaxHosts:  100000,
+}
+
+// hostBaselines holds a baseline per host.
+type hostBaselines struct {
+	sync.Mutex
+	conf  BaselineConfig
+	hosts map[string]*baseline
+}
+
+func newHostBaselines(conf BaselineConfig) *hostBaselines {
+	return &hostBaselines{
+		conf:  conf,
+		hosts: make(map[string]*baseline),
+	}
+}
+
+// observe adds the value to the baseline for the host
+// and returns the z-score and true if the value is anomalous.
+// The score is computed before the value is added to the baseline.
+func (h *hostBaselines) observe(host string, v float64) (mean float64, score float64, anomaly bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	b, ok := h.hosts[host]
+	if !ok {
+		if len(h.hosts) >= h.conf.MaxHosts {
+			return 0, 0, false
+		}
+
+		b = new(baseline)
+		h.hosts[host] = b
+	}
+
+	mean = b.mean
+
+	if b.count >= h.conf.Warmup {
+		score = b.score(v)
+		anomaly = score > h.conf.Threshold
+	}
+
+	b.update(v, h.conf.Alpha)
+
+	return mean, score, anomaly
+}
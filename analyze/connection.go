@@ -0,0 +1,73 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package analyze
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const connectionBytesName = "connection-bytes"
+
+// connectionBytesDetector keeps a baseline of the bytes transferred per connection for each source host,
+// and reports connections that exceed it. This is useful to spot data exfiltration and unusual downloads.
+type connectionBytesDetector struct {
+	baselines *hostBaselines
+	emit      func(a *types.Alert)
+}
+
+func newConnectionBytesDetector(conf BaselineConfig) *connectionBytesDetector {
+	return &connectionBytesDetector{
+		baselines: newHostBaselines(conf),
+		emit:      writeAlert,
+	}
+}
+
+// Name returns the name of the detector.
+func (d *connectionBytesDetector) Name() string {
+	return connectionBytesName
+}
+
+// Analyze updates the baseline for the source host of the connection.
+func (d *connectionBytesDetector) Analyze(record types.AuditRecord) {
+	c, ok := record.(*types.Connection)
+	if !ok || c.SrcIP == "" {
+		return
+	}
+
+	size := c.BytesClientToServer + c.BytesServerToClient
+	if size == 0 {
+		size = int64(c.TotalSize)
+	}
+
+	mean, score, anomaly := d.baselines.observe(c.SrcIP, float64(size))
+	if !anomaly {
+		return
+	}
+
+	d.emit(&types.Alert{
+		Timestamp:   c.TimestampFirst,
+		Name:        "Unusual connection size",
+		Description: fmt.Sprintf("connection transferred %d bytes, the average for the host is %.0f bytes (score %.2f)", size, mean, score),
+		SrcIP:       c.SrcIP,
+		SrcPort:     c.SrcPort,
+		DstIP:       c.DstIP,
+		DstPort:     c.DstPort,
+		Protocol:    c.TransportProto,
+		Notes:       "detector: " + connectionBytesName + ", bytes: " + strconv.FormatInt(size, 10),
+		CommunityID: c.CommunityID,
+	})
+}
@@ -0,0 +1,114 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	analyzeLog = zap.NewNop()
+
+	errUnknownDetector = errors.New("unknown anomaly detector")
+)
+
+// SetLogger sets the logger for the analyze package.
+func SetLogger(l *zap.Logger) {
+	analyzeLog = l
+}
+
+// builtinDetector describes an anomaly detector that ships with netcap.
+type builtinDetector struct {
+	description string
+	types       []types.Type
+	create      func(conf BaselineConfig) types.AnomalyDetector
+}
+
+// builtins contains all anomaly detectors that can be enabled by name.
+var builtins = map[string]builtinDetector{
+	connectionBytesName: {
+		description: "Connections transferring an unusual amount of bytes for the source host",
+		types:       []types.Type{types.Type_NC_Connection},
+		create: func(conf BaselineConfig) types.AnomalyDetector {
+			return newConnectionBytesDetector(conf)
+		},
+	},
+	dnsVolumeName: {
+		description: "Unusual number of DNS queries per minute for the source host",
+		types:       []types.Type{types.Type_NC_DNS},
+		create: func(conf BaselineConfig) types.AnomalyDetector {
+			return newDNSVolumeDetector(conf)
+		},
+	},
+}
+
+// Detectors returns the names of all builtin anomaly detectors.
+func Detectors() []string {
+	names := make([]string, 0, len(builtins))
+	for n := range builtins {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ShowDetectors prints the names and descriptions of all builtin anomaly detectors.
+func ShowDetectors() {
+	for _, n := range Detectors() {
+		fmt.Println("+", n, "-", builtins[n].description)
+	}
+}
+
+// RegisterDetectors registers the builtin detectors from a comma separated list of names.
+// Using 'all' enables all builtin detectors.
+func RegisterDetectors(names string, conf BaselineConfig) error {
+	list := strings.Split(names, ",")
+	if strings.TrimSpace(names) == "all" {
+		list = Detectors()
+	}
+
+	for _, n := range list {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+
+		b, ok := builtins[n]
+		if !ok {
+			return fmt.Errorf("%w: %s", errUnknownDetector, n)
+		}
+
+		types.RegisterAnomalyDetector(b.create(conf), b.types...)
+		analyzeLog.Info("registered anomaly detector", zap.String("name", n))
+	}
+
+	return nil
+}
+
+// writeAlert emits an alert.
+func writeAlert(a *types.Alert) {
+	if err := alert.Emit(a); err != nil {
+		analyzeLog.Debug("dropping alert", zap.String("name", a.Name), zap.Error(err))
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var testConfig = BaselineConfig{
+	Alpha:     0.1,
+	Threshold: 4,
+	Warmup:    10,
+	MaxHosts:  10,
+}
+
+func TestBaseline(t *testing.T) {
+	h := newHostBaselines(testConfig)
+
+	for i := 0; i < 100; i++ {
+		if _, _, anomaly := h.observe("10.0.0.1", float64(1000+i%10)); anomaly {
+			t.Fatal("unexpected anomaly at observation", i)
+		}
+	}
+
+	if _, _, anomaly := h.observe("10.0.0.1", 1005); anomaly {
+		t.Fatal("unexpected anomaly for regular value")
+	}
+
+	if _, _, anomaly := h.observe("10.0.0.1", 100000); !anomaly {
+		t.Fatal("expected anomaly for outlier")
+	}
+
+	// no alerts during warmup
+	if _, _, anomaly := h.observe("10.0.0.2", 100000); anomaly {
+		t.Fatal("unexpected anomaly during warmup")
+	}
+}
+
+func TestConnectionBytesDetector(t *testing.T) {
+	var (
+		d      = newConnectionBytesDetector(testConfig)
+		alerts []*types.Alert
+	)
+
+	d.emit = func(a *types.Alert) {
+		alerts = append(alerts, a)
+	}
+
+	for i := 0; i < 50; i++ {
+		d.Analyze(&types.Connection{
+			SrcIP:               "192.168.1.2",
+			BytesClientToServer: int64(500 + i%5),
+			BytesServerToClient: 1500,
+		})
+	}
+
+	d.Analyze(&types.Connection{
+		SrcIP:               "192.168.1.2",
+		DstIP:               "1.2.3.4",
+		BytesClientToServer: 50000000,
+	})
+
+	if len(alerts) != 1 {
+		t.Fatal("expected 1 alert, got", len(alerts))
+	}
+
+	if alerts[0].SrcIP != "192.168.1.2" || alerts[0].DstIP != "1.2.3.4" {
+		t.Fatal("unexpected alert", alerts[0])
+	}
+}
+
+func TestDNSVolumeDetector(t *testing.T) {
+	var (
+		d      = newDNSVolumeDetector(testConfig)
+		alerts []*types.Alert
+		ts     = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	d.emit = func(a *types.Alert) {
+		alerts = append(alerts, a)
+	}
+
+	query := func(offset time.Duration) {
+		d.Analyze(&types.DNS{
+			Timestamp: ts.Add(offset).UnixNano(),
+			SrcIP:     "192.168.1.2",
+		})
+	}
+
+	// 5 queries per minute for 30 minutes
+	for m := 0; m < 30; m++ {
+		for q := 0; q < 5; q++ {
+			query(time.Duration(m)*time.Minute + time.Duration(q)*time.Second)
+		}
+	}
+
+	// responses are ignored
+	d.Analyze(&types.DNS{QR: true, SrcIP: "192.168.1.2"})
+
+	if len(alerts) != 0 {
+		t.Fatal("unexpected alerts", alerts)
+	}
+
+	// burst of 500 queries in the next minute
+	for q := 0; q < 500; q++ {
+		query(30*time.Minute + time.Duration(q)*time.Millisecond)
+	}
+
+	// next window closes the burst
+	query(32 * time.Minute)
+
+	if len(alerts) != 1 {
+		t.Fatal("expected 1 alert, got", len(alerts))
+	}
+}
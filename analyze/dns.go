@@ -0,0 +1,116 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package analyze
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	dnsVolumeName = "dns-volume"
+
+	// dnsVolumeWindow is the interval for counting DNS queries.
+	dnsVolumeWindow = time.Minute
+)
+
+// dnsWindow holds the number of queries for a host in the current time window.
+type dnsWindow struct {
+	start int64
+	count int
+}
+
+// dnsVolumeDetector counts the DNS queries sent by each host per time window,
+// and reports windows that exceed the baseline for the host, which can indicate DNS tunneling or malware beacons.
+// The windows are based on the timestamps of the audit records, so this works for live capture and offline analysis.
+type dnsVolumeDetector struct {
+	sync.Mutex
+	windows   map[string]*dnsWindow
+	baselines *hostBaselines
+	emit      func(a *types.Alert)
+}
+
+func newDNSVolumeDetector(conf BaselineConfig) *dnsVolumeDetector {
+	return &dnsVolumeDetector{
+		windows:   make(map[string]*dnsWindow),
+		baselines: newHostBaselines(conf),
+		emit:      writeAlert,
+	}
+}
+
+// Name returns the name of the detector.
+func (d *dnsVolumeDetector) Name() string {
+	return dnsVolumeName
+}
+
+// Analyze counts DNS queries and evaluates the query volume of a host once its time window has passed.
+func (d *dnsVolumeDetector) Analyze(record types.AuditRecord) {
+	dns, ok := record.(*types.DNS)
+	if !ok || dns.QR || dns.SrcIP == "" {
+		return
+	}
+
+	d.Lock()
+
+	w, ok := d.windows[dns.SrcIP]
+	if !ok {
+		if len(d.windows) >= d.baselines.conf.MaxHosts {
+			d.Unlock()
+
+			return
+		}
+
+		d.windows[dns.SrcIP] = &dnsWindow{
+			start: dns.Timestamp,
+			count: 1,
+		}
+		d.Unlock()
+
+		return
+	}
+
+	if dns.Timestamp-w.start < int64(dnsVolumeWindow) {
+		w.count++
+		d.Unlock()
+
+		return
+	}
+
+	// window has passed: evaluate and start a new one
+	var (
+		count = w.count
+		start = w.start
+	)
+
+	w.start = dns.Timestamp
+	w.count = 1
+	d.Unlock()
+
+	mean, score, anomaly := d.baselines.observe(dns.SrcIP, float64(count))
+	if !anomaly {
+		return
+	}
+
+	d.emit(&types.Alert{
+		Timestamp:   start,
+		Name:        "Unusual DNS query volume",
+		Description: fmt.Sprintf("host sent %d DNS queries within %s, the average is %.1f (score %.2f)", count, dnsVolumeWindow, mean, score),
+		SrcIP:       dns.SrcIP,
+		Protocol:    "DNS",
+		Notes:       "detector: " + dnsVolumeName,
+	})
+}
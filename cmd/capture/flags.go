@@ -42,6 +42,14 @@ var (
 	flagOutDir                 = fs.String("out", "", "specify output directory, will be created if it does not exist")
 	flagTimeout                = fs.Duration("timeout", 1*time.Second, "set the timeout for live capture, providing a value of zero will be substituted with pcap.BlockForever.")
 	flagLabels                 = fs.String("labels", "", "path to attacks for labeling audit records")
+	flagRules                  = fs.String("rules", "", "path to YAML file with detection rules that generate alerts")
+	flagYaraRules              = fs.String("yara", "", "path to a YARA rule file or a directory with rule files, to scan extracted files and reassembled TCP conversations")
+	flagDetectors              = fs.String("detectors", "", "comma separated list of builtin anomaly detectors to enable, use 'all' to enable all of them")
+
+	flagAlertDedup       = fs.Bool("alert-dedup", true, "deduplicate alerts and count their occurrences")
+	flagAlertKey         = fs.String("alert-key", defaults.AlertKey, "comma separated list of alert fields used to identify duplicate alerts")
+	flagAlertFlush       = fs.Duration("alert-flush-interval", defaults.AlertFlushInterval, "interval for writing deduplicated alerts")
+	flagAlertSuppression = fs.Duration("alert-suppress", defaults.AlertSuppressionWindow, "minimum time between writing the same alert again")
 
 	flagScatterDuration = fs.Duration("scatter-duration", 5*time.Minute, "interval for scatter chart")
 	flagScatter         = fs.Bool("scatter", true, "generate a scatter plot for labeled audit records")
@@ -52,6 +60,7 @@ var (
 	flagExclude = fs.String("exclude", "", "exclude specific decoders")
 
 	flagDecoders              = fs.Bool("decoders", false, "show all available decoders")
+	flagShowDetectors         = fs.Bool("show-detectors", false, "show all builtin anomaly detectors")
 	flagPrintProtocolOverview = fs.Bool("overview", false, "print a list of all available decoders and fields")
 
 	flagInterface    = fs.String("iface", "", "attach to network interface and capture in live mode")
@@ -86,6 +95,12 @@ var (
 	flagKibanaEndpoint   = fs.String("kibana", "", "kibana endpoint URL")
 	flagProto            = fs.Bool("proto", true, "output data as protobuf")
 	flagJSON             = fs.Bool("json", false, "output data as JSON")
+	flagFanOut           = fs.Bool("fanout", false, "write audit records to all enabled outputs at once, e.g. -proto and -elastic")
+	flagRotateInterval   = fs.Duration("rotate-interval", 0, "start new audit record files for each interval, e.g. 1h, 0 disables rotation by time")
+	flagRotateSize       = fs.Int("rotate-size", 0, "start new audit record files once the given number of MB has been written to them before compression, 0 disables rotation by size")
+	flagRotateHook       = fs.String("rotate-hook", "", "command executed for each finished audit record file with the path as last argument, or unix:<path> to notify a unix socket")
+	flagRetainAge        = fs.Duration("retain-age", 0, "remove rotated audit record files that are older, 0 keeps all files")
+	flagRetainSize       = fs.Int("retain-size", 0, "remove the oldest rotated audit record files once all files of a type exceed the given size in MB, 0 disables the limit")
 	flagContext          = fs.Bool("context", true, "add packet flow context to selected audit records")
 	flagHTTPShutdown     = fs.Bool("http-shutdown", false, "create local endpoint to trigger teardown via HTTP")
 
@@ -94,7 +109,13 @@ var (
 	flagQuiet          = fs.Bool("quiet", false, "don't print infos to stdout")
 	flagPrintProgress  = fs.Bool("progress", false, "force printing progress to stderr even in quiet mode")
 
-	flagFileStorage = fs.String("fileStorage", "", "path to extracted files")
+	flagFileStorage        = fs.String("fileStorage", "", "path to extracted files")
+	flagFileDedup          = fs.Bool("file-dedup", false, "store each unique extracted file once under its SHA-256 hash and record all occurrences in a manifest")
+	flagFileStorageQuota   = fs.Int("file-storage-quota", 0, "maximum size of the deduplicated file storage in MB, the files seen least recently are evicted first, 0 disables the limit")
+	flagFileUnpack         = fs.Bool("file-unpack", false, "recursively unpack zip, tar, gzip and bzip2 archives from extracted files, and write a file record for each member")
+	flagFileUnpackDepth    = fs.Int("file-unpack-depth", defaults.UnpackMaxDepth, "maximum nesting depth of unpacked archives")
+	flagFileUnpackMaxSize  = fs.Int("file-unpack-max-size", defaults.UnpackMaxSize, "maximum number of bytes in MB unpacked from a single extracted file, including nested archives")
+	flagFileUnpackMaxRatio = fs.Int("file-unpack-max-ratio", defaults.UnpackMaxRatio, "maximum ratio between the unpacked and the compressed size of an archive member")
 
 	flagReverseDNS    = fs.Bool("reverse-dns", false, "resolve ips to domains via the operating systems default dns resolver")
 	flagLocalDNS      = fs.Bool("local-dns", false, "resolve DNS locally via hosts file in the database dir")
@@ -136,6 +157,10 @@ var (
 	flagFlowTimeOut                    = fs.Duration("flow-timeout", defaults.FlowTimeOut, "closes flows older than flowTimeout")
 	flagClosePendingTimeout            = fs.Duration("close-pending-timeout", defaults.ClosePendingTimeout, "reassembly: close connections that have pending bytes")
 	flagCloseInactiveTimeout           = fs.Duration("close-inactive-timeout", defaults.CloseInactiveTimeout, "reassembly: close connections that are inactive")
+	flagUDPIdleTimeout                 = fs.Duration("udp-idle-timeout", defaults.UDPIdleTimeout, "udp: decode conversations that did not receive any data after X, 0 waits until teardown")
+	flagUDPMaxStreamSize               = fs.Int("udp-max-stream-size", defaults.UDPMaxStreamSize, "udp: decode conversations once they contain more than X bytes, 0 disables the limit")
+	flagReassemblyBudget               = fs.Int("reassembly-budget", defaults.ReassemblyBudget, "reassembly: maximum number of MB buffered for TCP reassembly and the conversation data of all connections, 0 disables the limit")
+	flagReassemblyConnMax              = fs.Int("reassembly-conn-max", defaults.ReassemblyConnMax, "reassembly: maximum number of MB buffered for the conversation data of a single TCP connection, 0 disables the limit")
 	flagUseRE2                         = fs.Bool("re2", true, "if true uses the default golang re2 regex engine for service detection")
 	flagStopAfterHarvesterMatch        = fs.Bool("stop-after-harvester-match", true, "stop processing the conversation after the first credential harvester returned a result")
 	flagStopAfterServiceProbeMatch     = fs.Bool("stop-after-service-match", true, "stop processing the conversation after the first service probe returned a result")
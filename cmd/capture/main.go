@@ -64,6 +64,19 @@ func Run() {
 	// TODO: move to utils and use in other cli tools
 	checkArgs()
 
+	rotation := io.RotationConfig{
+		RotateInterval: *flagRotateInterval,
+		RotateSize:     int64(*flagRotateSize) * 1024 * 1024,
+		RotateHook:     *flagRotateHook,
+		RetainAge:      *flagRetainAge,
+		RetainSize:     int64(*flagRetainSize) * 1024 * 1024,
+	}
+
+	// reject invalid hooks before any file is written, instead of failing on the first rotation
+	if err = rotation.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
 	if *flagGenerateConfig {
 		io.GenerateConfig(fs, "capture")
 
@@ -124,6 +137,13 @@ func Run() {
 		return
 	}
 
+	// print anomaly detectors and exit
+	if *flagShowDetectors {
+		analyze.ShowDetectors()
+
+		return
+	}
+
 	// live mode?
 	var live bool
 	if *flagInterface != "" {
@@ -301,17 +321,19 @@ func Run() {
 		Scatter:               *flagScatter,
 		ScatterDuration:       *flagScatterDuration,
 		DecoderConfig: &config.Config{
-			Quiet:         *flagQuiet,
-			PrintProgress: *flagPrintProgress,
-			Buffer:        *flagBuffer,
-			MemBufferSize: *flagMemBufferSize,
-			Compression:   *flagCompress,
-			CSV:           *flagCSV,
-			UnixSocket:    *flagUNIX,
-			Encode:        *flagEncode,
-			Label:         *flagLabels != "",
-			Null:          *flagNull,
-			Elastic:       *flagElastic,
+			Quiet:          *flagQuiet,
+			PrintProgress:  *flagPrintProgress,
+			Buffer:         *flagBuffer,
+			MemBufferSize:  *flagMemBufferSize,
+			Compression:    *flagCompress,
+			CSV:            *flagCSV,
+			UnixSocket:     *flagUNIX,
+			Encode:         *flagEncode,
+			Label:          *flagLabels != "",
+			Null:           *flagNull,
+			Elastic:        *flagElastic,
+			FanOut:         *flagFanOut,
+			RotationConfig: rotation,
 			ElasticConfig: io.ElasticConfig{
 				ElasticAddrs:   elasticAddrs,
 				ElasticUser:    *flagElasticUser,
@@ -347,7 +369,17 @@ func Run() {
 			FlowTimeOut:                    *flagFlowTimeOut,
 			CloseInactiveTimeOut:           *flagCloseInactiveTimeout,
 			ClosePendingTimeOut:            *flagClosePendingTimeout,
+			UDPIdleTimeout:                 *flagUDPIdleTimeout,
+			UDPMaxStreamSize:               *flagUDPMaxStreamSize,
+			ReassemblyBudget:               int64(*flagReassemblyBudget) * 1024 * 1024,
+			ReassemblyConnMax:              int64(*flagReassemblyConnMax) * 1024 * 1024,
 			FileStorage:                    *flagFileStorage,
+			FileStorageDedup:               *flagFileDedup,
+			FileStorageQuota:               int64(*flagFileStorageQuota) * 1024 * 1024,
+			UnpackArchives:                 *flagFileUnpack,
+			UnpackMaxDepth:                 *flagFileUnpackDepth,
+			UnpackMaxSize:                  int64(*flagFileUnpackMaxSize) * 1024 * 1024,
+			UnpackMaxRatio:                 *flagFileUnpackMaxRatio,
 			CalculateEntropy:               *flagCalcEntropy,
 			SaveConns:                      *flagSaveConns,
 			TCPDebug:                       *flagTCPDebug,
@@ -359,6 +391,13 @@ func Run() {
 			StopAfterServiceProbeMatch:     *flagStopAfterServiceProbeMatch,
 			StopAfterServiceCategoryMiss:   *flagStopAfterServiceCategoryMiss,
 			CustomRegex:                    *flagCustomCredsRegex,
+			Rules:                          *flagRules,
+			YaraRules:                      *flagYaraRules,
+			Detectors:                      *flagDetectors,
+			AlertDeduplication:             *flagAlertDedup,
+			AlertKey:                       *flagAlertKey,
+			AlertFlushInterval:             *flagAlertFlush,
+			AlertSuppressionWindow:         *flagAlertSuppression,
 			StreamBufferSize:               *flagStreamBufferSize,
 			NumStreamWorkers:               *flagNumStreamWorkers,
 			IgnoreDecoderInitErrors:        *flagIgnoreInitErrs,
@@ -487,6 +526,7 @@ func makeWriterConfig(name string, typ types.Type, elasticAddrs []string) *io.Wr
 		Type:       typ,
 		Null:       *flagNull,
 		Elastic:    *flagElastic,
+		FanOut:     *flagFanOut,
 		ElasticConfig: io.ElasticConfig{
 			ElasticAddrs:   elasticAddrs,
 			ElasticUser:    *flagElasticUser,
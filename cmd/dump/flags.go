@@ -35,6 +35,9 @@ var (
 	flagGenerateConfig  = fs.Bool("gen-config", false, "generate config")
 	_                   = fs.String("config", "", "read configuration from file at path")
 	flagSelect          = fs.String("select", "", "select specific fields of an audit records when generating csv or tables")
+	flagFilter          = fs.String("filter", "", "only dump audit records matching the filter expression, e.g. 'SrcIP in-subnet 10.0.0.0/8 && DstPort == 443'")
+	flagSince           = fs.String("since", "", "only dump audit records at or after the given time: RFC3339, date with optional time, unix seconds or a duration relative to now, e.g. 2h")
+	flagUntil           = fs.String("until", "", "only dump audit records before the given time, same formats as -since")
 	flagFields          = fs.Bool("fields", false, "print available fields for an audit record file and exit")
 	flagSeparator       = fs.String("sep", ",", "set separator string for csv output")
 	flagCSV             = fs.Bool("csv", false, "print output data as csv with header line")
@@ -20,11 +20,13 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/evilsocket/islazy/tui"
 	"github.com/mgutz/ansi"
 
 	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/filter"
 	"github.com/dreadl0ck/netcap/io"
 	"github.com/dreadl0ck/netcap/types"
 	"github.com/dreadl0ck/netcap/utils"
@@ -91,6 +93,26 @@ func Run() {
 
 	// read ncap file and print to stdout
 	if filepath.Ext(*flagInput) == defaults.FileExtension || filepath.Ext(*flagInput) == ".gz" {
+		// times without a zone are interpreted like the timestamps in the output
+		var (
+			now = time.Now()
+			loc = time.Local
+		)
+
+		if *flagUTC {
+			loc = time.UTC
+		}
+
+		since, errSince := filter.ParseTime(*flagSince, now, loc)
+		if errSince != nil {
+			log.Fatal(errSince)
+		}
+
+		until, errUntil := filter.ParseTime(*flagUntil, now, loc)
+		if errUntil != nil {
+			log.Fatal(errUntil)
+		}
+
 		err = io.Dump(
 			os.Stdout,
 			io.DumpConfig{
@@ -100,6 +122,9 @@ func Run() {
 				Structured:   *flagPrintStructured,
 				Table:        *flagTable,
 				Selection:    *flagSelect,
+				Filter:       *flagFilter,
+				Since:        since,
+				Until:        until,
 				UTC:          *flagUTC,
 				Fields:       *flagFields,
 				JSON:         *flagJSON,
@@ -13,6 +13,7 @@ func printHeader() {
 	fmt.Println("	$ net dump -read TCP.ncap.gz")
 	fmt.Println("	$ net dump -fields -read TCP.ncap.gz")
 	fmt.Println("	$ net dump -read TCP.ncap.gz -select Timestamp,SrcPort,DstPort > tcp.csv")
+	fmt.Println("	$ net dump -read HTTP.ncap.gz -filter 'StatusCode >= 400 && Host =~ \".*\\.example\\.com\"' -since 2h")
 	fmt.Println()
 }
 
@@ -47,6 +47,12 @@ var (
 	flagPromiscMode          = fs.Bool("promisc", true, "toggle promiscuous mode for live capture")
 	flagLogErrors            = fs.Bool("log-errors", false, "enable verbose packet decoding error logging")
 	flagFileStorage          = fs.String("fileStorage", "", "path to created extracted files (currently only for HTTP)")
+	flagFileDedup            = fs.Bool("file-dedup", false, "store each unique extracted file once under its SHA-256 hash and record all occurrences in a manifest")
+	flagFileStorageQuota     = fs.Int("file-storage-quota", 0, "maximum size of the deduplicated file storage in MB, the files seen least recently are evicted first, 0 disables the limit")
+	flagFileUnpack           = fs.Bool("file-unpack", false, "recursively unpack zip, tar, gzip and bzip2 archives from extracted files, and write a file record for each member")
+	flagFileUnpackDepth      = fs.Int("file-unpack-depth", defaults.UnpackMaxDepth, "maximum nesting depth of unpacked archives")
+	flagFileUnpackMaxSize    = fs.Int("file-unpack-max-size", defaults.UnpackMaxSize, "maximum number of bytes in MB unpacked from a single extracted file, including nested archives")
+	flagFileUnpackMaxRatio   = fs.Int("file-unpack-max-ratio", defaults.UnpackMaxRatio, "maximum ratio between the unpacked and the compressed size of an archive member")
 	flagCalcEntropy          = fs.Bool("entropy", false, "enable entropy calculation for Eth,IP,TCP and UDP payloads")
 	flagSnapLen              = fs.Int("snaplen", defaults.SnapLen, "configure snaplen for live capture from interface")
 	flagBaseLayer            = fs.String("base", "ethernet", "select base layer")
@@ -87,4 +93,8 @@ var (
 	flagFlowTimeOut          = fs.Duration("flow-timeout", defaults.FlowTimeOut, "closes flows older than flowTimeout")
 	flagClosePendingTimeout  = fs.Duration("close-pending-timeout", defaults.ClosePendingTimeout, "reassembly: close connections that have pending bytes after X")
 	flagCloseInactiveTimeout = fs.Duration("close-inactive-timeout", defaults.CloseInactiveTimeout, "reassembly: close connections that are inactive after X")
+	flagUDPIdleTimeout       = fs.Duration("udp-idle-timeout", defaults.UDPIdleTimeout, "udp: decode conversations that did not receive any data after X, 0 waits until teardown")
+	flagUDPMaxStreamSize     = fs.Int("udp-max-stream-size", defaults.UDPMaxStreamSize, "udp: decode conversations once they contain more than X bytes, 0 disables the limit")
+	flagReassemblyBudget     = fs.Int("reassembly-budget", defaults.ReassemblyBudget, "reassembly: maximum number of MB buffered for TCP reassembly and the conversation data of all connections, 0 disables the limit")
+	flagReassemblyConnMax    = fs.Int("reassembly-conn-max", defaults.ReassemblyConnMax, "reassembly: maximum number of MB buffered for the conversation data of a single TCP connection, 0 disables the limit")
 )
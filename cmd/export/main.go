@@ -139,7 +139,17 @@ func Run() {
 				FlowTimeOut:          *flagFlowTimeOut,
 				CloseInactiveTimeOut: *flagCloseInactiveTimeout,
 				ClosePendingTimeOut:  *flagClosePendingTimeout,
+				UDPIdleTimeout:       *flagUDPIdleTimeout,
+				UDPMaxStreamSize:     *flagUDPMaxStreamSize,
+				ReassemblyBudget:     int64(*flagReassemblyBudget) * 1024 * 1024,
+				ReassemblyConnMax:    int64(*flagReassemblyConnMax) * 1024 * 1024,
 				FileStorage:          *flagFileStorage,
+				FileStorageDedup:     *flagFileDedup,
+				FileStorageQuota:     int64(*flagFileStorageQuota) * 1024 * 1024,
+				UnpackArchives:       *flagFileUnpack,
+				UnpackMaxDepth:       *flagFileUnpackDepth,
+				UnpackMaxSize:        int64(*flagFileUnpackMaxSize) * 1024 * 1024,
+				UnpackMaxRatio:       *flagFileUnpackMaxRatio,
 				CalculateEntropy:     *flagCalcEntropy,
 				Quiet:                false,
 				PrintProgress:        false,
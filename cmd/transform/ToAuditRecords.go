@@ -70,7 +70,17 @@ var maltegoBaseConfig = collector.Config{
 		FlowTimeOut:                    defaults.FlowTimeOut,
 		CloseInactiveTimeOut:           defaults.CloseInactiveTimeout,
 		ClosePendingTimeOut:            defaults.ClosePendingTimeout,
+		UDPIdleTimeout:                 defaults.UDPIdleTimeout,
+		UDPMaxStreamSize:               defaults.UDPMaxStreamSize,
+		ReassemblyBudget:               defaults.ReassemblyBudget * 1024 * 1024,
+		ReassemblyConnMax:              defaults.ReassemblyConnMax * 1024 * 1024,
 		FileStorage:                    defaults.FileStorage,
+		FileStorageDedup:               false,
+		FileStorageQuota:               0,
+		UnpackArchives:                 false,
+		UnpackMaxDepth:                 defaults.UnpackMaxDepth,
+		UnpackMaxSize:                  defaults.UnpackMaxSize * 1024 * 1024,
+		UnpackMaxRatio:                 defaults.UnpackMaxRatio,
 		CalculateEntropy:               false,
 		SaveConns:                      true,
 		TCPDebug:                       false,
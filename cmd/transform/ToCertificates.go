@@ -0,0 +1,60 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dreadl0ck/maltego"
+	netmaltego "github.com/dreadl0ck/netcap/maltego"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+func toCertificates() {
+	netmaltego.CertificateTransform(
+		nil,
+		func(lt maltego.LocalTransform, trx *maltego.Transform, c *types.Certificate, min, max uint64, path string, ipaddr string) {
+			// only the leaf certificate identifies the server
+			if c.ServerIP == ipaddr && c.ChainIndex == 0 {
+				addCertificate(trx, c, path)
+			}
+		},
+	)
+}
+
+// addCertificate adds an entity for the certificate, identified by its SHA256 fingerprint.
+func addCertificate(trx *maltego.Transform, c *types.Certificate, path string) *maltego.Entity {
+	ent := addEntityWithPath(trx, "netcap.Certificate", c.Fingerprint, path)
+
+	ent.AddProperty("fingerprint", "Fingerprint", maltego.Strict, c.Fingerprint)
+	ent.AddProperty("subject", "Subject", maltego.Strict, c.Subject)
+	ent.AddProperty("issuer", "Issuer", maltego.Strict, c.Issuer)
+	ent.AddProperty("serial", "Serial Number", maltego.Strict, c.SerialNumber)
+	ent.AddProperty("notbefore", "Not Before", maltego.Strict, utils.UnixTimeToUTC(c.NotBefore))
+	ent.AddProperty("notafter", "Not After", maltego.Strict, utils.UnixTimeToUTC(c.NotAfter))
+	ent.AddProperty("selfsigned", "Self Signed", maltego.Strict, strconv.FormatBool(c.SelfSigned))
+	ent.AddProperty("expired", "Expired", maltego.Strict, strconv.FormatBool(c.Expired))
+
+	ent.AddDisplayInformation(
+		"Subject: "+c.Subject+"<br>"+
+			"Issuer: "+c.Issuer+"<br>"+
+			"Not After: "+utils.UnixTimeToUTC(c.NotAfter)+"<br>"+
+			"DNS Names: "+strings.Join(c.DNSNames, ", ")+"<br>",
+		"Certificate",
+	)
+
+	return ent
+}
@@ -41,6 +41,10 @@ func toFiles() {
 					}
 
 					di := "<h3>File</h3><p>Timestamp: " + utils.UnixTimeToUTC(file.Timestamp) + "</p><p>Source: " + file.Source + "</p><p>MD5: " + file.Hash + "</p><p>ContentType: " + file.ContentType + "</p><p>ContentTypeDetected: " + file.ContentTypeDetected + "</p><p>Host: " + file.Host + "</p><p>Length: " + strconv.Itoa(int(file.Length)) + "</p><p>Ident: " + file.Ident + "</p><p>SrcIP: " + file.SrcIP + "</p><p>DstIP: " + file.DstIP + "</p><p>SrcPort: " + strconv.FormatInt(int64(file.SrcPort), 10) + "</p><p>DstPort: " + strconv.FormatInt(int64(file.DstPort), 10) + "</p><p>Location: " + file.Location + "</p>"
+					if file.ParentSHA256 != "" {
+						di += "<p>Unpacked from: " + file.ParentSHA256 + "</p><p>Depth: " + strconv.Itoa(int(file.Depth)) + "</p>"
+					}
+
 					ent.AddDisplayInformation(di, "Netcap Info")
 
 					if filepath.IsAbs(file.Location) {
@@ -0,0 +1,43 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package transform
+
+import (
+	"strconv"
+
+	"github.com/dreadl0ck/maltego"
+	netmaltego "github.com/dreadl0ck/netcap/maltego"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+func toHostsForCertificate() {
+	netmaltego.CertificateTransform(
+		nil,
+		func(lt maltego.LocalTransform, trx *maltego.Transform, c *types.Certificate, min, max uint64, path string, ipaddr string) {
+			if c.Fingerprint != lt.Values["fingerprint"] {
+				return
+			}
+
+			ent := addEntityWithPath(trx, "netcap.IPAddr", c.ServerIP, path)
+			ent.AddProperty(netmaltego.PropertyIpAddr, netmaltego.PropertyIpAddrLabel, maltego.Strict, c.ServerIP)
+			ent.AddProperty("port", "Port", maltego.Strict, strconv.Itoa(int(c.ServerPort)))
+			ent.AddProperty("timestamp", "Timestamp", maltego.Strict, utils.UnixTimeToUTC(c.Timestamp))
+
+			if c.SNI != "" {
+				ent.AddDisplayInformation(c.SNI+"<br>", "Server Names")
+			}
+		},
+	)
+}
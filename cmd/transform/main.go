@@ -146,6 +146,9 @@ func Run() {
 		toConnectionsForPort,
 		toJA3Hashes,
 		toJA3SHashes,
+		toCertificates,
+		toHostsForCertificate,
+		toExpiredCertificates,
 		openConnectionInWireshark,
 		openFlowInWireshark,
 		openHostTrafficInWireshark,
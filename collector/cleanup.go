@@ -20,6 +20,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	netcapalert "github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/decoder/core"
 	"github.com/dreadl0ck/netcap/decoder/stream/alert"
 	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
 	"github.com/dreadl0ck/netcap/label/manager"
@@ -29,6 +31,7 @@ import (
 
 	"github.com/dreadl0ck/netcap/decoder/stream/tcp"
 	"github.com/dreadl0ck/netcap/resolvers"
+	"github.com/dreadl0ck/netcap/types"
 )
 
 // cleanup before leaving. closes all buffers and displays stats.
@@ -116,8 +119,16 @@ func (c *Collector) teardown() {
 		}
 	}
 
-	// flush all abstract decoders
+	// flush all abstract decoders, the alert decoder is closed last
+	var alertDecoder core.DecoderAPI
+
 	for _, d := range c.abstractDecoders {
+		if d.GetType() == types.Type_NC_Alert {
+			alertDecoder = d
+
+			continue
+		}
+
 		name, size := d.Destroy()
 		if size != 0 {
 			c.totalBytesWritten += size
@@ -125,6 +136,21 @@ func (c *Collector) teardown() {
 		}
 	}
 
+	// write pending alerts once all decoders have been torn down,
+	// so alerts emitted while destroying them are still suppressed and aggregated
+	if netcapalert.Instance != nil {
+		netcapalert.Instance.Stop()
+		netcapalert.Instance = nil
+	}
+
+	if alertDecoder != nil {
+		name, size := alertDecoder.Destroy()
+		if size != 0 {
+			c.totalBytesWritten += size
+			c.files[name] = humanize.Bytes(uint64(size))
+		}
+	}
+
 	if alert.SocketConn != nil {
 		err := alert.SocketConn.Close()
 		if err != nil {
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/analyze"
 	"github.com/dreadl0ck/netcap/encoder"
 	"github.com/dreadl0ck/netcap/io"
 
@@ -31,7 +33,9 @@ import (
 	"github.com/dreadl0ck/netcap/dpi"
 	"github.com/dreadl0ck/netcap/reassembly"
 	"github.com/dreadl0ck/netcap/resolvers"
+	"github.com/dreadl0ck/netcap/rule"
 	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/yara"
 )
 
 var errAborted = errors.New("operation aborted by user")
@@ -57,6 +61,48 @@ func (c *Collector) Init() (err error) {
 		io.InitLabelManager(c.config.Labels, c.config.DecoderConfig.Debug, c.config.Scatter, c.config.ScatterDuration)
 	}
 
+	// load detection rules
+	// anomaly detectors must be registered before the decoders create their audit record writers
+	if c.config.DecoderConfig.Rules != "" {
+		engine, errRules := rule.Load(c.config.DecoderConfig.Rules)
+		if errRules != nil {
+			return errRules
+		}
+
+		types.RegisterAnomalyDetector(engine, engine.Types()...)
+	}
+
+	// load YARA rules for scanning extracted files and TCP conversations
+	if c.config.DecoderConfig.YaraRules != "" {
+		yara.Instance, err = yara.Load(c.config.DecoderConfig.YaraRules)
+		if err != nil {
+			return err
+		}
+	}
+
+	// register builtin anomaly detectors
+	if c.config.DecoderConfig.Detectors != "" {
+		err = analyze.RegisterDetectors(c.config.DecoderConfig.Detectors, analyze.DefaultBaselineConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	// start alert deduplication
+	if c.config.DecoderConfig.AlertDeduplication {
+		key, errKey := alert.NewKeyFunc(strings.Split(c.config.DecoderConfig.AlertKey, ",")...)
+		if errKey != nil {
+			return errKey
+		}
+
+		alert.Instance = alert.NewManager(alert.Config{
+			Key:               key,
+			FlushInterval:     c.config.DecoderConfig.AlertFlushInterval,
+			SuppressionWindow: c.config.DecoderConfig.AlertSuppressionWindow,
+		})
+		alert.Instance.Start()
+	}
+
 	// create state machine options
 	tcp.StreamFactory.FSMOptions = reassembly.TCPSimpleFSMOptions{
 		SupportMissingEstablishment: c.config.DecoderConfig.AllowMissingInit,
@@ -218,6 +264,13 @@ func (c *Collector) Init() (err error) {
 
 	// wait for decoder init to finish
 	wg.Wait()
+
+	// DNS over TCP is handled by the DNS stream decoder, if connections are reassembled
+	for _, d := range c.streamDecoders {
+		if d.GetType() == types.Type_NC_DNS {
+			packet.SetDNSStreamDecoding(c.config.ReassembleConnections)
+		}
+	}
 	c.log.Info("initialized decoders",
 		zap.Int("packetDecoders", len(c.packetDecoders)),
 		zap.Int("streamDecoders", len(c.streamDecoders)),
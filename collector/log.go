@@ -18,6 +18,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/analyze"
 	"github.com/dreadl0ck/netcap/decoder/db"
 	"github.com/dreadl0ck/netcap/decoder/packet"
 	"github.com/dreadl0ck/netcap/decoder/stream/tcp"
@@ -26,6 +28,8 @@ import (
 	netio "github.com/dreadl0ck/netcap/io"
 	"github.com/dreadl0ck/netcap/logger"
 	"github.com/dreadl0ck/netcap/resolvers"
+	"github.com/dreadl0ck/netcap/rule"
+	"github.com/dreadl0ck/netcap/yara"
 )
 
 // initLogging can be used to open the logfile before calling Init()
@@ -94,6 +98,10 @@ func (c *Collector) initLogging() error {
 	}
 
 	packet.SetDecoderLogger(lDecoder)
+	rule.SetLogger(lDecoder)
+	yara.SetLogger(lDecoder)
+	analyze.SetLogger(lDecoder)
+	alert.SetLogger(lDecoder)
 
 	lDB, dbLogFile, err := logger.InitZapLogger(c.config.DecoderConfig.Out, "db", c.config.DecoderConfig.Debug)
 	if err != nil {
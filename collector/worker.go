@@ -81,6 +81,8 @@ func (c *Collector) worker(assembler *reassembly.Assembler) chan gopacket.Packet
 					ctx.SrcPort = utils.DecodePort(transportLayer.TransportFlow().Src().Raw())
 					ctx.DstPort = utils.DecodePort(transportLayer.TransportFlow().Dst().Raw())
 				}
+
+				ctx.CommunityID = utils.CommunityIDFromPacket(pkt)
 			}
 
 			// iterate over all layers
@@ -199,7 +201,7 @@ func (c *Collector) initWorkers() []chan gopacket.Packet {
 
 	// create assemblers
 	for i := range workers {
-		a := reassembly.NewAssembler(tcp.GetStreamPool())
+		a := tcp.NewAssembler()
 		c.assemblers = append(c.assemblers, a)
 		workers[i] = c.worker(a)
 	}
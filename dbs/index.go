@@ -34,6 +34,7 @@ import (
 	"github.com/blevesearch/bleve"
 	"github.com/dustin/go-humanize"
 
+	"github.com/dreadl0ck/netcap/decoder/db"
 	vuln "github.com/dreadl0ck/netcap/decoder/stream/vulnerability"
 	"github.com/dreadl0ck/netcap/utils"
 )
@@ -325,6 +326,9 @@ func IndexData(in string, out string, buildPath string, nvdIndexStart int, verbo
 		var (
 			years = yearRange(nvdIndexStart, time.Now().Year())
 			total int
+
+			// CVEs with the CPE names of the vulnerable software, for precise matching of versions
+			cves []*vuln.CVE
 		)
 
 		for _, year := range years {
@@ -431,6 +435,17 @@ func IndexData(in string, out string, buildPath string, nvdIndexStart int, verbo
 							fmt.Println(err)
 						}
 
+						if matches := vuln.VulnerableCPEs(v.Configurations.Nodes); len(matches) > 0 {
+							cves = append(cves, &vuln.CVE{
+								ID:           e.ID,
+								Description:  e.Description,
+								Severity:     e.Severity,
+								V2Score:      e.V2Score,
+								AccessVector: e.AccessVector,
+								Matches:      matches,
+							})
+						}
+
 						break
 					}
 				}
@@ -444,6 +459,15 @@ func IndexData(in string, out string, buildPath string, nvdIndexStart int, verbo
 		}
 
 		fmt.Println("loaded", total, "NVD CVEs in", time.Since(start))
+
+		cpePath := filepath.Join(out, db.VulnerabilityCPEDBName)
+
+		err := vuln.WriteCPEDatabase(cpePath, cves)
+		if err != nil {
+			log.Fatal("failed to write CPE database: ", err)
+		}
+
+		fmt.Println("wrote", len(cves), "CVEs with CPE names to", cpePath)
 	default:
 		log.Fatal("unknown keyword", in)
 	}
@@ -56,7 +56,17 @@ var DefaultConfig = &Config{
 	FlowTimeOut:                10 * time.Second,
 	CloseInactiveTimeOut:       24 * time.Hour,
 	ClosePendingTimeOut:        5 * time.Second,
+	UDPIdleTimeout:             defaults.UDPIdleTimeout,
+	UDPMaxStreamSize:           defaults.UDPMaxStreamSize,
+	ReassemblyBudget:           defaults.ReassemblyBudget * 1024 * 1024,
+	ReassemblyConnMax:          defaults.ReassemblyConnMax * 1024 * 1024,
 	FileStorage:                defaults.FileStorage,
+	FileStorageDedup:           false,
+	FileStorageQuota:           0,
+	UnpackArchives:             false,
+	UnpackMaxDepth:             defaults.UnpackMaxDepth,
+	UnpackMaxSize:              defaults.UnpackMaxSize * 1024 * 1024,
+	UnpackMaxRatio:             defaults.UnpackMaxRatio,
 	CalculateEntropy:           false,
 	SaveConns:                  false,
 	TCPDebug:                   false,
@@ -69,6 +79,10 @@ var DefaultConfig = &Config{
 	RemoveClosedStreams:        false,
 	CompressionBlockSize:       defaults.CompressionBlockSize,
 	CompressionLevel:           defaults.CompressionLevel,
+	AlertDeduplication:         true,
+	AlertKey:                   defaults.AlertKey,
+	AlertFlushInterval:         defaults.AlertFlushInterval,
+	AlertSuppressionWindow:     defaults.AlertSuppressionWindow,
 }
 
 // Config contains configuration parameters
@@ -86,6 +100,27 @@ type Config struct {
 	// CustomRegex to use for credentials harvester
 	CustomRegex string
 
+	// Path to a YAML file with detection rules that will be evaluated for each audit record
+	Rules string
+
+	// Path to a YARA rule file or a directory with rule files, used to scan extracted files and TCP conversations
+	YaraRules string
+
+	// Comma separated list of builtin anomaly detectors that will be enabled
+	Detectors string
+
+	// Deduplicate alerts before writing them
+	AlertDeduplication bool
+
+	// Comma separated list of alert fields used to identify duplicate alerts
+	AlertKey string
+
+	// Interval for writing deduplicated alerts
+	AlertFlushInterval time.Duration
+
+	// Minimum amount of time between writing the same alert again
+	AlertSuppressionWindow time.Duration
+
 	// Will create a memory dump at the specified path for debugging and profiling
 	MemProfile string
 
@@ -98,6 +133,24 @@ type Config struct {
 	// If a path is set files will be extracted and written to the specified path
 	FileStorage string
 
+	// Store each unique extracted file once under its SHA-256 hash, and record all occurrences in a manifest
+	FileStorageDedup bool
+
+	// Maximum size of the deduplicated file storage in bytes, zero disables the limit
+	FileStorageQuota int64
+
+	// Recursively unpack zip, tar, gzip and bzip2 archives from extracted files, and write a file record for each member
+	UnpackArchives bool
+
+	// Maximum nesting depth of unpacked archives
+	UnpackMaxDepth int
+
+	// Maximum number of bytes unpacked from a single extracted file in total, including nested archives
+	UnpackMaxSize int64
+
+	// Maximum ratio between the unpacked and the compressed size of an archive member
+	UnpackMaxRatio int
+
 	// Number of packets to arrive until the connections are checked for timeouts
 	ConnFlushInterval int
 
@@ -125,6 +178,18 @@ type Config struct {
 	// Close streams with pending bytes after
 	ClosePendingTimeOut time.Duration
 
+	// Flush UDP conversations that did not receive any data after, zero disables flushing before teardown
+	UDPIdleTimeout time.Duration
+
+	// Flush UDP conversations once they contain more bytes than this, zero disables the limit
+	UDPMaxStreamSize int
+
+	// Maximum number of bytes buffered for TCP reassembly and conversation data of all connections, zero disables the limit
+	ReassemblyBudget int64
+
+	// Maximum number of bytes buffered for the conversation data of a single TCP connection, zero disables the limit
+	ReassemblyConnMax int64
+
 	// Number of packets to arrive until the flows are checked for timeouts
 	FlowFlushInterval int
 
@@ -187,6 +252,12 @@ type Config struct {
 	// Discard all data and write nothing to disk
 	Null bool
 
+	// Write to all enabled output formats at once, instead of only the first one
+	FanOut bool
+
+	// Rotation of audit record files
+	io.RotationConfig
+
 	// Add context to supported audit records
 	AddContext bool
 
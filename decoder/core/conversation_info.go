@@ -27,4 +27,7 @@ type ConversationInfo struct {
 	ServerIP   string
 	ClientPort int32
 	ServerPort int32
+
+	// CommunityID is the Community ID flow hash of the conversation
+	CommunityID string
 }
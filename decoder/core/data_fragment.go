@@ -14,13 +14,15 @@
 package core
 
 import (
+	"time"
+
 	"github.com/dreadl0ck/gopacket"
 
 	"github.com/dreadl0ck/netcap/reassembly"
 )
 
-// dataFragment describes functionality of encapsulation structures for network data fragments.
-type dataFragment interface {
+// DataFragment describes functionality of encapsulation structures for network data fragments.
+type DataFragment interface {
 	Raw() []byte
 	Context() reassembly.AssemblerContext
 	Direction() reassembly.TCPFlowDirection
@@ -29,3 +31,13 @@ type dataFragment interface {
 	Network() gopacket.Flow
 	Transport() gopacket.Flow
 }
+
+// FragmentTimestamp returns the capture timestamp of the data fragment.
+// TCP fragments carry the timestamp in the assembler context.
+func FragmentTimestamp(d DataFragment) time.Time {
+	if ctx := d.Context(); ctx != nil {
+		return ctx.GetCaptureInfo().Timestamp
+	}
+
+	return d.CaptureInfo().Timestamp
+}
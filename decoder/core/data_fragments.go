@@ -19,7 +19,7 @@ import (
 )
 
 // DataFragments implements sort.Interface to sort data fragments based on their timestamps.
-type DataFragments []dataFragment
+type DataFragments []DataFragment
 
 // Size returns the fragments total data size.
 func (d DataFragments) Size() int {
@@ -35,7 +35,7 @@ type StreamData struct {
 	Trans              gopacket.Flow
 }
 
-// dataFragment interface implementation
+// DataFragment interface implementation
 
 // Raw returns the raw byte slice that makes up the data fragment.
 func (s *StreamData) Raw() []byte {
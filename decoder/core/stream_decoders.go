@@ -52,3 +52,16 @@ type StreamDecoderInterface interface {
 	// Decode parses the stream according to the identified protocol.
 	Decode()
 }
+
+// StreamingDecoderInterface is implemented by stream decoders that process a connection incrementally,
+// instead of waiting until the connection has been closed to decode the entire conversation.
+// The data fragments are passed to Consume as they are delivered by the reassembly,
+// Decode is invoked once the connection has been closed, to process any remaining state.
+// Streaming decoders receive all data via Consume and must not access the Data of the conversation.
+type StreamingDecoderInterface interface {
+	StreamDecoderInterface
+
+	// Consume processes the next fragment of the conversation,
+	// the fragments of each direction are passed in order.
+	Consume(fragment DataFragment)
+}
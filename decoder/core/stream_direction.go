@@ -0,0 +1,72 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package core
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// StreamDirection contains the data sent into one direction of a connection,
+// and the capture timestamps at which the data has been added.
+type StreamDirection struct {
+	data bytes.Buffer
+
+	// start offset and capture timestamp for each data fragment
+	offsets []int
+	times   []time.Time
+}
+
+// SplitDirections collects the data sent by the client and the server.
+func SplitDirections(data DataFragments) (client, server *StreamDirection) {
+	client, server = &StreamDirection{}, &StreamDirection{}
+
+	for _, d := range data {
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			client.Write(d.Raw(), FragmentTimestamp(d))
+		} else {
+			server.Write(d.Raw(), FragmentTimestamp(d))
+		}
+	}
+
+	return client, server
+}
+
+// Write appends a data fragment captured at the given time.
+func (s *StreamDirection) Write(data []byte, ts time.Time) {
+	s.offsets = append(s.offsets, s.data.Len())
+	s.times = append(s.times, ts)
+	s.data.Write(data)
+}
+
+// Bytes returns the data sent into the direction.
+func (s *StreamDirection) Bytes() []byte {
+	return s.data.Bytes()
+}
+
+// TimeAt returns the capture timestamp for the fragment that contains the offset.
+func (s *StreamDirection) TimeAt(offset int) time.Time {
+	i := sort.Search(len(s.offsets), func(i int) bool {
+		return s.offsets[i] > offset
+	})
+
+	if i == 0 {
+		return time.Time{}
+	}
+
+	return s.times[i-1]
+}
@@ -17,7 +17,11 @@ var (
 
 	// VulnerabilityDBName is the name of the database directory on disk
 	VulnerabilityDBName = "nvd.bleve"
-	dbLog               = zap.NewNop()
+
+	// VulnerabilityCPEDBName is the name of the file with the CPE names of vulnerable software
+	VulnerabilityCPEDBName = "nvd-cpe.json.gz"
+
+	dbLog = zap.NewNop()
 )
 
 // SetLogger will set the logger for this package.
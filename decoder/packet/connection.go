@@ -182,6 +182,7 @@ func handlePacket(p gopacket.Packet) proto.Message {
 		co.TimestampLast = p.Metadata().Timestamp.UnixNano()
 		co.TotalSize = int32(p.Metadata().Length)
 		co.NumPackets = 1
+		co.CommunityID = utils.CommunityIDFromPacket(p)
 		trackTCPStats(co, p)
 
 		if ll != nil {
@@ -51,6 +51,9 @@ type (
 		Layer       gopacket.LayerType
 		Handler     goPacketDecoderHandler
 
+		// optional, skips packets that are handled elsewhere
+		skip func(p gopacket.Packet) bool
+
 		writer io.AuditRecordWriter
 		Type   types.Type
 		export bool
@@ -181,6 +184,7 @@ func InitGoPacketDecoders(c *config.Config) (decoders map[gopacket.LayerType][]*
 				Chan:       c.Chan,
 				Null:       c.Null,
 				Elastic:    c.Elastic,
+				FanOut:     c.FanOut,
 				ElasticConfig: io.ElasticConfig{
 					ElasticAddrs:   c.ElasticAddrs,
 					ElasticUser:    c.ElasticUser,
@@ -188,6 +192,7 @@ func InitGoPacketDecoders(c *config.Config) (decoders map[gopacket.LayerType][]*
 					KibanaEndpoint: c.KibanaEndpoint,
 					BulkSize:       c.BulkSizeGoPacket,
 				},
+				RotationConfig:       c.RotationConfig,
 				Name:                 filename,
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
@@ -241,6 +246,10 @@ func newGoPacketDecoder(nt types.Type, lt gopacket.LayerType, description string
 // this calls the handler function of the decoder
 // and writes the serialized protobuf into the data pipe.
 func (dec *GoPacketDecoder) Decode(ctx *types.PacketContext, p gopacket.Packet, l gopacket.Layer) error {
+	if dec.skip != nil && dec.skip(p) {
+		return nil
+	}
+
 	record := dec.Handler(l, p.Metadata().Timestamp.UnixNano())
 	if record != nil {
 
@@ -23,6 +23,7 @@ import (
 	"github.com/dreadl0ck/tlsx"
 	"github.com/gogo/protobuf/proto"
 
+	streamtls "github.com/dreadl0ck/netcap/decoder/stream/tls"
 	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
 	"github.com/dreadl0ck/netcap/dpi"
 	"github.com/dreadl0ck/netcap/resolvers"
@@ -36,6 +37,13 @@ var (
 	LocalDNS = true
 )
 
+// maximum number of certificates stored for a single IP profile.
+const maxProfileCertificates = 100
+
+func init() {
+	streamtls.ServerCertificateHandler = addServerCertificate
+}
+
 // atomicIPProfileMap contains all connections and provides synchronized access.
 type atomicIPProfileMap struct {
 	// SrcIP to DeviceProfiles
@@ -221,6 +229,56 @@ func getIPProfile(ipAddr string, i *decoderutils.PacketInfo, source bool) *ipPro
 	return p
 }
 
+// addClientHello adds the SNI and JA3 fingerprint of a ClientHello to an existing profile.
+// This is used for handshakes that are not visible on the packet level, e.g. because they are carried in encrypted QUIC Initial packets.
+func addClientHello(ipAddr, sni, ja3Hash string) {
+	ipProfiles.Lock()
+	p, ok := ipProfiles.Items[ipAddr]
+	ipProfiles.Unlock()
+
+	if !ok {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if sni != "" {
+		p.SNIs[sni]++
+	}
+
+	if ja3Hash != "" {
+		if _, ok = p.Ja3Hashes[ja3Hash]; !ok {
+			p.Ja3Hashes[ja3Hash] = resolvers.LookupJa3(ja3Hash)
+		}
+	}
+}
+
+// addServerCertificate adds a certificate that was presented by the host during a TLS handshake to its profile.
+// Certificates of hosts without a profile are ignored.
+func addServerCertificate(ip string, cert *types.TLSCertificate) {
+	ipProfiles.Lock()
+	p, ok := ipProfiles.Items[ip]
+	ipProfiles.Unlock()
+
+	if !ok {
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.Certificates == nil {
+		p.Certificates = make(map[string]*types.TLSCertificate)
+	}
+
+	if _, exists := p.Certificates[cert.Fingerprint]; !exists && len(p.Certificates) >= maxProfileCertificates {
+		return
+	}
+
+	p.Certificates[cert.Fingerprint] = cert
+}
+
 func doSrcPortUpdate(p *ipProfile, srcPort int32, layerType string, dataLen uint64) {
 	var found bool
 
@@ -0,0 +1,55 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestAddServerCertificate(t *testing.T) {
+	const ip = "192.168.1.10"
+
+	p := &ipProfile{IPProfile: &types.IPProfile{Addr: ip}}
+
+	ipProfiles.Lock()
+	ipProfiles.Items[ip] = p
+	ipProfiles.Unlock()
+
+	defer func() {
+		ipProfiles.Lock()
+		delete(ipProfiles.Items, ip)
+		ipProfiles.Unlock()
+	}()
+
+	// hosts without a profile are ignored
+	addServerCertificate("192.168.1.11", &types.TLSCertificate{Fingerprint: "a"})
+
+	for i := 0; i < 2*maxProfileCertificates; i++ {
+		addServerCertificate(ip, &types.TLSCertificate{Fingerprint: strconv.Itoa(i)})
+	}
+
+	// known certificates are updated
+	addServerCertificate(ip, &types.TLSCertificate{Fingerprint: "0", Subject: "CN=netcap.test"})
+
+	if len(p.Certificates) != maxProfileCertificates {
+		t.Fatal("expected", maxProfileCertificates, "certificates, got", len(p.Certificates))
+	}
+
+	if p.Certificates["0"].Subject != "CN=netcap.test" {
+		t.Fatal("expected certificate to be updated", p.Certificates["0"])
+	}
+}
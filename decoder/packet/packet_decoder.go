@@ -202,6 +202,7 @@ func InitPacketDecoders(c *config.Config) (decoders []DecoderAPI, err error) {
 				Type:       dec.GetType(),
 				Null:       c.Null,
 				Elastic:    c.Elastic,
+				FanOut:     c.FanOut,
 				ElasticConfig: io.ElasticConfig{
 					ElasticAddrs:   c.ElasticAddrs,
 					ElasticUser:    c.ElasticUser,
@@ -209,6 +210,7 @@ func InitPacketDecoders(c *config.Config) (decoders []DecoderAPI, err error) {
 					KibanaEndpoint: c.KibanaEndpoint,
 					BulkSize:       c.BulkSizeCustom,
 				},
+				RotationConfig:       c.RotationConfig,
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
 				Out:                  c.Out,
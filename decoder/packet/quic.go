@@ -0,0 +1,255 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/software"
+	streamtls "github.com/dreadl0ck/netcap/decoder/stream/tls"
+	"github.com/dreadl0ck/netcap/resolvers"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+const (
+	// maximum number of handshakes that are tracked at the same time.
+	maxQUICHandshakes = 10000
+
+	// handshakes without new Initial packets are evicted after this duration.
+	quicHandshakeTimeout = time.Minute
+
+	// maximum size of the reassembled CRYPTO stream.
+	maxQUICCryptoStreamSize = 1 << 16
+
+	serviceQUIC = "QUIC"
+)
+
+// quicHandshake collects the CRYPTO frames from the Initial packets sent by a client,
+// since a large ClientHello can be split across multiple Initial packets.
+type quicHandshake struct {
+	frames     []*quicCryptoFrame
+	size       int
+	numPackets int32
+	lastSeen   time.Time
+
+	// set once the ClientHello has been extracted, retransmissions are ignored afterwards
+	done bool
+}
+
+// atomicQUICHandshakeMap contains all QUIC handshakes and provides synchronized access.
+type atomicQUICHandshakeMap struct {
+	// client address and destination connection ID to handshakes
+	Items map[string]*quicHandshake
+	sync.Mutex
+}
+
+var quicHandshakes = &atomicQUICHandshakeMap{
+	Items: make(map[string]*quicHandshake),
+}
+
+var quicDecoder = newPacketDecoder(
+	types.Type_NC_QUIC,
+	"QUIC",
+	"QUIC is a UDP based transport protocol, the TLS ClientHello is extracted from the encrypted Initial packets of the client",
+	nil,
+	func(p gopacket.Packet) proto.Message {
+		udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || len(udp.Payload) < quicMinInitialDatagramSize {
+			return nil
+		}
+
+		nl := p.NetworkLayer()
+		if nl == nil {
+			return nil
+		}
+
+		var (
+			data = udp.Payload
+			ts   = p.Metadata().Timestamp
+		)
+
+		// the client can coalesce multiple QUIC packets into a single datagram
+		for len(data) > 0 {
+			pkt, n, err := parseQUICInitial(data)
+			if err != nil {
+				return nil
+			}
+
+			if pkt != nil {
+				return quicClientHello(pkt, nl.NetworkFlow(), udp, ts)
+			}
+
+			data = data[n:]
+		}
+
+		return nil
+	},
+	nil,
+)
+
+// quicClientHello adds the CRYPTO frames of the Initial packet to the handshake
+// and returns a QUIC audit record once the ClientHello is complete.
+func quicClientHello(pkt *quicInitialPacket, flow gopacket.Flow, udp *layers.UDP, ts time.Time) proto.Message {
+	var (
+		srcIP   = flow.Src().String()
+		srcPort = strconv.Itoa(int(udp.SrcPort))
+		dcid    = hex.EncodeToString(pkt.dcid)
+		ident   = srcIP + ":" + srcPort + "/" + dcid
+	)
+
+	quicHandshakes.Lock()
+
+	h, ok := quicHandshakes.Items[ident]
+	if !ok {
+		if len(quicHandshakes.Items) >= maxQUICHandshakes {
+			quicHandshakes.evict(ts)
+
+			if len(quicHandshakes.Items) >= maxQUICHandshakes {
+				quicHandshakes.Unlock()
+
+				return nil
+			}
+		}
+
+		h = &quicHandshake{}
+		quicHandshakes.Items[ident] = h
+	}
+
+	if h.done {
+		quicHandshakes.Unlock()
+
+		return nil
+	}
+
+	h.numPackets++
+	h.lastSeen = ts
+
+	for _, f := range pkt.frames {
+		if h.size+len(f.data) > maxQUICCryptoStreamSize {
+			break
+		}
+
+		h.frames = append(h.frames, f)
+		h.size += len(f.data)
+	}
+
+	msg := h.clientHello()
+	if msg == nil {
+		quicHandshakes.Unlock()
+
+		return nil
+	}
+
+	// release the buffered frames
+	h.done = true
+	h.frames = nil
+	numPackets := h.numPackets
+
+	quicHandshakes.Unlock()
+
+	hello, ok := streamtls.ParseClientHello(msg, true)
+	if !ok {
+		return nil
+	}
+
+	addClientHello(srcIP, hello.SNI, hello.JA3)
+
+	communityID := utils.CommunityIDFromFlows(flow, udp.TransportFlow())
+
+	if product := resolvers.LookupJa3(hello.JA3); product != "" {
+		software.WriteSoftware([]*software.AtomicSoftware{
+			{
+				Software: &types.Software{
+					Timestamp:    ts.UnixNano(),
+					Product:      product,
+					SourceName:   "QUIC JA3",
+					SourceData:   hello.JA3,
+					Service:      serviceQUIC,
+					Flows:        []string{srcIP + ":" + srcPort + "->" + flow.Dst().String() + ":" + strconv.Itoa(int(udp.DstPort))},
+					CommunityIDs: []string{communityID},
+				},
+			},
+		}, nil)
+	}
+
+	return &types.QUIC{
+		Timestamp:   ts.UnixNano(),
+		SrcIP:       srcIP,
+		DstIP:       flow.Dst().String(),
+		SrcPort:     int32(udp.SrcPort),
+		DstPort:     int32(udp.DstPort),
+		Version:     pkt.version.name,
+		DCID:        dcid,
+		SCID:        hex.EncodeToString(pkt.scid),
+		TokenLength: int32(pkt.tokenLength),
+		SNI:         hello.SNI,
+		ALPNs:       hello.ALPNs,
+		TLSVersion:  hello.Version,
+		JA3:         hello.JA3,
+		JA4:         hello.JA4,
+		NumPackets:  numPackets,
+		CommunityID: communityID,
+	}
+}
+
+// clientHello reassembles the CRYPTO stream and returns the ClientHello handshake message once it is complete.
+func (h *quicHandshake) clientHello() []byte {
+	sort.SliceStable(h.frames, func(i, j int) bool {
+		return h.frames[i].offset < h.frames[j].offset
+	})
+
+	var buf []byte
+
+	for _, f := range h.frames {
+		// stop at the first gap
+		if f.offset > uint64(len(buf)) {
+			break
+		}
+
+		// skip data that has been seen already
+		if end := f.offset + uint64(len(f.data)); end > uint64(len(buf)) {
+			buf = append(buf, f.data[uint64(len(buf))-f.offset:]...)
+		}
+	}
+
+	if len(buf) < 4 {
+		return nil
+	}
+
+	length := 4 + (int(buf[1])<<16 | int(buf[2])<<8 | int(buf[3]))
+	if len(buf) < length {
+		return nil
+	}
+
+	return buf[:length]
+}
+
+// evict removes handshakes that did not receive Initial packets within the timeout.
+// The caller must hold the lock.
+func (a *atomicQUICHandshakeMap) evict(now time.Time) {
+	for ident, h := range a.Items {
+		if now.Sub(h.lastSeen) > quicHandshakeTimeout {
+			delete(a.Items, ident)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/hkdf"
+)
+
+// QUIC versions with support for decrypting Initial packets.
+const (
+	quicVersion1       uint32 = 0x00000001
+	quicVersion2       uint32 = 0x6b3343cf
+	quicVersionDraft29 uint32 = 0xff00001d
+)
+
+const (
+	// maximum length of a connection ID, see RFC 9000, Section 17.2.
+	quicMaxConnectionIDLength = 20
+
+	// minimum size of UDP datagrams that carry client Initial packets, see RFC 9000, Section 14.1.
+	quicMinInitialDatagramSize = 1200
+
+	// the header protection sample starts 4 bytes after the packet number offset and is 16 bytes long.
+	quicSampleOffset = 4
+	quicSampleLength = 16
+)
+
+// QUIC frame types that can appear in Initial packets.
+const (
+	quicFramePadding            = 0x00
+	quicFramePing               = 0x01
+	quicFrameACK                = 0x02
+	quicFrameACKECN             = 0x03
+	quicFrameCrypto             = 0x06
+	quicFrameConnectionClose    = 0x1c
+	quicFrameConnectionCloseApp = 0x1d
+)
+
+// limits for the CRYPTO frames in a single Initial packet.
+const (
+	quicMaxCryptoFrames    = 64
+	quicMaxCryptoFrameSize = 1 << 16
+)
+
+var (
+	errQUICShortHeader        = errors.New("not a QUIC long header packet")
+	errQUICUnsupportedVersion = errors.New("unsupported QUIC version")
+	errQUICInvalidPacket      = errors.New("invalid QUIC packet")
+	errQUICInvalidFrame       = errors.New("invalid QUIC frame")
+)
+
+// quicVersion contains the parameters for protecting Initial packets of a QUIC version.
+type quicVersion struct {
+	name        string
+	salt        []byte
+	labelPrefix string
+	initialType uint8
+	retryType   uint8
+}
+
+var quicVersions = map[uint32]*quicVersion{
+	// RFC 9001, Section 5.2
+	quicVersion1: {
+		name:        "1",
+		salt:        []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a},
+		labelPrefix: "quic",
+		initialType: 0,
+		retryType:   3,
+	},
+	// RFC 9369, Section 3.3
+	quicVersion2: {
+		name:        "2",
+		salt:        []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9},
+		labelPrefix: "quicv2",
+		initialType: 1,
+		retryType:   0,
+	},
+	quicVersionDraft29: {
+		name:        "draft-29",
+		salt:        []byte{0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97, 0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99},
+		labelPrefix: "quic",
+		initialType: 0,
+		retryType:   3,
+	},
+}
+
+// quicInitialPacket is a decrypted QUIC Initial packet.
+type quicInitialPacket struct {
+	version     *quicVersion
+	dcid        []byte
+	scid        []byte
+	tokenLength int
+	frames      []*quicCryptoFrame
+}
+
+// quicCryptoFrame is a fragment of the TLS handshake carried in a CRYPTO frame.
+type quicCryptoFrame struct {
+	offset uint64
+	data   []byte
+}
+
+// parseQUICInitial parses the first QUIC packet in data and decrypts it with the client Initial keys.
+// Multiple QUIC packets can be coalesced into a single datagram,
+// the number of bytes that belong to the first packet is returned as well.
+// For packets other than Initial packets, the returned packet is nil.
+func parseQUICInitial(data []byte) (*quicInitialPacket, int, error) {
+	var (
+		s       = cryptobyte.String(data)
+		first   uint8
+		version uint32
+		dcid    cryptobyte.String
+		scid    cryptobyte.String
+	)
+
+	if !s.ReadUint8(&first) || first&0xc0 != 0xc0 {
+		return nil, 0, errQUICShortHeader
+	}
+
+	if !s.ReadUint32(&version) {
+		return nil, 0, errQUICInvalidPacket
+	}
+
+	v, ok := quicVersions[version]
+	if !ok {
+		return nil, 0, errQUICUnsupportedVersion
+	}
+
+	if !s.ReadUint8LengthPrefixed(&dcid) || len(dcid) > quicMaxConnectionIDLength ||
+		!s.ReadUint8LengthPrefixed(&scid) || len(scid) > quicMaxConnectionIDLength {
+		return nil, 0, errQUICInvalidPacket
+	}
+
+	var (
+		typ         = (first >> 4) & 0x03
+		tokenLength uint64
+		length      uint64
+	)
+
+	switch typ {
+	case v.retryType:
+		// retry packets have no length field and extend to the end of the datagram
+		return nil, len(data), nil
+	case v.initialType:
+		if !readQUICVarint(&s, &tokenLength) || !s.Skip(int(tokenLength)) {
+			return nil, 0, errQUICInvalidPacket
+		}
+	}
+
+	if !readQUICVarint(&s, &length) || length > uint64(len(s)) {
+		return nil, 0, errQUICInvalidPacket
+	}
+
+	var (
+		pnOffset = len(data) - len(s)
+		end      = pnOffset + int(length)
+	)
+
+	if typ != v.initialType {
+		return nil, end, nil
+	}
+
+	if int(length) < quicSampleOffset+quicSampleLength {
+		return nil, 0, errQUICInvalidPacket
+	}
+
+	payload, err := v.decryptInitial(data[:end], pnOffset, dcid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	frames, err := parseQUICFrames(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &quicInitialPacket{
+		version:     v,
+		dcid:        dcid,
+		scid:        scid,
+		tokenLength: int(tokenLength),
+		frames:      frames,
+	}, end, nil
+}
+
+// clientInitialKeys derives the key, IV and header protection key for Initial packets sent by the client
+// from the destination connection ID chosen by the client, see RFC 9001, Section 5.2.
+func (v *quicVersion) clientInitialKeys(dcid []byte) (key, iv, hp []byte) {
+	var (
+		initialSecret = hkdf.Extract(sha256.New, dcid, v.salt)
+		clientSecret  = hkdfExpandLabel(initialSecret, "client in", sha256.Size)
+	)
+
+	return hkdfExpandLabel(clientSecret, v.labelPrefix+" key", 16),
+		hkdfExpandLabel(clientSecret, v.labelPrefix+" iv", 12),
+		hkdfExpandLabel(clientSecret, v.labelPrefix+" hp", 16)
+}
+
+// decryptInitial removes the header protection and decrypts the payload of an Initial packet sent by the client.
+func (v *quicVersion) decryptInitial(data []byte, pnOffset int, dcid []byte) ([]byte, error) {
+	var (
+		key, iv, hp = v.clientInitialKeys(dcid)
+
+		// do not modify the packet data
+		pkt  = append([]byte(nil), data...)
+		mask = make([]byte, aes.BlockSize)
+	)
+
+	hpCipher, err := aes.NewCipher(hp)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := pkt[pnOffset+quicSampleOffset : pnOffset+quicSampleOffset+quicSampleLength]
+	hpCipher.Encrypt(mask, sample)
+
+	// long headers protect the lower four bits of the first byte
+	pkt[0] ^= mask[0] & 0x0f
+
+	var (
+		pnLength = int(pkt[0]&0x03) + 1
+		pn       uint64
+	)
+
+	for i := 0; i < pnLength; i++ {
+		pkt[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(pkt[pnOffset+i])
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// the nonce is the IV combined with the packet number
+	nonce := iv
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+
+	return aead.Open(nil, nonce, pkt[pnOffset+pnLength:], pkt[:pnOffset+pnLength])
+}
+
+// parseQUICFrames collects the CRYPTO frames from the payload of an Initial packet.
+func parseQUICFrames(payload []byte) ([]*quicCryptoFrame, error) {
+	var (
+		s      = cryptobyte.String(payload)
+		frames []*quicCryptoFrame
+	)
+
+	for !s.Empty() {
+		var typ uint64
+		if !readQUICVarint(&s, &typ) {
+			return nil, errQUICInvalidFrame
+		}
+
+		switch typ {
+		case quicFramePadding, quicFramePing:
+		case quicFrameACK, quicFrameACKECN:
+			var largest, delay, numRanges, firstRange uint64
+			if !readQUICVarint(&s, &largest) || !readQUICVarint(&s, &delay) ||
+				!readQUICVarint(&s, &numRanges) || !readQUICVarint(&s, &firstRange) {
+				return nil, errQUICInvalidFrame
+			}
+
+			// gap and length for each range
+			values := 2 * numRanges
+			if typ == quicFrameACKECN {
+				values += 3
+			}
+
+			for i := uint64(0); i < values; i++ {
+				var val uint64
+				if !readQUICVarint(&s, &val) {
+					return nil, errQUICInvalidFrame
+				}
+			}
+		case quicFrameCrypto:
+			var (
+				offset, length uint64
+				data           []byte
+			)
+
+			if !readQUICVarint(&s, &offset) || !readQUICVarint(&s, &length) ||
+				length > quicMaxCryptoFrameSize || !s.ReadBytes(&data, int(length)) {
+				return nil, errQUICInvalidFrame
+			}
+
+			if len(frames) == quicMaxCryptoFrames {
+				return nil, errQUICInvalidFrame
+			}
+
+			frames = append(frames, &quicCryptoFrame{offset: offset, data: data})
+		case quicFrameConnectionClose, quicFrameConnectionCloseApp:
+			// the connection is closed, nothing of interest follows
+			return frames, nil
+		default:
+			return nil, errQUICInvalidFrame
+		}
+	}
+
+	return frames, nil
+}
+
+// readQUICVarint reads a variable length integer, see RFC 9000, Section 16.
+// The two most significant bits of the first byte encode the length.
+func readQUICVarint(s *cryptobyte.String, out *uint64) bool {
+	var first uint8
+	if !s.ReadUint8(&first) {
+		return false
+	}
+
+	v := uint64(first & 0x3f)
+
+	for n := 1<<(first>>6) - 1; n > 0; n-- {
+		var b uint8
+		if !s.ReadUint8(&b) {
+			return false
+		}
+
+		v = v<<8 | uint64(b)
+	}
+
+	*out = v
+
+	return true
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from TLS 1.3 with an empty context, see RFC 8446, Section 7.1.
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	var b cryptobyte.Builder
+
+	b.AddUint16(uint16(length))
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte("tls13 " + label))
+	})
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {})
+
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, secret, b.BytesOrPanic()), out); err != nil {
+		panic(err)
+	}
+
+	return out
+}
@@ -0,0 +1,280 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package packet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"golang.org/x/crypto/cryptobyte"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// test vectors from RFC 9001, Appendix A.1 and RFC 9369, Appendix A.1.
+func TestQUICClientInitialKeys(t *testing.T) {
+	dcid := unhex(t, "8394c8f03e515708")
+
+	for version, expected := range map[uint32][3]string{
+		quicVersion1: {"1f369613dd76d5467730efcbe3b1a22d", "fa044b2f42a3fd3b46fb255c", "9f50449e04a0e810283a1e9933adedd2"},
+		quicVersion2: {"8b1a0bc121284290a29e0971b5cd045d", "91f73e2351d8fa91660e909f", "45b95e15235d6f45a6b19cbcb0294ba9"},
+	} {
+		key, iv, hp := quicVersions[version].clientInitialKeys(dcid)
+
+		if hex.EncodeToString(key) != expected[0] || hex.EncodeToString(iv) != expected[1] || hex.EncodeToString(hp) != expected[2] {
+			t.Fatalf("unexpected keys for version %x: %x %x %x", version, key, iv, hp)
+		}
+	}
+}
+
+func buildClientHello() []byte {
+	var b cryptobyte.Builder
+
+	b.AddUint8(1)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(0x0303)
+		b.AddBytes(make([]byte, 32))
+		b.AddUint8(0)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x1301)
+			b.AddUint16(0x1302)
+			b.AddUint16(0x1303)
+		})
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(0)
+		})
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			// server name
+			b.AddUint16(0)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8(0)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes([]byte("www.netcap.test"))
+					})
+				})
+			})
+			// ALPN
+			b.AddUint16(16)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes([]byte("h3"))
+					})
+				})
+			})
+			// supported versions
+			b.AddUint16(43)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16(0x0304)
+				})
+			})
+			// QUIC transport parameters, padded to force the message into two packets
+			b.AddUint16(57)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(make([]byte, 1500))
+			})
+		})
+	})
+
+	return b.BytesOrPanic()
+}
+
+// buildInitial creates a protected client Initial packet, padded to the minimum datagram size.
+func buildInitial(t *testing.T, version uint32, dcid []byte, pn uint16, frames []byte) []byte {
+	t.Helper()
+
+	var (
+		v           = quicVersions[version]
+		key, iv, hp = v.clientInitialKeys(dcid)
+		header      cryptobyte.Builder
+	)
+
+	// leave room for the header and the authentication tag
+	if pad := quicMinInitialDatagramSize - (16 + len(dcid)) - 16 - len(frames); pad > 0 {
+		frames = append(frames, make([]byte, pad)...)
+	}
+
+	length := 2 + len(frames) + 16
+
+	header.AddUint8(0xc0 | v.initialType<<4 | 0x01)
+	header.AddUint32(version)
+	header.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(dcid) })
+	header.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{1, 2, 3, 4}) })
+	header.AddUint8(0) // token length
+	header.AddUint16(0x4000 | uint16(length))
+	header.AddUint16(pn)
+
+	var (
+		hdr      = header.BytesOrPanic()
+		pnOffset = len(hdr) - 2
+	)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv[len(iv)-1] ^= byte(pn)
+	iv[len(iv)-2] ^= byte(pn >> 8)
+
+	pkt := aead.Seal(hdr, iv, frames, hdr)
+
+	hpCipher, err := aes.NewCipher(hp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mask := make([]byte, aes.BlockSize)
+	hpCipher.Encrypt(mask, pkt[pnOffset+quicSampleOffset:pnOffset+quicSampleOffset+quicSampleLength])
+
+	pkt[0] ^= mask[0] & 0x0f
+	pkt[pnOffset] ^= mask[1]
+	pkt[pnOffset+1] ^= mask[2]
+
+	return pkt
+}
+
+func cryptoFrame(offset int, data []byte) []byte {
+	var b cryptobyte.Builder
+
+	b.AddUint8(quicFrameCrypto)
+	b.AddUint16(0x4000 | uint16(offset))
+	b.AddUint16(0x4000 | uint16(len(data)))
+	b.AddBytes(data)
+
+	return b.BytesOrPanic()
+}
+
+func udpPacket(t *testing.T, payload []byte) gopacket.Packet {
+	t.Helper()
+
+	var (
+		buf = gopacket.NewSerializeBuffer()
+		ip  = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    net.IP{192, 168, 1, 2},
+			DstIP:    net.IP{192, 168, 1, 10},
+		}
+		udp = &layers.UDP{SrcPort: 55555, DstPort: 443}
+	)
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{SrcMAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, DstMAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, EthernetType: layers.EthernetTypeIPv4},
+		ip, udp, gopacket.Payload(payload),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+	p.Metadata().Timestamp = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	return p
+}
+
+func TestQUICDecoder(t *testing.T) {
+	for _, version := range []uint32{quicVersion1, quicVersion2} {
+		var (
+			dcid  = []byte{byte(version), 0x94, 0xc8, 0xf0, 0x3e, 0x51, 0x57, 0x08}
+			hello = buildClientHello()
+			split = 900
+		)
+
+		// the second half of the ClientHello is sent first
+		second := buildInitial(t, version, dcid, 1, cryptoFrame(split, hello[split:]))
+		if quicDecoder.Handler(udpPacket(t, second)) != nil {
+			t.Fatal("expected no record for incomplete ClientHello")
+		}
+
+		first := buildInitial(t, version, dcid, 0, cryptoFrame(0, hello[:split]))
+
+		// coalesced with a trailing packet that can not be decrypted
+		r, ok := quicDecoder.Handler(udpPacket(t, append(first, bytes.Repeat([]byte{0xff}, 20)...))).(*types.QUIC)
+		if !ok {
+			t.Fatal("expected QUIC record for version", quicVersions[version].name)
+		}
+
+		if r.Version != quicVersions[version].name || r.DCID != hex.EncodeToString(dcid) || r.SCID != "01020304" || r.NumPackets != 2 {
+			t.Fatal("unexpected header fields", r)
+		}
+
+		if r.SNI != "www.netcap.test" || len(r.ALPNs) != 1 || r.ALPNs[0] != "h3" || r.TLSVersion != "TLS 1.3" || len(r.JA3) != 32 {
+			t.Fatal("unexpected ClientHello", r)
+		}
+
+		if !strings.HasPrefix(r.JA4, "q13d0304h3_") || r.SrcIP != "192.168.1.2" || r.DstPort != 443 {
+			t.Fatal("unexpected record", r)
+		}
+
+		// retransmissions are ignored
+		if quicDecoder.Handler(udpPacket(t, first)) != nil {
+			t.Fatal("expected no record for retransmitted Initial")
+		}
+	}
+}
+
+func TestQUICInvalidPackets(t *testing.T) {
+	dcid := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	valid := buildInitial(t, quicVersion1, dcid, 0, cryptoFrame(0, buildClientHello()[:100]))
+
+	tampered := append([]byte(nil), valid...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	unknownVersion := append([]byte(nil), valid...)
+	unknownVersion[4] = 0x42
+
+	for _, data := range [][]byte{
+		tampered,
+		unknownVersion,
+		valid[:40],
+		bytes.Repeat([]byte{0x40}, quicMinInitialDatagramSize),
+	} {
+		if pkt, _, err := parseQUICInitial(data); err == nil && pkt != nil {
+			t.Fatal("expected packet to be rejected")
+		}
+	}
+}
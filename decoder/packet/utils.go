@@ -258,9 +258,9 @@ func ShowDecoders(verbose bool) {
 	printDecoderStats("Stream", func() []core.DecoderAPI {
 		var res []core.DecoderAPI
 
-		for _, s := range stream.DefaultStreamDecoders {
+		stream.ApplyActionToStreamDecoders(func(s core.StreamDecoderAPI) {
 			res = append(res, s)
-		}
+		})
 
 		return res
 	}())
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/dreadl0ck/netcap/decoder/stream/alert"
+	"github.com/dreadl0ck/netcap/decoder/stream/certificate"
 	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
 	"github.com/dreadl0ck/netcap/decoder/stream/exploit"
 	"github.com/dreadl0ck/netcap/decoder/stream/file"
@@ -16,6 +17,7 @@ import (
 	"github.com/dreadl0ck/netcap/decoder/stream/service"
 	"github.com/dreadl0ck/netcap/decoder/stream/software"
 	"github.com/dreadl0ck/netcap/decoder/stream/vulnerability"
+	"github.com/dreadl0ck/netcap/decoder/stream/websocket"
 
 	"github.com/mgutz/ansi"
 	"github.com/pkg/errors"
@@ -43,6 +45,8 @@ var DefaultAbstractDecoders = []core.DecoderAPI{
 	vulnerability.Decoder,
 	credentials.Decoder,
 	alert.Decoder,
+	certificate.Decoder,
+	websocket.Decoder,
 } // contains all available abstract decoders
 
 // package level init.
@@ -161,6 +165,7 @@ func InitAbstractDecoders(c *config.Config) (decoders []core.DecoderAPI, err err
 				Type:    d.GetType(),
 				Null:    c.Null,
 				Elastic: c.Elastic,
+				FanOut:  c.FanOut,
 				ElasticConfig: netio.ElasticConfig{
 					ElasticAddrs:   c.ElasticAddrs,
 					ElasticUser:    c.ElasticUser,
@@ -168,6 +173,7 @@ func InitAbstractDecoders(c *config.Config) (decoders []core.DecoderAPI, err err
 					KibanaEndpoint: c.KibanaEndpoint,
 					BulkSize:       c.BulkSizeCustom,
 				},
+				RotationConfig:       c.RotationConfig,
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
 				Out:                  c.Out,
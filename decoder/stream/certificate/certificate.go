@@ -0,0 +1,80 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package certificate provides the audit records for the certificates presented by servers during TLS handshakes.
+package certificate
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var certLog = zap.NewNop()
+
+// Decoder for writing certificate audit records to disk.
+var Decoder = &decoder.AbstractDecoder{
+	Type:        types.Type_NC_Certificate,
+	Name:        "Certificate",
+	Description: "X.509 certificates presented by servers in the TLS handshake, one record for each certificate in the chain",
+	PostInit: func(d *decoder.AbstractDecoder) (err error) {
+		certLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"certificate",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	DeInit: func(d *decoder.AbstractDecoder) error {
+		return certLog.Sync()
+	},
+}
+
+// WriteCertificate writes the audit record for a certificate.
+// Records are only written if the decoder has been initialized.
+func WriteCertificate(c *types.Certificate) {
+	if Decoder.Writer == nil {
+		return
+	}
+
+	// a certificate is self signed if subject and issuer are identical
+	c.SelfSigned = c.Subject != "" && c.Subject == c.Issuer
+
+	// expiry is evaluated relative to the time the certificate was observed
+	c.Expired = c.NotAfter != 0 && c.NotAfter < c.Timestamp
+
+	certLog.Debug("certificate",
+		zap.String("server", c.ServerIP),
+		zap.String("fingerprint", c.Fingerprint),
+		zap.String("subject", c.Subject),
+		zap.Int32("chainIndex", c.ChainIndex),
+	)
+
+	if decoderconfig.Instance.ExportMetrics {
+		c.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(c)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
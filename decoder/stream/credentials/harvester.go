@@ -26,6 +26,7 @@ import (
 	"github.com/dreadl0ck/gopacket"
 
 	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/utils"
 )
 
 const (
@@ -99,7 +100,7 @@ func harvesterDebug(ident string, data []byte, args ...interface{}) {
 }
 
 // RunHarvesters will use the service probes to determine the service type based on the provided banner.
-func RunHarvesters(banner []byte, transport gopacket.Flow, ident string, firstPacket time.Time) {
+func RunHarvesters(banner []byte, network, transport gopacket.Flow, ident string, firstPacket time.Time) {
 	// only use harvesters when credential audit record type is loaded
 	// useHarvesters is set after the custom decoder initialization
 	if !useHarvesters {
@@ -107,8 +108,9 @@ func RunHarvesters(banner []byte, transport gopacket.Flow, ident string, firstPa
 	}
 
 	var (
-		found bool
-		tried *credentialHarvester
+		found       bool
+		tried       *credentialHarvester
+		communityID = utils.CommunityIDFromFlows(network, transport)
 	)
 
 	// convert service port to integer
@@ -125,6 +127,7 @@ func RunHarvesters(banner []byte, transport gopacket.Flow, ident string, firstPa
 	// check if its a well known port and use the harvester for that one
 	if ch, ok := harvesterPortMapping[dstPort]; ok {
 		if creds := ch(banner, ident, firstPacket); creds != nil { // write audit record
+			creds.CommunityID = communityID
 			WriteCredentials(creds)
 
 			// we found a match and will stop processing
@@ -139,6 +142,7 @@ func RunHarvesters(banner []byte, transport gopacket.Flow, ident string, firstPa
 
 	if ch, ok := harvesterPortMapping[srcPort]; ok {
 		if creds := ch(banner, ident, firstPacket); creds != nil { // write audit record
+			creds.CommunityID = communityID
 			WriteCredentials(creds)
 
 			// we found a match and will stop processing
@@ -159,6 +163,7 @@ func RunHarvesters(banner []byte, transport gopacket.Flow, ident string, firstPa
 			if &ch != tried {
 				// execute harvester
 				if creds := ch(banner, ident, firstPacket); creds != nil { // write audit record
+					creds.CommunityID = communityID
 					WriteCredentials(creds)
 
 					// stop after a match if configured
@@ -0,0 +1,218 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dns
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var dnsLog = zap.NewNop()
+
+const (
+	// size of the DNS message header.
+	headerSize = 12
+
+	// minimum size of a query: header and a question for the root domain.
+	minQuerySize = headerSize + 5
+)
+
+// Decoder for DNS messages carried over a TCP stream or inside of other protocols, e.g. DNS over HTTPS.
+// The messages are written as DNS audit records, just like the ones produced by the packet decoder for UDP.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_DNS,
+	Name:        "DNSStream",
+	Description: "DNS messages transferred over TCP, for example zone transfers and large responses, as well as DNS over HTTPS",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		dnsLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"dns",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		// a query must be prefixed with its length
+		// and have exactly one question
+		if len(client) < 2+minQuerySize {
+			return false
+		}
+
+		var (
+			length = int(binary.BigEndian.Uint16(client[:2]))
+			query  = client[2:]
+		)
+
+		if length < minQuerySize || length > len(query) || query[2]&0x80 != 0 || binary.BigEndian.Uint16(query[4:6]) != 1 {
+			return false
+		}
+
+		if len(server) == 0 {
+			return true
+		}
+
+		// the first response must answer the first query
+		if len(server) < 2+headerSize {
+			return false
+		}
+
+		response := server[2:]
+
+		return response[0] == query[0] && response[1] == query[1] && response[2]&0x80 != 0
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return dnsLog.Sync()
+	},
+	Factory: &dnsReader{},
+	Typ:     core.TCP,
+}
+
+// NewRecord converts the DNS layer into an audit record.
+func NewRecord(dns *layers.DNS, timestamp int64) *types.DNS {
+	var questions []*types.DNSQuestion
+	for _, q := range dns.Questions {
+		questions = append(questions, &types.DNSQuestion{
+			Class: int32(q.Class),
+			Name:  string(q.Name),
+			Type:  int32(q.Type),
+		})
+	}
+
+	var answers []*types.DNSResourceRecord
+	for _, a := range dns.Answers {
+		answers = append(answers, newResourceRecord(a))
+	}
+
+	var auths []*types.DNSResourceRecord
+	for _, a := range dns.Authorities {
+		auths = append(auths, newResourceRecord(a))
+	}
+
+	var adds []*types.DNSResourceRecord
+	for _, a := range dns.Additionals {
+		adds = append(adds, newResourceRecord(a))
+	}
+
+	return &types.DNS{
+		Timestamp:    timestamp,
+		ID:           int32(dns.ID),
+		QR:           dns.QR,
+		OpCode:       int32(dns.OpCode),
+		AA:           dns.AA,
+		TC:           dns.TC,
+		RD:           dns.RD,
+		RA:           dns.RA,
+		Z:            int32(dns.Z),
+		ResponseCode: int32(dns.ResponseCode),
+		QDCount:      int32(dns.QDCount),
+		ANCount:      int32(dns.ANCount),
+		NSCount:      int32(dns.NSCount),
+		ARCount:      int32(dns.ARCount),
+		// Entries
+		Questions:   questions,
+		Answers:     answers,
+		Authorities: auths,
+		Additionals: adds,
+	}
+}
+
+func newResourceRecord(a layers.DNSResourceRecord) *types.DNSResourceRecord {
+	return &types.DNSResourceRecord{
+		Name:       string(a.Name),
+		Type:       int32(a.Type),
+		Class:      int32(a.Class),
+		TTL:        a.TTL,
+		DataLength: int32(a.DataLength),
+		Data:       a.Data,
+		IP:         a.IP.String(),
+		NS:         a.NS,
+		CNAME:      a.CNAME,
+		PTR:        a.PTR,
+		SOA: &types.DNSSOA{
+			MName:   a.SOA.MName,
+			RName:   a.SOA.RName,
+			Serial:  a.SOA.Serial,
+			Refresh: a.SOA.Refresh,
+			Retry:   a.SOA.Retry,
+			Expire:  a.SOA.Expire,
+			Minimum: a.SOA.Minimum,
+		},
+		SRV: &types.DNSSRV{
+			Priority: int32(a.SRV.Priority),
+			Weight:   int32(a.SRV.Weight),
+			Port:     int32(a.SRV.Port),
+			Name:     a.SRV.Name,
+		},
+		MX: &types.DNSMX{
+			Preference: int32(a.MX.Preference),
+			Name:       string(a.MX.Name),
+		},
+		TXTs: a.TXTs,
+	}
+}
+
+// WriteMessage decodes a DNS message that was sent over the conversation and writes it as audit record.
+// Client indicates whether the message has been sent by the client.
+func WriteMessage(conv *core.ConversationInfo, msg []byte, timestamp int64, client bool) error {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return nil
+	}
+
+	var layer layers.DNS
+
+	err := layer.DecodeFromBytes(msg, gopacket.NilDecodeFeedback)
+	if err != nil {
+		return err
+	}
+
+	d := NewRecord(&layer, timestamp)
+
+	if client {
+		d.SrcIP, d.SrcPort = conv.ClientIP, conv.ClientPort
+		d.DstIP, d.DstPort = conv.ServerIP, conv.ServerPort
+	} else {
+		d.SrcIP, d.SrcPort = conv.ServerIP, conv.ServerPort
+		d.DstIP, d.DstPort = conv.ClientIP, conv.ClientPort
+	}
+
+	d.CommunityID = conv.CommunityID
+
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		d.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err = Decoder.Writer.Write(d)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dns
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+)
+
+type dnsReader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new DNS reader.
+func (h *dnsReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &dnsReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the length prefixed DNS messages sent into both directions.
+// A stream can carry multiple queries, and a zone transfer is answered with a sequence of messages.
+func (h *dnsReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	client, server := core.SplitDirections(h.conversation.Data)
+
+	h.decodeMessages(client, true)
+	h.decodeMessages(server, false)
+}
+
+func (h *dnsReader) decodeMessages(s *core.StreamDirection, client bool) {
+	data := s.Bytes()
+
+	for offset := 0; offset+2 <= len(data); {
+		length := int(binary.BigEndian.Uint16(data[offset:]))
+
+		start := offset + 2
+		if start+length > len(data) {
+			dnsLog.Debug("incomplete DNS message",
+				zap.String("ident", h.conversation.Ident),
+				zap.Int("length", length),
+				zap.Int("available", len(data)-start),
+			)
+
+			return
+		}
+
+		err := WriteMessage(h.conversation, data[start:start+length], s.TimeAt(offset).UnixNano(), client)
+		if err != nil {
+			utils.ErrorMap.Inc(err.Error())
+			dnsLog.Debug("failed to decode DNS message",
+				zap.String("ident", h.conversation.Ident),
+				zap.Error(err),
+			)
+		}
+
+		offset = start + length
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// query type for zone transfers.
+const typeAXFR layers.DNSType = 252
+
+// message serializes a DNS message with a length prefix.
+func message(t *testing.T, id uint16, response bool, qtype layers.DNSType, answers ...layers.DNSResourceRecord) []byte {
+	t.Helper()
+
+	d := &layers.DNS{
+		ID:      id,
+		QR:      response,
+		QDCount: 1,
+		Questions: []layers.DNSQuestion{
+			{Name: []byte("example.com"), Type: qtype, Class: layers.DNSClassIN},
+		},
+		Answers: answers,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := d.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := make([]byte, 2, 2+len(buf.Bytes()))
+	binary.BigEndian.PutUint16(msg, uint16(len(buf.Bytes())))
+
+	return append(msg, buf.Bytes()...)
+}
+
+func aRecord(ip string) layers.DNSResourceRecord {
+	return layers.DNSResourceRecord{
+		Name:  []byte("www.example.com"),
+		Type:  layers.DNSTypeA,
+		Class: layers.DNSClassIN,
+		TTL:   300,
+		IP:    net.ParseIP(ip),
+	}
+}
+
+func TestDNSStreamDecoder(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	w := writers[0]
+	defer cleanup()
+
+	var (
+		query     = message(t, 1, false, typeAXFR)
+		response1 = message(t, 1, true, typeAXFR, aRecord("10.0.0.1"), aRecord("10.0.0.2"))
+		response2 = message(t, 1, true, typeAXFR, aRecord("10.0.0.3"))
+	)
+
+	if !Decoder.CanDecode(query, response1) {
+		t.Fatal("expected decoder to match")
+	}
+
+	if Decoder.CanDecode([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), nil) {
+		t.Fatal("expected decoder not to match HTTP")
+	}
+
+	if Decoder.CanDecode(query, message(t, 2, true, layers.DNSTypeA)) {
+		t.Fatal("expected decoder not to match response with different ID")
+	}
+
+	// the second response spans two segments
+	conv := streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 53,
+	},
+		streamtest.Fragment{Client: true, Data: query},
+		streamtest.Fragment{Data: response1},
+		streamtest.Fragment{Data: response2[:10]},
+		streamtest.Fragment{Data: response2[10:]},
+	)
+
+	(&dnsReader{}).New(conv).Decode()
+
+	if len(w.Records) != 3 {
+		t.Fatal("expected 3 records, got", len(w.Records))
+	}
+
+	q := w.Records[0].(*types.DNS)
+	if q.QR || q.Questions[0].Type != int32(typeAXFR) || q.SrcIP != conv.ClientIP || q.DstPort != 53 || q.Timestamp != ts.UnixNano() {
+		t.Fatal("unexpected query", q)
+	}
+
+	r := w.Records[2].(*types.DNS)
+	if !r.QR || len(r.Answers) != 1 || r.Answers[0].IP != "10.0.0.3" || r.SrcIP != conv.ServerIP || r.Timestamp != ts.Add(2*time.Second).UnixNano() {
+		t.Fatal("unexpected response", r)
+	}
+}
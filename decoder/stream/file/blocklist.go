@@ -0,0 +1,225 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+)
+
+// BlocklistFileName is the name of the file hash blocklist in the database directory.
+// Each line contains a MD5, SHA-1, SHA-256 or ssdeep hash, optionally followed by a comma and a description.
+// Empty lines and lines starting with # are ignored.
+const BlocklistFileName = "hash-blocklist.csv"
+
+// SimilarityThreshold is the minimum ssdeep score for a file to match a fuzzy hash from the blocklist.
+var SimilarityThreshold = 80
+
+// blocklist contains the hashes of known bad files.
+type blocklist struct {
+
+	// MD5, SHA-1 and SHA-256 hashes mapped to their description
+	hashes map[string]string
+
+	// ssdeep hashes
+	fuzzy []*fuzzyEntry
+}
+
+type fuzzyEntry struct {
+	hash        string
+	description string
+}
+
+// blocklistMatch describes the blocklist entry matched by a file.
+type blocklistMatch struct {
+	hash        string
+	description string
+
+	// similarity of fuzzy hashes, 100 for exact matches
+	score int
+}
+
+var hashBlocklist *blocklist
+
+// LoadBlocklist loads the file hash blocklist at the given path,
+// extracted files that match an entry will raise an alert.
+func LoadBlocklist(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		errClose := f.Close()
+		if errClose != nil {
+			fileLog.Error("failed to close hash blocklist", zap.Error(errClose))
+		}
+	}()
+
+	b, err := parseBlocklist(f)
+	if err != nil {
+		return 0, err
+	}
+
+	hashBlocklist = b
+
+	return len(b.hashes) + len(b.fuzzy), nil
+}
+
+func parseBlocklist(r io.Reader) (*blocklist, error) {
+	var (
+		b = &blocklist{
+			hashes: make(map[string]string),
+		}
+		scanner = bufio.NewScanner(r)
+		line    int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var (
+			parts       = strings.SplitN(text, ",", 2)
+			hash        = strings.TrimSpace(parts[0])
+			description string
+		)
+
+		if len(parts) == 2 {
+			description = strings.Trim(strings.TrimSpace(parts[1]), "\"")
+		}
+
+		if strings.Contains(hash, ":") {
+			if _, err := utils.SSDEEPCompare(hash, hash); err != nil {
+				return nil, fmt.Errorf("invalid ssdeep hash on line %d: %w", line, err)
+			}
+
+			b.fuzzy = append(b.fuzzy, &fuzzyEntry{
+				hash:        hash,
+				description: description,
+			})
+
+			continue
+		}
+
+		switch len(hash) {
+		case 32, 40, 64: // MD5, SHA-1, SHA-256
+			b.hashes[strings.ToLower(hash)] = description
+		default:
+			return nil, fmt.Errorf("invalid hash on line %d: %q", line, hash)
+		}
+	}
+
+	return b, scanner.Err()
+}
+
+// match checks the hashes of the file against the blocklist.
+func (b *blocklist) match(f *types.File) *blocklistMatch {
+	for _, h := range []string{f.SHA256, f.SHA1, f.Hash} {
+		if h == "" {
+			continue
+		}
+
+		if description, ok := b.hashes[h]; ok {
+			return &blocklistMatch{
+				hash:        h,
+				description: description,
+				score:       100,
+			}
+		}
+	}
+
+	if f.SSDEEP == "" {
+		return nil
+	}
+
+	var best *blocklistMatch
+
+	for _, e := range b.fuzzy {
+		score, err := utils.SSDEEPCompare(f.SSDEEP, e.hash)
+		if err != nil || score < SimilarityThreshold {
+			continue
+		}
+
+		if best == nil || score > best.score {
+			best = &blocklistMatch{
+				hash:        e.hash,
+				description: e.description,
+				score:       score,
+			}
+		}
+	}
+
+	return best
+}
+
+// checkBlocklist raises an alert if the file matches an entry from the hash blocklist.
+func checkBlocklist(f *types.File) {
+	if hashBlocklist == nil {
+		return
+	}
+
+	m := hashBlocklist.match(f)
+	if m == nil {
+		return
+	}
+
+	description := "extracted file " + f.Name + " matches blocklisted hash " + m.hash
+	if m.score < 100 {
+		description += " with similarity " + strconv.Itoa(m.score)
+	}
+
+	if m.description != "" {
+		description += ": " + m.description
+	}
+
+	err := alert.Emit(&types.Alert{
+		Timestamp:   f.Timestamp,
+		Name:        "Blocklisted file",
+		Description: description,
+		SrcIP:       f.SrcIP,
+		SrcPort:     strconv.Itoa(int(f.SrcPort)),
+		DstIP:       f.DstIP,
+		DstPort:     strconv.Itoa(int(f.DstPort)),
+		Protocol:    sourceProtocol(f),
+		Notes:       "sha256: " + f.SHA256 + ", location: " + f.Location,
+		CommunityID: f.CommunityID,
+	})
+	if err != nil {
+		fileLog.Error("failed to emit alert for blocklisted file", zap.String("location", f.Location), zap.Error(err))
+	}
+}
+
+// sourceProtocol returns the protocol that transferred the file, the source of a file starts with it.
+func sourceProtocol(f *types.File) string {
+	if fields := strings.Fields(f.Source); len(fields) > 0 {
+		return fields[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,170 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// testFileData returns deterministic data that is large enough for a meaningful fuzzy hash.
+func testFileData() []byte {
+	var b bytes.Buffer
+
+	for i := 0; b.Len() < 32*1024; i++ {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat(string(rune('a'+i%26)), i%17))
+		b.WriteString(" of the test file\n")
+	}
+
+	return b.Bytes()
+}
+
+func TestSetHashes(t *testing.T) {
+	f := &types.File{}
+	SetHashes(f, []byte("netcap"))
+
+	if f.Hash != "191f451669684d9490a36ddfabe7610c" {
+		t.Fatal("unexpected md5:", f.Hash)
+	}
+
+	if f.SHA1 != "e4b7baab4b55dd1110d7ece0edfa3e41d3ee737a" {
+		t.Fatal("unexpected sha1:", f.SHA1)
+	}
+
+	if f.SHA256 != "9eea47e8be248d1b6667cf1a935b7f34792feba73ac9157d3d4a661ae2510ef5" {
+		t.Fatal("unexpected sha256:", f.SHA256)
+	}
+
+	if f.SSDEEP == "" {
+		t.Fatal("missing ssdeep hash")
+	}
+}
+
+func TestParseBlocklist(t *testing.T) {
+	b, err := parseBlocklist(strings.NewReader(`# comment
+
+d41d8cd98f00b204e9800998ecf8427e,empty file
+DA39A3EE5E6B4B0D3255BFEF95601890AFD80709
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855, "empty file"
+3:abcdefgh:abcd,fuzzy
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b.hashes) != 3 || len(b.fuzzy) != 1 {
+		t.Fatal("unexpected number of entries", len(b.hashes), len(b.fuzzy))
+	}
+
+	if d := b.hashes["da39a3ee5e6b4b0d3255bfef95601890afd80709"]; d != "" {
+		t.Fatal("unexpected description", d)
+	}
+
+	if d := b.hashes["e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"]; d != "empty file" {
+		t.Fatal("unexpected description", d)
+	}
+
+	if _, err = parseBlocklist(strings.NewReader("1234,too short")); err == nil {
+		t.Fatal("expected error for invalid hash")
+	}
+
+	if _, err = parseBlocklist(strings.NewReader("x:abc:abc")); err == nil {
+		t.Fatal("expected error for invalid ssdeep hash")
+	}
+}
+
+func TestBlocklistMatch(t *testing.T) {
+	var (
+		data     = testFileData()
+		modified = append([]byte{}, data...)
+		original = &types.File{}
+		similar  = &types.File{}
+		other    = &types.File{}
+	)
+
+	copy(modified[len(modified)/2:], "a small modification in the middle of the file")
+
+	SetHashes(original, data)
+	SetHashes(similar, modified)
+	SetHashes(other, bytes.Repeat([]byte("unrelated content "), 2048))
+
+	b, err := parseBlocklist(strings.NewReader(strings.ToUpper(original.SHA256) + ",malware\n" + original.SSDEEP + ",malware family\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := b.match(original)
+	if m == nil || m.description != "malware" || m.score != 100 {
+		t.Fatal("expected exact match, got", m)
+	}
+
+	m = b.match(similar)
+	if m == nil || m.description != "malware family" || m.score < SimilarityThreshold || m.score == 100 {
+		t.Fatal("expected fuzzy match, got", m)
+	}
+
+	if m = b.match(other); m != nil {
+		t.Fatal("unexpected match", m)
+	}
+}
+
+func TestCheckBlocklist(t *testing.T) {
+	var alerts []*types.Alert
+
+	alert.Instance = alert.NewManager(alert.Config{
+		Write: func(a *types.Alert) error {
+			alerts = append(alerts, a)
+
+			return nil
+		},
+	})
+	defer func() {
+		alert.Instance = nil
+		hashBlocklist = nil
+	}()
+
+	f := &types.File{
+		Name:    "payload.exe",
+		Source:  "HTTP RESPONSE from /payload.exe",
+		SrcIP:   "10.0.0.1",
+		DstIP:   "10.0.0.2",
+		SrcPort: 80,
+		DstPort: 40000,
+	}
+	SetHashes(f, testFileData())
+
+	var err error
+
+	hashBlocklist, err = parseBlocklist(strings.NewReader(f.SHA256 + ",dropper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkBlocklist(f)
+	alert.Instance.Flush()
+
+	if len(alerts) != 1 {
+		t.Fatal("expected 1 alert, got", len(alerts))
+	}
+
+	a := alerts[0]
+	if a.Protocol != "HTTP" || a.SrcPort != "80" || !strings.Contains(a.Description, "dropper") {
+		t.Fatal("unexpected alert", a)
+	}
+}
@@ -14,19 +14,104 @@
 package file
 
 import (
+	"encoding/hex"
 	"log"
+	"os"
+	"path/filepath"
 	"sync/atomic"
 
+	"github.com/dreadl0ck/cryptoutils"
+	"go.uber.org/zap"
+
 	"github.com/dreadl0ck/netcap/decoder"
 	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/resolvers"
 	"github.com/dreadl0ck/netcap/types"
+	"github.com/dreadl0ck/netcap/utils"
+	"github.com/dreadl0ck/netcap/yara"
 )
 
+var fileLog = zap.NewNop()
+
 // Decoder for protocol analysis and writing audit records to disk.
 var Decoder = &decoder.AbstractDecoder{
 	Type:        types.Type_NC_File,
 	Name:        "File",
 	Description: "A file that was transferred over the network",
+	PostInit: func(d *decoder.AbstractDecoder) (err error) {
+		fileLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"file",
+			decoderconfig.Instance.Debug,
+		)
+		if err != nil {
+			return err
+		}
+
+		if decoderconfig.Instance.FileStorage != "" && decoderconfig.Instance.FileStorageDedup {
+			Storage, err = NewStore(
+				filepath.Join(decoderconfig.Instance.Out, decoderconfig.Instance.FileStorage),
+				decoderconfig.Instance.FileStorageQuota,
+			)
+			if err != nil {
+				return err
+			}
+
+			fileLog.Info("opened deduplicated file storage", zap.Int64("size", Storage.Size()), zap.Int64("quota", decoderconfig.Instance.FileStorageQuota))
+		}
+
+		// the hash blocklist is optional
+		path := filepath.Join(resolvers.DataBaseFolderPath, BlocklistFileName)
+		if _, err = os.Stat(path); err != nil {
+			return nil
+		}
+
+		num, err := LoadBlocklist(path)
+		if err != nil {
+			return err
+		}
+
+		fileLog.Info("loaded file hash blocklist", zap.String("path", path), zap.Int("total", num))
+
+		return nil
+	},
+	DeInit: func(d *decoder.AbstractDecoder) error {
+		if Storage != nil {
+			err := Storage.Close()
+			if err != nil {
+				fileLog.Error("failed to close file storage", zap.Error(err))
+			}
+		}
+
+		return fileLog.Sync()
+	},
+}
+
+// SetHashes computes the hashes for the contents of the file.
+func SetHashes(f *types.File, data []byte) {
+	f.Hash = hex.EncodeToString(cryptoutils.MD5Data(data))
+	f.SHA1 = hex.EncodeToString(cryptoutils.Sha1Data(data))
+	f.SHA256 = hex.EncodeToString(cryptoutils.Sha256Data(data))
+	f.SSDEEP = utils.SSDEEP(data)
+}
+
+// ScanContents scans the contents of the file with the loaded YARA rules, and raises an alert for each matching rule.
+func ScanContents(f *types.File, data []byte) {
+	if yara.Instance == nil {
+		return
+	}
+
+	yara.Instance.ScanAndAlert(data, &yara.Flow{
+		Timestamp:   f.Timestamp,
+		SrcIP:       f.SrcIP,
+		DstIP:       f.DstIP,
+		SrcPort:     f.SrcPort,
+		DstPort:     f.DstPort,
+		Protocol:    sourceProtocol(f),
+		CommunityID: f.CommunityID,
+		Notes:       "file: " + f.Name + ", sha256: " + f.SHA256 + ", location: " + f.Location,
+	})
 }
 
 // WriteFile writeDeviceProfile writes the profile.
@@ -41,4 +126,6 @@ func WriteFile(f *types.File) {
 	if err != nil {
 		log.Fatal("failed to write proto: ", err)
 	}
+
+	checkBlocklist(f)
 }
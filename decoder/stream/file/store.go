@@ -0,0 +1,356 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// ManifestFileName is the name of the manifest in the root directory of the store.
+const ManifestFileName = "manifest.jsonl"
+
+// manifest events.
+const (
+	eventStore     = "store"
+	eventDuplicate = "duplicate"
+	eventEvict     = "evict"
+	eventSkip      = "skip"
+)
+
+var errFileTooLarge = errors.New("file exceeds the storage quota")
+
+// Storage is the content addressed store for extracted files,
+// it is only set if deduplication of extracted files is enabled.
+var Storage *Store
+
+// ManifestEntry is a line of the manifest.
+// Every occurrence of a file is recorded, as well as the eviction of stored files.
+type ManifestEntry struct {
+	Event       string `json:"event"`
+	Timestamp   int64  `json:"timestamp"`
+	SHA256      string `json:"sha256"`
+	Size        int64  `json:"size"`
+	Location    string `json:"location,omitempty"`
+	Name        string `json:"name,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Ident       string `json:"ident,omitempty"`
+	SrcIP       string `json:"srcIP,omitempty"`
+	DstIP       string `json:"dstIP,omitempty"`
+	SrcPort     int32  `json:"srcPort,omitempty"`
+	DstPort     int32  `json:"dstPort,omitempty"`
+	CommunityID string `json:"communityID,omitempty"`
+}
+
+// storedObject is a unique file in the store.
+type storedObject struct {
+	hash     string
+	location string
+	size     int64
+
+	// timestamp of the last occurrence
+	lastSeen int64
+}
+
+// Store saves each unique file once under its SHA-256 hash,
+// and keeps a manifest with all occurrences of the files.
+// If a quota is set, the files seen least recently are evicted once the stored files exceed it.
+type Store struct {
+	sync.Mutex
+
+	root  string
+	quota int64
+	size  int64
+
+	// stored objects by hash
+	objects map[string]*list.Element
+
+	// stored objects, ordered from least to most recently seen
+	lru *list.List
+
+	manifest *os.File
+	encoder  *json.Encoder
+}
+
+// NewStore opens the store at the given root directory, zero disables the quota.
+// An existing manifest is replayed, so files stored by previous runs are deduplicated as well.
+func NewStore(root string, quota int64) (*Store, error) {
+	err := os.MkdirAll(filepath.Join(root, "objects"), defaults.DirectoryPermission)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		root:    root,
+		quota:   quota,
+		objects: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+
+	path := filepath.Join(root, ManifestFileName)
+
+	err = s.replay(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.manifest, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, defaults.FilePermission)
+	if err != nil {
+		return nil, err
+	}
+
+	s.encoder = json.NewEncoder(s.manifest)
+
+	// the quota might have been lowered since the last run
+	s.evict("")
+
+	return s, nil
+}
+
+// replay restores the stored objects from the manifest.
+func (s *Store) replay(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	defer func() {
+		errClose := f.Close()
+		if errClose != nil {
+			fileLog.Error("failed to close manifest", zap.Error(errClose))
+		}
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var e ManifestEntry
+
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// skip lines that were not written completely
+			continue
+		}
+
+		switch e.Event {
+		case eventStore, eventDuplicate:
+			if elem, ok := s.objects[e.SHA256]; ok {
+				s.touch(elem, e.Timestamp)
+
+				continue
+			}
+
+			if e.Event == eventDuplicate {
+				continue
+			}
+
+			s.add(&storedObject{
+				hash:     e.SHA256,
+				location: e.Location,
+				size:     e.Size,
+				lastSeen: e.Timestamp,
+			})
+		case eventEvict:
+			if elem, ok := s.objects[e.SHA256]; ok {
+				s.remove(elem)
+			}
+		}
+	}
+
+	// drop objects whose files have been deleted
+	for elem := s.lru.Front(); elem != nil; {
+		next := elem.Next()
+
+		if _, err = os.Stat(elem.Value.(*storedObject).location); err != nil {
+			s.remove(elem)
+		}
+
+		elem = next
+	}
+
+	return scanner.Err()
+}
+
+// Add stores the contents of the file, unless a file with the same hash is stored already.
+// The hashes of the file must be set, the location of the stored object is set on the file.
+func (s *Store) Add(f *types.File, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	entry := &ManifestEntry{
+		Event:       eventDuplicate,
+		Timestamp:   f.Timestamp,
+		SHA256:      f.SHA256,
+		Size:        int64(len(data)),
+		Name:        f.Name,
+		ContentType: f.ContentTypeDetected,
+		Source:      f.Source,
+		Host:        f.Host,
+		Ident:       f.Ident,
+		SrcIP:       f.SrcIP,
+		DstIP:       f.DstIP,
+		SrcPort:     f.SrcPort,
+		DstPort:     f.DstPort,
+		CommunityID: f.CommunityID,
+	}
+
+	if elem, ok := s.objects[f.SHA256]; ok {
+		s.touch(elem, f.Timestamp)
+
+		f.Location = elem.Value.(*storedObject).location
+		entry.Location = f.Location
+
+		return s.write(entry)
+	}
+
+	if s.quota > 0 && int64(len(data)) > s.quota {
+		entry.Event = eventSkip
+
+		err := s.write(entry)
+		if err != nil {
+			return err
+		}
+
+		return errFileTooLarge
+	}
+
+	location := filepath.Join(s.root, "objects", f.SHA256[:2], f.SHA256+ExtensionForContentType(f.ContentTypeDetected))
+
+	err := os.MkdirAll(filepath.Dir(location), defaults.DirectoryPermission)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(location, data, defaults.FilePermission)
+	if err != nil {
+		return err
+	}
+
+	s.add(&storedObject{
+		hash:     f.SHA256,
+		location: location,
+		size:     int64(len(data)),
+		lastSeen: f.Timestamp,
+	})
+
+	f.Location = location
+	entry.Event = eventStore
+	entry.Location = location
+
+	err = s.write(entry)
+	if err != nil {
+		return err
+	}
+
+	s.evict(f.SHA256)
+
+	return nil
+}
+
+// Size returns the total size of the stored files.
+func (s *Store) Size() int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.size
+}
+
+// Close closes the manifest.
+func (s *Store) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.manifest.Close()
+}
+
+// evict removes the least recently seen files until the quota is met.
+// The file with the hash that is passed in was just added, and will not be evicted.
+func (s *Store) evict(keep string) {
+	if s.quota <= 0 {
+		return
+	}
+
+	for elem := s.lru.Front(); elem != nil && s.size > s.quota; {
+		next := elem.Next()
+		o := elem.Value.(*storedObject)
+
+		if o.hash != keep {
+			err := os.Remove(o.location)
+			if err != nil && !os.IsNotExist(err) {
+				fileLog.Error("failed to evict file", zap.String("location", o.location), zap.Error(err))
+			}
+
+			s.remove(elem)
+
+			err = s.write(&ManifestEntry{
+				Event:     eventEvict,
+				Timestamp: o.lastSeen,
+				SHA256:    o.hash,
+				Size:      o.size,
+				Location:  o.location,
+			})
+			if err != nil {
+				fileLog.Error("failed to write manifest", zap.Error(err))
+			}
+		}
+
+		elem = next
+	}
+}
+
+func (s *Store) add(o *storedObject) {
+	s.objects[o.hash] = s.lru.PushBack(o)
+	s.size += o.size
+}
+
+func (s *Store) remove(elem *list.Element) {
+	o := s.lru.Remove(elem).(*storedObject)
+	delete(s.objects, o.hash)
+	s.size -= o.size
+}
+
+// touch marks the object as most recently seen.
+func (s *Store) touch(elem *list.Element, ts int64) {
+	o := elem.Value.(*storedObject)
+	if ts > o.lastSeen {
+		o.lastSeen = ts
+	}
+
+	s.lru.MoveToBack(elem)
+}
+
+func (s *Store) write(e *ManifestEntry) error {
+	if s.encoder == nil {
+		return nil
+	}
+
+	return s.encoder.Encode(e)
+}
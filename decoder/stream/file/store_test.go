@@ -0,0 +1,207 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func newTestFile(t *testing.T, ts int64, data []byte) *types.File {
+	t.Helper()
+
+	f := &types.File{
+		Timestamp:           ts,
+		Name:                "test.txt",
+		ContentTypeDetected: "text/plain",
+		SrcIP:               "10.0.0.1",
+		DstIP:               "10.0.0.2",
+	}
+	SetHashes(f, data)
+
+	return f
+}
+
+func readManifest(t *testing.T, root string) []*ManifestEntry {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(root, ManifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var (
+		entries []*ManifestEntry
+		scanner = bufio.NewScanner(f)
+	)
+
+	for scanner.Scan() {
+		e := new(ManifestEntry)
+		if err = json.Unmarshal(scanner.Bytes(), e); err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func TestStoreDeduplication(t *testing.T) {
+	root, err := ioutil.TempDir("", "netcap-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewStore(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("the same javascript library")
+
+	for i := 0; i < 3; i++ {
+		f := newTestFile(t, int64(i), data)
+
+		if err = s.Add(f, data); err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Location != filepath.Join(root, "objects", f.SHA256[:2], f.SHA256+".txt") {
+			t.Fatal("unexpected location", f.Location)
+		}
+	}
+
+	if s.Size() != int64(len(data)) {
+		t.Fatal("expected file to be stored once, size", s.Size())
+	}
+
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readManifest(t, root)
+	if len(entries) != 3 || entries[0].Event != eventStore || entries[1].Event != eventDuplicate || entries[2].Event != eventDuplicate {
+		t.Fatal("unexpected manifest entries", entries)
+	}
+
+	if entries[2].Timestamp != 2 || entries[2].SrcIP != "10.0.0.1" || entries[2].Location != entries[0].Location {
+		t.Fatal("unexpected manifest entry", entries[2])
+	}
+
+	// the stored files are restored from the manifest
+	s, err = NewStore(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.Size() != int64(len(data)) {
+		t.Fatal("expected stored file after reopening, size", s.Size())
+	}
+
+	f := newTestFile(t, 3, data)
+	if err = s.Add(f, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries = readManifest(t, root); entries[len(entries)-1].Event != eventDuplicate {
+		t.Fatal("expected duplicate after reopening, got", entries[len(entries)-1].Event)
+	}
+}
+
+func TestStoreQuota(t *testing.T) {
+	root, err := ioutil.TempDir("", "netcap-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s, err := NewStore(root, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var (
+		a = bytes.Repeat([]byte("a"), 10)
+		b = bytes.Repeat([]byte("b"), 10)
+		c = bytes.Repeat([]byte("c"), 10)
+	)
+
+	fa := newTestFile(t, 1, a)
+	fb := newTestFile(t, 2, b)
+	fc := newTestFile(t, 3, c)
+
+	for _, f := range []struct {
+		file *types.File
+		data []byte
+	}{{fa, a}, {fb, b}, {fa, a}, {fc, c}} {
+		if err = s.Add(f.file, f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// b was seen least recently, since a occurred again
+	if _, err = os.Stat(fb.Location); !os.IsNotExist(err) {
+		t.Fatal("expected least recently seen file to be evicted")
+	}
+
+	for _, f := range []*types.File{fa, fc} {
+		if _, err = os.Stat(f.Location); err != nil {
+			t.Fatal("expected file to be stored", err)
+		}
+	}
+
+	if s.Size() != 20 {
+		t.Fatal("unexpected size", s.Size())
+	}
+
+	big := bytes.Repeat([]byte("d"), 30)
+	fd := newTestFile(t, 4, big)
+
+	if err = s.Add(fd, big); err != errFileTooLarge {
+		t.Fatal("expected error for file exceeding the quota, got", err)
+	}
+
+	if fd.Location != "" {
+		t.Fatal("unexpected location for file exceeding the quota", fd.Location)
+	}
+
+	entries := readManifest(t, root)
+
+	var evicted int
+	for _, e := range entries {
+		if e.Event == eventEvict {
+			evicted++
+
+			if e.SHA256 != fb.SHA256 {
+				t.Fatal("unexpected eviction", e.SHA256)
+			}
+		}
+	}
+
+	if evicted != 1 || entries[len(entries)-1].Event != eventSkip {
+		t.Fatal("unexpected manifest entries", len(entries), evicted)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"path"
+	"strings"
+)
+
+// archive formats that can be unpacked.
+const (
+	archiveNone  = ""
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveGzip  = "gzip"
+	archiveBzip2 = "bzip2"
+)
+
+var (
+	errUnpackSizeLimit  = errors.New("unpacked size exceeds the limit")
+	errUnpackRatioLimit = errors.New("compression ratio exceeds the limit")
+)
+
+// UnpackConfig limits the extraction of archive members, to protect against decompression bombs.
+type UnpackConfig struct {
+	// maximum number of bytes unpacked in total, including nested archives, zero disables the limit
+	MaxSize int64
+
+	// maximum ratio between the unpacked and the compressed size of a member, zero disables the limit
+	MaxRatio int
+}
+
+// Member is a file that has been extracted from an archive.
+type Member struct {
+	// path of the member inside the archive
+	Name string
+	Data []byte
+}
+
+// Unpacker extracts the members of archives.
+// The size limit applies to all members extracted by the same unpacker,
+// so a single unpacker should be used for a file and all archives nested inside it.
+type Unpacker struct {
+	conf      UnpackConfig
+	remaining int64
+}
+
+// NewUnpacker returns an unpacker with the given limits.
+func NewUnpacker(conf UnpackConfig) *Unpacker {
+	return &Unpacker{
+		conf:      conf,
+		remaining: conf.MaxSize,
+	}
+}
+
+// archiveFormat detects the archive format from the magic bytes of the data.
+// OOXML documents (docx, xlsx, pptx) are zip archives, and are unpacked as such.
+func archiveFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return archiveZip
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return archiveGzip
+	case bytes.HasPrefix(data, []byte("BZh")):
+		return archiveBzip2
+	case len(data) > 262 && bytes.Equal(data[257:262], []byte("ustar")):
+		return archiveTar
+	}
+
+	return archiveNone
+}
+
+// IsArchive checks whether the data is an archive that can be unpacked.
+func IsArchive(data []byte) bool {
+	return archiveFormat(data) != archiveNone
+}
+
+// Unpack extracts the members of the archive, nested archives are not unpacked.
+// The name of the archive is used to name the contents of gzip and bzip2 streams.
+// If a limit is exceeded, the members extracted so far are returned together with the error.
+// Data that is not a supported archive results in no members and no error.
+func (u *Unpacker) Unpack(name string, data []byte) ([]*Member, error) {
+	switch archiveFormat(data) {
+	case archiveZip:
+		return u.unpackZip(data)
+	case archiveTar:
+		return u.unpackTar(data)
+	case archiveGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		memberName := r.Name
+		if memberName == "" {
+			memberName = strings.TrimSuffix(path.Base(name), path.Ext(name))
+		}
+
+		return u.unpackStream(memberName, r, int64(len(data)))
+	case archiveBzip2:
+		return u.unpackStream(strings.TrimSuffix(path.Base(name), path.Ext(name)), bzip2.NewReader(bytes.NewReader(data)), int64(len(data)))
+	}
+
+	return nil, nil
+}
+
+func (u *Unpacker) unpackStream(name string, r io.Reader, compressedSize int64) ([]*Member, error) {
+	data, err := u.read(r, compressedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Member{{Name: name, Data: data}}, nil
+}
+
+func (u *Unpacker) unpackZip(data []byte) ([]*Member, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*Member
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, errOpen := f.Open()
+		if errOpen != nil {
+			// encrypted members or unsupported compression methods
+			continue
+		}
+
+		contents, errRead := u.read(rc, int64(f.CompressedSize64))
+		_ = rc.Close()
+
+		if errRead != nil {
+			if errRead == errUnpackSizeLimit || errRead == errUnpackRatioLimit {
+				return members, errRead
+			}
+
+			continue
+		}
+
+		members = append(members, &Member{Name: f.Name, Data: contents})
+	}
+
+	return members, nil
+}
+
+func (u *Unpacker) unpackTar(data []byte) ([]*Member, error) {
+	var (
+		r       = tar.NewReader(bytes.NewReader(data))
+		members []*Member
+	)
+
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return members, nil
+		}
+
+		if err != nil {
+			return members, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		// tar archives are not compressed, only the size limit applies
+		contents, err := u.read(r, 0)
+		if err != nil {
+			return members, err
+		}
+
+		members = append(members, &Member{Name: hdr.Name, Data: contents})
+	}
+}
+
+// read reads the contents of a member, and enforces the size and compression ratio limits.
+// A compressed size of zero disables the ratio check.
+func (u *Unpacker) read(r io.Reader, compressedSize int64) ([]byte, error) {
+	var (
+		limit    = u.remaining
+		errLimit = errUnpackSizeLimit
+	)
+
+	if u.conf.MaxSize <= 0 {
+		limit = math.MaxInt64 - 1
+	}
+
+	if u.conf.MaxRatio > 0 && compressedSize > 0 {
+		if maxSize := compressedSize * int64(u.conf.MaxRatio); maxSize < limit {
+			limit = maxSize
+			errLimit = errUnpackRatioLimit
+		}
+	}
+
+	if limit < 0 {
+		limit = 0
+	}
+
+	// read one byte more than allowed, to detect if the limit is exceeded
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, errLimit
+	}
+
+	u.remaining -= int64(len(data))
+
+	return data, nil
+}
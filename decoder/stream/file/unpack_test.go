@@ -0,0 +1,175 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func makeZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   = zip.NewWriter(&buf)
+	)
+
+	for name, data := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+
+	var tarBuf bytes.Buffer
+
+	tw := tar.NewWriter(&tarBuf)
+
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = tw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+
+	if _, err = gw.Write(tarBuf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestUnpackNested(t *testing.T) {
+	inner := makeZip(t, map[string][]byte{
+		"docs/readme.txt": []byte("call +1 202 555 0143"),
+	})
+
+	archive := makeTarGz(t, "inner.zip", inner)
+	u := NewUnpacker(UnpackConfig{MaxSize: 1024 * 1024, MaxRatio: 100})
+
+	// gzip stream
+	members, err := u.Unpack("archive.tar.gz", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(members) != 1 || members[0].Name != "archive.tar" {
+		t.Fatal("unexpected gzip members", members)
+	}
+
+	// tar archive
+	members, err = u.Unpack(members[0].Name, members[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(members) != 1 || members[0].Name != "inner.zip" || !bytes.Equal(members[0].Data, inner) {
+		t.Fatal("unexpected tar members", members)
+	}
+
+	// zip archive
+	members, err = u.Unpack(members[0].Name, members[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(members) != 1 || members[0].Name != "docs/readme.txt" || string(members[0].Data) != "call +1 202 555 0143" {
+		t.Fatal("unexpected zip members", members)
+	}
+
+	if IsArchive(members[0].Data) {
+		t.Fatal("text file detected as archive")
+	}
+}
+
+func TestUnpackRatioLimit(t *testing.T) {
+	bomb := makeZip(t, map[string][]byte{
+		"zeros": make([]byte, 1024*1024),
+	})
+
+	u := NewUnpacker(UnpackConfig{MaxSize: 10 * 1024 * 1024, MaxRatio: 100})
+
+	members, err := u.Unpack("bomb.zip", bomb)
+	if err != errUnpackRatioLimit {
+		t.Fatal("expected ratio limit error, got", err)
+	}
+
+	if len(members) != 0 {
+		t.Fatal("expected no members, got", len(members))
+	}
+
+	// without a ratio limit the member is extracted
+	members, err = NewUnpacker(UnpackConfig{MaxSize: 10 * 1024 * 1024}).Unpack("bomb.zip", bomb)
+	if err != nil || len(members) != 1 || len(members[0].Data) != 1024*1024 {
+		t.Fatal("expected member to be extracted", err)
+	}
+}
+
+func TestUnpackSizeLimit(t *testing.T) {
+	archive := makeZip(t, map[string][]byte{
+		"a": bytes.Repeat([]byte("a"), 600),
+	})
+
+	u := NewUnpacker(UnpackConfig{MaxSize: 1000})
+
+	members, err := u.Unpack("first.zip", archive)
+	if err != nil || len(members) != 1 {
+		t.Fatal("expected first archive to be unpacked", err)
+	}
+
+	// the limit applies to all archives unpacked by the same unpacker
+	_, err = u.Unpack("second.zip", archive)
+	if err != errUnpackSizeLimit {
+		t.Fatal("expected size limit error, got", err)
+	}
+}
+
+func TestUnpackNoArchive(t *testing.T) {
+	members, err := NewUnpacker(UnpackConfig{}).Unpack("index.html", []byte("<html></html>"))
+	if err != nil || members != nil {
+		t.Fatal("expected no members and no error", members, err)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ftp
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dreadl0ck/cryptoutils"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	// maxDataConnections is the maximum number of buffered data connections, and of announced endpoints.
+	maxDataConnections = 1000
+
+	// maxDataSize is the maximum number of buffered bytes for all data connections.
+	maxDataSize = 256 * 1024 * 1024
+
+	// dataConnectionTimeout is the time after which announced endpoints and buffered connections that have not been claimed are dropped.
+	dataConnectionTimeout = 10 * time.Minute
+)
+
+var errIncompleteTransfer = errors.New("incomplete transfer")
+
+// dataConnection is a TCP connection to an endpoint announced on an FTP control connection,
+// that has been closed before the transfer has been decoded.
+type dataConnection struct {
+	key  string
+	conv *core.ConversationInfo
+	data []byte
+
+	// position in the insertion order
+	elem *list.Element
+}
+
+// expectedTransfer is a transfer from a control connection, whose data connection has not been decoded yet.
+type expectedTransfer struct {
+	control  *core.ConversationInfo
+	transfer *types.FTPTransfer
+	seen     time.Time
+
+	// the negotiated and the fallback address
+	keys []string
+}
+
+// dataStore buffers data connections until they are claimed by their control connection.
+// Data connections and control connections are decoded independently once they have been closed,
+// typically the data connection is closed before the transfer is acknowledged on the control connection.
+// Only connections to endpoints that have been announced with PASV, EPSV, PORT or EPRT
+// on an open control connection are buffered.
+// If the control connection is processed first, the transfer is registered as expected instead.
+type dataStore struct {
+	sync.Mutex
+
+	// endpoints negotiated on control connections, mapped to the time they were announced
+	announced map[string]time.Time
+
+	// buffered data connections, mapped to the address of their server
+	conns map[string]*dataConnection
+
+	// buffered data connections, in the order they were added
+	order *list.List
+
+	// total number of buffered bytes
+	size int
+
+	// transfers without a data connection, mapped to the negotiated address
+	expected map[string]*expectedTransfer
+}
+
+var store = newDataStore()
+
+func newDataStore() *dataStore {
+	return &dataStore{
+		announced: make(map[string]time.Time),
+		conns:     make(map[string]*dataConnection),
+		order:     list.New(),
+		expected:  make(map[string]*expectedTransfer),
+	}
+}
+
+func endpointKey(ip string, port int32) string {
+	return ip + ":" + strconv.Itoa(int(port))
+}
+
+// SaveDataConnection is called for TCP connections that did not match any stream decoder.
+// Connections to an endpoint that has been announced on an FTP control connection are buffered
+// for correlation with the transfer, or saved immediately if the control connection is expecting them.
+func SaveDataConnection(conv *core.ConversationInfo) {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil || !store.wanted(endpointKey(conv.ServerIP, conv.ServerPort), conv.FirstClientPacket) {
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, d := range conv.Data {
+		buf.Write(d.Raw())
+	}
+
+	if buf.Len() == 0 || buf.Len() > maxDataSize {
+		return
+	}
+
+	if e := store.add(conv, buf.Bytes()); e != nil {
+		e.transfer.Length = int64(buf.Len())
+		e.transfer.Hash = hex.EncodeToString(cryptoutils.MD5Data(buf.Bytes()))
+
+		ftpLog.Debug("matched data connection for expected transfer",
+			zap.String("ident", conv.Ident),
+			zap.String("name", e.transfer.Filename),
+		)
+
+		saveTransfer(e.control, conv, e.transfer, buf.Bytes())
+	}
+}
+
+// announce registers the endpoint negotiated for a data connection on an open control connection.
+// The fallback IP is announced as well, since the negotiated address might differ from the observed one behind NAT.
+func (s *dataStore) announce(ip, fallbackIP string, port int32, ts time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.announced) >= maxDataConnections {
+		for k, seen := range s.announced {
+			if ts.Sub(seen) > dataConnectionTimeout {
+				delete(s.announced, k)
+			}
+		}
+
+		// drop an arbitrary endpoint if all are still valid
+		for k := range s.announced {
+			if len(s.announced) < maxDataConnections {
+				break
+			}
+
+			delete(s.announced, k)
+		}
+	}
+
+	s.announced[endpointKey(ip, port)] = ts
+	s.announced[endpointKey(fallbackIP, port)] = ts
+}
+
+// wanted checks if a control connection has announced the endpoint or is expecting a transfer for it.
+func (s *dataStore) wanted(key string, ts time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.expected[key]; ok {
+		return true
+	}
+
+	seen, ok := s.announced[key]
+
+	return ok && ts.Sub(seen) <= dataConnectionTimeout
+}
+
+// add buffers the data connection, unless a transfer is expecting it.
+// In this case, the expected transfer is returned.
+func (s *dataStore) add(conv *core.ConversationInfo, data []byte) *expectedTransfer {
+	key := endpointKey(conv.ServerIP, conv.ServerPort)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if e, ok := s.expected[key]; ok {
+		for _, k := range e.keys {
+			delete(s.expected, k)
+		}
+
+		return e
+	}
+
+	// each announcement is used for a single data connection
+	if _, ok := s.announced[key]; !ok {
+		return nil
+	}
+
+	delete(s.announced, key)
+
+	if old, ok := s.conns[key]; ok {
+		// port reuse, replace the previous connection
+		s.remove(old)
+	}
+
+	c := &dataConnection{
+		key:  key,
+		conv: conv,
+		data: data,
+	}
+
+	s.conns[key] = c
+	c.elem = s.order.PushBack(c)
+	s.size += len(data)
+
+	s.evict(conv.FirstClientPacket)
+
+	return nil
+}
+
+// take returns and removes the buffered data connection for the negotiated address.
+// If no connection is found for the negotiated IP, the fallback IP is tried.
+func (s *dataStore) take(ip, fallbackIP string, port int32) (*core.ConversationInfo, []byte) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, addr := range []string{ip, fallbackIP} {
+		if c, ok := s.conns[endpointKey(addr, port)]; ok {
+			s.remove(c)
+
+			return c.conv, c.data
+		}
+	}
+
+	return nil, nil
+}
+
+// expect registers a transfer for a data connection that has not been decoded yet.
+func (s *dataStore) expect(ip, fallbackIP string, port int32, control *core.ConversationInfo, t *types.FTPTransfer) {
+	s.Lock()
+	defer s.Unlock()
+
+	e := &expectedTransfer{
+		control:  control,
+		transfer: t,
+		seen:     time.Unix(0, t.Timestamp),
+		keys:     []string{endpointKey(ip, port), endpointKey(fallbackIP, port)},
+	}
+
+	// drop transfers that have not been claimed
+	for k, v := range s.expected {
+		if e.seen.Sub(v.seen) > dataConnectionTimeout {
+			delete(s.expected, k)
+		}
+	}
+
+	for _, k := range e.keys {
+		s.expected[k] = e
+	}
+}
+
+// remove deletes a buffered connection, the caller must hold the lock.
+func (s *dataStore) remove(c *dataConnection) {
+	delete(s.conns, c.key)
+	s.order.Remove(c.elem)
+	s.size -= len(c.data)
+}
+
+// evict drops the oldest buffered connections until the limits are satisfied,
+// as well as connections that have not been claimed within the timeout.
+// The caller must hold the lock.
+func (s *dataStore) evict(now time.Time) {
+	for s.order.Len() > 0 {
+		c := s.order.Front().Value.(*dataConnection)
+
+		if s.order.Len() <= maxDataConnections && s.size <= maxDataSize && now.Sub(c.conv.FirstClientPacket) <= dataConnectionTimeout {
+			return
+		}
+
+		s.remove(c)
+	}
+}
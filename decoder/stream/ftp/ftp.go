@@ -0,0 +1,87 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ftp
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	ftpLog        = zap.NewNop()
+	ftpLogSugared = ftpLog.Sugar()
+
+	serviceFTP        = "FTP"
+	ftpServiceReady   = []byte("220")
+	ftpName           = []byte("FTP")
+	ftpClientCommands = [][]byte{
+		[]byte("USER "),
+		[]byte("AUTH "),
+		[]byte("FEAT"),
+		[]byte("SYST"),
+		[]byte("OPTS "),
+	}
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_FTP,
+	Name:        serviceFTP,
+	Description: "The File Transfer Protocol is used to transfer files between a client and a server",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		ftpLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"ftp",
+			decoderconfig.Instance.Debug,
+		)
+		if err != nil {
+			return err
+		}
+
+		ftpLogSugared = ftpLog.Sugar()
+
+		return nil
+	},
+	CanDecode: func(client, server []byte) bool {
+		if !bytes.HasPrefix(server, ftpServiceReady) {
+			return false
+		}
+
+		// the banner does not always contain the service name
+		// so the first client command is checked as well, to distinguish FTP from SMTP
+		if bytes.Contains(bytes.ToUpper(server), ftpName) {
+			return true
+		}
+
+		for _, p := range ftpClientCommands {
+			if bytes.HasPrefix(bytes.ToUpper(client), p) {
+				return true
+			}
+		}
+
+		return false
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return ftpLog.Sync()
+	},
+	Factory: &ftpReader{},
+	Typ:     core.TCP,
+}
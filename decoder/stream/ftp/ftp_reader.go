@@ -0,0 +1,676 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ftp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dreadl0ck/cryptoutils"
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+/*
+ * FTP protocol
+ */
+
+const (
+	// FTP commands.
+	ftpUser = "USER"
+	ftpPass = "PASS"
+	ftpAuth = "AUTH"
+	ftpPort = "PORT"
+	ftpEprt = "EPRT"
+	ftpPasv = "PASV"
+	ftpEpsv = "EPSV"
+	ftpRetr = "RETR"
+	ftpStor = "STOR"
+	ftpStou = "STOU"
+	ftpAppe = "APPE"
+	ftpList = "LIST"
+	ftpNlst = "NLST"
+	ftpMlsd = "MLSD"
+
+	// FTP reply codes.
+	ftpReplyServiceReady   = 220
+	ftpReplyPassive        = 227
+	ftpReplyExtendedPasv   = 229
+	ftpReplyLoggedIn       = 230
+	ftpReplyAuthOK         = 234
+	ftpReplyPreliminaryMin = 100
+	ftpReplyPreliminaryMax = 199
+	ftpReplyCompletionMin  = 200
+	ftpReplyCompletionMax  = 299
+
+	// maxControlSize limits the data of a control connection that is kept by the reader while the connection is open.
+	maxControlSize = 1024 * 1024
+
+	// maxLineLength limits incomplete lines that are scanned for data endpoints, e.g. after the connection has been upgraded to TLS.
+	maxLineLength = 4096
+)
+
+// ftpLine is a single line of the control connection.
+type ftpLine struct {
+	client    bool
+	timestamp time.Time
+	text      string
+}
+
+// dataEndpoint is the address for a data connection, negotiated on the control connection.
+type dataEndpoint struct {
+	ip      string
+	port    int32
+	passive bool
+}
+
+type ftpReader struct {
+	conversation *core.ConversationInfo
+
+	ftp *types.FTP
+
+	// commands waiting for a reply
+	pending []*types.FTPCommand
+
+	// data endpoint for the next transfer
+	endpoint *dataEndpoint
+
+	// user name, until login succeeded
+	user, pass string
+
+	// multi line reply
+	replyCode int
+	replyText []string
+
+	// set once the connection has been upgraded to TLS
+	encrypted bool
+
+	// fragments collected while the connection is open,
+	// they are kept by the reader, since the connection releases them once they have been consumed
+	fragments core.DataFragments
+	size      int
+
+	// incomplete lines of each direction, scanned for data endpoints
+	clientLine []byte
+	serverLine []byte
+}
+
+// New will instantiate a new FTP reader.
+func (h *ftpReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &ftpReader{
+		conversation: conv,
+	}
+}
+
+// Consume collects the control connection while it is open,
+// and announces the endpoints negotiated for data connections,
+// so the data connections are kept until the transfer has been decoded.
+func (h *ftpReader) Consume(d core.DataFragment) {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	if h.size < maxControlSize {
+		h.fragments = append(h.fragments, d)
+		h.size += len(d.Raw())
+	}
+
+	ts := core.FragmentTimestamp(d)
+
+	if d.Direction() == reassembly.TCPDirClientToServer {
+		h.clientLine = h.scanLines(h.clientLine, d.Raw(), true, ts)
+	} else {
+		h.serverLine = h.scanLines(h.serverLine, d.Raw(), false, ts)
+	}
+}
+
+// scanLines appends the data to the incomplete line of one direction,
+// and announces the data endpoints in all complete lines.
+func (h *ftpReader) scanLines(line, data []byte, client bool, ts time.Time) []byte {
+	line = append(line, data...)
+
+	for {
+		i := bytes.IndexByte(line, '\n')
+		if i < 0 {
+			break
+		}
+
+		h.announce(strings.TrimRight(string(line[:i]), "\r"), client, ts)
+		line = line[i+1:]
+	}
+
+	if len(line) == 0 || len(line) > maxLineLength {
+		return nil
+	}
+
+	return append([]byte(nil), line...)
+}
+
+// announce registers the data endpoint of a PORT or EPRT command, or of a PASV or EPSV reply.
+func (h *ftpReader) announce(line string, client bool, ts time.Time) {
+	var e *dataEndpoint
+
+	if client {
+		switch cmd, arg := splitCommand(line); cmd {
+		case ftpPort:
+			e = parsePort(arg)
+		case ftpEprt:
+			e = parseEprt(arg)
+		}
+	} else {
+		code, final, text := parseReply(line)
+		if !final {
+			return
+		}
+
+		switch code {
+		case ftpReplyPassive:
+			e = parsePasv(text)
+		case ftpReplyExtendedPasv:
+			e = parseEpsv(text, h.conversation.ServerIP)
+		}
+	}
+
+	if e == nil {
+		return
+	}
+
+	ftpLog.Debug("data endpoint announced",
+		zap.String("ident", h.conversation.Ident),
+		zap.String("ip", e.ip),
+		zap.Int32("port", e.port),
+	)
+
+	store.announce(e.ip, h.fallbackIP(e), e.port, ts)
+}
+
+// fallbackIP returns the observed address of the host that accepts the data connection.
+// The negotiated address might differ from it, in case the host is behind NAT.
+func (h *ftpReader) fallbackIP(e *dataEndpoint) string {
+	if e.passive {
+		return h.conversation.ServerIP
+	}
+
+	return h.conversation.ClientIP
+}
+
+// Decode parses the control connection and correlates the file transfers with their data connections.
+func (h *ftpReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	h.ftp = &types.FTP{
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		ClientIP:    h.conversation.ClientIP,
+		ServerIP:    h.conversation.ServerIP,
+		ClientPort:  h.conversation.ClientPort,
+		ServerPort:  h.conversation.ServerPort,
+		CommunityID: h.conversation.CommunityID,
+	}
+
+	data := h.conversation.Data
+	if len(h.fragments) > 0 {
+		data = h.fragments
+		sort.Stable(data)
+	}
+
+	for _, l := range splitLines(data) {
+		if h.encrypted {
+			break
+		}
+
+		if l.client {
+			h.handleCommand(l)
+		} else {
+			h.handleReply(l)
+		}
+	}
+
+	if h.ftp.Banner == "" && len(h.ftp.Commands) == 0 {
+		return
+	}
+
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		h.ftp.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(h.ftp)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
+
+// handleCommand processes a command sent by the client.
+func (h *ftpReader) handleCommand(l *ftpLine) {
+	cmd, arg := splitCommand(l.text)
+	if cmd == "" {
+		return
+	}
+
+	c := &types.FTPCommand{
+		Timestamp: l.timestamp.UnixNano(),
+		Command:   cmd,
+		Argument:  arg,
+	}
+
+	switch cmd {
+	case ftpUser:
+		h.user = arg
+	case ftpPass:
+		h.pass = arg
+	case ftpPort:
+		if e := parsePort(arg); e != nil {
+			h.endpoint = e
+		}
+	case ftpEprt:
+		if e := parseEprt(arg); e != nil {
+			h.endpoint = e
+		}
+	}
+
+	ftpLog.Debug("command",
+		zap.String("ident", h.conversation.Ident),
+		zap.String("command", cmd),
+		zap.String("argument", arg),
+	)
+
+	h.ftp.Commands = append(h.ftp.Commands, c)
+	h.pending = append(h.pending, c)
+}
+
+// handleReply processes a reply line sent by the server.
+// Multi line replies start with the code followed by a dash, and end with a line starting with the code followed by a space.
+func (h *ftpReader) handleReply(l *ftpLine) {
+	code, final, text := parseReply(l.text)
+
+	if h.replyCode != 0 {
+		h.replyText = append(h.replyText, strings.TrimSpace(text))
+		if code != h.replyCode || !final {
+			return
+		}
+
+		text = strings.Join(h.replyText, "\n")
+		h.replyCode = 0
+		h.replyText = nil
+	} else if code == 0 {
+		// unexpected line
+		return
+	} else if !final {
+		h.replyCode = code
+		h.replyText = []string{text}
+
+		return
+	}
+
+	ftpLog.Debug("reply",
+		zap.String("ident", h.conversation.Ident),
+		zap.Int("code", code),
+		zap.String("text", text),
+	)
+
+	if len(h.pending) == 0 {
+		if code == ftpReplyServiceReady && h.ftp.Banner == "" {
+			h.ftp.Banner = text
+		}
+
+		return
+	}
+
+	c := h.pending[0]
+	c.ReplyCode = int32(code)
+	c.ReplyMessage = text
+
+	// preliminary replies are followed by another reply for the same command
+	if code >= ftpReplyPreliminaryMin && code <= ftpReplyPreliminaryMax {
+		return
+	}
+
+	h.pending = h.pending[1:]
+	h.handleCompletion(c, code, text)
+}
+
+// handleCompletion evaluates the final reply for a command.
+func (h *ftpReader) handleCompletion(c *types.FTPCommand, code int, text string) {
+	success := code >= ftpReplyCompletionMin && code <= ftpReplyCompletionMax
+
+	switch c.Command {
+	case ftpUser, ftpPass:
+		if code == ftpReplyLoggedIn {
+			h.ftp.User = h.user
+			h.ftp.Pass = h.pass
+		}
+	case ftpAuth:
+		if code == ftpReplyAuthOK {
+			h.encrypted = true
+		}
+	case ftpPasv:
+		if code == ftpReplyPassive {
+			if e := parsePasv(text); e != nil {
+				h.endpoint = e
+			}
+		}
+	case ftpEpsv:
+		if code == ftpReplyExtendedPasv {
+			if e := parseEpsv(text, h.conversation.ServerIP); e != nil {
+				h.endpoint = e
+			}
+		}
+	case ftpRetr, ftpStor, ftpStou, ftpAppe, ftpList, ftpNlst, ftpMlsd:
+		h.handleTransfer(c, success)
+	}
+}
+
+// handleTransfer creates a transfer record and correlates it with the data connection.
+func (h *ftpReader) handleTransfer(c *types.FTPCommand, success bool) {
+	if h.endpoint == nil {
+		return
+	}
+
+	t := &types.FTPTransfer{
+		Timestamp: c.Timestamp,
+		Command:   c.Command,
+		Filename:  c.Argument,
+		Passive:   h.endpoint.passive,
+		DataIP:    h.endpoint.ip,
+		DataPort:  h.endpoint.port,
+		Complete:  success,
+	}
+
+	// each data connection is used for a single transfer
+	e := h.endpoint
+	h.endpoint = nil
+
+	h.ftp.Transfers = append(h.ftp.Transfers, t)
+
+	fallbackIP := h.fallbackIP(e)
+
+	conv, data := store.take(e.ip, fallbackIP, e.port)
+	if conv == nil {
+		// data connection has not been decoded yet
+		store.expect(e.ip, fallbackIP, e.port, h.conversation, t)
+
+		return
+	}
+
+	t.Length = int64(len(data))
+	t.Hash = hex.EncodeToString(cryptoutils.MD5Data(data))
+
+	saveTransfer(h.conversation, conv, t, data)
+}
+
+// saveTransfer writes the transferred data into the file storage.
+// Directory listings are not saved.
+func saveTransfer(control, conv *core.ConversationInfo, t *types.FTPTransfer, data []byte) {
+	if decoderconfig.Instance.FileStorage == "" {
+		return
+	}
+
+	switch t.Command {
+	case ftpList, ftpNlst, ftpMlsd:
+		return
+	}
+
+	var errTransfer error
+	if !t.Complete {
+		if !decoderconfig.Instance.WriteIncomplete {
+			return
+		}
+
+		errTransfer = errIncompleteTransfer
+	}
+
+	err := streamutils.SaveFile(
+		conv,
+		serviceFTP,
+		path.Base(t.Filename),
+		errTransfer,
+		data,
+		nil,
+		control.ServerIP+":"+strconv.Itoa(int(control.ServerPort)),
+		"",
+	)
+	if err != nil {
+		ftpLog.Error("failed to save file",
+			zap.String("ident", conv.Ident),
+			zap.String("name", t.Filename),
+			zap.Error(err),
+		)
+	}
+}
+
+// splitLines splits the conversation into lines, preserving the direction and timestamp.
+func splitLines(data core.DataFragments) []*ftpLine {
+	var (
+		lines  []*ftpLine
+		client bytes.Buffer
+		server bytes.Buffer
+	)
+
+	add := func(buf *bytes.Buffer, isClient bool, ts time.Time, flush bool) {
+		for {
+			b := buf.Bytes()
+
+			i := bytes.IndexByte(b, '\n')
+			if i < 0 {
+				if flush && len(b) > 0 {
+					i = len(b)
+				} else {
+					return
+				}
+			}
+
+			lines = append(lines, &ftpLine{
+				client:    isClient,
+				timestamp: ts,
+				text:      strings.TrimRight(string(b[:i]), "\r\n"),
+			})
+
+			buf.Next(i + 1)
+		}
+	}
+
+	var last time.Time
+
+	for _, d := range data {
+		ts := core.FragmentTimestamp(d)
+		last = ts
+
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			client.Write(d.Raw())
+			add(&client, true, ts, false)
+		} else {
+			server.Write(d.Raw())
+			add(&server, false, ts, false)
+		}
+	}
+
+	add(&client, true, last, true)
+	add(&server, false, last, true)
+
+	return lines
+}
+
+// splitCommand cuts the line into command and argument.
+func splitCommand(line string) (cmd, arg string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", ""
+	}
+
+	parts := strings.SplitN(line, " ", 2)
+	cmd = strings.ToUpper(parts[0])
+
+	if len(parts) > 1 {
+		arg = strings.TrimSpace(parts[1])
+	}
+
+	return cmd, arg
+}
+
+// parseReply parses the reply code and returns whether this is the final line of the reply.
+// For lines that do not start with a reply code, zero is returned.
+func parseReply(line string) (code int, final bool, text string) {
+	if len(line) < 3 {
+		return 0, false, line
+	}
+
+	code, err := strconv.Atoi(line[:3])
+	if err != nil || code < 100 || code > 599 {
+		return 0, false, line
+	}
+
+	if len(line) == 3 {
+		return code, true, ""
+	}
+
+	switch line[3] {
+	case ' ':
+		return code, true, strings.TrimSpace(line[4:])
+	case '-':
+		return code, false, strings.TrimSpace(line[4:])
+	default:
+		return 0, false, line
+	}
+}
+
+// parseHostPort parses the address format used by PORT and PASV: h1,h2,h3,h4,p1,p2.
+func parseHostPort(s string) (ip string, port int32, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), ",")
+	if len(parts) != 6 {
+		return "", 0, false
+	}
+
+	var nums [6]int
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 || n > 255 {
+			return "", 0, false
+		}
+
+		nums[i] = n
+	}
+
+	ip = strconv.Itoa(nums[0]) + "." + strconv.Itoa(nums[1]) + "." + strconv.Itoa(nums[2]) + "." + strconv.Itoa(nums[3])
+
+	return ip, int32(nums[4]<<8 | nums[5]), true
+}
+
+// parsePort parses the argument for the PORT command, e.g: PORT 192,168,1,2,7,138.
+func parsePort(arg string) *dataEndpoint {
+	ip, port, ok := parseHostPort(arg)
+	if !ok {
+		return nil
+	}
+
+	return &dataEndpoint{ip: ip, port: port}
+}
+
+// parsePasv parses the reply for the PASV command, e.g: 227 Entering Passive Mode (192,168,1,2,7,138).
+func parsePasv(text string) *dataEndpoint {
+	var (
+		start = strings.IndexByte(text, '(')
+		end   = strings.LastIndexByte(text, ')')
+	)
+
+	if start >= 0 && end > start {
+		text = text[start+1 : end]
+	} else {
+		// the parentheses are optional
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return nil
+		}
+
+		text = strings.TrimSuffix(fields[len(fields)-1], ".")
+	}
+
+	ip, port, ok := parseHostPort(text)
+	if !ok {
+		return nil
+	}
+
+	return &dataEndpoint{ip: ip, port: port, passive: true}
+}
+
+// parseEprt parses the argument for the EPRT command, e.g: EPRT |1|132.235.1.2|6275|.
+func parseEprt(arg string) *dataEndpoint {
+	arg = strings.TrimSpace(arg)
+	if len(arg) < 3 || arg[0] != arg[len(arg)-1] {
+		return nil
+	}
+
+	parts := strings.Split(arg[1:len(arg)-1], arg[:1])
+	if len(parts) != 3 {
+		return nil
+	}
+
+	port, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil
+	}
+
+	return &dataEndpoint{ip: parts[1], port: int32(port)}
+}
+
+// parseEpsv parses the reply for the EPSV command, e.g: 229 Entering Extended Passive Mode (|||6446|).
+// The server address is not included in the reply, the address of the control connection is used instead.
+func parseEpsv(text, serverIP string) *dataEndpoint {
+	var (
+		start = strings.IndexByte(text, '(')
+		end   = strings.LastIndexByte(text, ')')
+	)
+
+	if start < 0 || end < start {
+		return nil
+	}
+
+	// the port is enclosed by the same delimiter, e.g. |||6446|
+	inner := text[start+1 : end]
+	if len(inner) < 3 || inner[0] != inner[len(inner)-1] {
+		return nil
+	}
+
+	parts := strings.Split(inner[1:len(inner)-1], inner[:1])
+
+	if len(parts) != 3 {
+		return nil
+	}
+
+	port, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil
+	}
+
+	return &dataEndpoint{ip: serverIP, port: int32(port), passive: true}
+}
@@ -0,0 +1,252 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package ftp
+
+import (
+	"testing"
+	"time"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/io/iotest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func conversation(client, server string, clientPort, serverPort int32, lines ...string) *core.ConversationInfo {
+	// each line is terminated with CRLF, including the payload of data connections
+	return streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   client,
+		ServerIP:   server,
+		ClientPort: clientPort,
+		ServerPort: serverPort,
+	}, streamtest.Lines(lines...)...)
+}
+
+func TestFTPDecoder(t *testing.T) {
+	decoderconfig.Instance = &decoderconfig.Config{}
+
+	w := &iotest.RecordWriter{}
+	Decoder.Writer = w
+	store = newDataStore()
+
+	defer func() {
+		Decoder.Writer = nil
+	}()
+
+	control := conversation("192.168.1.2", "192.168.1.10", 49999, 21,
+		"220-Welcome",
+		"220 ProFTPD Server ready.",
+		"C: USER anonymous",
+		"331 Anonymous login ok, send your complete email address as your password",
+		"C: PASS guest@example.com",
+		"230 Anonymous access granted, restrictions apply",
+		"C: PASV",
+		"227 Entering Passive Mode (192,168,1,10,7,245).",
+		"C: RETR files/test.txt",
+		"150 Opening BINARY mode data connection for test.txt (11 bytes)",
+		"226 Transfer complete",
+		"C: PORT 192,168,1,2,7,138",
+		"200 PORT command successful",
+		"C: STOR upload.bin",
+		"150 Opening BINARY mode data connection for upload.bin",
+		"226 Transfer complete",
+		"C: QUIT",
+		"221 Goodbye.",
+	)
+
+	if !Decoder.CanDecode(control.Data[2].Raw(), control.Data[0].Raw()) {
+		t.Fatal("expected decoder to match control connection")
+	}
+
+	// the data endpoints are announced while the control connection is open
+	r := (&ftpReader{}).New(&core.ConversationInfo{
+		Ident:    control.Ident,
+		ClientIP: control.ClientIP,
+		ServerIP: control.ServerIP,
+	}).(*ftpReader)
+
+	for _, d := range control.Data {
+		r.Consume(d)
+	}
+
+	// passive data connection, closed before the control connection
+	SaveDataConnection(conversation("192.168.1.2", "192.168.1.10", 50000, 2037, "hello world"))
+
+	// active data connection from port 20
+	SaveDataConnection(conversation("192.168.1.10", "192.168.1.2", 20, 1930, "C: uploaded content"))
+
+	if len(store.conns) != 2 {
+		t.Fatal("expected 2 buffered data connections, got", len(store.conns))
+	}
+
+	r.Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	f := w.Records[0].(*types.FTP)
+
+	if f.Banner != "Welcome\nProFTPD Server ready." {
+		t.Fatalf("unexpected banner: %q", f.Banner)
+	}
+
+	if f.User != "anonymous" || f.Pass != "guest@example.com" {
+		t.Fatal("unexpected credentials", f.User, f.Pass)
+	}
+
+	if len(f.Commands) != 7 {
+		t.Fatal("expected 7 commands, got", len(f.Commands))
+	}
+
+	if f.Commands[3].ReplyCode != 226 {
+		t.Fatal("expected final reply code for RETR, got", f.Commands[4].ReplyCode)
+	}
+
+	if len(f.Transfers) != 2 {
+		t.Fatal("expected 2 transfers, got", len(f.Transfers))
+	}
+
+	retr := f.Transfers[0]
+	if !retr.Passive || retr.DataIP != "192.168.1.10" || retr.DataPort != 2037 || retr.Length != 13 || !retr.Complete {
+		t.Fatal("unexpected passive transfer", retr)
+	}
+
+	stor := f.Transfers[1]
+	if stor.Passive || stor.DataIP != "192.168.1.2" || stor.DataPort != 1930 || stor.Length != 18 || stor.Filename != "upload.bin" {
+		t.Fatal("unexpected active transfer", stor)
+	}
+
+	if len(store.conns) != 0 || store.size != 0 {
+		t.Fatal("expected data connections to be claimed")
+	}
+}
+
+func TestFTPExpectedTransfer(t *testing.T) {
+	decoderconfig.Instance = &decoderconfig.Config{}
+
+	w := &iotest.RecordWriter{}
+	Decoder.Writer = w
+	store = newDataStore()
+
+	defer func() {
+		Decoder.Writer = nil
+	}()
+
+	control := conversation("10.0.0.1", "10.0.0.2", 40000, 21,
+		"220 FTP server",
+		"C: EPSV",
+		"229 Entering Extended Passive Mode (|||6446|)",
+		"C: RETR a.txt",
+		"150 Ok",
+		"226 Ok",
+	)
+
+	(&ftpReader{}).New(control).Decode()
+
+	f := w.Records[0].(*types.FTP)
+	if len(f.Transfers) != 1 || f.Transfers[0].DataPort != 6446 {
+		t.Fatal("unexpected transfers", f.Transfers)
+	}
+
+	if len(store.expected) == 0 {
+		t.Fatal("expected transfer to be registered")
+	}
+
+	SaveDataConnection(conversation("10.0.0.1", "10.0.0.2", 40001, 6446, "abc"))
+
+	if len(store.expected) != 0 || len(store.conns) != 0 {
+		t.Fatal("expected data connection to be claimed")
+	}
+
+	if f.Transfers[0].Length != 5 {
+		t.Fatal("expected length to be updated, got", f.Transfers[0].Length)
+	}
+}
+
+func TestFTPUnannouncedDataConnection(t *testing.T) {
+	decoderconfig.Instance = &decoderconfig.Config{}
+
+	Decoder.Writer = &iotest.RecordWriter{}
+	store = newDataStore()
+
+	defer func() {
+		Decoder.Writer = nil
+	}()
+
+	// connections between unprivileged ports are only kept, if a control connection announced the endpoint
+	SaveDataConnection(conversation("10.0.0.1", "10.0.0.2", 40001, 6446, "abc"))
+
+	if len(store.conns) != 0 || store.size != 0 {
+		t.Fatal("expected unannounced data connection to be ignored")
+	}
+
+	store.announce("10.0.0.2", "10.0.0.2", 6446, ts)
+
+	// announcements expire
+	late := conversation("10.0.0.1", "10.0.0.2", 40001, 6446, "abc")
+	late.FirstClientPacket = ts.Add(dataConnectionTimeout + time.Second)
+	SaveDataConnection(late)
+
+	if len(store.conns) != 0 {
+		t.Fatal("expected data connection after the timeout to be ignored")
+	}
+
+	SaveDataConnection(conversation("10.0.0.1", "10.0.0.2", 40001, 6446, "abc"))
+
+	if len(store.conns) != 1 || len(store.announced) != 0 {
+		t.Fatal("expected announced data connection to be buffered", len(store.conns), len(store.announced))
+	}
+}
+
+func TestParseDataEndpoints(t *testing.T) {
+	if e := parsePasv("Entering Passive Mode (127,0,0,1,4,1)"); e == nil || e.ip != "127.0.0.1" || e.port != 1025 {
+		t.Fatal("failed to parse PASV reply", e)
+	}
+
+	if e := parsePasv("Entering Passive Mode 127,0,0,1,4,1."); e == nil || e.port != 1025 {
+		t.Fatal("failed to parse PASV reply without parentheses", e)
+	}
+
+	if e := parseEprt("|2|::1|5282|"); e == nil || e.ip != "::1" || e.port != 5282 {
+		t.Fatal("failed to parse EPRT argument", e)
+	}
+
+	if e := parseEpsv("Entering Extended Passive Mode (|||6446|)", "1.1.1.1"); e == nil || e.ip != "1.1.1.1" || e.port != 6446 {
+		t.Fatal("failed to parse EPSV reply", e)
+	}
+
+	for _, reply := range []string{
+		"Entering Extended Passive Mode (|)",
+		"Entering Extended Passive Mode ()",
+		"Entering Extended Passive Mode (||)",
+		"Entering Extended Passive Mode (|||6446!)",
+		"Entering Extended Passive Mode )(",
+	} {
+		if e := parseEpsv(reply, "1.1.1.1"); e != nil {
+			t.Fatal("expected invalid EPSV reply", reply, e)
+		}
+	}
+
+	if e := parseEprt("|"); e != nil {
+		t.Fatal("expected invalid EPRT argument")
+	}
+
+	if e := parsePort("1,2,3"); e != nil {
+		t.Fatal("expected invalid PORT argument")
+	}
+}
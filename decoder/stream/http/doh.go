@@ -0,0 +1,86 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/dns"
+)
+
+const (
+	// media type for DNS messages in the wire format, see RFC 8484.
+	mimeDNSMessage = "application/dns-message"
+
+	// query parameter that contains the base64url encoded DNS message for GET requests.
+	paramDNS = "dns"
+
+	// path that is commonly used for the DNS over HTTPS endpoint.
+	pathDNSQuery = "/dns-query"
+
+	headerAccept = "Accept"
+)
+
+// isDNSMessage checks if the media type of the header is a DNS message.
+func isDNSMessage(h http.Header) bool {
+	return strings.HasPrefix(strings.ToLower(h.Get(headerContentType)), mimeDNSMessage)
+}
+
+// dohQuery returns the DNS message for a DNS over HTTPS request,
+// or nil if the request does not carry a DNS query.
+func dohQuery(req *http.Request, body []byte) []byte {
+	switch req.Method {
+	case methodPOST:
+		if isDNSMessage(req.Header) {
+			return body
+		}
+	case methodGET:
+		// other applications might use a parameter with the same name,
+		// so require the client to accept a DNS message or the well known path
+		param := req.URL.Query().Get(paramDNS)
+		if param == "" || !strings.Contains(strings.ToLower(req.Header.Get(headerAccept)), mimeDNSMessage) && !strings.HasSuffix(req.URL.Path, pathDNSQuery) {
+			return nil
+		}
+
+		// the message is encoded without padding, but tolerate it anyways
+		msg, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(param, "="))
+		if err != nil {
+			return nil
+		}
+
+		return msg
+	}
+
+	return nil
+}
+
+// decodeDoH writes the DNS message carried by a DNS over HTTPS request or response.
+func (h *httpReader) decodeDoH(msg []byte, timestamp int64, client bool) {
+	if len(msg) == 0 {
+		return
+	}
+
+	err := dns.WriteMessage(h.conversation, msg, timestamp, client)
+	if err != nil {
+		httpLog.Debug("failed to decode DNS over HTTPS message",
+			zap.String("ident", h.conversation.Ident),
+			zap.Bool("client", client),
+			zap.Error(err),
+		)
+	}
+}
@@ -31,7 +31,7 @@ var httpLog = zap.NewNop()
 var Decoder = &decoder.StreamDecoder{
 	Type:        types.Type_NC_HTTP,
 	Name:        "HTTP",
-	Description: "The Hypertext Transfer Protocol is powering the world wide web",
+	Description: "The Hypertext Transfer Protocol is powering the world wide web, including cleartext HTTP/2 and gRPC",
 	PostInit: func(sd *decoder.StreamDecoder) error {
 		var err error
 		httpLog, _, err = logging.InitZapLogger(
@@ -42,7 +42,7 @@ var Decoder = &decoder.StreamDecoder{
 		return err
 	},
 	CanDecode: func(client, server []byte) bool {
-		return containsHTTPProtocolName(server) && containsHTTPMethod(client)
+		return bytes.HasPrefix(client, http2Preface) || containsHTTPProtocolName(server) && containsHTTPMethod(client)
 	},
 	DeInit: func(sd *decoder.StreamDecoder) error {
 		return httpLog.Sync()
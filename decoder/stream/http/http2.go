@@ -0,0 +1,528 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	protoHTTP2 = "HTTP/2.0"
+
+	// initial size of the HPACK dynamic table, see RFC 7541 section 4.2.
+	initialHeaderTableSize = 4096
+
+	// maximum size of the HPACK dynamic table that peers are allowed to switch to.
+	maxHeaderTableSize = 64 * 1024
+
+	// maximum frame size that can be negotiated, see RFC 7540 section 4.2.
+	maxFrameSize = 1<<24 - 1
+
+	mimeGRPC = "application/grpc"
+)
+
+// http2Preface is sent by clients that connect with prior knowledge of HTTP/2 support, see RFC 7540 section 3.4.
+var http2Preface = []byte(http2.ClientPreface)
+
+// errStreamIncomplete is passed to the file extraction for streams that have not been ended by the peer.
+var errStreamIncomplete = errors.New("stream incomplete")
+
+// http2Message is the request or response sent on a stream.
+type http2Message struct {
+	timestamp time.Time
+	pseudo    map[string]string
+	header    http.Header
+	trailer   http.Header
+	body      bytes.Buffer
+	ended     bool
+}
+
+// http2Stream is a single request and response exchange.
+type http2Stream struct {
+	id       uint32
+	request  *http2Message
+	response *http2Message
+	reset    bool
+}
+
+// isHTTP2 checks if the client connected with prior knowledge of HTTP/2 support.
+func (h *httpReader) isHTTP2() bool {
+	var client []byte
+
+	for _, d := range h.conversation.Data {
+		if d.Direction() != reassembly.TCPDirClientToServer {
+			continue
+		}
+
+		client = append(client, d.Raw()...)
+		if len(client) >= len(http2Preface) {
+			break
+		}
+	}
+
+	return bytes.HasPrefix(client, http2Preface)
+}
+
+// decodeHTTP2 demultiplexes the streams of a HTTP/2 connection and writes a HTTP record for each stream.
+func (h *httpReader) decodeHTTP2() {
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		streams        = make(map[uint32]*http2Stream)
+	)
+
+	h.readFrames(client, false, streams)
+	h.readFrames(server, true, streams)
+
+	ids := make([]uint32, 0, len(streams))
+	for id := range streams {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		h.writeHTTP2Stream(streams[id])
+	}
+}
+
+// readFrames reads the frames sent into one direction and collects the messages of all streams.
+// Each direction has its own HPACK decoder, since the header compression state is maintained per direction.
+func (h *httpReader) readFrames(d *core.StreamDirection, fromServer bool, streams map[uint32]*http2Stream) {
+	var (
+		data = d.Bytes()
+		r    = bytes.NewReader(data)
+	)
+
+	if !fromServer {
+		if !bytes.HasPrefix(data, http2Preface) {
+			return
+		}
+
+		_, _ = r.Seek(int64(len(http2Preface)), io.SeekStart)
+	}
+
+	dec := hpack.NewDecoder(initialHeaderTableSize, nil)
+	dec.SetAllowedMaxDynamicTableSize(maxHeaderTableSize)
+
+	fr := http2.NewFramer(ioutil.Discard, r)
+	fr.ReadMetaHeaders = dec
+	fr.SetMaxReadFrameSize(maxFrameSize)
+
+	stream := func(id uint32) *http2Stream {
+		s, ok := streams[id]
+		if !ok {
+			s = &http2Stream{id: id}
+			streams[id] = s
+		}
+
+		return s
+	}
+
+	for {
+		var (
+			offset = len(data) - r.Len()
+			ts     = d.TimeAt(offset)
+		)
+
+		f, err := fr.ReadFrame()
+		if err != nil {
+			var se http2.StreamError
+			if errors.As(err, &se) {
+				// the connection can continue after a stream error
+				stream(se.StreamID).reset = true
+
+				continue
+			}
+
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				httpLog.Debug("failed to read HTTP/2 frame",
+					zap.String("ident", h.conversation.Ident),
+					zap.Bool("server", fromServer),
+					zap.Int("offset", offset),
+					zap.Error(err),
+				)
+			}
+
+			return
+		}
+
+		switch f := f.(type) {
+		case *http2.MetaHeadersFrame:
+			s := stream(f.StreamID)
+			if fromServer {
+				s.response = addHeaders(s.response, f.Fields, ts, true)
+			} else {
+				s.request = addHeaders(s.request, f.Fields, ts, false)
+			}
+
+			setEnded(s, fromServer, f.StreamEnded())
+		case *http2.DataFrame:
+			s := stream(f.StreamID)
+			m := s.request
+			if fromServer {
+				m = s.response
+			}
+
+			// data must be preceded by headers
+			if m != nil {
+				m.body.Write(f.Data())
+			}
+
+			setEnded(s, fromServer, f.StreamEnded())
+		case *http2.PushPromiseFrame:
+			if !f.HeadersEnded() {
+				httpLog.Debug("fragmented HTTP/2 push promise, header compression state lost",
+					zap.String("ident", h.conversation.Ident),
+				)
+
+				return
+			}
+
+			// the promised request is sent by the server, and has to be decoded to keep the compression state in sync
+			fields, errDecode := dec.DecodeFull(f.HeaderBlockFragment())
+			if errDecode != nil {
+				return
+			}
+
+			s := stream(f.PromiseID)
+			s.request = addHeaders(s.request, fields, ts, false)
+			s.request.ended = true
+		case *http2.RSTStreamFrame:
+			stream(f.StreamID).reset = true
+		case *http2.GoAwayFrame:
+			httpLog.Debug("HTTP/2 connection closed",
+				zap.String("ident", h.conversation.Ident),
+				zap.Bool("server", fromServer),
+				zap.Stringer("code", f.ErrCode),
+			)
+		}
+	}
+}
+
+// addHeaders adds a header block to the message, a header block received after the message headers contains the trailers.
+// Informational responses are replaced by the final response.
+func addHeaders(m *http2Message, fields []hpack.HeaderField, ts time.Time, response bool) *http2Message {
+	if m != nil && (!response || !strings.HasPrefix(m.pseudo[":status"], "1")) {
+		if m.trailer == nil {
+			m.trailer = make(http.Header)
+		}
+
+		for _, f := range fields {
+			if !strings.HasPrefix(f.Name, ":") {
+				m.trailer.Add(f.Name, f.Value)
+			}
+		}
+
+		return m
+	}
+
+	m = &http2Message{
+		timestamp: ts,
+		pseudo:    make(map[string]string),
+		header:    make(http.Header),
+	}
+
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, ":") {
+			m.pseudo[f.Name] = f.Value
+		} else {
+			m.header.Add(f.Name, f.Value)
+		}
+	}
+
+	return m
+}
+
+func setEnded(s *http2Stream, fromServer, ended bool) {
+	if !ended {
+		return
+	}
+
+	if fromServer && s.response != nil {
+		s.response.ended = true
+	} else if !fromServer && s.request != nil {
+		s.request.ended = true
+	}
+}
+
+// writeHTTP2Stream writes the HTTP record for a stream and passes the bodies to the file extraction.
+func (h *httpReader) writeHTTP2Stream(s *http2Stream) {
+	if s.request == nil {
+		if s.response != nil {
+			// response without matching request
+			atomic.AddInt64(&streamutils.Stats.NumUnmatchedResp, 1)
+		}
+
+		return
+	}
+
+	var (
+		req = newHTTP2Request(s.request)
+		ht  = &types.HTTP{}
+	)
+
+	streamutils.Stats.Lock()
+	streamutils.Stats.Requests++
+	streamutils.Stats.Unlock()
+
+	// parse form values, the body is restored afterwards to detect the content type
+	err := req.ParseForm()
+	if err != nil {
+		httpLog.Debug("failed to read HTTP/2 form values",
+			zap.String("ident", h.conversation.Ident),
+			zap.Uint32("stream", s.id),
+			zap.Error(err),
+		)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(s.request.body.Bytes()))
+
+	if s.response != nil {
+		streamutils.Stats.Lock()
+		streamutils.Stats.Responses++
+		streamutils.Stats.Unlock()
+
+		atomic.AddInt64(&streamutils.Stats.NumResponses, 1)
+		atomic.AddInt64(&streamutils.Stats.NumFoundRequests, 1)
+
+		ht = newHTTPFromResponse(newHTTP2Response(s.response, req))
+	} else {
+		atomic.AddInt64(&streamutils.Stats.NumUnansweredRequests, 1)
+	}
+
+	if credentials.Decoder.Writer != nil {
+		h.searchForLoginParams(req)
+		h.searchForBasicAuth(req)
+	}
+
+	atomic.AddInt64(&streamutils.Stats.NumRequests, 1)
+
+	setRequest(ht, &httpRequest{
+		request:   req,
+		timestamp: s.request.timestamp.UnixNano(),
+		clientIP:  h.conversation.ClientIP,
+		serverIP:  h.conversation.ServerIP,
+	})
+
+	ht.StreamID = s.id
+	ht.CommunityID = h.conversation.CommunityID
+
+	setGRPC(ht, s)
+	writeHTTP(ht, h.conversation.Ident)
+
+	h.decodeHTTP2DoH(s, req)
+	h.saveHTTP2Bodies(s, req)
+}
+
+// newHTTP2Request creates a request from the pseudo header fields and headers of the message.
+func newHTTP2Request(m *http2Message) *http.Request {
+	var (
+		authority = m.pseudo[":authority"]
+		p         = m.pseudo[":path"]
+	)
+
+	u, err := url.ParseRequestURI(p)
+	if err != nil {
+		// CONNECT requests have no path
+		u = &url.URL{Host: authority}
+	}
+
+	if authority == "" {
+		authority = m.header.Get("Host")
+	}
+
+	return &http.Request{
+		Method:        m.pseudo[":method"],
+		URL:           u,
+		Proto:         protoHTTP2,
+		ProtoMajor:    2,
+		Header:        m.header,
+		Trailer:       m.trailer,
+		Body:          ioutil.NopCloser(bytes.NewReader(m.body.Bytes())),
+		ContentLength: contentLength(m),
+		Host:          authority,
+		RequestURI:    p,
+	}
+}
+
+// newHTTP2Response creates a response from the pseudo header fields and headers of the message.
+func newHTTP2Response(m *http2Message, req *http.Request) *http.Response {
+	code, _ := strconv.Atoi(m.pseudo[":status"])
+
+	return &http.Response{
+		Status:        strconv.Itoa(code) + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         protoHTTP2,
+		ProtoMajor:    2,
+		Header:        m.header,
+		Trailer:       m.trailer,
+		Body:          ioutil.NopCloser(bytes.NewReader(m.body.Bytes())),
+		ContentLength: contentLength(m),
+		Request:       req,
+	}
+}
+
+// contentLength returns the value of the content-length header, or the length of the received data.
+func contentLength(m *http2Message) int64 {
+	if l, err := strconv.ParseInt(m.header.Get("Content-Length"), 10, 64); err == nil {
+		return l
+	}
+
+	return int64(m.body.Len())
+}
+
+// setGRPC sets the gRPC service, method and status for streams that carry a gRPC call.
+// The status is sent in the trailers, or in the headers for responses without a body.
+func setGRPC(ht *types.HTTP, s *http2Stream) {
+	if !strings.HasPrefix(s.request.header.Get(headerContentType), mimeGRPC) {
+		return
+	}
+
+	// the path has the format /package.Service/Method
+	if parts := strings.Split(strings.TrimPrefix(s.request.pseudo[":path"], "/"), "/"); len(parts) == 2 {
+		ht.GRPCService = parts[0]
+		ht.GRPCMethod = parts[1]
+	}
+
+	if s.response == nil {
+		return
+	}
+
+	status, message := s.response.trailer.Get("Grpc-Status"), s.response.trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = s.response.header.Get("Grpc-Status"), s.response.header.Get("Grpc-Message")
+	}
+
+	if status != "" {
+		ht.GRPCStatus = grpcStatusName(status)
+	}
+
+	// the message is percent encoded
+	if m, err := url.PathUnescape(message); err == nil {
+		message = m
+	}
+
+	ht.GRPCMessage = removeCommas(message)
+}
+
+// grpcStatusCodes contains the names of the gRPC status codes.
+var grpcStatusCodes = []string{
+	"OK",
+	"CANCELLED",
+	"UNKNOWN",
+	"INVALID_ARGUMENT",
+	"DEADLINE_EXCEEDED",
+	"NOT_FOUND",
+	"ALREADY_EXISTS",
+	"PERMISSION_DENIED",
+	"RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION",
+	"ABORTED",
+	"OUT_OF_RANGE",
+	"UNIMPLEMENTED",
+	"INTERNAL",
+	"UNAVAILABLE",
+	"DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// grpcStatusName returns the name for a gRPC status code, unknown codes are returned unchanged.
+func grpcStatusName(code string) string {
+	c, err := strconv.Atoi(code)
+	if err != nil || c < 0 || c >= len(grpcStatusCodes) {
+		return code
+	}
+
+	return grpcStatusCodes[c]
+}
+
+// decodeHTTP2DoH writes the DNS messages of a DNS over HTTPS exchange carried in a HTTP/2 stream.
+func (h *httpReader) decodeHTTP2DoH(s *http2Stream, req *http.Request) {
+	h.decodeDoH(dohQuery(req, s.request.body.Bytes()), s.request.timestamp.UnixNano(), true)
+
+	if s.response != nil && isDNSMessage(s.response.header) {
+		h.decodeDoH(s.response.body.Bytes(), s.response.timestamp.UnixNano(), false)
+	}
+}
+
+// saveHTTP2Bodies passes the request and response bodies to the file extraction, if configured.
+func (h *httpReader) saveHTTP2Bodies(s *http2Stream, req *http.Request) {
+	if decoderconfig.Instance.FileStorage == "" {
+		return
+	}
+
+	name := path.Base(req.URL.Path)
+
+	save := func(m *http2Message, source string) {
+		var err error
+
+		if !m.ended || s.reset {
+			if !decoderconfig.Instance.WriteIncomplete {
+				return
+			}
+
+			err = errStreamIncomplete
+		}
+
+		err = streamutils.SaveFile(
+			h.conversation,
+			source,
+			name,
+			err,
+			m.body.Bytes(),
+			m.header[headerContentEncoding],
+			req.Host,
+			m.header.Get(headerContentType),
+		)
+		if err != nil {
+			httpLog.Error("failed to save HTTP/2 body",
+				zap.String("ident", h.conversation.Ident),
+				zap.Uint32("stream", s.id),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if req.Method == methodPOST || req.Method == methodPUT {
+		save(s.request, "HTTP2 "+req.Method+" REQUEST to "+req.URL.Path)
+	}
+
+	if s.response != nil {
+		save(s.response, "HTTP2 RESPONSE from "+req.Host+req.URL.Path)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// http2Peer encodes the frames sent into one direction of a connection.
+type http2Peer struct {
+	buf bytes.Buffer
+	fr  *http2.Framer
+	enc *hpack.Encoder
+	hb  bytes.Buffer
+}
+
+func newHTTP2Peer() *http2Peer {
+	p := &http2Peer{}
+	p.fr = http2.NewFramer(&p.buf, nil)
+	p.enc = hpack.NewEncoder(&p.hb)
+
+	return p
+}
+
+func (p *http2Peer) headers(t *testing.T, id uint32, end bool, fields ...string) {
+	t.Helper()
+
+	p.hb.Reset()
+
+	for i := 0; i < len(fields); i += 2 {
+		if err := p.enc.WriteField(hpack.HeaderField{Name: fields[i], Value: fields[i+1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := p.fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      id,
+		BlockFragment: p.hb.Bytes(),
+		EndStream:     end,
+		EndHeaders:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (p *http2Peer) data(t *testing.T, id uint32, end bool, data []byte) {
+	t.Helper()
+
+	if err := p.fr.WriteData(id, end, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flush returns the frames written since the last call as a stream fragment.
+func (p *http2Peer) flush(dir reassembly.TCPFlowDirection, offset time.Duration) *core.StreamData {
+	d := &core.StreamData{
+		RawData: append([]byte(nil), p.buf.Bytes()...),
+		Dir:     dir,
+		CaptureInformation: gopacket.CaptureInfo{
+			Timestamp: ts.Add(offset),
+		},
+	}
+
+	p.buf.Reset()
+
+	return d
+}
+
+func TestHTTP2(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	defer cleanup()
+
+	w := writers[0]
+
+	var (
+		client = newHTTP2Peer()
+		server = newHTTP2Peer()
+		data   core.DataFragments
+	)
+
+	client.buf.WriteString(http2.ClientPreface)
+
+	if err := client.fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a unary gRPC call
+	client.headers(t, 1, false,
+		":method", "POST",
+		":scheme", "http",
+		":authority", "greeter.svc:50051",
+		":path", "/helloworld.Greeter/SayHello",
+		"content-type", "application/grpc",
+		"te", "trailers",
+	)
+	client.data(t, 1, true, []byte{0, 0, 0, 0, 7, 0x0a, 0x05, 'w', 'o', 'r', 'l', 'd'})
+
+	// a second request on the same connection, using the dynamic table of the HPACK encoder
+	client.headers(t, 3, true,
+		":method", "GET",
+		":scheme", "http",
+		":authority", "greeter.svc:50051",
+		":path", "/index.html?lang=en",
+		"cookie", "session=1",
+	)
+
+	data = append(data, client.flush(reassembly.TCPDirClientToServer, 0))
+
+	if err := server.fr.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.headers(t, 1, false, ":status", "200", "content-type", "application/grpc")
+	server.headers(t, 3, false, ":status", "200", "content-type", "text/html", "server", "netcap")
+	server.data(t, 3, true, []byte("<html></html>"))
+	server.headers(t, 1, true, "grpc-status", "5", "grpc-message", "world%20not%20found")
+
+	data = append(data, server.flush(reassembly.TCPDirServerToClient, time.Second))
+
+	conv := &core.ConversationInfo{
+		Data:              data,
+		Ident:             "192.168.1.2:49999->192.168.1.10:50051",
+		FirstClientPacket: ts,
+		FirstServerPacket: ts.Add(time.Second),
+		ClientIP:          "192.168.1.2",
+		ServerIP:          "192.168.1.10",
+		ClientPort:        49999,
+		ServerPort:        50051,
+		CommunityID:       "1:abc",
+	}
+
+	if !Decoder.CanDecode(data[0].Raw(), data[1].Raw()) {
+		t.Fatal("expected decoder to match")
+	}
+
+	(&httpReader{}).New(conv).Decode()
+
+	if len(w.Records) != 2 {
+		t.Fatal("expected 2 records, got", len(w.Records))
+	}
+
+	grpc := w.Records[0].(*types.HTTP)
+	if grpc.StreamID != 1 || grpc.Proto != protoHTTP2 || grpc.Method != "POST" || grpc.Host != "greeter.svc:50051" || grpc.URL != "/helloworld.Greeter/SayHello" || grpc.StatusCode != 200 {
+		t.Fatal("unexpected gRPC request", grpc)
+	}
+
+	if grpc.GRPCService != "helloworld.Greeter" || grpc.GRPCMethod != "SayHello" || grpc.GRPCStatus != "NOT_FOUND" || grpc.GRPCMessage != "world not found" {
+		t.Fatal("unexpected gRPC call", grpc.GRPCService, grpc.GRPCMethod, grpc.GRPCStatus, grpc.GRPCMessage)
+	}
+
+	if grpc.ReqContentLength != 12 || grpc.Timestamp != ts.UnixNano() || grpc.CommunityID != "1:abc" {
+		t.Fatal("unexpected gRPC request", grpc)
+	}
+
+	get := w.Records[1].(*types.HTTP)
+	if get.StreamID != 3 || get.Method != "GET" || get.Host != "greeter.svc:50051" || get.URL != "/index.html?lang=en" || get.GRPCService != "" {
+		t.Fatal("unexpected request", get)
+	}
+
+	if get.StatusCode != 200 || get.ResContentLength != 13 || get.ResContentType != "text/html" || get.ServerName != "netcap" {
+		t.Fatal("unexpected response", get)
+	}
+
+	if len(get.ReqCookies) != 1 || get.ReqCookies[0].Value != "1" || get.Parameters["lang"] != "en" {
+		t.Fatal("unexpected cookies or parameters", get.ReqCookies, get.Parameters)
+	}
+}
+
+func TestGRPCStatusName(t *testing.T) {
+	for code, name := range map[string]string{
+		"0":  "OK",
+		"14": "UNAVAILABLE",
+		"16": "UNAUTHENTICATED",
+		"17": "17",
+		"x":  "x",
+	} {
+		if n := grpcStatusName(code); n != name {
+			t.Fatal("expected", name, "for", code, "got", n)
+		}
+	}
+}
@@ -73,6 +73,10 @@ type httpReader struct {
 
 	requests  []*httpRequest
 	responses []*httpResponse
+
+	// set once the server switched the protocol to WebSocket,
+	// the remaining data is handed to the WebSocket decoder after the HTTP messages have been processed
+	upgraded bool
 }
 
 // New constructs a new http stream decoder.
@@ -89,6 +93,13 @@ func (h *httpReader) Decode() {
 		return
 	}
 
+	// cleartext HTTP/2 with prior knowledge
+	if h.isHTTP2() {
+		h.decodeHTTP2()
+
+		return
+	}
+
 	streamutils.DecodeConversation(
 		h.conversation.Ident,
 		h.conversation.Data,
@@ -100,6 +111,10 @@ func (h *httpReader) Decode() {
 		},
 	)
 
+	if h.upgraded {
+		h.decodeWebSocket()
+	}
+
 	// iterate over responses
 	for _, res := range h.responses { // populate types.HTTP with all infos from response
 		ht := newHTTPFromResponse(res.response)
@@ -130,6 +145,7 @@ func (h *httpReader) Decode() {
 			continue
 		}
 
+		ht.CommunityID = h.conversation.CommunityID
 		writeHTTP(ht, h.conversation.Ident)
 	}
 
@@ -147,6 +163,7 @@ func (h *httpReader) Decode() {
 			atomic.AddInt64(&streamutils.Stats.NumRequests, 1)
 			atomic.AddInt64(&streamutils.Stats.NumUnansweredRequests, 1)
 
+			ht.CommunityID = h.conversation.CommunityID
 			writeHTTP(ht, h.conversation.Ident)
 		} else {
 			atomic.AddInt64(&streamutils.Stats.NumNilRequests, 1)
@@ -159,11 +176,12 @@ func (h *httpReader) searchForBasicAuth(req *http.Request) {
 	if u, p, ok := req.BasicAuth(); ok {
 		if u != "" || p != "" {
 			credentials.WriteCredentials(&types.Credentials{
-				Timestamp: h.conversation.FirstClientPacket.UnixNano(),
-				Service:   "HTTP Basic Auth",
-				Flow:      h.conversation.Ident,
-				User:      u,
-				Password:  p,
+				Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+				Service:     "HTTP Basic Auth",
+				Flow:        h.conversation.Ident,
+				User:        u,
+				Password:    p,
+				CommunityID: h.conversation.CommunityID,
 			})
 		}
 	}
@@ -192,12 +210,13 @@ func (h *httpReader) searchForLoginParams(req *http.Request) {
 		}
 
 		credentials.WriteCredentials(&types.Credentials{
-			Timestamp: h.conversation.FirstClientPacket.UnixNano(),
-			Service:   "HTTP",
-			Flow:      h.conversation.Ident,
-			User:      strings.Join(values, "; "),
-			Password:  pass,
-			Notes:     "Login Parameters",
+			Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+			Service:     "HTTP",
+			Flow:        h.conversation.Ident,
+			User:        strings.Join(values, "; "),
+			Password:    pass,
+			Notes:       "Login Parameters",
+			CommunityID: h.conversation.CommunityID,
 		})
 	}
 }
@@ -248,6 +267,10 @@ func writeHTTP(h *types.HTTP, ident string) {
 // HTTP Response
 
 func (h *httpReader) readResponse(b *bufio.Reader) error {
+	if h.upgraded {
+		return discardUpgraded(b)
+	}
+
 	// try to read HTTP response from the buffered reader
 	res, err := http.ReadResponse(b, nil)
 	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
@@ -307,6 +330,10 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 	streamutils.Stats.Responses++
 	streamutils.Stats.Unlock()
 
+	if res.StatusCode == http.StatusSwitchingProtocols && isWebSocketUpgrade(res.Header) {
+		h.upgraded = true
+	}
+
 	h.responses = append(h.responses, &httpResponse{
 		response:  res,
 		timestamp: h.conversation.FirstServerPacket.UnixNano(),
@@ -314,6 +341,11 @@ func (h *httpReader) readResponse(b *bufio.Reader) error {
 		serverIP:  h.conversation.ServerIP,
 	})
 
+	// DNS over HTTPS response
+	if isDNSMessage(res.Header) {
+		h.decodeDoH(body, h.conversation.FirstServerPacket.UnixNano(), false)
+	}
+
 	// write responses to disk if configured
 	if (err == nil || decoderconfig.Instance.WriteIncomplete) && decoderconfig.Instance.FileStorage != "" {
 
@@ -370,6 +402,10 @@ func (h *httpReader) findRequest(res *http.Response) string {
 // HTTP Request
 
 func (h *httpReader) readRequest(b *bufio.Reader) error {
+	if h.upgraded {
+		return discardUpgraded(b)
+	}
+
 	req, err := http.ReadRequest(b)
 	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 		return err
@@ -435,6 +471,9 @@ func (h *httpReader) readRequest(b *bufio.Reader) error {
 
 	h.requests = append(h.requests, request)
 
+	// DNS over HTTPS query
+	h.decodeDoH(dohQuery(req, body), t, true)
+
 	if req.Method == methodPOST {
 		// write request payload to disk if configured
 		if (err == nil || decoderconfig.Instance.WriteIncomplete) && decoderconfig.Instance.FileStorage != "" {
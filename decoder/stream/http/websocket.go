@@ -0,0 +1,136 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/websocket"
+)
+
+const (
+	headerUpgrade             = "Upgrade"
+	headerWebSocketProtocol   = "Sec-Websocket-Protocol"
+	headerWebSocketExtensions = "Sec-Websocket-Extensions"
+	upgradeWebSocket          = "websocket"
+)
+
+// isWebSocketUpgrade checks if the header requests or confirms a protocol switch to WebSocket.
+func isWebSocketUpgrade(header http.Header) bool {
+	return strings.EqualFold(strings.TrimSpace(header.Get(headerUpgrade)), upgradeWebSocket)
+}
+
+// discardUpgraded skips the data sent after the protocol switch, since it does not contain HTTP messages.
+func discardUpgraded(b *bufio.Reader) error {
+	_, _ = io.Copy(ioutil.Discard, b)
+
+	return io.EOF
+}
+
+// decodeWebSocket hands the data following the opening handshake to the WebSocket decoder.
+func (h *httpReader) decodeWebSocket() {
+	if websocket.Decoder.Writer == nil {
+		return
+	}
+
+	var (
+		client, server = core.SplitDirections(h.conversation.Data)
+		req, reqEnd    = webSocketRequest(client.Bytes())
+		res, resEnd    = webSocketResponse(server.Bytes())
+	)
+
+	if res == nil {
+		return
+	}
+
+	s := websocket.NewSession(
+		h.conversation,
+		"",
+		"",
+		res.Header.Get(headerWebSocketProtocol),
+		strings.Join(res.Header[headerWebSocketExtensions], ","),
+	)
+
+	if req != nil {
+		s.Host = req.Host
+		s.URL = req.URL.String()
+
+		websocket.Decode(s, false, client.Bytes()[reqEnd:], func(offset int) time.Time {
+			return client.TimeAt(reqEnd + offset)
+		})
+	} else {
+		httpLog.Debug("WebSocket upgrade request not found",
+			zap.String("ident", h.conversation.Ident),
+		)
+	}
+
+	websocket.Decode(s, true, server.Bytes()[resEnd:], func(offset int) time.Time {
+		return server.TimeAt(resEnd + offset)
+	})
+}
+
+// webSocketRequest reads the requests sent by the client until the upgrade request,
+// and returns it along with the offset at which the WebSocket frames start.
+func webSocketRequest(data []byte) (*http.Request, int) {
+	var (
+		r  = bytes.NewReader(data)
+		br = bufio.NewReader(r)
+	)
+
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return nil, 0
+		}
+
+		_, _ = io.Copy(ioutil.Discard, req.Body)
+		_ = req.Body.Close()
+
+		if isWebSocketUpgrade(req.Header) {
+			return req, len(data) - r.Len() - br.Buffered()
+		}
+	}
+}
+
+// webSocketResponse reads the responses sent by the server until the protocol switch,
+// and returns it along with the offset at which the WebSocket frames start.
+func webSocketResponse(data []byte) (*http.Response, int) {
+	var (
+		r  = bytes.NewReader(data)
+		br = bufio.NewReader(r)
+	)
+
+	for {
+		res, err := http.ReadResponse(br, nil)
+		if err != nil {
+			return nil, 0
+		}
+
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		_ = res.Body.Close()
+
+		if res.StatusCode == http.StatusSwitchingProtocols && isWebSocketUpgrade(res.Header) {
+			return res, len(data) - r.Len() - br.Buffered()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/decoder/stream/websocket"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestWebSocketUpgrade(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder, websocket.Decoder)
+	defer cleanup()
+
+	httpRecords, wsRecords := writers[0], writers[1]
+
+	conv := streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 80,
+	},
+		streamtest.Fragment{Client: true, Data: []byte("GET /index.html HTTP/1.1\r\nHost: example.com\r\n\r\n")},
+		streamtest.Fragment{Data: []byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")},
+		streamtest.Fragment{Client: true, Data: []byte("GET /chat HTTP/1.1\r\n" +
+			"Host: example.com\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+			"Sec-WebSocket-Protocol: chat, superchat\r\n" +
+			"Sec-WebSocket-Version: 13\r\n\r\n")},
+		streamtest.Fragment{Data: []byte("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n" +
+			"Sec-WebSocket-Protocol: chat\r\n\r\n" +
+			// unmasked text frame
+			"\x81\x05Hello")},
+		// masked text frame
+		streamtest.Fragment{Client: true, Data: []byte("\x81\x85\x37\xfa\x21\x3d\x7f\x9f\x4d\x51\x58")},
+		// masked close frame without status code
+		streamtest.Fragment{Client: true, Data: []byte("\x88\x80\x37\xfa\x21\x3d")},
+	)
+
+	(&httpReader{}).New(conv).Decode()
+
+	if len(httpRecords.Records) != 2 {
+		t.Fatal("expected 2 HTTP records, got", len(httpRecords.Records))
+	}
+
+	if upgrade := httpRecords.Records[1].(*types.HTTP); upgrade.URL != "/chat" || upgrade.StatusCode != 101 {
+		t.Fatal("unexpected upgrade request", upgrade)
+	}
+
+	if len(wsRecords.Records) != 3 {
+		t.Fatal("expected 3 WebSocket records, got", len(wsRecords.Records))
+	}
+
+	client := wsRecords.Records[0].(*types.WebSocket)
+	if client.FromServer || client.Payload != "Hello" || !client.Masked || client.Timestamp != ts.Add(4*time.Second).UnixNano() {
+		t.Fatal("unexpected client message", client)
+	}
+
+	if client.Host != "example.com" || client.URL != "/chat" || client.Protocol != "chat" {
+		t.Fatal("unexpected session information", client)
+	}
+
+	if c := wsRecords.Records[1].(*types.WebSocket); c.Opcode != "close" || c.Timestamp != ts.Add(5*time.Second).UnixNano() {
+		t.Fatal("unexpected close frame", c)
+	}
+
+	server := wsRecords.Records[2].(*types.WebSocket)
+	if !server.FromServer || server.Payload != "Hello" || server.Masked || server.Timestamp != ts.Add(3*time.Second).UnixNano() {
+		t.Fatal("unexpected server message", server)
+	}
+}
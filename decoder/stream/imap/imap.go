@@ -0,0 +1,76 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package imap
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	imapLog        = zap.NewNop()
+	imapLogSugared = imapLog.Sugar()
+
+	serviceIMAP         = "IMAP"
+	imapGreeting        = []byte("* OK")
+	imapPreAuthGreeting = []byte("* PREAUTH")
+	imapName            = []byte("IMAP")
+	imapReadyName       = []byte("READY")
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_IMAP,
+	Name:        serviceIMAP,
+	Description: "The Internet Message Access Protocol is used to access emails on a mail server",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		imapLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"imap",
+			decoderconfig.Instance.Debug,
+		)
+		if err != nil {
+			return err
+		}
+
+		imapLogSugared = imapLog.Sugar()
+
+		return nil
+	},
+	CanDecode: func(client, server []byte) bool {
+		if bytes.HasPrefix(server, imapPreAuthGreeting) {
+			return true
+		}
+
+		if !bytes.HasPrefix(server, imapGreeting) {
+			return false
+		}
+
+		upper := bytes.ToUpper(server)
+
+		return bytes.Contains(upper, imapName) || bytes.Contains(upper, imapReadyName)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return imapLog.Sync()
+	},
+	Factory: &imapReader{},
+	Typ:     core.TCP,
+}
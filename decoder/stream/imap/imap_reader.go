@@ -0,0 +1,606 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package imap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/mail"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+/*
+ * IMAP protocol
+ */
+
+const (
+	// IMAP commands.
+	imapLogin        = "LOGIN"
+	imapAuthenticate = "AUTHENTICATE"
+	imapSelect       = "SELECT"
+	imapExamine      = "EXAMINE"
+	imapAppend       = "APPEND"
+	imapStartTLS     = "STARTTLS"
+	imapUID          = "UID"
+
+	// IMAP responses.
+	imapOK      = "OK"
+	imapFetch   = "FETCH"
+	imapUntag   = "*"
+	imapCont    = "+"
+	imapPreAuth = "PREAUTH"
+
+	// SASL mechanisms.
+	saslPlain = "PLAIN"
+	saslLogin = "LOGIN"
+
+	// maxLiteralSize limits the size of a literal, to avoid allocating large buffers for malformed streams.
+	maxLiteralSize = 64 * 1024 * 1024
+)
+
+// fetch items that contain a complete RFC822 message.
+var imapMessageItems = map[string]struct{}{
+	"RFC822":   {},
+	"BODY[]":   {},
+	"BINARY[]": {},
+}
+
+// literals are announced at the end of a line, e.g: {310} or {310+} for non synchronizing literals.
+var reLiteral = regexp.MustCompile(`\{(\d+)\+?\}$`)
+
+// literal announcement at the start of a token, the remainder of the line follows the literal directly.
+var reLiteralToken = regexp.MustCompile(`^\{\d+\+?\}`)
+
+// imapLine is a logical line of the protocol,
+// the literals embedded in the line are replaced by their announcement and returned separately.
+type imapLine struct {
+	text      string
+	literals  [][]byte
+	timestamp time.Time
+}
+
+// imapStream is the data sent into one direction of the connection.
+type imapStream struct {
+	*core.StreamDirection
+
+	data []byte
+	pos  int
+}
+
+// newStreams splits the conversation into the data sent by the client and the server.
+func newStreams(data core.DataFragments) (client, server *imapStream) {
+	c, s := core.SplitDirections(data)
+
+	return &imapStream{StreamDirection: c, data: c.Bytes()}, &imapStream{StreamDirection: s, data: s.Bytes()}
+}
+
+// next returns the next logical line.
+func (s *imapStream) next() (*imapLine, bool) {
+	if s.pos >= len(s.data) {
+		return nil, false
+	}
+
+	var (
+		l    = &imapLine{timestamp: s.TimeAt(s.pos)}
+		text strings.Builder
+	)
+
+	for s.pos < len(s.data) {
+		var (
+			segment []byte
+			i       = bytes.IndexByte(s.data[s.pos:], '\n')
+		)
+
+		if i < 0 {
+			segment = s.data[s.pos:]
+			s.pos = len(s.data)
+		} else {
+			segment = s.data[s.pos : s.pos+i+1]
+			s.pos += i + 1
+		}
+
+		segment = bytes.TrimRight(segment, "\r\n")
+		text.Write(segment)
+
+		m := reLiteral.FindSubmatch(segment)
+		if m == nil {
+			break
+		}
+
+		n, err := strconv.Atoi(string(m[1]))
+		if err != nil || n > maxLiteralSize {
+			break
+		}
+
+		if s.pos+n > len(s.data) {
+			n = len(s.data) - s.pos
+		}
+
+		l.literals = append(l.literals, s.data[s.pos:s.pos+n])
+		s.pos += n
+	}
+
+	l.text = text.String()
+
+	return l, true
+}
+
+type imapReader struct {
+	conversation *core.ConversationInfo
+
+	imap *types.IMAP
+
+	// commands mapped to their tags
+	commands map[string]*types.IMAPCommand
+
+	// authentication command that is waiting for continuation data from the client
+	auth     *types.IMAPCommand
+	authMech string
+	authStep int
+	authUser string
+	authPass string
+
+	// credentials mapped to the tag of the authentication command, until authentication succeeded
+	authByTag map[string][2]string
+
+	// number of lines received from the server
+	numResponses int
+}
+
+// New will instantiate a new IMAP reader.
+func (h *imapReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &imapReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the stream according to the IMAP protocol.
+func (h *imapReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	h.imap = &types.IMAP{
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		ClientIP:    h.conversation.ClientIP,
+		ServerIP:    h.conversation.ServerIP,
+		ClientPort:  h.conversation.ClientPort,
+		ServerPort:  h.conversation.ServerPort,
+		CommunityID: h.conversation.CommunityID,
+	}
+	h.commands = make(map[string]*types.IMAPCommand)
+	h.authByTag = make(map[string][2]string)
+
+	client, server := newStreams(h.conversation.Data)
+
+	// commands are matched to the responses by their tag,
+	// so both directions can be processed independently
+	for l, ok := client.next(); ok; l, ok = client.next() {
+		if !h.handleCommand(l) {
+			break
+		}
+	}
+
+	for l, ok := server.next(); ok; l, ok = server.next() {
+		if !h.handleResponse(l) {
+			break
+		}
+	}
+
+	if h.imap.Banner == "" && len(h.imap.Commands) == 0 {
+		return
+	}
+
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		h.imap.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(h.imap)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
+
+// handleCommand processes a line sent by the client.
+// It returns false once the connection is upgraded to TLS.
+func (h *imapReader) handleCommand(l *imapLine) bool {
+	if h.auth != nil {
+		h.handleAuthData(l.text)
+
+		return true
+	}
+
+	parts := strings.SplitN(l.text, " ", 3)
+	if len(parts) < 2 {
+		return true
+	}
+
+	c := &types.IMAPCommand{
+		Timestamp: l.timestamp.UnixNano(),
+		Tag:       parts[0],
+		Command:   strings.ToUpper(parts[1]),
+	}
+
+	if len(parts) > 2 {
+		c.Arguments = parts[2]
+	}
+
+	// UID prefixes FETCH, SEARCH, STORE, COPY and MOVE
+	if c.Command == imapUID {
+		sub := strings.SplitN(c.Arguments, " ", 2)
+		c.Command += " " + strings.ToUpper(sub[0])
+
+		if len(sub) > 1 {
+			c.Arguments = sub[1]
+		} else {
+			c.Arguments = ""
+		}
+	}
+
+	imapLog.Debug("command",
+		zap.String("ident", h.conversation.Ident),
+		zap.String("tag", c.Tag),
+		zap.String("command", c.Command),
+	)
+
+	h.imap.Commands = append(h.imap.Commands, c)
+	h.commands[c.Tag] = c
+
+	args := tokenize(c.Arguments, l.literals)
+
+	switch c.Command {
+	case imapLogin:
+		if len(args) > 1 {
+			h.authByTag[c.Tag] = [2]string{args[0], args[1]}
+		}
+	case imapAuthenticate:
+		if len(args) == 0 {
+			break
+		}
+
+		h.auth = c
+		h.authMech = strings.ToUpper(args[0])
+		h.authStep = 0
+		h.authUser, h.authPass = "", ""
+
+		// SASL initial response
+		if len(args) > 1 {
+			h.handleAuthData(args[1])
+		}
+	case imapAppend:
+		// the message is the last literal
+		if len(l.literals) > 0 {
+			h.parseMail(l.literals[len(l.literals)-1])
+		}
+	case imapStartTLS:
+		return false
+	}
+
+	return true
+}
+
+// handleAuthData processes the continuation data for the AUTHENTICATE command.
+func (h *imapReader) handleAuthData(data string) {
+	c := h.auth
+
+	// cancelled by the client
+	if data == imapUntag {
+		h.auth = nil
+
+		return
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		imapLog.Debug("failed to decode authentication data",
+			zap.String("ident", h.conversation.Ident),
+			zap.Error(err),
+		)
+
+		h.auth = nil
+
+		return
+	}
+
+	switch h.authMech {
+	case saslPlain:
+		// authorization identity, authentication identity and password separated by NUL
+		fields := bytes.Split(decoded, []byte{0})
+		if len(fields) == 3 {
+			h.authUser, h.authPass = string(fields[1]), string(fields[2])
+		}
+
+		h.auth = nil
+	case saslLogin:
+		if h.authStep == 0 {
+			h.authUser = string(decoded)
+			h.authStep++
+
+			return
+		}
+
+		h.authPass = string(decoded)
+		h.auth = nil
+	default:
+		h.auth = nil
+	}
+
+	if h.auth == nil && h.authUser != "" {
+		h.authByTag[c.Tag] = [2]string{h.authUser, h.authPass}
+	}
+}
+
+// handleResponse processes a line sent by the server.
+// It returns false once the connection is upgraded to TLS.
+func (h *imapReader) handleResponse(l *imapLine) bool {
+	h.numResponses++
+
+	parts := strings.SplitN(l.text, " ", 3)
+	if len(parts) < 2 || parts[0] == imapCont {
+		return true
+	}
+
+	if parts[0] == imapUntag {
+		status := strings.ToUpper(parts[1])
+
+		// greeting
+		if h.numResponses == 1 && (status == imapOK || status == imapPreAuth) {
+			h.imap.Banner = strings.TrimPrefix(l.text, "* ")
+
+			return true
+		}
+
+		// untagged FETCH responses carry the message data: * 12 FETCH (UID 1 RFC822 {310}
+		if len(parts) == 3 && strings.HasPrefix(strings.ToUpper(parts[2]), imapFetch) {
+			h.handleFetch(l)
+		}
+
+		return true
+	}
+
+	c, ok := h.commands[parts[0]]
+	if !ok {
+		return true
+	}
+
+	c.Status = strings.ToUpper(parts[1])
+	if len(parts) > 2 {
+		c.Response = parts[2]
+	}
+
+	if c.Status != imapOK {
+		return true
+	}
+
+	switch {
+	case c.Command == imapLogin || c.Command == imapAuthenticate:
+		if creds, exists := h.authByTag[c.Tag]; exists {
+			h.imap.User, h.imap.Pass = creds[0], creds[1]
+		}
+	case c.Command == imapSelect || c.Command == imapExamine:
+		if args := tokenize(c.Arguments, nil); len(args) > 0 {
+			h.addMailbox(args[0])
+		}
+	case c.Command == imapStartTLS:
+		return false
+	}
+
+	return true
+}
+
+// handleFetch extracts the messages from a FETCH response.
+func (h *imapReader) handleFetch(l *imapLine) {
+	for _, data := range messageLiterals(l.text, l.literals) {
+		h.parseMail(data)
+	}
+}
+
+// messageLiterals returns the values of the data items in a FETCH response that contain a message.
+// The data items are pairs of a name and a value, literals nested in other values, e.g. in an ENVELOPE, are skipped.
+func messageLiterals(s string, literals [][]byte) (msgs [][]byte) {
+	var (
+		i       = strings.IndexByte(s, '(')
+		depth   int
+		index   int
+		name    string
+		isValue bool
+	)
+
+	if i < 0 {
+		return nil
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case ' ':
+			i++
+		case '(':
+			// a parenthesized value, e.g: ENVELOPE (...) or FLAGS (\Seen)
+			if depth == 1 {
+				isValue = false
+			}
+
+			depth++
+			i++
+		case ')':
+			depth--
+			if depth == 0 {
+				return msgs
+			}
+
+			i++
+		case '"':
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' {
+					i++
+				}
+
+				i++
+			}
+
+			i++
+
+			if depth == 1 {
+				isValue = !isValue
+			}
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 || index >= len(literals) {
+				return msgs
+			}
+
+			if depth == 1 && isValue {
+				if _, ok := imapMessageItems[name]; ok {
+					msgs = append(msgs, literals[index])
+				}
+			}
+
+			if depth == 1 {
+				isValue = !isValue
+			}
+
+			index++
+			i += end + 1
+		default:
+			// atoms can contain a section with spaces and parentheses, e.g: BODY[HEADER.FIELDS (FROM)]
+			start, section := i, false
+			for i < len(s) && (section || (s[i] != ' ' && s[i] != '(' && s[i] != ')')) {
+				switch s[i] {
+				case '[':
+					section = true
+				case ']':
+					section = false
+				}
+
+				i++
+			}
+
+			if depth == 1 {
+				if !isValue {
+					name = strings.ToUpper(s[start:i])
+				}
+
+				isValue = !isValue
+			}
+		}
+	}
+
+	return msgs
+}
+
+// parseMail hands the message to the mail decoder.
+func (h *imapReader) parseMail(data []byte) {
+	if len(data) == 0 || mail.Decoder.Writer == nil {
+		return
+	}
+
+	m := mail.Parse(h.conversation, data, "", "", imapLogSugared, serviceIMAP)
+	mail.WriteMail(m)
+
+	h.imap.MailIDs = append(h.imap.MailIDs, m.ID)
+}
+
+// addMailbox adds the mailbox if it has not been seen before.
+func (h *imapReader) addMailbox(name string) {
+	for _, m := range h.imap.Mailboxes {
+		if m == name {
+			return
+		}
+	}
+
+	h.imap.Mailboxes = append(h.imap.Mailboxes, name)
+}
+
+// tokenize splits the arguments of a command into atoms, quoted strings and literals.
+// Parenthesized lists are returned as a single token.
+func tokenize(s string, literals [][]byte) []string {
+	var (
+		tokens []string
+		index  int
+	)
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ' ':
+			i++
+		case '"':
+			var b strings.Builder
+
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+
+				b.WriteByte(s[i])
+				i++
+			}
+
+			i++
+
+			tokens = append(tokens, b.String())
+		case '(':
+			depth, start := 0, i
+			for i < len(s) {
+				if s[i] == '(' {
+					depth++
+				} else if s[i] == ')' {
+					depth--
+					if depth == 0 {
+						i++
+
+						break
+					}
+				}
+				i++
+			}
+
+			tokens = append(tokens, s[start:i])
+		default:
+			start := i
+			for i < len(s) && s[i] != ' ' {
+				i++
+			}
+
+			tok := s[start:i]
+			if loc := reLiteralToken.FindStringIndex(tok); loc != nil && index < len(literals) {
+				// the literal is followed by the remainder of the line, e.g: {5}) or {5}
+				i = start + loc[1]
+				tok = string(literals[index])
+				index++
+			}
+
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return tokens
+}
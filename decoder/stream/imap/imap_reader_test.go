@@ -0,0 +1,189 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package imap
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/mail"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func conversation(lines ...string) *core.ConversationInfo {
+	return streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 143,
+	}, streamtest.Lines(lines...)...)
+}
+
+func TestIMAPDecoder(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder, mail.Decoder)
+	w, mw := writers[0], writers[1]
+	defer cleanup()
+
+	msg := "From: alice@example.com\r\nTo: bob@example.com\r\nSubject: Hello\r\n\r\nHi Bob!\r\n"
+
+	conv := conversation(
+		"* OK [CAPABILITY IMAP4rev1 STARTTLS] Dovecot ready.",
+		"C: a1 LOGIN bob \"s3cr\\\"et\"",
+		"a1 OK Logged in",
+		"C: a2 SELECT INBOX",
+		"* 1 EXISTS",
+		"a2 OK [READ-WRITE] Select completed",
+		"C: a3 UID FETCH 1 (FLAGS RFC822)",
+		fmt.Sprintf("* 1 FETCH (UID 1 FLAGS (\\Seen) RFC822 {%d}\r\n%s)", len(msg), msg),
+		"a3 OK Fetch completed",
+		"C: a4 EXAMINE \"Sent Items\"",
+		"a4 NO Mailbox does not exist",
+		"C: a5 LOGOUT",
+		"* BYE Logging out",
+		"a5 OK Logout completed",
+	)
+
+	if !Decoder.CanDecode(conv.Data[1].Raw(), conv.Data[0].Raw()) {
+		t.Fatal("expected decoder to match")
+	}
+
+	(&imapReader{}).New(conv).Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	i := w.Records[0].(*types.IMAP)
+
+	if i.Banner != "OK [CAPABILITY IMAP4rev1 STARTTLS] Dovecot ready." {
+		t.Fatalf("unexpected banner: %q", i.Banner)
+	}
+
+	if i.User != "bob" || i.Pass != "s3cr\"et" {
+		t.Fatal("unexpected credentials", i.User, i.Pass)
+	}
+
+	if len(i.Commands) != 5 {
+		t.Fatal("expected 5 commands, got", len(i.Commands))
+	}
+
+	if c := i.Commands[2]; c.Command != "UID FETCH" || c.Status != "OK" || c.Timestamp != ts.Add(6*time.Second).UnixNano() {
+		t.Fatal("unexpected command", c)
+	}
+
+	if len(i.Mailboxes) != 1 || i.Mailboxes[0] != "INBOX" {
+		t.Fatal("unexpected mailboxes", i.Mailboxes)
+	}
+
+	if len(mw.Records) != 1 || len(i.MailIDs) != 1 {
+		t.Fatal("expected 1 mail, got", len(mw.Records))
+	}
+
+	m := mw.Records[0].(*types.Mail)
+	if m.Subject != "Hello" || m.From != "alice@example.com" || m.Origin != serviceIMAP || m.ID != i.MailIDs[0] {
+		t.Fatal("unexpected mail", m)
+	}
+}
+
+func TestIMAPFetchEnvelope(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder, mail.Decoder)
+	w, mw := writers[0], writers[1]
+	defer cleanup()
+
+	var (
+		date   = "Wed, 1 Jan 2020 12:00:00 +0000"
+		header = "Subject: Header\r\n\r\n"
+		msg    = "From: alice@example.com\r\nSubject: Body\r\n\r\nHi Bob!\r\n"
+	)
+
+	// the literals nested in the ENVELOPE and the header fields must not be taken for the message
+	conv := conversation(
+		"* OK IMAP server ready",
+		"C: a1 FETCH 1 (ENVELOPE BODY[HEADER.FIELDS (SUBJECT)] BODY[])",
+		fmt.Sprintf("* 1 FETCH (ENVELOPE ({%d}\r\n%s \"Body\" NIL NIL NIL NIL NIL NIL NIL \"<1@example.com>\") BODY[HEADER.FIELDS (SUBJECT)] {%d}\r\n%s BODY[] {%d}\r\n%s)",
+			len(date), date, len(header), header, len(msg), msg),
+		"a1 OK Fetch completed",
+	)
+
+	(&imapReader{}).New(conv).Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	if len(mw.Records) != 1 {
+		t.Fatal("expected 1 mail, got", len(mw.Records))
+	}
+
+	if m := mw.Records[0].(*types.Mail); m.Subject != "Body" || m.From != "alice@example.com" {
+		t.Fatal("unexpected mail", m)
+	}
+}
+
+func TestIMAPAuthenticate(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder, mail.Decoder)
+	w, mw := writers[0], writers[1]
+	defer cleanup()
+
+	msg := "Subject: Draft\r\n\r\ntext\r\n"
+
+	conv := conversation(
+		"* OK IMAP server ready",
+		"C: 1 AUTHENTICATE PLAIN",
+		"+ ",
+		"C: AGFsaWNlAHBhc3N3b3Jk",
+		"1 OK authenticated",
+		fmt.Sprintf("C: 2 APPEND Drafts (\\Draft) {%d+}\r\n%s", len(msg), msg),
+		"2 OK append completed",
+		"C: 3 STARTTLS",
+		"3 OK begin TLS",
+		"C: \x16\x03\x01",
+	)
+
+	(&imapReader{}).New(conv).Decode()
+
+	i := w.Records[0].(*types.IMAP)
+
+	if i.User != "alice" || i.Pass != "password" {
+		t.Fatal("unexpected credentials", i.User, i.Pass)
+	}
+
+	if len(i.Commands) != 3 || i.Commands[1].Command != "APPEND" {
+		t.Fatal("unexpected commands", i.Commands)
+	}
+
+	if len(mw.Records) != 1 || mw.Records[0].(*types.Mail).Subject != "Draft" {
+		t.Fatal("expected appended mail to be parsed")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	toks := tokenize("\"a b\" (\\Seen \\Draft) {3} atom", [][]byte{[]byte("lit")})
+
+	expected := []string{"a b", "(\\Seen \\Draft)", "lit", "atom"}
+	if len(toks) != len(expected) {
+		t.Fatal("unexpected tokens", toks)
+	}
+
+	for i, tok := range expected {
+		if toks[i] != tok {
+			t.Fatal("unexpected tokens", toks)
+		}
+	}
+}
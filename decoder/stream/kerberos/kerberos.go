@@ -0,0 +1,97 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"encoding/binary"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	kerberosLog        = zap.NewNop()
+	kerberosLogSugared = kerberosLog.Sugar()
+
+	serviceKerberos = "Kerberos"
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_Kerberos,
+	Name:        serviceKerberos,
+	Description: "Kerberos is the default authentication protocol in Windows domains, clients request tickets for services from the key distribution center",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		kerberosLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"kerberos",
+			decoderconfig.Instance.Debug,
+		)
+		if err != nil {
+			return err
+		}
+
+		kerberosLogSugared = kerberosLog.Sugar()
+
+		return nil
+	},
+	CanDecode: func(client, server []byte) bool {
+		if !isKerberosMessage(client, msgTypeASReq, msgTypeTGSReq) {
+			return false
+		}
+
+		return len(server) == 0 || isKerberosMessage(server, msgTypeASRep, msgTypeTGSRep, msgTypeError)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return kerberosLog.Sync()
+	},
+	Factory: &kerberosReader{},
+	Typ:     core.All,
+}
+
+// isKerberosMessage checks if the data starts with one of the given message types.
+// Messages sent over TCP are prefixed with their length.
+func isKerberosMessage(data []byte, msgTypes ...int) bool {
+	if isFramed(data) {
+		if len(data) < tcpLengthBytes || int(binary.BigEndian.Uint32(data)) > maxMessageSize {
+			return false
+		}
+
+		data = data[tcpLengthBytes:]
+	}
+
+	// the application tag must be followed by a length
+	if len(data) < 2 {
+		return false
+	}
+
+	for _, t := range msgTypes {
+		if data[0] == byte(application(t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isFramed checks whether the data starts with the length prefix used over TCP,
+// instead of the application tag that a message sent over UDP starts with.
+func isFramed(data []byte) bool {
+	return len(data) > 0 && data[0]&classMask != classConstructedApplication
+}
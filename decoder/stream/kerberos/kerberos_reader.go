@@ -0,0 +1,246 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const (
+	transportTCP = "TCP"
+	transportUDP = "UDP"
+)
+
+type kerberosReader struct {
+	conversation *core.ConversationInfo
+}
+
+// New will instantiate a new Kerberos reader.
+func (h *kerberosReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &kerberosReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the messages exchanged with the key distribution center.
+// Requests are answered in order, so each request is matched with the next reply or error sent by the server,
+// and one audit record is written for each exchange.
+func (h *kerberosReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	if len(h.conversation.Data) == 0 {
+		return
+	}
+
+	transport := transportUDP
+	if isFramed(h.conversation.Data[0].Raw()) {
+		transport = transportTCP
+	}
+
+	requests, replies := h.readMessages(transport == transportTCP)
+
+	for i := 0; i < len(requests) || i < len(replies); i++ {
+		var req, res *message
+
+		if i < len(requests) {
+			req = requests[i]
+		}
+
+		if i < len(replies) {
+			res = replies[i]
+		}
+
+		h.writeKerberos(newRecord(req, res, transport))
+	}
+}
+
+// readMessages parses the requests sent by the client and the replies sent by the server.
+// Over UDP, each datagram contains a single message.
+// Over TCP, the messages are prefixed with their length and can span multiple fragments.
+func (h *kerberosReader) readMessages(framed bool) (requests, replies []*message) {
+	if framed {
+		client, server := core.SplitDirections(h.conversation.Data)
+
+		return h.readFramed(client), h.readFramed(server)
+	}
+
+	for _, d := range h.conversation.Data {
+		m, err := parseMessage(d.Raw())
+		if err != nil {
+			h.logError(err)
+
+			continue
+		}
+
+		m.timestamp = core.FragmentTimestamp(d)
+
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			requests = append(requests, m)
+		} else {
+			replies = append(replies, m)
+		}
+	}
+
+	return requests, replies
+}
+
+// readFramed parses the length prefixed messages of a TCP stream.
+func (h *kerberosReader) readFramed(s *core.StreamDirection) (msgs []*message) {
+	var (
+		data = s.Bytes()
+		pos  int
+	)
+
+	for pos+tcpLengthBytes <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if length > maxMessageSize || pos+tcpLengthBytes+length > len(data) {
+			break
+		}
+
+		m, err := parseMessage(data[pos+tcpLengthBytes : pos+tcpLengthBytes+length])
+		if err != nil {
+			h.logError(err)
+		} else {
+			m.timestamp = s.TimeAt(pos)
+			msgs = append(msgs, m)
+		}
+
+		pos += tcpLengthBytes + length
+	}
+
+	return msgs
+}
+
+// logError logs a message that could not be parsed.
+func (h *kerberosReader) logError(err error) {
+	kerberosLog.Debug("failed to parse message",
+		zap.String("ident", h.conversation.Ident),
+		zap.Error(err),
+	)
+}
+
+// newRecord creates the audit record for a request and the matching reply, either of them can be missing.
+func newRecord(req, res *message, transport string) *types.Kerberos {
+	k := &types.Kerberos{
+		TransportProto: transport,
+	}
+
+	if req != nil {
+		k.Timestamp = req.timestamp.UnixNano()
+		k.RequestType = messageTypes[req.msgType]
+		k.ClientName = req.clientName
+		k.Realm = req.realm
+		k.ServiceName = req.serviceName
+		k.Till = timestamp(req.till)
+		k.RenewTill = timestamp(req.renewTill)
+		k.PreAuthenticated = req.preAuthenticated()
+
+		weak := len(req.eTypes) > 0
+		for _, e := range req.eTypes {
+			k.EncryptionTypes = append(k.EncryptionTypes, encryptionTypeName(e))
+			weak = weak && weakEncryptionTypes[e]
+		}
+
+		// a client that only offers weak encryption types is likely trying to obtain a ticket that can be cracked
+		k.WeakEncryption = weak
+	}
+
+	if res == nil {
+		return k
+	}
+
+	if k.Timestamp == 0 {
+		k.Timestamp = res.timestamp.UnixNano()
+	}
+
+	k.ResponseType = messageTypes[res.msgType]
+
+	// the client name of a TGS-REQ is encrypted, but the server includes it in the reply
+	if k.ClientName == "" {
+		k.ClientName = res.clientName
+	}
+
+	if k.Realm == "" {
+		k.Realm = res.realm
+	}
+
+	if k.ServiceName == "" {
+		k.ServiceName = res.serviceName
+	}
+
+	switch res.msgType {
+	case msgTypeASRep, msgTypeTGSRep:
+		k.TicketEncryptionType = encryptionTypeName(res.ticketEType)
+		k.ReplyEncryptionType = encryptionTypeName(res.replyEType)
+		k.WeakEncryption = k.WeakEncryption || weakEncryptionTypes[res.ticketEType] || weakEncryptionTypes[res.replyEType]
+
+		// the reply is encrypted with the key of the client, without pre-authentication anyone can request it
+		k.NoPreAuthRequired = res.msgType == msgTypeASRep && req != nil && !k.PreAuthenticated
+	case msgTypeError:
+		k.ErrorCode = int32(res.errorCode)
+		k.ErrorName = errorName(res.errorCode)
+	}
+
+	return k
+}
+
+// timestamp returns the unix timestamp in nanoseconds, or zero for an unset time.
+func timestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+// writeKerberos writes the Kerberos audit record.
+func (h *kerberosReader) writeKerberos(k *types.Kerberos) {
+	k.ClientIP = h.conversation.ClientIP
+	k.ServerIP = h.conversation.ServerIP
+	k.ClientPort = h.conversation.ClientPort
+	k.ServerPort = h.conversation.ServerPort
+	k.CommunityID = h.conversation.CommunityID
+
+	kerberosLog.Debug("exchange",
+		zap.String("ident", h.conversation.Ident),
+		zap.String("request", k.RequestType),
+		zap.String("response", k.ResponseType),
+		zap.String("client", k.ClientName),
+		zap.String("service", k.ServiceName),
+	)
+
+	if decoderconfig.Instance.ExportMetrics {
+		k.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(k)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	ts   = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	till = time.Date(2037, 9, 13, 2, 48, 5, 0, time.UTC)
+
+	krbtgt = []string{"krbtgt", "CORP.LOCAL"}
+	mssql  = []string{"MSSQLSvc", "sql.corp.local"}
+)
+
+func addInt(b *cryptobyte.Builder, tag int, v int64) {
+	b.AddASN1(explicit(tag), func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(v)
+	})
+}
+
+func addString(b *cryptobyte.Builder, tag int, s string) {
+	b.AddASN1(explicit(tag), func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1.GeneralString, func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(s))
+		})
+	})
+}
+
+func addPrincipal(b *cryptobyte.Builder, tag int, names ...string) {
+	b.AddASN1(explicit(tag), func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 1)
+			b.AddASN1(explicit(1), func(b *cryptobyte.Builder) {
+				b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for _, n := range names {
+						b.AddASN1(asn1.GeneralString, func(b *cryptobyte.Builder) {
+							b.AddBytes([]byte(n))
+						})
+					}
+				})
+			})
+		})
+	})
+}
+
+func addEncryptedData(b *cryptobyte.Builder, tag int, eType int64) {
+	b.AddASN1(explicit(tag), func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, eType)
+			b.AddASN1(explicit(2), func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(make([]byte, 32))
+			})
+		})
+	})
+}
+
+func build(f cryptobyte.BuilderContinuation) []byte {
+	var b cryptobyte.Builder
+
+	f(&b)
+
+	return b.BytesOrPanic()
+}
+
+// request creates an AS-REQ or TGS-REQ, the client name is only set if it is not empty.
+func request(msgType int, paDataTypes []int64, cname string, sname []string, eTypes ...int64) []byte {
+	return build(func(b *cryptobyte.Builder) {
+		b.AddASN1(application(msgType), func(b *cryptobyte.Builder) {
+			b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				addInt(b, 1, 5)
+				addInt(b, 2, int64(msgType))
+
+				if len(paDataTypes) > 0 {
+					b.AddASN1(explicit(3), func(b *cryptobyte.Builder) {
+						b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+							for _, t := range paDataTypes {
+								b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+									addInt(b, 1, t)
+									b.AddASN1(explicit(2), func(b *cryptobyte.Builder) {
+										b.AddASN1OctetString([]byte{0x30, 0x00})
+									})
+								})
+							}
+						})
+					})
+				}
+
+				b.AddASN1(explicit(4), func(b *cryptobyte.Builder) {
+					b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						b.AddASN1(explicit(0), func(b *cryptobyte.Builder) {
+							b.AddASN1BitString([]byte{0x40, 0x81, 0x00, 0x10})
+						})
+
+						if cname != "" {
+							addPrincipal(b, 1, cname)
+						}
+
+						addString(b, 2, "CORP.LOCAL")
+						addPrincipal(b, 3, sname...)
+
+						b.AddASN1(explicit(5), func(b *cryptobyte.Builder) {
+							b.AddASN1GeneralizedTime(till)
+						})
+						b.AddASN1(explicit(6), func(b *cryptobyte.Builder) {
+							b.AddASN1GeneralizedTime(till)
+						})
+						addInt(b, 7, 12345)
+						b.AddASN1(explicit(8), func(b *cryptobyte.Builder) {
+							b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+								for _, e := range eTypes {
+									b.AddASN1Int64(e)
+								}
+							})
+						})
+					})
+				})
+			})
+		})
+	})
+}
+
+// reply creates an AS-REP or TGS-REP.
+func reply(msgType int, cname string, sname []string, ticketEType, replyEType int64) []byte {
+	return build(func(b *cryptobyte.Builder) {
+		b.AddASN1(application(msgType), func(b *cryptobyte.Builder) {
+			b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				addInt(b, 0, 5)
+				addInt(b, 1, int64(msgType))
+				addString(b, 3, "CORP.LOCAL")
+				addPrincipal(b, 4, cname)
+				b.AddASN1(explicit(5), func(b *cryptobyte.Builder) {
+					b.AddASN1(application(tagTicket), func(b *cryptobyte.Builder) {
+						b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+							addInt(b, 0, 5)
+							addString(b, 1, "CORP.LOCAL")
+							addPrincipal(b, 2, sname...)
+							addEncryptedData(b, 3, ticketEType)
+						})
+					})
+				})
+				addEncryptedData(b, 6, replyEType)
+			})
+		})
+	})
+}
+
+// krbError creates a KRB-ERROR.
+func krbError(code int64) []byte {
+	return build(func(b *cryptobyte.Builder) {
+		b.AddASN1(application(msgTypeError), func(b *cryptobyte.Builder) {
+			b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				addInt(b, 0, 5)
+				addInt(b, 1, msgTypeError)
+				b.AddASN1(explicit(4), func(b *cryptobyte.Builder) {
+					b.AddASN1GeneralizedTime(ts)
+				})
+				addInt(b, 5, 0)
+				addInt(b, 6, code)
+				addString(b, 9, "CORP.LOCAL")
+				addPrincipal(b, 10, "krbtgt", "CORP.LOCAL")
+			})
+		})
+	})
+}
+
+// framed prefixes the message with its length, as done over TCP.
+func framed(msg []byte) []byte {
+	b := make([]byte, tcpLengthBytes, tcpLengthBytes+len(msg))
+	binary.BigEndian.PutUint32(b, uint32(len(msg)))
+
+	return append(b, msg...)
+}
+
+func conversation(fragments ...streamtest.Fragment) *core.ConversationInfo {
+	return streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 88,
+	}, fragments...)
+}
+
+func decode(t *testing.T, conv *core.ConversationInfo) []*types.Kerberos {
+	t.Helper()
+
+	writers, cleanup := streamtest.Setup(Decoder)
+	defer cleanup()
+
+	w := writers[0]
+
+	Decoder.Factory.New(conv).Decode()
+
+	records := make([]*types.Kerberos, len(w.Records))
+	for i, r := range w.Records {
+		records[i] = r.(*types.Kerberos)
+	}
+
+	return records
+}
+
+func TestKerberosUDP(t *testing.T) {
+	var (
+		req = request(msgTypeASReq, []int64{128}, "svc_backup", krbtgt, 18, 17, 23)
+		res = reply(msgTypeASRep, "svc_backup", krbtgt, 18, 23)
+	)
+
+	if !Decoder.CanDecode(req, res) || Decoder.CanDecode(res, req) || Decoder.CanDecode([]byte("GET /"), nil) {
+		t.Fatal("unexpected result from CanDecode")
+	}
+
+	records := decode(t, conversation(
+		streamtest.Fragment{Client: true, Data: req},
+		streamtest.Fragment{Data: res},
+	))
+
+	if len(records) != 1 {
+		t.Fatal("expected 1 record, got", len(records))
+	}
+
+	k := records[0]
+	if k.TransportProto != transportUDP || k.RequestType != "AS-REQ" || k.ResponseType != "AS-REP" {
+		t.Fatal("unexpected message types", k.TransportProto, k.RequestType, k.ResponseType)
+	}
+
+	if k.ClientName != "svc_backup" || k.Realm != "CORP.LOCAL" || k.ServiceName != "krbtgt/CORP.LOCAL" {
+		t.Fatal("unexpected principals", k.ClientName, k.Realm, k.ServiceName)
+	}
+
+	if strings.Join(k.EncryptionTypes, ",") != "aes256-cts-hmac-sha1-96,aes128-cts-hmac-sha1-96,rc4-hmac" {
+		t.Fatal("unexpected encryption types", k.EncryptionTypes)
+	}
+
+	if k.TicketEncryptionType != "aes256-cts-hmac-sha1-96" || k.ReplyEncryptionType != "rc4-hmac" {
+		t.Fatal("unexpected reply encryption types", k.TicketEncryptionType, k.ReplyEncryptionType)
+	}
+
+	if k.Timestamp != ts.UnixNano() || k.Till != till.UnixNano() || k.RenewTill != till.UnixNano() {
+		t.Fatal("unexpected times", k.Timestamp, k.Till, k.RenewTill)
+	}
+
+	// the AS-REP was issued without pre-authentication, and the reply is encrypted with RC4
+	if k.PreAuthenticated || !k.NoPreAuthRequired || !k.WeakEncryption {
+		t.Fatal("unexpected flags", k.PreAuthenticated, k.NoPreAuthRequired, k.WeakEncryption)
+	}
+}
+
+func TestKerberosTCP(t *testing.T) {
+	tgsReq := framed(request(msgTypeTGSReq, []int64{1}, "", mssql, 23))
+
+	records := decode(t, conversation(
+		streamtest.Fragment{Client: true, Data: framed(request(msgTypeASReq, nil, "alice", krbtgt, 18, 17))},
+		streamtest.Fragment{Data: framed(krbError(25))},
+		streamtest.Fragment{Client: true, Data: framed(request(msgTypeASReq, []int64{paEncTimestamp}, "alice", krbtgt, 18, 17))},
+		streamtest.Fragment{Data: framed(reply(msgTypeASRep, "alice", krbtgt, 18, 18))},
+		// the request is split across two fragments
+		streamtest.Fragment{Client: true, Data: tgsReq[:20]},
+		streamtest.Fragment{Client: true, Data: tgsReq[20:]},
+		streamtest.Fragment{Data: framed(reply(msgTypeTGSRep, "alice", mssql, 23, 18))},
+	))
+
+	if len(records) != 3 {
+		t.Fatal("expected 3 records, got", len(records))
+	}
+
+	for _, k := range records {
+		if k.TransportProto != transportTCP {
+			t.Fatal("unexpected transport", k.TransportProto)
+		}
+	}
+
+	if k := records[0]; k.ResponseType != "KRB-ERROR" || k.ErrorCode != 25 || k.ErrorName != "KDC_ERR_PREAUTH_REQUIRED" || k.NoPreAuthRequired {
+		t.Fatal("unexpected error", k.ResponseType, k.ErrorCode, k.ErrorName, k.NoPreAuthRequired)
+	}
+
+	if k := records[1]; !k.PreAuthenticated || k.NoPreAuthRequired || k.WeakEncryption || k.Timestamp != ts.Add(2*time.Second).UnixNano() {
+		t.Fatal("unexpected authentication", k.PreAuthenticated, k.NoPreAuthRequired, k.WeakEncryption, k.Timestamp)
+	}
+
+	k := records[2]
+	if k.RequestType != "TGS-REQ" || k.ResponseType != "TGS-REP" || k.Timestamp != ts.Add(4*time.Second).UnixNano() {
+		t.Fatal("unexpected message types", k.RequestType, k.ResponseType, k.Timestamp)
+	}
+
+	// the client name of a TGS-REQ is taken from the reply
+	if k.ClientName != "alice" || k.ServiceName != "MSSQLSvc/sql.corp.local" {
+		t.Fatal("unexpected principals", k.ClientName, k.ServiceName)
+	}
+
+	// only RC4 has been requested for the service ticket
+	if k.TicketEncryptionType != "rc4-hmac" || !k.WeakEncryption || k.PreAuthenticated {
+		t.Fatal("unexpected ticket", k.TicketEncryptionType, k.WeakEncryption, k.PreAuthenticated)
+	}
+}
@@ -0,0 +1,431 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package kerberos
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+/*
+ * Kerberos V5 messages, see RFC 4120
+ */
+
+const (
+	// message types, each message is wrapped into an application tag with the message type.
+	msgTypeASReq  = 10
+	msgTypeASRep  = 11
+	msgTypeTGSReq = 12
+	msgTypeTGSRep = 13
+	msgTypeError  = 30
+
+	// application tag of a ticket.
+	tagTicket = 1
+
+	// pre-authentication data types that prove the identity of the client.
+	paEncTimestamp = 2
+	paPKAsReqOld   = 14
+	paPKAsReq      = 16
+	paFXFast       = 136
+
+	// ASN.1 identifier bits of constructed application tags.
+	classMask                   = 0xe0
+	classConstructedApplication = 0x60
+
+	// messages sent over TCP are prefixed with a 4 byte length.
+	tcpLengthBytes = 4
+	maxMessageSize = 1024 * 1024
+)
+
+var messageTypes = map[int]string{
+	msgTypeASReq:  "AS-REQ",
+	msgTypeASRep:  "AS-REP",
+	msgTypeTGSReq: "TGS-REQ",
+	msgTypeTGSRep: "TGS-REP",
+	msgTypeError:  "KRB-ERROR",
+}
+
+// encryption types, see RFC 3961, RFC 3962, RFC 4757 and RFC 8009.
+var encryptionTypes = map[int]string{
+	1:  "des-cbc-crc",
+	2:  "des-cbc-md4",
+	3:  "des-cbc-md5",
+	16: "des3-cbc-sha1-kd",
+	17: "aes128-cts-hmac-sha1-96",
+	18: "aes256-cts-hmac-sha1-96",
+	19: "aes128-cts-hmac-sha256-128",
+	20: "aes256-cts-hmac-sha384-192",
+	23: "rc4-hmac",
+	24: "rc4-hmac-exp",
+	25: "camellia128-cts-cmac",
+	26: "camellia256-cts-cmac",
+}
+
+// weak encryption types, tickets encrypted with these can be cracked offline.
+var weakEncryptionTypes = map[int]bool{
+	1:  true,
+	2:  true,
+	3:  true,
+	23: true,
+	24: true,
+}
+
+var errorCodes = map[int]string{
+	0:  "KDC_ERR_NONE",
+	1:  "KDC_ERR_NAME_EXP",
+	2:  "KDC_ERR_SERVICE_EXP",
+	3:  "KDC_ERR_BAD_PVNO",
+	6:  "KDC_ERR_C_PRINCIPAL_UNKNOWN",
+	7:  "KDC_ERR_S_PRINCIPAL_UNKNOWN",
+	8:  "KDC_ERR_PRINCIPAL_NOT_UNIQUE",
+	9:  "KDC_ERR_NULL_KEY",
+	12: "KDC_ERR_POLICY",
+	13: "KDC_ERR_BADOPTION",
+	14: "KDC_ERR_ETYPE_NOSUPP",
+	16: "KDC_ERR_PADATA_TYPE_NOSUPP",
+	18: "KDC_ERR_CLIENT_REVOKED",
+	20: "KDC_ERR_TGT_REVOKED",
+	23: "KDC_ERR_KEY_EXPIRED",
+	24: "KDC_ERR_PREAUTH_FAILED",
+	25: "KDC_ERR_PREAUTH_REQUIRED",
+	29: "KDC_ERR_SVC_UNAVAILABLE",
+	31: "KRB_AP_ERR_BAD_INTEGRITY",
+	32: "KRB_AP_ERR_TKT_EXPIRED",
+	33: "KRB_AP_ERR_TKT_NYV",
+	34: "KRB_AP_ERR_REPEAT",
+	35: "KRB_AP_ERR_NOT_US",
+	36: "KRB_AP_ERR_BADMATCH",
+	37: "KRB_AP_ERR_SKEW",
+	41: "KRB_AP_ERR_MODIFIED",
+	52: "KRB_ERR_RESPONSE_TOO_BIG",
+	60: "KRB_ERR_GENERIC",
+	68: "KDC_ERR_WRONG_REALM",
+}
+
+var (
+	errInvalidMessage     = errors.New("invalid kerberos message")
+	errUnsupportedMessage = errors.New("unsupported kerberos message type")
+)
+
+// message contains the unencrypted fields of a KDC request, reply or error.
+type message struct {
+	msgType   int
+	timestamp time.Time
+
+	clientName  string
+	realm       string
+	serviceName string
+
+	// request
+	paDataTypes []int
+	till        time.Time
+	renewTill   time.Time
+	eTypes      []int
+
+	// reply
+	ticketEType int
+	replyEType  int
+
+	// error
+	errorCode int
+}
+
+// preAuthenticated checks if the request contains pre-authentication data.
+func (m *message) preAuthenticated() bool {
+	for _, t := range m.paDataTypes {
+		switch t {
+		case paEncTimestamp, paPKAsReqOld, paPKAsReq, paFXFast:
+			return true
+		}
+	}
+
+	return false
+}
+
+// application returns the tag for a constructed application specific element.
+func application(n int) asn1.Tag {
+	return asn1.Tag(n) | classConstructedApplication
+}
+
+// explicit returns the tag for an explicitly tagged context specific element.
+func explicit(n int) asn1.Tag {
+	return asn1.Tag(n).Constructed().ContextSpecific()
+}
+
+// parseMessage parses a single Kerberos message.
+func parseMessage(data []byte) (*message, error) {
+	var (
+		input = cryptobyte.String(data)
+		body  cryptobyte.String
+		tag   asn1.Tag
+	)
+
+	if !input.ReadAnyASN1(&body, &tag) {
+		return nil, errInvalidMessage
+	}
+
+	var ok bool
+
+	m := new(message)
+
+	switch tag {
+	case application(msgTypeASReq), application(msgTypeTGSReq):
+		ok = m.parseRequest(body)
+	case application(msgTypeASRep), application(msgTypeTGSRep):
+		ok = m.parseReply(body)
+	case application(msgTypeError):
+		ok = m.parseError(body)
+	default:
+		return nil, errUnsupportedMessage
+	}
+
+	if !ok {
+		return nil, errInvalidMessage
+	}
+
+	return m, nil
+}
+
+// parseRequest parses a KDC-REQ.
+func (m *message) parseRequest(body cryptobyte.String) bool {
+	var (
+		seq, paData, reqBody cryptobyte.String
+		hasPAData            bool
+	)
+
+	if !body.ReadASN1(&seq, asn1.SEQUENCE) ||
+		!seq.SkipASN1(explicit(1)) || // pvno
+		!readInt(&seq, explicit(2), &m.msgType) ||
+		!seq.ReadOptionalASN1(&paData, &hasPAData, explicit(3)) ||
+		!seq.ReadASN1(&reqBody, explicit(4)) ||
+		!reqBody.ReadASN1(&reqBody, asn1.SEQUENCE) {
+		return false
+	}
+
+	if hasPAData && !m.parsePAData(paData) {
+		return false
+	}
+
+	var (
+		eTypes             cryptobyte.String
+		hasRenewTill       bool
+		renewTill          cryptobyte.String
+		cname, sname       cryptobyte.String
+		hasCName, hasSName bool
+	)
+
+	if !reqBody.SkipASN1(explicit(0)) || // kdc-options
+		!reqBody.ReadOptionalASN1(&cname, &hasCName, explicit(1)) ||
+		!readString(&reqBody, explicit(2), &m.realm) ||
+		!reqBody.ReadOptionalASN1(&sname, &hasSName, explicit(3)) ||
+		!reqBody.SkipOptionalASN1(explicit(4)) || // from
+		!readTime(&reqBody, explicit(5), &m.till) ||
+		!reqBody.ReadOptionalASN1(&renewTill, &hasRenewTill, explicit(6)) ||
+		!reqBody.SkipASN1(explicit(7)) || // nonce
+		!reqBody.ReadASN1(&eTypes, explicit(8)) ||
+		!eTypes.ReadASN1(&eTypes, asn1.SEQUENCE) {
+		return false
+	}
+
+	if hasCName && !parsePrincipalName(cname, &m.clientName) {
+		return false
+	}
+
+	if hasSName && !parsePrincipalName(sname, &m.serviceName) {
+		return false
+	}
+
+	if hasRenewTill && !renewTill.ReadASN1GeneralizedTime(&m.renewTill) {
+		return false
+	}
+
+	for !eTypes.Empty() {
+		var eType int
+		if !eTypes.ReadASN1Integer(&eType) {
+			return false
+		}
+
+		m.eTypes = append(m.eTypes, eType)
+	}
+
+	return true
+}
+
+// parseReply parses a KDC-REP.
+// The ticket and the reply are encrypted, only the encryption types can be extracted from them.
+func (m *message) parseReply(body cryptobyte.String) bool {
+	var (
+		seq, cname, ticket, encPart cryptobyte.String
+		ticketSeq, ticketEncPart    cryptobyte.String
+	)
+
+	if !body.ReadASN1(&seq, asn1.SEQUENCE) ||
+		!seq.SkipASN1(explicit(0)) || // pvno
+		!readInt(&seq, explicit(1), &m.msgType) ||
+		!seq.SkipOptionalASN1(explicit(2)) || // padata
+		!readString(&seq, explicit(3), &m.realm) ||
+		!seq.ReadASN1(&cname, explicit(4)) ||
+		!seq.ReadASN1(&ticket, explicit(5)) ||
+		!seq.ReadASN1(&encPart, explicit(6)) ||
+		!parsePrincipalName(cname, &m.clientName) ||
+		!parseEncryptionType(encPart, &m.replyEType) {
+		return false
+	}
+
+	var sname cryptobyte.String
+
+	return ticket.ReadASN1(&ticket, application(tagTicket)) &&
+		ticket.ReadASN1(&ticketSeq, asn1.SEQUENCE) &&
+		ticketSeq.SkipASN1(explicit(0)) && // tkt-vno
+		ticketSeq.SkipASN1(explicit(1)) && // realm
+		ticketSeq.ReadASN1(&sname, explicit(2)) &&
+		ticketSeq.ReadASN1(&ticketEncPart, explicit(3)) &&
+		parsePrincipalName(sname, &m.serviceName) &&
+		parseEncryptionType(ticketEncPart, &m.ticketEType)
+}
+
+// parseError parses a KRB-ERROR.
+func (m *message) parseError(body cryptobyte.String) bool {
+	var (
+		seq, cname, sname cryptobyte.String
+		hasCName          bool
+	)
+
+	if !body.ReadASN1(&seq, asn1.SEQUENCE) ||
+		!seq.SkipASN1(explicit(0)) || // pvno
+		!readInt(&seq, explicit(1), &m.msgType) ||
+		!seq.SkipOptionalASN1(explicit(2)) || // ctime
+		!seq.SkipOptionalASN1(explicit(3)) || // cusec
+		!seq.SkipASN1(explicit(4)) || // stime
+		!seq.SkipASN1(explicit(5)) || // susec
+		!readInt(&seq, explicit(6), &m.errorCode) ||
+		!seq.SkipOptionalASN1(explicit(7)) || // crealm
+		!seq.ReadOptionalASN1(&cname, &hasCName, explicit(8)) ||
+		!readString(&seq, explicit(9), &m.realm) ||
+		!seq.ReadASN1(&sname, explicit(10)) ||
+		!parsePrincipalName(sname, &m.serviceName) {
+		return false
+	}
+
+	if hasCName && !parsePrincipalName(cname, &m.clientName) {
+		return false
+	}
+
+	return true
+}
+
+// parsePAData collects the types of the pre-authentication data.
+func (m *message) parsePAData(data cryptobyte.String) bool {
+	if !data.ReadASN1(&data, asn1.SEQUENCE) {
+		return false
+	}
+
+	for !data.Empty() {
+		var (
+			paData cryptobyte.String
+			typ    int
+		)
+
+		if !data.ReadASN1(&paData, asn1.SEQUENCE) || !readInt(&paData, explicit(1), &typ) {
+			return false
+		}
+
+		m.paDataTypes = append(m.paDataTypes, typ)
+	}
+
+	return true
+}
+
+// parsePrincipalName parses a PrincipalName, the name components are joined with a slash.
+func parsePrincipalName(data cryptobyte.String, out *string) bool {
+	var seq, names cryptobyte.String
+
+	if !data.ReadASN1(&seq, asn1.SEQUENCE) ||
+		!seq.SkipASN1(explicit(0)) || // name-type
+		!seq.ReadASN1(&names, explicit(1)) ||
+		!names.ReadASN1(&names, asn1.SEQUENCE) {
+		return false
+	}
+
+	var parts []string
+
+	for !names.Empty() {
+		var name cryptobyte.String
+		if !names.ReadASN1(&name, asn1.GeneralString) {
+			return false
+		}
+
+		parts = append(parts, string(name))
+	}
+
+	*out = strings.Join(parts, "/")
+
+	return true
+}
+
+// parseEncryptionType reads the encryption type of EncryptedData.
+func parseEncryptionType(data cryptobyte.String, out *int) bool {
+	var seq cryptobyte.String
+
+	return data.ReadASN1(&seq, asn1.SEQUENCE) && readInt(&seq, explicit(0), out)
+}
+
+// readInt reads an explicitly tagged integer.
+func readInt(s *cryptobyte.String, tag asn1.Tag, out *int) bool {
+	var v cryptobyte.String
+
+	return s.ReadASN1(&v, tag) && v.ReadASN1Integer(out)
+}
+
+// readString reads an explicitly tagged KerberosString.
+func readString(s *cryptobyte.String, tag asn1.Tag, out *string) bool {
+	var v, str cryptobyte.String
+	if !s.ReadASN1(&v, tag) || !v.ReadASN1(&str, asn1.GeneralString) {
+		return false
+	}
+
+	*out = string(str)
+
+	return true
+}
+
+// readTime reads an explicitly tagged KerberosTime.
+func readTime(s *cryptobyte.String, tag asn1.Tag, out *time.Time) bool {
+	var v cryptobyte.String
+
+	return s.ReadASN1(&v, tag) && v.ReadASN1GeneralizedTime(out)
+}
+
+// encryptionTypeName returns the name of the encryption type, or its number if it is unknown.
+func encryptionTypeName(eType int) string {
+	if name, ok := encryptionTypes[eType]; ok {
+		return name
+	}
+
+	return strconv.Itoa(eType)
+}
+
+// errorName returns the name of the error code, or its number if it is unknown.
+func errorName(code int) string {
+	if name, ok := errorCodes[code]; ok {
+		return name
+	}
+
+	return strconv.Itoa(code)
+}
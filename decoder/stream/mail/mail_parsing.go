@@ -178,12 +178,13 @@ func Parse(conv *core.ConversationInfo, buf []byte, from, to string, logger *zap
 						Vendor:    userInfo.Vendor,
 						Version:   userInfo.Version,
 						// DeviceProfiles: []string{dpIdent},
-						SourceName: "Mail UserAgent",
-						SourceData: ua,
-						Service:    origin,
-						Flows:      []string{conv.Ident},
-						Notes:      userInfo.Full,
-						OS:         userInfo.OS,
+						SourceName:   "Mail UserAgent",
+						SourceData:   ua,
+						Service:      origin,
+						Flows:        []string{conv.Ident},
+						Notes:        userInfo.Full,
+						OS:           userInfo.OS,
+						CommunityIDs: []string{conv.CommunityID},
 					},
 				},
 			}, nil)
@@ -196,13 +197,14 @@ func Parse(conv *core.ConversationInfo, buf []byte, from, to string, logger *zap
 			software.WriteSoftware([]*software.AtomicSoftware{
 				{
 					Software: &types.Software{
-						Timestamp:  ti,
-						Product:    strings.TrimSpace(matches[1]),
-						Vendor:     strings.Split(matches[1], " ")[0],
-						Version:    strings.TrimPrefix(matches[0], matches[1]),
-						SourceName: "X-Mailer",
-						Service:    origin,
-						Flows:      []string{conv.Ident},
+						Timestamp:    ti,
+						Product:      strings.TrimSpace(matches[1]),
+						Vendor:       strings.Split(matches[1], " ")[0],
+						Version:      strings.TrimPrefix(matches[0], matches[1]),
+						SourceName:   "X-Mailer",
+						Service:      origin,
+						Flows:        []string{conv.Ident},
+						CommunityIDs: []string{conv.CommunityID},
 					},
 				},
 			}, nil)
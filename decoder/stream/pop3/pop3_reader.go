@@ -127,23 +127,25 @@ func (h *pop3Reader) Decode() {
 
 	mails, user, pass, token := h.processPOP3Conversation()
 	pop3Msg := &types.POP3{
-		Timestamp: h.conversation.FirstClientPacket.UnixNano(),
-		ClientIP:  h.conversation.ClientIP,
-		ServerIP:  h.conversation.ServerIP,
-		AuthToken: token,
-		User:      user,
-		Pass:      pass,
-		MailIDs:   mails,
-		Commands:  commands,
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		ClientIP:    h.conversation.ClientIP,
+		ServerIP:    h.conversation.ServerIP,
+		AuthToken:   token,
+		User:        user,
+		Pass:        pass,
+		MailIDs:     mails,
+		Commands:    commands,
+		CommunityID: h.conversation.CommunityID,
 	}
 
 	if user != "" || pass != "" {
 		credentials.WriteCredentials(&types.Credentials{
-			Timestamp: h.conversation.FirstClientPacket.UnixNano(),
-			Service:   servicePOP3,
-			Flow:      h.conversation.Ident,
-			User:      user,
-			Password:  pass,
+			Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+			Service:     servicePOP3,
+			Flow:        h.conversation.Ident,
+			User:        user,
+			Password:    pass,
+			CommunityID: h.conversation.CommunityID,
 		})
 	}
 
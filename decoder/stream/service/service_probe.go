@@ -147,25 +147,26 @@ func (s *serviceProbe) String() string {
 	return b.String()
 }
 
-func writeSoftwareFromBanner(serv *service, ident, probeIdent string) {
+func writeSoftwareFromBanner(serv *service, ident, communityID, probeIdent string) {
 	software.WriteSoftware([]*software.AtomicSoftware{
 		{
 			Software: &types.Software{
-				Timestamp:  serv.Timestamp,
-				Product:    serv.Product,
-				Vendor:     serv.Vendor,
-				Version:    serv.Version,
-				SourceName: "Service Probe Match: " + probeIdent,
-				Service:    serv.Name,
-				Flows:      []string{ident},
-				Notes:      "Protocol: " + serv.Protocol,
+				Timestamp:    serv.Timestamp,
+				Product:      serv.Product,
+				Vendor:       serv.Vendor,
+				Version:      serv.Version,
+				SourceName:   "Service Probe Match: " + probeIdent,
+				Service:      serv.Name,
+				Flows:        []string{ident},
+				Notes:        "Protocol: " + serv.Protocol,
+				CommunityIDs: []string{communityID},
 			},
 		},
 	}, nil)
 }
 
 // MatchServiceProbes will check the service banner against the probes.
-func MatchServiceProbes(serv *service, banner []byte, ident string) {
+func MatchServiceProbes(serv *service, banner []byte, ident, communityID string) {
 	var (
 		expectedCategory string
 		found            bool
@@ -183,7 +184,7 @@ func MatchServiceProbes(serv *service, banner []byte, ident string) {
 	if expectedCategory != "" {
 		if probes, ok := serviceProbes[expectedCategory]; ok {
 			serviceLog.Debug("matching probes", zap.String("ident", ident), zap.String("expectedCategory", expectedCategory))
-			found, matched = matchProbes(serv, probes, banner, ident)
+			found, matched = matchProbes(serv, probes, banner, ident, communityID)
 			serviceLogSugared.Info(ident, "found?", found, "at", matched, "of", len(probes), "expected", expectedCategory)
 		}
 		if !found && decoderconfig.Instance.StopAfterServiceCategoryMiss {
@@ -200,7 +201,7 @@ func MatchServiceProbes(serv *service, banner []byte, ident string) {
 				continue
 			}
 
-			found, matched = matchProbes(serv, probes, banner, ident)
+			found, matched = matchProbes(serv, probes, banner, ident, communityID)
 			if found && decoderconfig.Instance.StopAfterServiceProbeMatch {
 				serviceLogSugared.Info(ident, "FOUND at", matched, "of", len(probes), "expected", expectedCategory)
 				return
@@ -211,7 +212,7 @@ func MatchServiceProbes(serv *service, banner []byte, ident string) {
 	}
 }
 
-func matchProbes(serv *service, probes []*serviceProbe, banner []byte, ident string) (found bool, index int) {
+func matchProbes(serv *service, probes []*serviceProbe, banner []byte, ident, communityID string) (found bool, index int) {
 	for i, probe := range probes {
 		if decoderconfig.Instance.UseRE2 {
 			if m := probe.RegEx.FindStringSubmatch(string(banner)); m != nil {
@@ -228,7 +229,7 @@ func matchProbes(serv *service, probes []*serviceProbe, banner []byte, ident str
 					serviceLogSugared.Info(probe, "\n\nSERVICE:\n"+proto.MarshalTextString(serv.Service), "\nBanner:", "\n"+hex.Dump(banner))
 				}
 
-				writeSoftwareFromBanner(serv, ident, probe.Ident)
+				writeSoftwareFromBanner(serv, ident, communityID, probe.Ident)
 
 				// return true if search shall be stopped after the first match
 				if decoderconfig.Instance.StopAfterServiceProbeMatch {
@@ -253,7 +254,7 @@ func matchProbes(serv *service, probes []*serviceProbe, banner []byte, ident str
 					serviceLogSugared.Info(probe, "\n\nSERVICE:\n"+proto.MarshalTextString(serv.Service), "\nBanner:", "\n"+hex.Dump(banner))
 				}
 
-				writeSoftwareFromBanner(serv, ident, probe.Ident)
+				writeSoftwareFromBanner(serv, ident, communityID, probe.Ident)
 
 				// return true if search shall be stopped after the first match
 				if decoderconfig.Instance.StopAfterServiceProbeMatch {
@@ -193,7 +193,7 @@ func (b bannerTest) testClassifyBanner(t *testing.T) {
 	ident := "127.0.0.1:4322->127.0.0.1:21"
 	serv.Flows = []string{ident}
 
-	MatchServiceProbes(serv, []byte(b.banner), ident)
+	MatchServiceProbes(serv, []byte(b.banner), ident, "")
 
 	if serv.Product != b.product {
 		t.Fatal("unexpected product, expected", b.product, "got:", serv.Product)
@@ -0,0 +1,120 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package smb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NTLM authentication messages, see MS-NLMP.
+const (
+	ntlmMessageChallenge    uint32 = 2
+	ntlmMessageAuthenticate uint32 = 3
+
+	// strings are encoded as UTF-16 if the flag is set, otherwise the OEM character set is used
+	ntlmNegotiateUnicode uint32 = 0x00000001
+
+	// the NTLMv1 response has a fixed length, NTLMv2 responses are longer
+	ntlmV1ResponseLength = 24
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmAuthenticate contains the fields of an NTLM AUTHENTICATE message.
+type ntlmAuthenticate struct {
+	user        string
+	domain      string
+	workstation string
+	lmResponse  []byte
+	ntResponse  []byte
+}
+
+// findNTLMSSP returns the NTLM message from a security buffer.
+// The message is usually wrapped in a SPNEGO token, which is not decoded.
+func findNTLMSSP(buf []byte) []byte {
+	i := bytes.Index(buf, ntlmSignature)
+	if i < 0 {
+		return nil
+	}
+
+	return buf[i:]
+}
+
+// parseNTLMChallenge returns the server challenge from an NTLM CHALLENGE message.
+func parseNTLMChallenge(msg []byte) ([]byte, bool) {
+	if len(msg) < 32 || binary.LittleEndian.Uint32(msg[8:12]) != ntlmMessageChallenge {
+		return nil, false
+	}
+
+	return msg[24:32], true
+}
+
+// parseNTLMAuthenticate parses an NTLM AUTHENTICATE message.
+// The variable length fields are referenced by a length and an offset relative to the start of the message.
+func parseNTLMAuthenticate(msg []byte) (*ntlmAuthenticate, bool) {
+	if len(msg) < 64 || binary.LittleEndian.Uint32(msg[8:12]) != ntlmMessageAuthenticate {
+		return nil, false
+	}
+
+	var (
+		flags = binary.LittleEndian.Uint32(msg[60:64])
+		valid = true
+	)
+
+	field := func(offset int) []byte {
+		var (
+			length = int(binary.LittleEndian.Uint16(msg[offset : offset+2]))
+			start  = int(binary.LittleEndian.Uint32(msg[offset+4 : offset+8]))
+		)
+
+		if start+length > len(msg) || start+length < start {
+			valid = false
+
+			return nil
+		}
+
+		return msg[start : start+length]
+	}
+
+	str := func(b []byte) string {
+		if flags&ntlmNegotiateUnicode != 0 {
+			return decodeUTF16(b)
+		}
+
+		return string(b)
+	}
+
+	a := &ntlmAuthenticate{
+		lmResponse:  field(12),
+		ntResponse:  field(20),
+		domain:      str(field(28)),
+		user:        str(field(36)),
+		workstation: str(field(44)),
+	}
+
+	return a, valid
+}
+
+// hash formats the challenge and response in the format used by password crackers,
+// e.g. hashcat mode 5600 for NTLMv2 and mode 5500 for NTLMv1.
+func (a *ntlmAuthenticate) hash(challenge []byte) (hash, version string) {
+	if len(a.ntResponse) > ntlmV1ResponseLength {
+		// the first 16 bytes of the response are the NTProofStr, followed by the client blob
+		return fmt.Sprintf("%s::%s:%x:%x:%x", a.user, a.domain, challenge, a.ntResponse[:16], a.ntResponse[16:]), "NTLMv2"
+	}
+
+	return fmt.Sprintf("%s::%s:%x:%x:%x", a.user, a.domain, a.lmResponse, a.ntResponse, challenge), "NTLMv1"
+}
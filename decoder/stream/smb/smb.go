@@ -0,0 +1,81 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package smb
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	smbLog        = zap.NewNop()
+	smbLogSugared = smbLog.Sugar()
+
+	serviceSMB = "SMB"
+
+	// protocol identifiers at the start of a message
+	smb1ProtocolID      = []byte{0xff, 'S', 'M', 'B'}
+	smb2ProtocolID      = []byte{0xfe, 'S', 'M', 'B'}
+	smb2TransformID     = []byte{0xfd, 'S', 'M', 'B'}
+	smb2CompressionID   = []byte{0xfc, 'S', 'M', 'B'}
+	netBIOSSessionBytes = 4
+)
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_SMB,
+	Name:        serviceSMB,
+	Description: "The Server Message Block protocol version 2 and 3 provides access to files, printers and named pipes on Windows networks",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		smbLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"smb",
+			decoderconfig.Instance.Debug,
+		)
+		if err != nil {
+			return err
+		}
+
+		smbLogSugared = smbLog.Sugar()
+
+		return nil
+	},
+	CanDecode: func(client, server []byte) bool {
+		// the client starts with a negotiate request, clients that support SMB1 send an SMB1 negotiate request first
+		if !isSMBMessage(client, smb1ProtocolID) && !isSMBMessage(client, smb2ProtocolID) {
+			return false
+		}
+
+		return len(server) == 0 || isSMBMessage(server, smb1ProtocolID) || isSMBMessage(server, smb2ProtocolID)
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return smbLog.Sync()
+	},
+	Factory: &smbReader{},
+	Typ:     core.TCP,
+}
+
+// isSMBMessage checks if the data starts with a NetBIOS session message that contains the given protocol identifier.
+func isSMBMessage(data, protocolID []byte) bool {
+	return len(data) >= netBIOSSessionBytes+len(protocolID) &&
+		data[0] == 0 &&
+		bytes.Equal(data[netBIOSSessionBytes:netBIOSSessionBytes+len(protocolID)], protocolID)
+}
@@ -0,0 +1,700 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package smb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf16"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+/*
+ * SMB2 protocol, see MS-SMB2
+ */
+
+const (
+	// SMB2 commands.
+	smbNegotiate    uint16 = 0x00
+	smbSessionSetup uint16 = 0x01
+	smbTreeConnect  uint16 = 0x03
+	smbCreate       uint16 = 0x05
+	smbClose        uint16 = 0x06
+	smbRead         uint16 = 0x08
+	smbWrite        uint16 = 0x09
+	smbCancel       uint16 = 0x0c
+
+	// header flags.
+	smbFlagResponse uint32 = 0x00000001
+	smbFlagAsync    uint32 = 0x00000002
+
+	// NT status codes.
+	statusSuccess                uint32 = 0x00000000
+	statusPending                uint32 = 0x00000103
+	statusBufferOverflow         uint32 = 0x80000005
+	statusMoreProcessingRequired uint32 = 0xc0000016
+
+	// the server replies with the wildcard dialect to an SMB1 negotiate request, if it supports SMB 2.1 or later.
+	smbDialectWildcard uint16 = 0x02ff
+
+	smbHeaderLength = 64
+	smbFileIDLength = 16
+
+	// file ID used by operations in compound requests, to refer to the file opened by a preceding create.
+	smbRelatedFileID = "ffffffffffffffffffffffffffffffff"
+
+	smbIPCShare = "IPC$"
+
+	// limits to avoid excessive memory usage.
+	maxFileSize           = 512 * 1024 * 1024
+	maxFilesPerConnection = 10000
+
+	// maximum number of missing bytes in a file,
+	// so the peer can not force large allocations with a small write at a high offset.
+	maxFileGap = 16 * 1024 * 1024
+)
+
+var dialects = map[uint16]string{
+	0x0202: "2.0.2",
+	0x0210: "2.1",
+	0x0300: "3.0",
+	0x0302: "3.0.2",
+	0x0311: "3.1.1",
+}
+
+var createDispositions = map[uint32]string{
+	0: "SUPERSEDE",
+	1: "OPEN",
+	2: "CREATE",
+	3: "OPEN_IF",
+	4: "OVERWRITE",
+	5: "OVERWRITE_IF",
+}
+
+var createActions = map[uint32]string{
+	0: "SUPERSEDED",
+	1: "OPENED",
+	2: "CREATED",
+	3: "OVERWRITTEN",
+}
+
+var errIncompleteFile = errors.New("incomplete file transfer")
+
+// smbStream is the data sent into one direction of the connection.
+type smbStream struct {
+	*core.StreamDirection
+
+	data []byte
+}
+
+// newStreams splits the conversation into the data sent by the client and the server.
+func newStreams(data core.DataFragments) (client, server *smbStream) {
+	c, s := core.SplitDirections(data)
+
+	return &smbStream{StreamDirection: c, data: c.Bytes()}, &smbStream{StreamDirection: s, data: s.Bytes()}
+}
+
+// smbMessage is a single SMB2 request or response.
+type smbMessage struct {
+	command   uint16
+	status    uint32
+	flags     uint32
+	messageID uint64
+	treeID    uint32
+	timestamp time.Time
+
+	// the message including the header, offsets in the message body are relative to the start of the header
+	data []byte
+}
+
+// body returns the message without the header.
+func (m *smbMessage) body() []byte {
+	return m.data[smbHeaderLength:]
+}
+
+// slice returns the data at the offset relative to the start of the header, or nil if it is out of bounds.
+func (m *smbMessage) slice(offset, length int) []byte {
+	if offset < 0 || length < 0 || offset+length > len(m.data) {
+		return nil
+	}
+
+	return m.data[offset : offset+length]
+}
+
+// readMessages splits the stream into the NetBIOS session messages and parses the SMB2 messages they contain.
+// Encrypted SMB3 messages can not be decoded, they are only counted.
+func (s *smbStream) readMessages() (msgs []*smbMessage, numEncrypted int) {
+	pos := 0
+
+	for pos+netBIOSSessionBytes <= len(s.data) {
+		// only session messages carry SMB data
+		if s.data[pos] != 0 {
+			break
+		}
+
+		length := int(s.data[pos+1])<<16 | int(s.data[pos+2])<<8 | int(s.data[pos+3])
+		if pos+netBIOSSessionBytes+length > len(s.data) {
+			break
+		}
+
+		var (
+			msg = s.data[pos+netBIOSSessionBytes : pos+netBIOSSessionBytes+length]
+			ts  = s.TimeAt(pos)
+		)
+
+		pos += netBIOSSessionBytes + length
+
+		switch {
+		case bytes.HasPrefix(msg, smb2ProtocolID):
+			msgs = append(msgs, parseCompound(msg, ts)...)
+		case bytes.HasPrefix(msg, smb2TransformID):
+			numEncrypted++
+		}
+	}
+
+	return msgs, numEncrypted
+}
+
+// parseCompound parses a chain of SMB2 messages, the header of each message contains the offset to the next one.
+func parseCompound(data []byte, ts time.Time) (msgs []*smbMessage) {
+	for len(data) >= smbHeaderLength && bytes.HasPrefix(data, smb2ProtocolID) {
+		var (
+			next = int(binary.LittleEndian.Uint32(data[20:24]))
+			end  = len(data)
+		)
+
+		if next > 0 && next <= len(data) {
+			end = next
+		}
+
+		if end < smbHeaderLength {
+			break
+		}
+
+		m := &smbMessage{
+			status:    binary.LittleEndian.Uint32(data[8:12]),
+			command:   binary.LittleEndian.Uint16(data[12:14]),
+			flags:     binary.LittleEndian.Uint32(data[16:20]),
+			messageID: binary.LittleEndian.Uint64(data[24:32]),
+			timestamp: ts,
+			data:      data[:end],
+		}
+
+		// asynchronous messages carry an async ID instead of the tree ID
+		if m.flags&smbFlagAsync == 0 {
+			m.treeID = binary.LittleEndian.Uint32(data[36:40])
+		}
+
+		msgs = append(msgs, m)
+
+		if next == 0 || next >= len(data) {
+			break
+		}
+
+		data = data[next:]
+	}
+
+	return msgs
+}
+
+// smbChunk is data that has been read from or written to a file at an offset.
+type smbChunk struct {
+	offset uint64
+	data   []byte
+}
+
+// smbFile is a file that has been opened by the client.
+type smbFile struct {
+	*types.SMBFile
+	chunks []*smbChunk
+}
+
+type smbReader struct {
+	conversation *core.ConversationInfo
+
+	smb *types.SMB
+
+	// tree paths by tree ID
+	trees map[uint32]string
+
+	// open files by file ID
+	files map[string]*smbFile
+
+	// file ID of the last successful create, for related operations in compound requests
+	lastFileID string
+
+	// server challenge from the last NTLM CHALLENGE message
+	challenge []byte
+}
+
+// New will instantiate a new SMB reader.
+func (h *smbReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &smbReader{
+		conversation: conv,
+		trees:        make(map[uint32]string),
+		files:        make(map[string]*smbFile),
+	}
+}
+
+// Decode parses the SMB2 messages of the connection.
+// Requests are matched with their responses by the message ID and processed in the order they were sent.
+func (h *smbReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	h.smb = &types.SMB{
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		ClientIP:    h.conversation.ClientIP,
+		ServerIP:    h.conversation.ServerIP,
+		ClientPort:  h.conversation.ClientPort,
+		ServerPort:  h.conversation.ServerPort,
+		CommunityID: h.conversation.CommunityID,
+	}
+
+	var (
+		client, server           = newStreams(h.conversation.Data)
+		requests, encryptedReq   = client.readMessages()
+		responses, encryptedResp = server.readMessages()
+		final                    = make(map[uint64]*smbMessage, len(responses))
+	)
+
+	h.smb.Encrypted = encryptedReq > 0 || encryptedResp > 0
+
+	for _, res := range responses {
+		if res.flags&smbFlagResponse == 0 {
+			continue
+		}
+
+		// skip interim responses for requests that are processed asynchronously
+		if res.status == statusPending && res.flags&smbFlagAsync != 0 {
+			continue
+		}
+
+		// the response to an SMB1 negotiate request is not matched with an SMB2 request
+		if res.command == smbNegotiate {
+			h.handleNegotiate(res)
+		}
+
+		final[res.messageID] = res
+	}
+
+	for _, req := range requests {
+		if req.flags&smbFlagResponse != 0 || req.command == smbCancel {
+			continue
+		}
+
+		h.smb.NumCommands++
+
+		h.handleRequest(req, final[req.messageID])
+	}
+
+	// files that were not closed during the capture
+	for id, f := range h.files {
+		h.saveFile(f)
+		delete(h.files, id)
+	}
+
+	if h.smb.Dialect == "" && h.smb.NumCommands == 0 {
+		return
+	}
+
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		h.smb.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(h.smb)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
+
+// handleRequest processes a request together with its response, the response is nil if it has not been captured.
+func (h *smbReader) handleRequest(req, res *smbMessage) {
+	smbLog.Debug("request",
+		zap.String("ident", h.conversation.Ident),
+		zap.Uint16("command", req.command),
+		zap.Uint64("messageID", req.messageID),
+	)
+
+	switch req.command {
+	case smbSessionSetup:
+		h.handleSessionSetup(req, res)
+	case smbTreeConnect:
+		h.handleTreeConnect(req, res)
+	case smbCreate:
+		h.handleCreate(req, res)
+	case smbRead:
+		h.handleRead(req, res)
+	case smbWrite:
+		h.handleWrite(req, res)
+	case smbClose:
+		h.handleClose(req)
+	}
+}
+
+// handleNegotiate sets the dialect selected by the server.
+func (h *smbReader) handleNegotiate(res *smbMessage) {
+	body := res.body()
+	if len(body) < 6 || res.status != statusSuccess {
+		return
+	}
+
+	dialect := binary.LittleEndian.Uint16(body[4:6])
+	if dialect == smbDialectWildcard {
+		return
+	}
+
+	if name, ok := dialects[dialect]; ok {
+		h.smb.Dialect = name
+	} else {
+		h.smb.Dialect = fmt.Sprintf("0x%04x", dialect)
+	}
+}
+
+// handleSessionSetup extracts the NTLM authentication from the security buffers.
+func (h *smbReader) handleSessionSetup(req, res *smbMessage) {
+	// the server challenge is sent in the first response, to continue the authentication
+	if res != nil && res.status == statusMoreProcessingRequired && len(res.body()) >= 8 {
+		body := res.body()
+
+		buf := res.slice(int(binary.LittleEndian.Uint16(body[4:6])), int(binary.LittleEndian.Uint16(body[6:8])))
+		if challenge, ok := parseNTLMChallenge(findNTLMSSP(buf)); ok {
+			h.challenge = challenge
+		}
+	}
+
+	body := req.body()
+	if len(body) < 16 {
+		return
+	}
+
+	buf := req.slice(int(binary.LittleEndian.Uint16(body[12:14])), int(binary.LittleEndian.Uint16(body[14:16])))
+
+	auth, ok := parseNTLMAuthenticate(findNTLMSSP(buf))
+	if !ok || auth.user == "" {
+		return
+	}
+
+	success := res != nil && res.status == statusSuccess
+	if success || h.smb.User == "" {
+		h.smb.User = auth.user
+		h.smb.Domain = auth.domain
+		h.smb.Workstation = auth.workstation
+	}
+
+	if len(auth.ntResponse) == 0 || h.challenge == nil {
+		return
+	}
+
+	hash, version := auth.hash(h.challenge)
+	if !success {
+		version += ", authentication failed"
+	}
+
+	credentials.WriteCredentials(&types.Credentials{
+		Timestamp:   req.timestamp.UnixNano(),
+		Service:     serviceSMB,
+		Flow:        h.conversation.Ident,
+		User:        auth.domain + "\\" + auth.user,
+		Password:    hash,
+		Notes:       version,
+		CommunityID: h.conversation.CommunityID,
+	})
+}
+
+// handleTreeConnect stores the path of the share for the tree ID assigned by the server.
+func (h *smbReader) handleTreeConnect(req, res *smbMessage) {
+	body := req.body()
+	if len(body) < 8 || res == nil || res.status != statusSuccess {
+		return
+	}
+
+	tree := decodeUTF16(req.slice(int(binary.LittleEndian.Uint16(body[4:6])), int(binary.LittleEndian.Uint16(body[6:8]))))
+	if tree == "" {
+		return
+	}
+
+	h.trees[res.treeID] = tree
+
+	for _, t := range h.smb.Trees {
+		if t == tree {
+			return
+		}
+	}
+
+	h.smb.Trees = append(h.smb.Trees, tree)
+}
+
+// handleCreate records a file open and tracks the file ID assigned by the server.
+func (h *smbReader) handleCreate(req, res *smbMessage) {
+	body := req.body()
+	if len(body) < 48 || len(h.smb.Files) >= maxFilesPerConnection {
+		return
+	}
+
+	f := &types.SMBFile{
+		Timestamp:   req.timestamp.UnixNano(),
+		Tree:        h.trees[req.treeID],
+		Name:        decodeUTF16(req.slice(int(binary.LittleEndian.Uint16(body[44:46])), int(binary.LittleEndian.Uint16(body[46:48])))),
+		Disposition: createDispositions[binary.LittleEndian.Uint32(body[36:40])],
+	}
+
+	h.smb.Files = append(h.smb.Files, f)
+
+	if res == nil {
+		return
+	}
+
+	f.Status = fmt.Sprintf("0x%08x", res.status)
+
+	resBody := res.body()
+	if res.status != statusSuccess || len(resBody) < 80 {
+		return
+	}
+
+	f.Action = createActions[binary.LittleEndian.Uint32(resBody[4:8])]
+	f.EndOfFile = int64(binary.LittleEndian.Uint64(resBody[48:56]))
+
+	h.lastFileID = hex.EncodeToString(resBody[64 : 64+smbFileIDLength])
+	h.files[h.lastFileID] = &smbFile{SMBFile: f}
+}
+
+// handleRead adds the data returned by the server to the file.
+func (h *smbReader) handleRead(req, res *smbMessage) {
+	body := req.body()
+	if len(body) < 32 || res == nil || (res.status != statusSuccess && res.status != statusBufferOverflow) {
+		return
+	}
+
+	f := h.file(body[16 : 16+smbFileIDLength])
+	if f == nil {
+		return
+	}
+
+	resBody := res.body()
+	if len(resBody) < 8 {
+		return
+	}
+
+	data := res.slice(int(resBody[2]), int(binary.LittleEndian.Uint32(resBody[4:8])))
+	if data == nil {
+		return
+	}
+
+	if f.addChunk(binary.LittleEndian.Uint64(body[8:16]), data) {
+		f.BytesRead += int64(len(data))
+	}
+}
+
+// handleWrite adds the data sent by the client to the file, if the server confirmed the write.
+func (h *smbReader) handleWrite(req, res *smbMessage) {
+	body := req.body()
+	if len(body) < 32 || res == nil || res.status != statusSuccess {
+		return
+	}
+
+	f := h.file(body[16 : 16+smbFileIDLength])
+	if f == nil {
+		return
+	}
+
+	data := req.slice(int(binary.LittleEndian.Uint16(body[2:4])), int(binary.LittleEndian.Uint32(body[4:8])))
+	if data == nil {
+		return
+	}
+
+	if f.addChunk(binary.LittleEndian.Uint64(body[8:16]), data) {
+		f.BytesWritten += int64(len(data))
+	}
+}
+
+// handleClose saves the file.
+func (h *smbReader) handleClose(req *smbMessage) {
+	body := req.body()
+	if len(body) < 8+smbFileIDLength {
+		return
+	}
+
+	id := h.fileID(body[8 : 8+smbFileIDLength])
+	if f, ok := h.files[id]; ok {
+		h.saveFile(f)
+		delete(h.files, id)
+	}
+}
+
+// fileID returns the hex encoded file ID,
+// operations in compound requests can refer to the file opened by a preceding create.
+func (h *smbReader) fileID(id []byte) string {
+	s := hex.EncodeToString(id)
+	if s == smbRelatedFileID {
+		return h.lastFileID
+	}
+
+	return s
+}
+
+func (h *smbReader) file(id []byte) *smbFile {
+	return h.files[h.fileID(id)]
+}
+
+// saveFile writes the data that has been read from or written to the file into the file storage.
+// Named pipes on the IPC$ share are not saved.
+func (h *smbReader) saveFile(f *smbFile) {
+	if decoderconfig.Instance.FileStorage == "" || len(f.chunks) == 0 || strings.HasSuffix(f.Tree, smbIPCShare) {
+		return
+	}
+
+	data, complete := f.assemble()
+	if data == nil {
+		return
+	}
+
+	var errTransfer error
+	if !complete {
+		if !decoderconfig.Instance.WriteIncomplete {
+			return
+		}
+
+		errTransfer = errIncompleteFile
+	}
+
+	err := streamutils.SaveFile(
+		h.conversation,
+		serviceSMB,
+		path.Base(strings.ReplaceAll(f.Name, "\\", "/")),
+		errTransfer,
+		data,
+		nil,
+		f.Tree,
+		"",
+	)
+	if err != nil {
+		smbLog.Error("failed to save file",
+			zap.String("ident", h.conversation.Ident),
+			zap.String("name", f.Name),
+			zap.Error(err),
+		)
+	}
+}
+
+// addChunk adds the data at the offset to the file.
+// Chunks that start beyond the maximum file size are dropped, so the end of a chunk can not overflow.
+func (f *smbFile) addChunk(offset uint64, data []byte) bool {
+	if offset > maxFileSize || offset+uint64(len(data)) < offset {
+		smbLog.Debug("dropped chunk beyond the maximum file size",
+			zap.String("name", f.Name),
+			zap.Uint64("offset", offset),
+			zap.Int("length", len(data)),
+		)
+
+		return false
+	}
+
+	f.chunks = append(f.chunks, &smbChunk{
+		offset: offset,
+		data:   data,
+	})
+
+	return true
+}
+
+// assemble places the chunks at their offsets.
+// The file is complete if there are no gaps, and if the data read covers the size of the file reported when it was opened.
+func (f *smbFile) assemble() (data []byte, complete bool) {
+	sort.SliceStable(f.chunks, func(i, j int) bool {
+		return f.chunks[i].offset < f.chunks[j].offset
+	})
+
+	var length, end, received uint64
+
+	for _, c := range f.chunks {
+		received += uint64(len(c.data))
+
+		if e := c.offset + uint64(len(c.data)); e > length {
+			length = e
+		}
+	}
+
+	if length > maxFileSize {
+		smbLog.Info("file exceeds maximum size",
+			zap.String("name", f.Name),
+			zap.Uint64("length", length),
+		)
+
+		return nil, false
+	}
+
+	if length > received && length-received > maxFileGap {
+		smbLog.Info("file has too many missing bytes",
+			zap.String("name", f.Name),
+			zap.Uint64("length", length),
+			zap.Uint64("received", received),
+		)
+
+		return nil, false
+	}
+
+	data = make([]byte, length)
+	complete = true
+
+	for _, c := range f.chunks {
+		if c.offset > end {
+			complete = false
+		}
+
+		copy(data[c.offset:], c.data)
+
+		if e := c.offset + uint64(len(c.data)); e > end {
+			end = e
+		}
+	}
+
+	if f.BytesRead > 0 && f.BytesWritten == 0 && int64(length) < f.EndOfFile {
+		complete = false
+	}
+
+	return data, complete
+}
+
+// decodeUTF16 decodes a little endian UTF-16 string.
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,409 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package smb
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/credentials"
+	"github.com/dreadl0ck/netcap/decoder/stream/file"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+
+	return b
+}
+
+// message is an SMB2 message that is sent by the client or the server.
+type message struct {
+	client    bool
+	command   uint16
+	messageID uint64
+	treeID    uint32
+	status    uint32
+	flags     uint32
+	body      []byte
+}
+
+func (m *message) bytes() []byte {
+	h := make([]byte, smbHeaderLength)
+	copy(h, smb2ProtocolID)
+	binary.LittleEndian.PutUint16(h[4:], smbHeaderLength)
+	binary.LittleEndian.PutUint32(h[8:], m.status)
+	binary.LittleEndian.PutUint16(h[12:], m.command)
+
+	flags := m.flags
+	if !m.client {
+		flags |= smbFlagResponse
+	}
+
+	binary.LittleEndian.PutUint32(h[16:], flags)
+	binary.LittleEndian.PutUint64(h[24:], m.messageID)
+	binary.LittleEndian.PutUint32(h[36:], m.treeID)
+
+	data := append(h, m.body...)
+	length := len(data)
+
+	return append([]byte{0, byte(length >> 16), byte(length >> 8), byte(length)}, data...)
+}
+
+// body creates a message body with the given fixed length,
+// the buffer is appended and the offset relative to the header is returned as well.
+func body(fixed int, buffer []byte) ([]byte, uint16) {
+	return append(make([]byte, fixed), buffer...), uint16(smbHeaderLength + fixed)
+}
+
+func put16(b []byte, offset int, v uint16) []byte {
+	binary.LittleEndian.PutUint16(b[offset:], v)
+
+	return b
+}
+
+func put32(b []byte, offset int, v uint32) []byte {
+	binary.LittleEndian.PutUint32(b[offset:], v)
+
+	return b
+}
+
+func put64(b []byte, offset int, v uint64) []byte {
+	binary.LittleEndian.PutUint64(b[offset:], v)
+
+	return b
+}
+
+func ntlmChallenge() []byte {
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	put32(msg, 8, ntlmMessageChallenge)
+	copy(msg[24:], []byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	// SPNEGO wrapping
+	return append([]byte{0xa1, 0x81, 0x00, 0x30}, msg...)
+}
+
+func ntlmAuth(domain, user, workstation string, nt []byte) []byte {
+	msg := make([]byte, 64)
+	copy(msg, ntlmSignature)
+	put32(msg, 8, ntlmMessageAuthenticate)
+	put32(msg, 60, ntlmNegotiateUnicode)
+
+	for i, f := range [][]byte{make([]byte, 24), nt, utf16le(domain), utf16le(user), utf16le(workstation)} {
+		offset := 12 + i*8
+		put16(msg, offset, uint16(len(f)))
+		put32(msg, offset+4, uint32(len(msg)))
+		msg = append(msg, f...)
+	}
+
+	return append([]byte{0xa1, 0x82, 0x01, 0x00}, msg...)
+}
+
+func sessionSetupRequest(token []byte) []byte {
+	b, offset := body(24, token)
+	put16(b, 12, offset)
+
+	return put16(b, 14, uint16(len(token)))
+}
+
+func sessionSetupResponse(token []byte) []byte {
+	b, offset := body(8, token)
+	put16(b, 4, offset)
+
+	return put16(b, 6, uint16(len(token)))
+}
+
+func treeConnectRequest(path string) []byte {
+	p := utf16le(path)
+	b, offset := body(8, p)
+	put16(b, 4, offset)
+
+	return put16(b, 6, uint16(len(p)))
+}
+
+func createRequest(name string, disposition uint32) []byte {
+	n := utf16le(name)
+	b, offset := body(56, n)
+	put32(b, 36, disposition)
+	put16(b, 44, offset)
+
+	return put16(b, 46, uint16(len(n)))
+}
+
+func createResponse(action uint32, eof uint64, id byte) []byte {
+	b := make([]byte, 88)
+	put32(b, 4, action)
+	put64(b, 48, eof)
+
+	for i := 64; i < 80; i++ {
+		b[i] = id
+	}
+
+	return b
+}
+
+func fileID(id byte) []byte {
+	b := make([]byte, smbFileIDLength)
+	for i := range b {
+		b[i] = id
+	}
+
+	return b
+}
+
+func readRequest(id byte, offset uint64, length uint32) []byte {
+	b := make([]byte, 48)
+	put32(b, 4, length)
+	put64(b, 8, offset)
+	copy(b[16:], fileID(id))
+
+	return b
+}
+
+func readResponse(data string) []byte {
+	b, offset := body(16, []byte(data))
+	b[2] = byte(offset)
+
+	return put32(b, 4, uint32(len(data)))
+}
+
+func writeRequest(id byte, offset uint64, data string) []byte {
+	b, dataOffset := body(48, []byte(data))
+	put16(b, 2, dataOffset)
+	put32(b, 4, uint32(len(data)))
+	put64(b, 8, offset)
+	copy(b[16:], fileID(id))
+
+	return b
+}
+
+func writeResponse(count uint32) []byte {
+	return put32(make([]byte, 16), 4, count)
+}
+
+func closeRequest(id byte) []byte {
+	b := make([]byte, 24)
+	copy(b[8:], fileID(id))
+
+	return b
+}
+
+func conversation(msgs ...*message) *core.ConversationInfo {
+	fragments := make([]streamtest.Fragment, len(msgs))
+	for i, m := range msgs {
+		fragments[i] = streamtest.Fragment{Client: m.client, Data: m.bytes()}
+	}
+
+	return streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 445,
+	}, fragments...)
+}
+
+func TestSMBDecoder(t *testing.T) {
+	out, err := ioutil.TempDir("", "smb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(out)
+
+	writers, cleanup := streamtest.Setup(Decoder, credentials.Decoder, file.Decoder)
+	defer cleanup()
+
+	decoderconfig.Instance.Out = out
+	decoderconfig.Instance.FileStorage = "files"
+
+	var (
+		w, creds, files = writers[0], writers[1], writers[2]
+		nt              = append([]byte{0xaa, 0xbb, 0xcc, 0xdd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, make([]byte, 32)...)
+	)
+
+	conv := conversation(
+		&message{client: true, command: smbNegotiate, messageID: 0, body: make([]byte, 36)},
+		&message{command: smbNegotiate, messageID: 0, body: put16(make([]byte, 64), 4, 0x0311)},
+		&message{client: true, command: smbSessionSetup, messageID: 1, body: sessionSetupRequest([]byte("NTLMSSP\x00\x01\x00\x00\x00"))},
+		&message{command: smbSessionSetup, messageID: 1, status: statusMoreProcessingRequired, body: sessionSetupResponse(ntlmChallenge())},
+		&message{client: true, command: smbSessionSetup, messageID: 2, body: sessionSetupRequest(ntlmAuth("CORP", "alice", "WS01", nt))},
+		&message{command: smbSessionSetup, messageID: 2, body: sessionSetupResponse(nil)},
+		&message{client: true, command: smbTreeConnect, messageID: 3, body: treeConnectRequest(`\\fileserver\share`)},
+		&message{command: smbTreeConnect, messageID: 3, treeID: 5, body: make([]byte, 16)},
+		// file that is downloaded in two chunks, the responses are sent in reverse order
+		&message{client: true, command: smbCreate, messageID: 4, treeID: 5, body: createRequest(`docs\report.txt`, 1)},
+		&message{command: smbCreate, messageID: 4, treeID: 5, body: createResponse(1, 11, 0x01)},
+		&message{client: true, command: smbRead, messageID: 5, treeID: 5, body: readRequest(0x01, 0, 6)},
+		&message{client: true, command: smbRead, messageID: 6, treeID: 5, body: readRequest(0x01, 6, 5)},
+		&message{command: smbRead, messageID: 6, treeID: 5, body: readResponse("world")},
+		&message{command: smbRead, messageID: 5, treeID: 5, body: readResponse("hello ")},
+		&message{client: true, command: smbClose, messageID: 7, treeID: 5, body: closeRequest(0x01)},
+		&message{command: smbClose, messageID: 7, treeID: 5, body: make([]byte, 60)},
+		// file that is uploaded
+		&message{client: true, command: smbCreate, messageID: 8, treeID: 5, body: createRequest(`upload.bin`, 5)},
+		&message{command: smbCreate, messageID: 8, treeID: 5, flags: smbFlagAsync, status: statusPending, body: make([]byte, 8)},
+		&message{command: smbCreate, messageID: 8, treeID: 5, flags: smbFlagAsync, body: createResponse(2, 0, 0x02)},
+		&message{client: true, command: smbWrite, messageID: 9, treeID: 5, body: writeRequest(0x02, 0, "uploaded content")},
+		&message{command: smbWrite, messageID: 9, treeID: 5, body: writeResponse(16)},
+		&message{client: true, command: smbClose, messageID: 10, treeID: 5, body: closeRequest(0x02)},
+		// file that does not exist
+		&message{client: true, command: smbCreate, messageID: 11, treeID: 5, body: createRequest(`missing.txt`, 1)},
+		&message{command: smbCreate, messageID: 11, treeID: 5, status: 0xc0000034, body: make([]byte, 8)},
+	)
+
+	if !Decoder.CanDecode(conv.Data[0].Raw(), conv.Data[1].Raw()) {
+		t.Fatal("expected decoder to match")
+	}
+
+	(&smbReader{}).New(conv).Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	s := w.Records[0].(*types.SMB)
+
+	if s.Dialect != "3.1.1" || s.User != "alice" || s.Domain != "CORP" || s.Workstation != "WS01" || s.NumCommands != 12 {
+		t.Fatal("unexpected session", s)
+	}
+
+	if len(s.Trees) != 1 || s.Trees[0] != `\\fileserver\share` {
+		t.Fatal("unexpected trees", s.Trees)
+	}
+
+	if len(s.Files) != 3 {
+		t.Fatal("expected 3 files, got", len(s.Files))
+	}
+
+	if f := s.Files[0]; f.Name != `docs\report.txt` || f.Disposition != "OPEN" || f.Action != "OPENED" || f.BytesRead != 11 || f.EndOfFile != 11 || f.Tree != s.Trees[0] {
+		t.Fatal("unexpected download", f)
+	}
+
+	if f := s.Files[1]; f.Name != "upload.bin" || f.Disposition != "OVERWRITE_IF" || f.Action != "CREATED" || f.BytesWritten != 16 {
+		t.Fatal("unexpected upload", f)
+	}
+
+	if f := s.Files[2]; f.Status != "0xc0000034" || f.Action != "" {
+		t.Fatal("unexpected failed create", f)
+	}
+
+	if len(creds.Records) != 1 {
+		t.Fatal("expected 1 credential, got", len(creds.Records))
+	}
+
+	c := creds.Records[0].(*types.Credentials)
+	if c.User != `CORP\alice` || c.Notes != "NTLMv2" || !strings.HasPrefix(c.Password, "alice::CORP:0102030405060708:aabbccdd000000000000000000000000:") {
+		t.Fatal("unexpected credentials", c)
+	}
+
+	if len(files.Records) != 2 {
+		t.Fatal("expected 2 files, got", len(files.Records))
+	}
+
+	for i, expected := range []string{"hello world", "uploaded content"} {
+		f := files.Records[i].(*types.File)
+
+		data, err := ioutil.ReadFile(f.Location)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != expected || f.Source != serviceSMB || !strings.HasPrefix(f.Location, filepath.Join(out, "files")) {
+			t.Fatal("unexpected file", f, string(data))
+		}
+	}
+}
+
+func TestSMBIncompleteFile(t *testing.T) {
+	f := &smbFile{
+		SMBFile: &types.SMBFile{EndOfFile: 20, BytesRead: 8},
+		chunks: []*smbChunk{
+			{offset: 4, data: []byte("data")},
+			{offset: 0, data: []byte("some")},
+		},
+	}
+
+	data, complete := f.assemble()
+	if string(data) != "somedata" || complete {
+		t.Fatal("expected incomplete file", string(data), complete)
+	}
+
+	f.chunks = []*smbChunk{{offset: 10, data: []byte("data")}}
+	f.EndOfFile = 0
+
+	if _, complete = f.assemble(); complete {
+		t.Fatal("expected file with gap to be incomplete")
+	}
+
+	// a small write at a high offset is not assembled
+	f.chunks = []*smbChunk{{offset: maxFileSize - 4, data: []byte("data")}}
+
+	if data, _ = f.assemble(); data != nil {
+		t.Fatal("expected sparse file to be rejected, got", len(data), "bytes")
+	}
+}
+
+func TestSMBChunkOffsetOverflow(t *testing.T) {
+	out, err := ioutil.TempDir("", "smb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(out)
+
+	writers, cleanup := streamtest.Setup(Decoder, file.Decoder)
+	defer cleanup()
+
+	decoderconfig.Instance.Out = out
+	decoderconfig.Instance.FileStorage = "files"
+
+	w, files := writers[0], writers[1]
+
+	// the end of the written data overflows the offset
+	(&smbReader{}).New(conversation(
+		&message{client: true, command: smbCreate, messageID: 1, treeID: 5, body: createRequest(`upload.bin`, 5)},
+		&message{command: smbCreate, messageID: 1, treeID: 5, body: createResponse(2, 0, 0x01)},
+		&message{client: true, command: smbWrite, messageID: 2, treeID: 5, body: writeRequest(0x01, ^uint64(0)-3, "datadata")},
+		&message{command: smbWrite, messageID: 2, treeID: 5, body: writeResponse(8)},
+		&message{client: true, command: smbClose, messageID: 3, treeID: 5, body: closeRequest(0x01)},
+	)).Decode()
+
+	if len(w.Records) != 1 || len(files.Records) != 0 {
+		t.Fatal("expected no file to be saved", len(w.Records), len(files.Records))
+	}
+
+	if f := w.Records[0].(*types.SMB).Files[0]; f.BytesWritten != 0 {
+		t.Fatal("expected the chunk to be dropped", f)
+	}
+
+	f := &smbFile{SMBFile: &types.SMBFile{}}
+	if f.addChunk(maxFileSize+1, []byte("data")) || f.addChunk(^uint64(0)-3, []byte("datadata")) || len(f.chunks) != 0 {
+		t.Fatal("expected chunks beyond the maximum file size to be dropped")
+	}
+}
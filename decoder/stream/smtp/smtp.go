@@ -28,8 +28,8 @@ import (
 )
 
 var (
-	smtpLog               *zap.Logger
-	smtpLogSugared        *zap.SugaredLogger
+	smtpLog               = zap.NewNop()
+	smtpLogSugared        = smtpLog.Sugar()
 	smtpServiceReadyBytes = []byte(strconv.Itoa(smtpServiceReady))
 	smtpName              = []byte("SMTP")
 )
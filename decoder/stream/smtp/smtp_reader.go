@@ -14,22 +14,18 @@
 package smtp
 
 import (
-	"bufio"
-	"errors"
-	"io"
-	"net/textproto"
+	"bytes"
 	"strconv"
 	"strings"
 	"sync/atomic"
 
 	"github.com/mgutz/ansi"
-	"go.uber.org/zap"
 
 	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
 	"github.com/dreadl0ck/netcap/decoder/core"
 	"github.com/dreadl0ck/netcap/decoder/stream/mail"
-	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
 	decoderutils "github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
 	"github.com/dreadl0ck/netcap/types"
 )
 
@@ -98,6 +94,9 @@ const (
 	smtpTransactionFailed       = 554 // Transaction failed
 )
 
+// maxLineLength limits incomplete lines that are buffered, e.g. after the connection has been upgraded to TLS.
+const maxLineLength = 64 * 1024
+
 type smtpReader struct {
 	conversation *core.ConversationInfo
 
@@ -107,6 +106,12 @@ type smtpReader struct {
 	resIndex      int
 
 	user, pass, token string
+
+	// incomplete lines of each direction
+	clientLine, serverLine []byte
+
+	// lines collected for the next request and response
+	requestData, responseData []string
 }
 
 func validSMTPCommand(cmd string) bool {
@@ -138,23 +143,49 @@ func (h *smtpReader) New(conv *core.ConversationInfo) core.StreamDecoderInterfac
 	}
 }
 
-// Decode parses the stream according to the SMTP protocol.
-func (h *smtpReader) Decode() {
+// Consume parses the requests and responses in the complete lines of the fragment,
+// so the data of the connection does not need to be kept until it is closed.
+func (h *smtpReader) Consume(d core.DataFragment) {
 	// prevent nil pointer access if decoder is not initialized
 	if Decoder.Writer == nil {
 		return
 	}
 
-	streamutils.DecodeConversation(
-		h.conversation.Ident,
-		h.conversation.Data,
-		func(b *bufio.Reader) error {
-			return h.readRequest(b)
-		},
-		func(b *bufio.Reader) error {
-			return h.readResponse(b)
-		},
-	)
+	if d.Direction() == reassembly.TCPDirClientToServer {
+		h.clientLine = scanLines(h.clientLine, d.Raw(), h.readRequest)
+	} else {
+		h.serverLine = scanLines(h.serverLine, d.Raw(), h.readResponse)
+	}
+}
+
+// scanLines appends the data to the incomplete line of one direction and handles all complete lines.
+// The remaining incomplete line is returned.
+func scanLines(line, data []byte, handle func(line string)) []byte {
+	line = append(line, data...)
+
+	for {
+		i := bytes.IndexByte(line, '\n')
+		if i < 0 {
+			break
+		}
+
+		handle(strings.TrimSuffix(string(line[:i]), "\r"))
+		line = line[i+1:]
+	}
+
+	if len(line) == 0 || len(line) > maxLineLength {
+		return nil
+	}
+
+	return append([]byte(nil), line...)
+}
+
+// Decode processes the requests and responses parsed from the stream according to the SMTP protocol.
+func (h *smtpReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
 
 	var commands []string
 
@@ -167,13 +198,14 @@ func (h *smtpReader) Decode() {
 	mails := h.processSMTPConversation()
 
 	smtpMsg := &types.SMTP{
-		Timestamp: h.conversation.FirstClientPacket.UnixNano(),
-		SrcIP:     h.conversation.ClientIP,
-		DstIP:     h.conversation.ServerIP,
-		SrcPort:   h.conversation.ClientPort,
-		DstPort:   h.conversation.ServerPort,
-		MailIDs:   mails,
-		Commands:  commands,
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		SrcIP:       h.conversation.ClientIP,
+		DstIP:       h.conversation.ServerIP,
+		SrcPort:     h.conversation.ClientPort,
+		DstPort:     h.conversation.ServerPort,
+		MailIDs:     mails,
+		Commands:    commands,
+		CommunityID: h.conversation.CommunityID,
 	}
 
 	// export metrics if configured
@@ -194,68 +226,40 @@ func smtpDebug(args ...interface{}) {
 	smtpLogSugared.Info(args...)
 }
 
-func (h *smtpReader) readRequest(b *bufio.Reader) error {
-	var (
-		tp   = textproto.NewReader(b)
-		data []string
-	)
-
-nextLine:
-
-	// Parse the first line of the response.
-	line, err := tp.ReadLine()
-	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-		return err
-	} else if err != nil {
-		smtpLog.Error("SMTP Request error",
-			zap.String("ident", h.conversation.Ident),
-			zap.Error(err),
-		)
-
-		return err
-	}
-
+// readRequest handles a line sent by the client.
+// Lines that do not start with a command are collected, and added as data to the next request.
+func (h *smtpReader) readRequest(line string) {
 	smtpDebug(ansi.Red, h.conversation.Ident, "readSMTPRequest", line, ansi.Reset)
 
 	cmd, args := getSMTPCommand(line)
 
-	if cmd == smtpDot {
-
-		smtpDebug("collected data", strings.Join(data, "\n"))
+	switch {
+	case cmd == smtpDot:
+		smtpDebug("collected data", strings.Join(h.requestData, "\n"))
 
 		h.smtpRequests = append(h.smtpRequests, &types.SMTPRequest{
 			Command: smtpDATA,
-			Data:    strings.Join(data, "\n"),
+			Data:    strings.Join(h.requestData, "\n"),
 		})
-
-		return nil
-	}
-
-	if validSMTPCommand(cmd) {
-
-		if cmd == smtpDATA {
-			goto nextLine
-		}
-
+	case cmd == smtpDATA:
+		return
+	case validSMTPCommand(cmd):
 		h.smtpRequests = append(h.smtpRequests, &types.SMTPRequest{
 			Command:  cmd,
 			Argument: strings.Join(args, " "),
-			Data:     strings.Join(data, "\n"),
+			Data:     strings.Join(h.requestData, "\n"),
 		})
-
-	} else { // its data
+	default: // its data
 		if line == "" {
 			line = "\n"
 		}
-		data = append(data, line)
-		goto nextLine
-	}
 
-	if cmd == smtpQUIT {
-		return io.EOF
+		h.requestData = append(h.requestData, line)
+
+		return
 	}
 
-	return nil
+	h.requestData = nil
 }
 
 // cuts the line into command and arguments.
@@ -273,37 +277,19 @@ func getSMTPCommand(line string) (string, []string) {
 	return strings.ToUpper(cmd[0]), cmd[1:]
 }
 
-func (h *smtpReader) readResponse(b *bufio.Reader) error {
-	var (
-		tp   = textproto.NewReader(b)
-		data []string
-		cmd  string
-		args []string
-	)
-
-nextLine:
-	// Parse the first line of the response.
-	line, err := tp.ReadLine()
-	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-		return err
-	} else if err != nil {
-		smtpLog.Error(
-			"failed to read next SMTP response line",
-			zap.String("ident", h.conversation.Ident),
-			zap.Error(err),
-		)
-		return err
-	}
-
+// readResponse handles a line sent by the server.
+// The lines of a multiline response are collected, and added as data to the response.
+func (h *smtpReader) readResponse(line string) {
 	smtpDebug(ansi.Blue, h.conversation.Ident, "readSMTPResponse", line, ansi.Reset)
 
-	cmd, args = getSMTPCommand(line)
+	cmd, args := getSMTPCommand(line)
 
 	// handle data in response
 	if strings.Contains(cmd, "-") {
 		// more to come
-		data = append(data, line)
-		goto nextLine
+		h.responseData = append(h.responseData, line)
+
+		return
 	}
 
 	code, err := strconv.Atoi(cmd)
@@ -314,15 +300,10 @@ nextLine:
 	h.smtpResponses = append(h.smtpResponses, &types.SMTPResponse{
 		ResponseCode: int32(code),
 		Parameter:    strings.Join(args, " "),
-		Data:         strings.Join(data, "\n"),
+		Data:         strings.Join(h.responseData, "\n"),
 	})
 
-	// if QUIT was acked - quit
-	if code == smtpOK && strings.Contains(args[len(args)-1], smtpQUIT) {
-		return io.EOF
-	}
-
-	return nil
+	h.responseData = nil
 }
 
 // process the SMTP conversation and returns a list of extracted mail identifiers
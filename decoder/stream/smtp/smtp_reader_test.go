@@ -13,6 +13,17 @@
 
 package smtp
 
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/mail"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
 // 220 smtp-gw11.han.skanova.net ESMTP Service ready
 // HELO passwordnedxp
 // 250 smtp-gw11.han.skanova.net
@@ -75,3 +86,68 @@ package smtp
 // 250 <54E6F832004A05C2> Mail accepted
 // QUIT
 // 221 smtp-gw11.han.skanova.net QUIT
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestSMTPConsume(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder, mail.Decoder)
+	defer cleanup()
+
+	w, mw := writers[0], writers[1]
+
+	fragments := streamtest.Lines(
+		"220 smtp.example.com ESMTP Service ready",
+		"C: EHLO client.example.com",
+		"250-smtp.example.com",
+		"250 SIZE 10240000",
+		"C: MAIL FROM: <alice@example.com>",
+		"250 OK",
+		"C: RCPT TO: <bob@example.com>",
+		"250 OK",
+		"C: DATA",
+		"354 Start mail input; end with <CRLF>.<CRLF>",
+		"C: From: alice@example.com\r\nTo: bob@example.com\r\nSubject: Hello\r\n\r\nHi Bob!\r\n.",
+		"250 Mail accepted",
+		"C: QUIT",
+		"221 Bye",
+	)
+
+	// a line that spans two fragments
+	data := fragments[10].Data
+	fragments = append(fragments[:10], append([]streamtest.Fragment{
+		{Client: true, Data: data[:20]},
+		{Client: true, Data: data[20:]},
+	}, fragments[11:]...)...)
+
+	conv := streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 25,
+	}, fragments...)
+
+	h := (&smtpReader{}).New(conv).(core.StreamingDecoderInterface)
+
+	for _, f := range conv.Data {
+		h.Consume(f)
+	}
+
+	h.Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	s := w.Records[0].(*types.SMTP)
+	if strings.Join(s.Commands, ",") != "EHLO,MAIL FROM,RCPT TO,DATA,QUIT" || len(s.MailIDs) != 1 {
+		t.Fatal("unexpected record", s)
+	}
+
+	if len(mw.Records) != 1 {
+		t.Fatal("expected 1 mail, got", len(mw.Records))
+	}
+
+	if m := mw.Records[0].(*types.Mail); m.Subject != "Hello" || m.ID != s.MailIDs[0] {
+		t.Fatal("unexpected mail", m)
+	}
+}
@@ -110,6 +110,16 @@ var Decoder = &decoder.AbstractDecoder{
 			return errors.Wrap(err, "failed to open vulnerability bleve index at: "+indexName)
 		}
 
+		// Load the CPE names of vulnerable software for precise matching, fall back to the bleve index if unavailable
+		cpeDBPath := filepath.Join(resolvers.DataBaseFolderPath, db.VulnerabilityCPEDBName)
+
+		numCVEs, err := vulnerability.LoadCPEDatabase(cpeDBPath)
+		if err != nil {
+			softwareLog.Warn("failed to load vulnerability CPE database, using bleve index", zap.String("path", cpeDBPath), zap.Error(err))
+		} else {
+			softwareLog.Info("loaded vulnerability CPE database", zap.Int("total", numCVEs))
+		}
+
 		softwareLog.Info("loaded Ja3/ja3S database", zap.Int("total_records", len(ja3db.Servers)))
 
 		return nil
@@ -472,12 +482,13 @@ func WhatSoftwareHTTP(flowIdent string, h *types.HTTP) (s []*AtomicSoftware) {
 						Vendor:    userInfo.Vendor,
 						Version:   userInfo.Version,
 						// DeviceProfiles: []string{dpIdent},
-						SourceName: "UserAgent",
-						SourceData: h.UserAgent,
-						Service:    "HTTP",
-						Flows:      []string{flowIdent},
-						Notes:      userInfo.Full,
-						OS:         userInfo.OS,
+						SourceName:   "UserAgent",
+						SourceData:   h.UserAgent,
+						Service:      "HTTP",
+						Flows:        []string{flowIdent},
+						Notes:        userInfo.Full,
+						OS:           userInfo.OS,
+						CommunityIDs: []string{h.CommunityID},
 					},
 				})
 			}
@@ -495,10 +506,11 @@ func WhatSoftwareHTTP(flowIdent string, h *types.HTTP) (s []*AtomicSoftware) {
 				Version:   values[2], // Version as found after the '/'
 				OS:        values[3], // potentially operating system
 				// DeviceProfiles: []string{dpIdent},
-				SourceName: "ServerName",
-				SourceData: h.ServerName,
-				Service:    "HTTP",
-				Flows:      []string{flowIdent},
+				SourceName:   "ServerName",
+				SourceData:   h.ServerName,
+				Service:      "HTTP",
+				Flows:        []string{flowIdent},
+				CommunityIDs: []string{h.CommunityID},
 			},
 		})
 	}
@@ -514,10 +526,11 @@ func WhatSoftwareHTTP(flowIdent string, h *types.HTTP) (s []*AtomicSoftware) {
 					Product:   values[1], // Name of the server (Apache, Nginx, ...)
 					Version:   values[2], // Version as found after the '/'
 					// DeviceProfiles: []string{dpIdent},
-					SourceName: "X-Powered-By",
-					SourceData: poweredBy,
-					Service:    "HTTP",
-					Flows:      []string{flowIdent},
+					SourceName:   "X-Powered-By",
+					SourceData:   poweredBy,
+					Service:      "HTTP",
+					Flows:        []string{flowIdent},
+					CommunityIDs: []string{h.CommunityID},
 				},
 			})
 		}
@@ -590,7 +603,7 @@ func WhatSoftwareHTTP(flowIdent string, h *types.HTTP) (s []*AtomicSoftware) {
 				if matchesHeader() {
 
 					// we found a match
-					s = append(s, makeSoftware(h.Timestamp, product, info.Website, sourceName, sourceData, flowIdent))
+					s = append(s, makeSoftware(h.Timestamp, product, info.Website, sourceName, sourceData, flowIdent, h.CommunityID))
 
 					if decoderconfig.Instance.StopAfterServiceProbeMatch {
 						return s
@@ -631,7 +644,7 @@ func WhatSoftwareHTTP(flowIdent string, h *types.HTTP) (s []*AtomicSoftware) {
 				if matchesCookie() {
 
 					// we found a match
-					s = append(s, makeSoftware(h.Timestamp, product, info.Website, sourceName, sourceData, flowIdent))
+					s = append(s, makeSoftware(h.Timestamp, product, info.Website, sourceName, sourceData, flowIdent, h.CommunityID))
 
 					if decoderconfig.Instance.StopAfterServiceProbeMatch {
 						return s
@@ -761,6 +774,10 @@ func WriteSoftware(software []*AtomicSoftware, update func(s *AtomicSoftware)) {
 		}
 		s.Unlock()
 		if item, ok := Store.Items[ident]; ok {
+			item.Lock()
+			item.CommunityIDs = addCommunityIDs(item.CommunityIDs, s.CommunityIDs)
+			item.Unlock()
+
 			if update != nil {
 				update(item)
 			}
@@ -784,6 +801,31 @@ func WriteSoftware(software []*AtomicSoftware, update func(s *AtomicSoftware)) {
 	}
 }
 
+// addCommunityIDs appends the community ids of the flows that are not yet associated with the software.
+func addCommunityIDs(ids []string, newIDs []string) []string {
+	for _, id := range newIDs {
+		if id == "" {
+			continue
+		}
+
+		var found bool
+
+		for _, existing := range ids {
+			if existing == id {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
 //// newSoftware creates a new device specific profile.
 //func newSoftware(i *decoderutils.PacketInfo) *AtomicSoftware {
 //	return &AtomicSoftware{
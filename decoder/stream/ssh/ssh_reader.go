@@ -41,9 +41,17 @@ import (
  * SSH - The Secure Shell Protocol
  */
 
+// 2255 bytes should be enough to capture ident (max 255 bytes) + kexInit (usually ~1200-1700 bytes)
+const maxHandshakeSize = 2255
+
 type sshReader struct {
 	conversation *core.ConversationInfo
 
+	clientBuf  bytes.Buffer
+	serverBuf  bytes.Buffer
+	clientDone bool
+	serverDone bool
+
 	clientIdent   string
 	serverIdent   string
 	clientKexInit *KexInitMsg
@@ -58,34 +66,76 @@ func (h *sshReader) New(conversation *core.ConversationInfo) core.StreamDecoderI
 	}
 }
 
-// Decode parses the stream according to the SSH protocol.
-func (h *sshReader) Decode() {
+// Consume collects the start of each direction while the connection is open,
+// and searches it for the ident and KexInit as soon as they have been received.
+func (h *sshReader) Consume(d core.DataFragment) {
 	// prevent nil pointer access if decoder is not initialized
 	if Decoder.Writer == nil {
 		return
 	}
 
-	var (
-		serverBuf bytes.Buffer
-		clientBuf bytes.Buffer
-	)
+	if d.Direction() == reassembly.TCPDirClientToServer {
+		if h.clientDone {
+			return
+		}
 
-	for _, d := range h.conversation.Data {
-		if d.Direction() == reassembly.TCPDirClientToServer {
-			// 2255k bytes should be enough to capture ident (max 255 bytes) + kexInit (usually ~1200-1700 bytes)
-			if clientBuf.Len() < 2255 {
-				clientBuf.Write(d.Raw())
-			}
-		} else {
-			// 2255k bytes should be enough to capture ident (max 255 bytes) + kexInit (usually ~1200-1700 bytes)
-			if serverBuf.Len() < 2255 {
-				serverBuf.Write(d.Raw())
-			}
+		h.clientBuf.Write(d.Raw())
+
+		if handshakeComplete(h.clientBuf.Bytes()) {
+			h.clientDone = true
+			h.searchKexInit(bufio.NewReader(&h.clientBuf), reassembly.TCPDirClientToServer)
+		}
+	} else {
+		if h.serverDone {
+			return
+		}
+
+		h.serverBuf.Write(d.Raw())
+
+		if handshakeComplete(h.serverBuf.Bytes()) {
+			h.serverDone = true
+			h.searchKexInit(bufio.NewReader(&h.serverBuf), reassembly.TCPDirServerToClient)
 		}
 	}
+}
+
+// handshakeComplete checks if the data contains the ident line followed by a complete packet,
+// or if enough data to search for the KexInit has been collected.
+func handshakeComplete(data []byte) bool {
+	if len(data) >= maxHandshakeSize {
+		return true
+	}
+
+	i := bytes.Index(data, []byte("\r\n"))
+	if i < 0 {
+		return false
+	}
+
+	packet := data[i+2:]
+	if len(packet) < 4 {
+		return false
+	}
+
+	return uint64(len(packet)-4) >= uint64(binary.BigEndian.Uint32(packet))
+}
+
+// Decode searches the data that has not been processed while the connection was open,
+// and updates the software audit records.
+func (h *sshReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	if !h.clientDone {
+		h.clientDone = true
+		h.searchKexInit(bufio.NewReader(&h.clientBuf), reassembly.TCPDirClientToServer)
+	}
 
-	h.searchKexInit(bufio.NewReader(&clientBuf), reassembly.TCPDirClientToServer)
-	h.searchKexInit(bufio.NewReader(&serverBuf), reassembly.TCPDirServerToClient)
+	if !h.serverDone {
+		h.serverDone = true
+		h.searchKexInit(bufio.NewReader(&h.serverBuf), reassembly.TCPDirServerToClient)
+	}
 
 	if len(h.software) == 0 {
 		return
@@ -115,14 +165,15 @@ func (h *sshReader) processSSHIdent(ident string, entity string) {
 		software.WriteSoftware([]*software.AtomicSoftware{
 			{
 				Software: &types.Software{
-					Timestamp:  h.conversation.FirstClientPacket.UnixNano(),
-					Product:    i.productName,
-					Version:    i.productVersion,
-					SourceName: "SSH " + entity + " Ident",
-					Service:    serviceSSH,
-					Flows:      []string{h.conversation.Ident},
-					Notes:      "SSH version: " + i.sshVersion + " OS: " + i.os,
-					SourceData: h.serverIdent,
+					Timestamp:    h.conversation.FirstClientPacket.UnixNano(),
+					Product:      i.productName,
+					Version:      i.productVersion,
+					SourceName:   "SSH " + entity + " Ident",
+					Service:      serviceSSH,
+					Flows:        []string{h.conversation.Ident},
+					Notes:        "SSH version: " + i.sshVersion + " OS: " + i.os,
+					SourceData:   h.serverIdent,
+					CommunityIDs: []string{h.conversation.CommunityID},
 				},
 			},
 		}, nil)
@@ -225,12 +276,13 @@ func (h *sshReader) searchKexInit(r *bufio.Reader, dir reassembly.TCPFlowDirecti
 
 		if dir == reassembly.TCPDirClientToServer {
 			err = Decoder.Writer.Write(&types.SSH{
-				Timestamp:  h.conversation.FirstClientPacket.UnixNano(),
-				HASSH:      hash,
-				Flow:       h.conversation.Ident,
-				Ident:      h.clientIdent,
-				Algorithms: raw,
-				IsClient:   true,
+				Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+				HASSH:       hash,
+				Flow:        h.conversation.Ident,
+				Ident:       h.clientIdent,
+				Algorithms:  raw,
+				IsClient:    true,
+				CommunityID: h.conversation.CommunityID,
 			})
 			if err != nil {
 				sshLog.Error("failed to flush ssh audit record", zap.Error(err))
@@ -243,12 +295,13 @@ func (h *sshReader) searchKexInit(r *bufio.Reader, dir reassembly.TCPFlowDirecti
 			sshLog.Info("found clientKexInit", zap.String("ident", h.conversation.Ident))
 		} else {
 			err = Decoder.Writer.Write(&types.SSH{
-				Timestamp:  h.conversation.FirstServerPacket.UnixNano(),
-				HASSH:      hash,
-				Flow:       utils.ReverseFlowIdent(h.conversation.Ident),
-				Ident:      h.serverIdent,
-				Algorithms: raw,
-				IsClient:   false,
+				Timestamp:   h.conversation.FirstServerPacket.UnixNano(),
+				HASSH:       hash,
+				Flow:        utils.ReverseFlowIdent(h.conversation.Ident),
+				Ident:       h.serverIdent,
+				Algorithms:  raw,
+				IsClient:    false,
+				CommunityID: h.conversation.CommunityID,
 			})
 			if err != nil {
 				sshLog.Error("failed to flush ssh audit record", zap.Error(err))
@@ -275,8 +328,9 @@ func (h *sshReader) searchKexInit(r *bufio.Reader, dir reassembly.TCPFlowDirecti
 				SourceData: hash,
 				Service:    serviceSSH,
 				// DPIResults:     protos,
-				Flows: []string{h.conversation.Ident},
-				Notes: "Likelihood: " + soft.Likelihood + " Possible OS: " + os + "SSH Version: " + sshVersion,
+				Flows:        []string{h.conversation.Ident},
+				Notes:        "Likelihood: " + soft.Likelihood + " Possible OS: " + os + "SSH Version: " + sshVersion,
+				CommunityIDs: []string{h.conversation.CommunityID},
 			})
 		}
 
@@ -16,8 +16,79 @@ package ssh
 import (
 	"encoding/binary"
 	"testing"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
 )
 
+func TestConsume(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	defer cleanup()
+
+	w := writers[0]
+
+	var (
+		payload = Marshal(&KexInitMsg{
+			KexAlgos:                []string{"curve25519-sha256"},
+			ServerHostKeyAlgos:      []string{"ssh-ed25519"},
+			CiphersClientServer:     []string{"aes128-ctr"},
+			CiphersServerClient:     []string{"aes128-ctr"},
+			MACsClientServer:        []string{"hmac-sha2-256"},
+			MACsServerClient:        []string{"hmac-sha2-256"},
+			CompressionClientServer: []string{"none"},
+			CompressionServerClient: []string{"none"},
+		})
+		padding = 4
+		packet  = make([]byte, 5, 5+len(payload)+padding)
+	)
+
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)+padding))
+	packet[4] = byte(padding)
+	packet = append(packet, payload...)
+	packet = append(packet, make([]byte, padding)...)
+
+	h := (&sshReader{}).New(&core.ConversationInfo{
+		Ident:       "192.168.1.2:49999->192.168.1.10:22",
+		CommunityID: "1:abc",
+	}).(core.StreamingDecoderInterface)
+
+	client := func(data []byte) {
+		h.Consume(&core.StreamData{
+			RawData: data,
+			Dir:     reassembly.TCPDirClientToServer,
+		})
+	}
+
+	client([]byte("SSH-2.0-netcap\r\n"))
+	client(packet[:100])
+
+	if len(w.Records) != 0 {
+		t.Fatal("expected no records for incomplete handshake, got", len(w.Records))
+	}
+
+	// the record is written as soon as the KexInit is complete, before the connection is closed
+	client(packet[100:])
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+
+	r := w.Records[0].(*types.SSH)
+	if !r.IsClient || r.Ident != "SSH-2.0-netcap" || r.Algorithms != "curve25519-sha256;aes128-ctr;hmac-sha2-256;none" || r.CommunityID != "1:abc" {
+		t.Fatal("unexpected record", r)
+	}
+
+	// later data is ignored
+	client(packet)
+	h.Decode()
+
+	if len(w.Records) != 1 {
+		t.Fatal("expected 1 record, got", len(w.Records))
+	}
+}
+
 func TestParseSSHInfoFromHasshDB(t *testing.T) {
 	sshVersion, product, version, os := parseSSHInfoFromHasshDB("SSH 2.0 | OpenSSH 7.4 ? Debian")
 	if sshVersion != "SSH 2.0" {
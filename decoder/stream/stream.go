@@ -8,10 +8,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dreadl0ck/netcap/decoder/stream/dns"
+	"github.com/dreadl0ck/netcap/decoder/stream/ftp"
 	"github.com/dreadl0ck/netcap/decoder/stream/http"
+	"github.com/dreadl0ck/netcap/decoder/stream/imap"
+	"github.com/dreadl0ck/netcap/decoder/stream/kerberos"
 	"github.com/dreadl0ck/netcap/decoder/stream/pop3"
+	"github.com/dreadl0ck/netcap/decoder/stream/smb"
 	"github.com/dreadl0ck/netcap/decoder/stream/smtp"
 	"github.com/dreadl0ck/netcap/decoder/stream/ssh"
+	"github.com/dreadl0ck/netcap/decoder/stream/tls"
 
 	"github.com/mgutz/ansi"
 	"github.com/pkg/errors"
@@ -37,19 +43,53 @@ var DefaultStreamDecoders = map[int32]core.StreamDecoderAPI{
 	110: pop3.Decoder,
 	22:  ssh.Decoder,
 	25:  smtp.Decoder,
+	21:  ftp.Decoder,
+	143: imap.Decoder,
+	53:  dns.Decoder,
+	443: tls.Decoder,
+	445: smb.Decoder,
+	88:  kerberos.Decoder,
 } // contains all available stream decoders
 
+// DefaultUDPStreamDecoders contains the stream decoders for UDP conversations mapped to their protocols default port
+// decoders for protocols that can be transported via TCP and UDP must be added to both maps.
+var DefaultUDPStreamDecoders = map[int32]core.StreamDecoderAPI{
+	88: kerberos.Decoder,
+}
+
 // package level init.
 func init() {
 	// collect all names for stream decoders on startup
-	for _, d := range DefaultStreamDecoders {
+	for _, d := range streamDecoders() {
 		decoderutils.AllDecoderNames[d.GetName()] = struct{}{}
 	}
 }
 
+// streamDecoders returns the stream decoders for TCP and UDP,
+// decoders that are registered for both transport protocols are only returned once.
+func streamDecoders() []core.StreamDecoderAPI {
+	var (
+		decoders []core.StreamDecoderAPI
+		seen     = make(map[core.StreamDecoderAPI]struct{})
+	)
+
+	for _, m := range []map[int32]core.StreamDecoderAPI{DefaultStreamDecoders, DefaultUDPStreamDecoders} {
+		for _, d := range m {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+
+			seen[d] = struct{}{}
+			decoders = append(decoders, d)
+		}
+	}
+
+	return decoders
+}
+
 // ApplyActionToStreamDecoders can be used to run custom code for all stream decoders.
 func ApplyActionToStreamDecoders(action func(api core.StreamDecoderAPI)) {
-	for _, d := range DefaultStreamDecoders {
+	for _, d := range streamDecoders() {
 		action(d)
 	}
 }
@@ -64,7 +104,7 @@ func ApplyActionToStreamDecodersAsync(action func(api core.StreamDecoderAPI)) {
 	}
 
 	wg := sync.WaitGroup{}
-	for _, d := range DefaultStreamDecoders {
+	for _, d := range streamDecoders() {
 		wg.Add(1)
 		go func(d core.StreamDecoderAPI) {
 			action(d)
@@ -83,9 +123,6 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 
 		// include map
 		inMap = make(map[string]bool)
-
-		// new selection
-		selection = make(map[int32]core.StreamDecoderAPI)
 	)
 
 	// if there are includes and the first item is not an empty string
@@ -101,15 +138,9 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 			}
 		}
 
-		// iterate over packet decoders and collect those that are named in the includeMap
-		for port, dec := range DefaultStreamDecoders {
-			if _, ok := inMap[dec.GetName()]; ok {
-				selection[port] = dec
-			}
-		}
-
-		// update packet decoders to new selection
-		DefaultStreamDecoders = selection
+		// update stream decoders to new selection
+		DefaultStreamDecoders = selectStreamDecoders(DefaultStreamDecoders, inMap)
+		DefaultUDPStreamDecoders = selectStreamDecoders(DefaultUDPStreamDecoders, inMap)
 	}
 
 	// iterate over excluded decoders
@@ -119,15 +150,9 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				return nil, errors.Wrap(errInvalidStreamDecoder, name)
 			}
 
-			// remove named decoder from defaultPacketDecoders
-			for port, dec := range DefaultStreamDecoders {
-				if name == dec.GetName() {
-					// remove decoder
-					delete(DefaultStreamDecoders, port)
-
-					break
-				}
-			}
+			// remove named decoder from the TCP and UDP stream decoders
+			removeStreamDecoder(DefaultStreamDecoders, name)
+			removeStreamDecoder(DefaultUDPStreamDecoders, name)
 		}
 	}
 
@@ -137,7 +162,7 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 	)
 
 	// initialize decoders
-	for _, d := range DefaultStreamDecoders {
+	for _, d := range streamDecoders() {
 
 		// reset decoder stat in case it is reinitialized at runtime.
 		d.(*decoder.StreamDecoder).NumRecordsWritten = 0
@@ -155,6 +180,7 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 				Type:    dec.GetType(),
 				Null:    c.Null,
 				Elastic: c.Elastic,
+				FanOut:  c.FanOut,
 				ElasticConfig: netio.ElasticConfig{
 					ElasticAddrs:   c.ElasticAddrs,
 					ElasticUser:    c.ElasticUser,
@@ -162,6 +188,7 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 					KibanaEndpoint: c.KibanaEndpoint,
 					BulkSize:       c.BulkSizeCustom,
 				},
+				RotationConfig:       c.RotationConfig,
 				Buffer:               c.Buffer,
 				Compress:             c.Compression,
 				Out:                  c.Out,
@@ -209,9 +236,31 @@ func InitDecoders(c *config.Config) (decoders []core.StreamDecoderAPI, err error
 	return decoders, nil
 }
 
+// selectStreamDecoders returns the decoders that are named in the include map.
+func selectStreamDecoders(decoders map[int32]core.StreamDecoderAPI, inMap map[string]bool) map[int32]core.StreamDecoderAPI {
+	selection := make(map[int32]core.StreamDecoderAPI)
+
+	for port, dec := range decoders {
+		if _, ok := inMap[dec.GetName()]; ok {
+			selection[port] = dec
+		}
+	}
+
+	return selection
+}
+
+// removeStreamDecoder removes the named decoder from all ports.
+func removeStreamDecoder(decoders map[int32]core.StreamDecoderAPI, name string) {
+	for port, dec := range decoders {
+		if name == dec.GetName() {
+			delete(decoders, port)
+		}
+	}
+}
+
 // isStreamDecoderLoaded checks if an abstract decoder is loaded.
 func isStreamDecoderLoaded(name string) bool {
-	for _, e := range DefaultStreamDecoders {
+	for _, e := range streamDecoders() {
 		if e.GetName() == name {
 			return true
 		}
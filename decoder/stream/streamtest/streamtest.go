@@ -0,0 +1,116 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package streamtest provides conversations and writers for testing stream decoders.
+package streamtest
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	netio "github.com/dreadl0ck/netcap/io"
+	"github.com/dreadl0ck/netcap/io/iotest"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// Fragment is a chunk of data sent by the client or the server.
+type Fragment struct {
+	Client bool
+	Data   []byte
+}
+
+// Lines returns a fragment for each line, terminated with CRLF.
+// Lines prefixed with 'C: ' are sent by the client, all others by the server.
+func Lines(lines ...string) []Fragment {
+	fragments := make([]Fragment, 0, len(lines))
+
+	for _, l := range lines {
+		f := Fragment{}
+		if strings.HasPrefix(l, "C: ") {
+			f.Client = true
+			l = l[3:]
+		}
+
+		f.Data = []byte(l + "\r\n")
+		fragments = append(fragments, f)
+	}
+
+	return fragments
+}
+
+// Endpoints of a conversation.
+type Endpoints struct {
+	ClientIP   string
+	ServerIP   string
+	ClientPort int32
+	ServerPort int32
+}
+
+// Conversation returns a conversation between the endpoints that starts at ts.
+// The fragments are captured one second apart.
+func Conversation(ts time.Time, e Endpoints, fragments ...Fragment) *core.ConversationInfo {
+	conv := &core.ConversationInfo{
+		Ident:             e.ClientIP + ":" + strconv.Itoa(int(e.ClientPort)) + "->" + e.ServerIP + ":" + strconv.Itoa(int(e.ServerPort)),
+		FirstClientPacket: ts,
+		FirstServerPacket: ts,
+		ClientIP:          e.ClientIP,
+		ServerIP:          e.ServerIP,
+		ClientPort:        e.ClientPort,
+		ServerPort:        e.ServerPort,
+	}
+
+	for i, f := range fragments {
+		dir := reassembly.TCPDirServerToClient
+		if f.Client {
+			dir = reassembly.TCPDirClientToServer
+		}
+
+		conv.Data = append(conv.Data, &core.StreamData{
+			RawData: f.Data,
+			Dir:     dir,
+			CaptureInformation: gopacket.CaptureInfo{
+				Timestamp: ts.Add(time.Duration(i) * time.Second),
+			},
+		})
+	}
+
+	return conv
+}
+
+// decoder is a decoder that writes audit records.
+type decoder interface {
+	SetWriter(netio.AuditRecordWriter)
+}
+
+// Setup resets the decoder config and attaches a RecordWriter to each decoder.
+// The returned function detaches the writers again.
+func Setup(decoders ...decoder) (writers []*iotest.RecordWriter, cleanup func()) {
+	decoderconfig.Instance = &decoderconfig.Config{}
+
+	for _, d := range decoders {
+		w := &iotest.RecordWriter{}
+		d.SetWriter(w)
+		writers = append(writers, w)
+	}
+
+	return writers, func() {
+		for _, d := range decoders {
+			d.SetWriter(nil)
+		}
+	}
+}
@@ -40,7 +40,7 @@ func saveTCPServiceBanner(s streamReader) {
 
 		// invoke the service probe matching on all streams towards this service
 		// TODO: make matching more banners than the first one configurable
-		service.MatchServiceProbes(sv, banner, s.Ident())
+		service.MatchServiceProbes(sv, banner, s.Ident(), utils.CommunityIDFromFlows(s.Network(), s.Transport()))
 
 		// ensure we don't duplicate any flows
 		for _, f := range sv.Flows {
@@ -79,7 +79,7 @@ func saveTCPServiceBanner(s streamReader) {
 		serv.Name = resolvers.LookupServiceByPort(dst, "TCP")
 	}
 
-	service.MatchServiceProbes(serv, banner, s.Ident())
+	service.MatchServiceProbes(serv, banner, s.Ident(), utils.CommunityIDFromFlows(s.Network(), s.Transport()))
 
 	// add new service
 	service.Store.Lock()
@@ -34,6 +34,7 @@ import (
 	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
 	"github.com/dreadl0ck/netcap/decoder/core"
 	"github.com/dreadl0ck/netcap/decoder/stream"
+	"github.com/dreadl0ck/netcap/decoder/stream/ftp"
 	"github.com/dreadl0ck/netcap/decoder/stream/udp"
 	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
 	"github.com/dreadl0ck/netcap/defaults"
@@ -81,8 +82,24 @@ type tcpConnection struct {
 	decoder  core.StreamDecoderInterface
 	tcpstate *reassembly.TCPSimpleFSM
 
+	// streaming is set if the decoder selected for the connection consumes the data incrementally
+	streaming core.StreamingDecoderInterface
+
 	wasMerged bool
 	fsmerr    bool
+
+	// set once a decoder has been selected while the connection is still open
+	selected bool
+
+	// number of bytes of conversation data buffered for the connection
+	bufferedBytes int64
+
+	// truncated is set once conversation data has been dropped, because a memory limit has been reached
+	truncated      bool
+	truncateReason string
+
+	// set once the conversation has been passed to the decoder
+	decoded bool
 }
 
 // Accept decides whether the TCP packet should be accepted
@@ -334,7 +351,7 @@ func (t *tcpConnection) ReassemblyComplete(ac reassembly.AssemblerContext, first
 		t.sortAndMergeFragments()
 
 		// save the full conversation to disk if enabled
-		err := streamutils.SaveConversation("TCP", t.merged, t.client.Ident(), t.client.FirstPacket(), t.client.Transport())
+		err := streamutils.SaveConversation("TCP", t.merged, t.client.Ident(), t.client.FirstPacket(), t.client.Network(), t.client.Transport())
 		if err != nil {
 			reassemblyLog.Error("failed to save stream", zap.Error(err), zap.String("ident", t.client.Ident()))
 		}
@@ -369,14 +386,61 @@ func (t *tcpConnection) decode() {
 	t.Lock()
 	defer t.Unlock()
 
+	// the conversation data is no longer accounted once it has been decoded
+	defer func() {
+		t.decoded = true
+		t.free(t.bufferedBytes)
+	}()
+
+	conv := t.conversationInfo(t.merged)
+
+	// scan the conversation for content signatures
+	streamutils.ScanConversation(conv)
+
+	// the data has already been passed to the decoder while the connection was open
+	if t.streaming != nil {
+		ti := time.Now()
+
+		t.streaming.Decode()
+
+		tcpStreamDecodeTime.WithLabelValues(reflect.TypeOf(t.streaming).String()).Set(float64(time.Since(ti).Nanoseconds()))
+
+		// ignore data that is delivered after the connection has been decoded
+		t.streaming = nil
+
+		return
+	}
+
 	// choose the decoder to run against the data stream
-	var (
-		cr, sr = t.client.DataSlice().First(), t.server.DataSlice().First()
-		found  bool
-	)
+	if d := t.selectDecoder(conv); d != nil {
+		t.decoder = d
+
+		ti := time.Now()
+
+		// streaming decoders that have not been selected while the connection was open receive the entire conversation now
+		if sd, ok := d.(core.StreamingDecoderInterface); ok {
+			for _, f := range t.merged {
+				sd.Consume(f)
+			}
+		}
 
-	conv := &core.ConversationInfo{
-		Data:              t.merged,
+		// call the associated decoder
+		t.decoder.Decode()
+
+		tcpStreamDecodeTime.WithLabelValues(reflect.TypeOf(t.decoder).String()).Set(float64(time.Since(ti).Nanoseconds()))
+
+		return
+	}
+
+	// connections without a matching decoder could be FTP data connections
+	ftp.SaveDataConnection(conv)
+}
+
+// conversationInfo creates the conversation for the connection with the given data fragments.
+// CAUTION: the connection needs to be locked when calling this.
+func (t *tcpConnection) conversationInfo(data core.DataFragments) *core.ConversationInfo {
+	return &core.ConversationInfo{
+		Data:              data,
 		Ident:             t.ident,
 		FirstClientPacket: t.client.FirstPacket(),
 		FirstServerPacket: t.server.FirstPacket(),
@@ -384,40 +448,36 @@ func (t *tcpConnection) decode() {
 		ServerIP:          t.client.Network().Dst().String(),
 		ClientPort:        utils.DecodePort(t.client.Transport().Src().Raw()),
 		ServerPort:        utils.DecodePort(t.client.Transport().Dst().Raw()),
+		CommunityID:       utils.CommunityIDFromFlows(t.client.Network(), t.client.Transport()),
 	}
+}
+
+// selectDecoder returns a decoder instance for the first stream decoder that matches the conversation,
+// or nil if no decoder matches.
+// CAUTION: the connection needs to be locked when calling this.
+func (t *tcpConnection) selectDecoder(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	cr, sr := t.client.DataSlice().First(), t.server.DataSlice().First()
 
 	// make a good first guess based on the destination port of the connection
 	if sd, exists := stream.DefaultStreamDecoders[utils.DecodePort(t.server.Transport().Dst().Raw())]; exists {
 		if sd.Transport() == core.TCP || sd.Transport() == core.All {
 			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
-				t.decoder = sd.GetReaderFactory().New(conv)
-				found = true
+				return sd.GetReaderFactory().New(conv)
 			}
 		}
 	}
 
 	// if no stream decoder for the port was found, or the stream decoder did not match
 	// try all available decoders and use the first one that matches
-	if !found {
-		for _, sd := range stream.DefaultStreamDecoders {
-			if sd.Transport() == core.TCP || sd.Transport() == core.All {
-				if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
-					t.decoder = sd.GetReaderFactory().New(conv)
-					break
-				}
+	for _, sd := range stream.DefaultStreamDecoders {
+		if sd.Transport() == core.TCP || sd.Transport() == core.All {
+			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
+				return sd.GetReaderFactory().New(conv)
 			}
 		}
 	}
 
-	// call the decoder if one was found
-	if t.decoder != nil {
-		ti := time.Now()
-
-		// call the associated decoder
-		t.decoder.Decode()
-
-		tcpStreamDecodeTime.WithLabelValues(reflect.TypeOf(t.decoder).String()).Set(float64(time.Since(ti).Nanoseconds()))
-	}
+	return nil
 }
 
 var aMu sync.Mutex
@@ -657,6 +717,8 @@ func CleanupReassembly(wait bool, assemblers []*reassembly.Assembler) {
 			{"Checksum", strconv.FormatBool(decoderconfig.Instance.Checksum)},
 			{"DefragIPv4", strconv.FormatBool(decoderconfig.Instance.DefragIPv4)},
 			{"WriteIncomplete", strconv.FormatBool(decoderconfig.Instance.WriteIncomplete)},
+			{"ReassemblyBudget", strconv.FormatInt(decoderconfig.Instance.ReassemblyBudget, 10)},
+			{"ReassemblyConnMax", strconv.FormatInt(decoderconfig.Instance.ReassemblyConnMax, 10)},
 		})
 
 		printProgress(1, 1)
@@ -683,6 +745,8 @@ func CleanupReassembly(wait bool, assemblers []*reassembly.Assembler) {
 			[]string{"biggest-chunk bytes", strconv.FormatInt(streamutils.Stats.BiggestChunkBytes, 10)},
 			[]string{"overlap packets", strconv.FormatInt(streamutils.Stats.OverlapPackets, 10)},
 			[]string{"overlap bytes", strconv.FormatInt(streamutils.Stats.OverlapBytes, 10)},
+			[]string{"truncated connections", strconv.FormatInt(streamutils.Stats.TruncatedConns, 10)},
+			[]string{"truncated bytes", strconv.FormatInt(streamutils.Stats.TruncatedBytes, 10)},
 			[]string{"saved TCP connections", strconv.FormatInt(streamutils.Stats.SavedTCPConnections, 10)},
 			[]string{"saved UDP conversations", strconv.FormatInt(streamutils.Stats.SavedUDPConnections, 10)},
 			[]string{"numSoftware", strconv.FormatInt(streamutils.Stats.NumSoftware, 10)},
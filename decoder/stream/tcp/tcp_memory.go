@@ -0,0 +1,202 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tcp
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+)
+
+// reasons for truncating the conversation data of a connection.
+const (
+	truncatedConnection = "connection"
+	truncatedBudget     = "budget"
+)
+
+// bufferedConversationBytes is the number of bytes of conversation data held by all connections.
+var bufferedConversationBytes int64
+
+var (
+	tcpBufferedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nc_tcp_buffered_bytes",
+			Help: "Number of bytes buffered for TCP reassembly (pages) and conversation data (conversations)",
+		},
+		[]string{"Type"},
+	)
+	tcpMemoryBudget = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nc_tcp_memory_budget_bytes",
+			Help: "Configured memory budget for TCP reassembly and conversation data, zero if unlimited",
+		},
+	)
+	tcpTruncatedBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nc_tcp_truncated_bytes",
+			Help: "Number of conversation bytes that were not buffered, because a memory limit has been reached",
+		},
+		[]string{"Reason"},
+	)
+	tcpTruncatedConnections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nc_tcp_truncated_connections",
+			Help: "Number of connections whose conversation data has been truncated, because a memory limit has been reached",
+		},
+		[]string{"Reason"},
+	)
+	tcpBudgetFlushes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nc_tcp_budget_flushes",
+			Help: "Number of out-of-order segments that were flushed instead of buffered, because the memory budget has been reached",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		tcpBufferedBytes,
+		tcpMemoryBudget,
+		tcpTruncatedBytes,
+		tcpTruncatedConnections,
+		tcpBudgetFlushes,
+	)
+}
+
+// the gauges for the buffered data are updated for every data fragment, so the label lookup is done only once.
+var (
+	tcpBufferedPages         = tcpBufferedBytes.WithLabelValues("pages")
+	tcpBufferedConversations = tcpBufferedBytes.WithLabelValues("conversations")
+)
+
+// NewAssembler creates an assembler for the stream pool of the connection factory,
+// which respects the configured memory limits.
+func NewAssembler() *reassembly.Assembler {
+	a := reassembly.NewAssembler(StreamFactory.StreamPool)
+
+	if max := decoderconfig.Instance.ReassemblyConnMax; max > 0 {
+		a.MaxBufferedPagesPerConnection = int(max / reassembly.PageSize)
+		if a.MaxBufferedPagesPerConnection == 0 {
+			a.MaxBufferedPagesPerConnection = 1
+		}
+	}
+
+	if decoderconfig.Instance.ReassemblyBudget > 0 {
+		a.BufferLimitReached = budgetExceeded
+	}
+
+	tcpMemoryBudget.Set(float64(decoderconfig.Instance.ReassemblyBudget))
+
+	return a
+}
+
+// bufferedBytes returns the number of bytes held for reassembly and conversation data.
+func bufferedBytes() int64 {
+	return reassembly.BufferedBytes() + atomic.LoadInt64(&bufferedConversationBytes)
+}
+
+// budgetExceeded checks if the memory budget has been used up.
+// The assembler flushes out-of-order data instead of buffering it in this case.
+func budgetExceeded() bool {
+	if bufferedBytes() < decoderconfig.Instance.ReassemblyBudget {
+		return false
+	}
+
+	tcpBudgetFlushes.Inc()
+
+	return true
+}
+
+// updateMemoryGauges sets the gauges for the buffered data.
+func updateMemoryGauges() {
+	tcpBufferedPages.Set(float64(reassembly.BufferedBytes()))
+	tcpBufferedConversations.Set(float64(atomic.LoadInt64(&bufferedConversationBytes)))
+}
+
+// reserve accounts n bytes of conversation data for the connection,
+// and returns false if the data must not be buffered, because a memory limit has been reached.
+// Once a connection has been truncated, all following data is dropped as well,
+// so decoders always see the start of the conversation without gaps.
+// The limits are checked without synchronization between connections, so the budget can be exceeded slightly.
+// CAUTION: the connection needs to be locked when calling this.
+func (t *tcpConnection) reserve(n int) bool {
+	// data delivered after the connection has been decoded is not accounted
+	if t.decoded {
+		return true
+	}
+
+	if !t.truncated {
+		reason := t.limitReached(int64(n))
+		if reason == "" {
+			t.bufferedBytes += int64(n)
+			atomic.AddInt64(&bufferedConversationBytes, int64(n))
+			updateMemoryGauges()
+
+			return true
+		}
+
+		t.truncated = true
+		t.truncateReason = reason
+
+		reassemblyLog.Info("truncating conversation data",
+			zap.String("ident", t.ident),
+			zap.String("reason", reason),
+			zap.Int64("buffered", t.bufferedBytes),
+		)
+
+		streamutils.Stats.Lock()
+		streamutils.Stats.TruncatedConns++
+		streamutils.Stats.Unlock()
+
+		tcpTruncatedConnections.WithLabelValues(reason).Inc()
+	}
+
+	streamutils.Stats.Lock()
+	streamutils.Stats.TruncatedBytes += int64(n)
+	streamutils.Stats.Unlock()
+
+	tcpTruncatedBytes.WithLabelValues(t.truncateReason).Add(float64(n))
+
+	return false
+}
+
+// limitReached returns the reason if buffering n more bytes for the connection would exceed a memory limit.
+func (t *tcpConnection) limitReached(n int64) string {
+	if max := decoderconfig.Instance.ReassemblyConnMax; max > 0 && t.bufferedBytes+n > max {
+		return truncatedConnection
+	}
+
+	if budget := decoderconfig.Instance.ReassemblyBudget; budget > 0 && bufferedBytes()+n > budget {
+		return truncatedBudget
+	}
+
+	return ""
+}
+
+// free releases the accounting for n bytes of conversation data of the connection.
+// CAUTION: the connection needs to be locked when calling this.
+func (t *tcpConnection) free(n int64) {
+	if n == 0 {
+		return
+	}
+
+	t.bufferedBytes -= n
+	atomic.AddInt64(&bufferedConversationBytes, -n)
+	updateMemoryGauges()
+}
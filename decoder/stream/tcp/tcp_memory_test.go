@@ -0,0 +1,269 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tcp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	streamutils "github.com/dreadl0ck/netcap/decoder/stream/utils"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/yara"
+)
+
+// streamingDecoder counts the fragments passed to it.
+type streamingDecoder struct {
+	consumed int
+	decoded  bool
+}
+
+func (d *streamingDecoder) Consume(core.DataFragment) { d.consumed++ }
+
+func (d *streamingDecoder) Decode() { d.decoded = true }
+
+func initTestConfig(connMax, budget int64) {
+	decoderconfig.Instance = &decoderconfig.Config{
+		ReassemblyConnMax:   connMax,
+		ReassemblyBudget:    budget,
+		BannerSize:          4,
+		HarvesterBannerSize: 4,
+		Quiet:               true,
+	}
+}
+
+// newTestConnection creates a connection from 10.0.0.1:49152 to 10.0.0.2:80 with a reader for each side.
+func newTestConnection(ident string) *tcpConnection {
+	t := &tcpConnection{
+		ident:     ident,
+		net:       gopacket.NewFlow(layers.EndpointIPv4, net.IP{10, 0, 0, 1}, net.IP{10, 0, 0, 2}),
+		transport: gopacket.NewFlow(layers.EndpointTCPPort, []byte{0xc0, 0x00}, []byte{0x00, 0x50}),
+	}
+
+	t.client = t.newTCPStreamReader(true)
+	t.server = t.newTCPStreamReader(false)
+
+	return t
+}
+
+// read passes data to the reader, the same way the reassembly does.
+func read(t *testing.T, r *tcpStreamReader, data string, dir reassembly.TCPFlowDirection) {
+	t.Helper()
+
+	r.dataChan = make(chan *core.StreamData, 1)
+	r.dataChan <- &core.StreamData{RawData: []byte(data), Dir: dir}
+
+	if _, err := r.Read(make([]byte, len(data))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func truncated() (conns, bytes int64) {
+	streamutils.Stats.Lock()
+	defer streamutils.Stats.Unlock()
+
+	return streamutils.Stats.TruncatedConns, streamutils.Stats.TruncatedBytes
+}
+
+func TestReserveConnectionLimit(t *testing.T) {
+	initTestConfig(10, 0)
+
+	var (
+		c               = newTestConnection("conn")
+		conns, numBytes = truncated()
+		metricConns     = testutil.ToFloat64(tcpTruncatedConnections.WithLabelValues(truncatedConnection))
+		metricBytes     = testutil.ToFloat64(tcpTruncatedBytes.WithLabelValues(truncatedConnection))
+		buffered        = atomic.LoadInt64(&bufferedConversationBytes)
+	)
+
+	if !c.reserve(6) || !c.reserve(4) {
+		t.Fatal("expected data up to the limit to be buffered")
+	}
+
+	if c.bufferedBytes != 10 || atomic.LoadInt64(&bufferedConversationBytes)-buffered != 10 {
+		t.Fatal("unexpected buffered bytes", c.bufferedBytes)
+	}
+
+	if c.reserve(1) {
+		t.Fatal("expected data exceeding the limit to be dropped")
+	}
+
+	if !c.truncated || c.truncateReason != truncatedConnection {
+		t.Fatal("expected the connection to be truncated", c.truncateReason)
+	}
+
+	// freeing memory does not resume buffering, decoders must not see gaps
+	c.free(6)
+
+	if c.reserve(2) {
+		t.Fatal("expected data after the truncation to be dropped")
+	}
+
+	if c.bufferedBytes != 4 {
+		t.Fatal("unexpected buffered bytes", c.bufferedBytes)
+	}
+
+	newConns, newBytes := truncated()
+	if newConns-conns != 1 || newBytes-numBytes != 3 {
+		t.Fatal("unexpected stats", newConns-conns, newBytes-numBytes)
+	}
+
+	if testutil.ToFloat64(tcpTruncatedConnections.WithLabelValues(truncatedConnection))-metricConns != 1 {
+		t.Fatal("unexpected truncated connections metric")
+	}
+
+	if testutil.ToFloat64(tcpTruncatedBytes.WithLabelValues(truncatedConnection))-metricBytes != 3 {
+		t.Fatal("unexpected truncated bytes metric")
+	}
+
+	c.free(c.bufferedBytes)
+}
+
+func TestReserveBudget(t *testing.T) {
+	initTestConfig(0, 10)
+
+	var (
+		a           = newTestConnection("a")
+		b           = newTestConnection("b")
+		metricConns = testutil.ToFloat64(tcpTruncatedConnections.WithLabelValues(truncatedBudget))
+	)
+
+	if atomic.LoadInt64(&bufferedConversationBytes) != 0 {
+		t.Fatal("expected no buffered conversation data")
+	}
+
+	if !a.reserve(8) {
+		t.Fatal("expected data within the budget to be buffered")
+	}
+
+	// the budget is shared between all connections
+	if b.reserve(4) {
+		t.Fatal("expected data exceeding the budget to be dropped")
+	}
+
+	if b.truncateReason != truncatedBudget || b.bufferedBytes != 0 {
+		t.Fatal("unexpected state", b.truncateReason, b.bufferedBytes)
+	}
+
+	if testutil.ToFloat64(tcpTruncatedConnections.WithLabelValues(truncatedBudget))-metricConns != 1 {
+		t.Fatal("unexpected truncated connections metric")
+	}
+
+	a.free(8)
+
+	if atomic.LoadInt64(&bufferedConversationBytes) != 0 {
+		t.Fatal("expected the budget to be released")
+	}
+
+	if !a.reserve(10) || b.reserve(1) {
+		t.Fatal("expected only the connection that has not been truncated to buffer data")
+	}
+
+	a.free(a.bufferedBytes)
+}
+
+func TestFreeOnRelease(t *testing.T) {
+	initTestConfig(0, 0)
+
+	var (
+		c = newTestConnection("release")
+		d = &streamingDecoder{}
+		r = c.client.(*tcpStreamReader)
+	)
+
+	c.streaming = d
+
+	// the first fragment is kept for the banner
+	read(t, r, "SSH-", reassembly.TCPDirClientToServer)
+	read(t, r, "2.0-", reassembly.TCPDirClientToServer)
+	read(t, r, "test", reassembly.TCPDirClientToServer)
+
+	if d.consumed != 3 {
+		t.Fatal("expected all fragments to be consumed, got", d.consumed)
+	}
+
+	if len(r.data) != 1 || c.bufferedBytes != 4 || atomic.LoadInt64(&bufferedConversationBytes) != 4 {
+		t.Fatal("expected the consumed fragments to be released", len(r.data), c.bufferedBytes)
+	}
+
+	c.free(c.bufferedBytes)
+}
+
+func TestReleaseWithYARA(t *testing.T) {
+	initTestConfig(0, 0)
+
+	yara.Instance = &yara.Scanner{}
+	yara.MaxConversationScanSize = 8
+
+	defer func() {
+		yara.Instance = nil
+		yara.MaxConversationScanSize = 1024 * 1024
+	}()
+
+	var (
+		c = newTestConnection("yara")
+		r = c.client.(*tcpStreamReader)
+	)
+
+	c.streaming = &streamingDecoder{}
+
+	// the data scanned with the YARA rules is kept
+	read(t, r, "SSH-", reassembly.TCPDirClientToServer)
+	read(t, r, "2.0-", reassembly.TCPDirClientToServer)
+	read(t, r, "test", reassembly.TCPDirClientToServer)
+	read(t, r, "more", reassembly.TCPDirClientToServer)
+
+	if len(r.data) != 2 || c.bufferedBytes != 8 {
+		t.Fatal("expected only the scanned fragments to be kept", len(r.data), c.bufferedBytes)
+	}
+
+	c.free(c.bufferedBytes)
+}
+
+func TestFreeOnDecode(t *testing.T) {
+	initTestConfig(0, 0)
+
+	var (
+		c = newTestConnection("decode")
+		d = &streamingDecoder{}
+	)
+
+	read(t, c.client.(*tcpStreamReader), "USER test\r\n", reassembly.TCPDirClientToServer)
+
+	if c.bufferedBytes != 11 || atomic.LoadInt64(&bufferedConversationBytes) != 11 {
+		t.Fatal("unexpected buffered bytes", c.bufferedBytes)
+	}
+
+	c.streaming = d
+	c.decode()
+
+	if !d.decoded || !c.decoded {
+		t.Fatal("expected the connection to be decoded")
+	}
+
+	if c.bufferedBytes != 0 || atomic.LoadInt64(&bufferedConversationBytes) != 0 {
+		t.Fatal("expected the conversation data to be released", c.bufferedBytes)
+	}
+
+	// data delivered after decoding is not accounted
+	if !c.reserve(100) || c.bufferedBytes != 0 {
+		t.Fatal("expected data after decoding not to be accounted")
+	}
+}
@@ -148,7 +148,7 @@ func (tsp *tcpStreamProcessor) streamWorker(wg *sync.WaitGroup) chan streamReade
 			if s.IsClient() {
 				// save the entire conversation.
 				// we only need to do this once, when the client part of the connection is closed
-				err := streamutils.SaveConversation("TCP", s.Merged(), s.Ident(), s.FirstPacket(), s.Transport())
+				err := streamutils.SaveConversation("TCP", s.Merged(), s.Ident(), s.FirstPacket(), s.Network(), s.Transport())
 				if err != nil {
 					fmt.Println("failed to save connection", err)
 				}
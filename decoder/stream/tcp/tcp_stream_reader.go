@@ -74,8 +74,11 @@ func (t *tcpStreamReader) Read(p []byte) (int, error) {
 	l := copy(p, data.RawData)
 
 	t.parent.Lock()
-	t.data = append(t.data, data)
+	if t.parent.reserve(len(data.RawData)) {
+		t.data = append(t.data, data)
+	}
 	t.numBytes += l
+	t.parent.consume(t, data)
 	t.parent.Unlock()
 
 	return l, nil
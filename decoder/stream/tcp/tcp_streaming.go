@@ -0,0 +1,118 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tcp
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/yara"
+)
+
+// consume is invoked for every data fragment that has been added to one side of the connection.
+// Once both sides have sent data, the decoder for the connection is selected,
+// if it implements the core.StreamingDecoderInterface, the fragments are passed to it while the connection is still open.
+// CAUTION: the connection needs to be locked when calling this.
+func (t *tcpConnection) consume(s *tcpStreamReader, data *core.StreamData) {
+	if t.streaming != nil {
+		t.streaming.Consume(data)
+		s.release()
+
+		return
+	}
+
+	if t.selected || len(t.client.DataSlice()) == 0 || len(t.server.DataSlice()) == 0 {
+		return
+	}
+
+	// the selection is made only once, decoders that need the entire conversation are selected again when it is complete
+	t.selected = true
+
+	sd, ok := t.selectDecoder(t.conversationInfo(nil)).(core.StreamingDecoderInterface)
+	if !ok {
+		return
+	}
+
+	reassemblyLog.Debug("decoding connection incrementally",
+		zap.String("ident", t.ident),
+	)
+
+	t.streaming = sd
+
+	// pass the data collected so far
+	fragments := append(append(core.DataFragments{}, t.client.DataSlice()...), t.server.DataSlice()...)
+	sort.Sort(fragments)
+
+	for _, f := range fragments {
+		sd.Consume(f)
+	}
+
+	t.client.(*tcpStreamReader).release()
+	t.server.(*tcpStreamReader).release()
+}
+
+// retainConversation checks if the entire conversation needs to be kept in memory,
+// although it is decoded incrementally.
+func retainConversation() bool {
+	return decoderconfig.Instance.SaveConns
+}
+
+// retainedBytes returns the number of bytes that are kept at the start of each direction.
+// The start of the stream is used for the service banner and the credential harvesters,
+// and it is scanned with the YARA rules, if they have been loaded.
+func retainedBytes() int {
+	keep := decoderconfig.Instance.BannerSize
+	if decoderconfig.Instance.HarvesterBannerSize > keep {
+		keep = decoderconfig.Instance.HarvesterBannerSize
+	}
+
+	if yara.Instance != nil && yara.MaxConversationScanSize > keep {
+		keep = yara.MaxConversationScanSize
+	}
+
+	return keep
+}
+
+// release drops the data fragments that have been passed to a streaming decoder,
+// except for the start of the stream.
+func (t *tcpStreamReader) release() {
+	if retainConversation() {
+		return
+	}
+
+	keep := retainedBytes()
+
+	var size int
+
+	for i, d := range t.data {
+		if size >= keep {
+			var dropped int64
+			for _, f := range t.data[i:] {
+				dropped += int64(len(f.Raw()))
+			}
+
+			t.parent.free(dropped)
+
+			// copy the retained fragments, so the dropped ones can be garbage collected
+			t.data = append(core.DataFragments(nil), t.data[:i]...)
+
+			return
+		}
+
+		size += len(d.Raw())
+	}
+}
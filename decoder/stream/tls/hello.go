@@ -0,0 +1,530 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dreadl0ck/ja3"
+	"github.com/dreadl0ck/tlsx"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// TLS extension numbers.
+const (
+	extensionServerName          uint16 = 0
+	extensionSupportedGroups     uint16 = 10
+	extensionPointFormats        uint16 = 11
+	extensionSignatureAlgorithms uint16 = 13
+	extensionALPN                uint16 = 16
+	extensionSessionTicket       uint16 = 35
+	extensionPreSharedKey        uint16 = 41
+	extensionSupportedVersions   uint16 = 43
+)
+
+// protocol versions.
+const (
+	versionSSL2  uint16 = 0x0200
+	versionSSL3  uint16 = 0x0300
+	versionTLS10 uint16 = 0x0301
+	versionTLS11 uint16 = 0x0302
+	versionTLS12 uint16 = 0x0303
+	versionTLS13 uint16 = 0x0304
+)
+
+var versionNames = map[uint16]string{
+	versionSSL2:  "SSL 2.0",
+	versionSSL3:  "SSL 3.0",
+	versionTLS10: "TLS 1.0",
+	versionTLS11: "TLS 1.1",
+	versionTLS12: "TLS 1.2",
+	versionTLS13: "TLS 1.3",
+}
+
+// version codes used in the JA4 fingerprints.
+var ja4Versions = map[uint16]string{
+	versionSSL2:  "s2",
+	versionSSL3:  "s3",
+	versionTLS10: "10",
+	versionTLS11: "11",
+	versionTLS12: "12",
+	versionTLS13: "13",
+}
+
+// transport protocols used in the JA4 fingerprints.
+const (
+	ja4TCP  = 't'
+	ja4QUIC = 'q'
+)
+
+// the random value of a ServerHello that is a HelloRetryRequest, see RFC 8446, Section 4.1.3.
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// versionName returns a human readable name for the protocol version.
+func versionName(v uint16) string {
+	if n, ok := versionNames[v]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// isGREASE checks if the value is reserved for GREASE, see RFC 8701.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+type clientHello struct {
+	version           uint16
+	sessionID         []byte
+	cipherSuites      []uint16
+	extensions        []uint16
+	sni               string
+	alpns             []string
+	supportedVersions []uint16
+	groups            []uint16
+	points            []uint8
+	signatureAlgs     []uint16
+	sessionTicket     bool
+	preSharedKey      bool
+}
+
+// parseClientHello parses the body of a ClientHello handshake message.
+func parseClientHello(data []byte) (*clientHello, bool) {
+	var (
+		ch      = &clientHello{}
+		s       = cryptobyte.String(data)
+		ciphers cryptobyte.String
+		comp    cryptobyte.String
+	)
+
+	if !s.ReadUint16(&ch.version) || !s.Skip(32) ||
+		!s.ReadUint8LengthPrefixed((*cryptobyte.String)(&ch.sessionID)) ||
+		!s.ReadUint16LengthPrefixed(&ciphers) ||
+		!s.ReadUint8LengthPrefixed(&comp) {
+		return nil, false
+	}
+
+	for !ciphers.Empty() {
+		var c uint16
+		if !ciphers.ReadUint16(&c) {
+			return nil, false
+		}
+
+		ch.cipherSuites = append(ch.cipherSuites, c)
+	}
+
+	// no extensions
+	if s.Empty() {
+		return ch, true
+	}
+
+	var exts cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&exts) {
+		return nil, false
+	}
+
+	for !exts.Empty() {
+		var (
+			typ uint16
+			ext cryptobyte.String
+		)
+
+		if !exts.ReadUint16(&typ) || !exts.ReadUint16LengthPrefixed(&ext) {
+			return nil, false
+		}
+
+		ch.extensions = append(ch.extensions, typ)
+
+		switch typ {
+		case extensionServerName:
+			var list cryptobyte.String
+			if !ext.ReadUint16LengthPrefixed(&list) {
+				break
+			}
+
+			for !list.Empty() {
+				var (
+					nameType uint8
+					name     cryptobyte.String
+				)
+
+				if !list.ReadUint8(&nameType) || !list.ReadUint16LengthPrefixed(&name) {
+					break
+				}
+
+				// host_name
+				if nameType == 0 {
+					ch.sni = string(name)
+				}
+			}
+		case extensionALPN:
+			ch.alpns = readProtocolList(ext)
+		case extensionSupportedVersions:
+			var list cryptobyte.String
+			if ext.ReadUint8LengthPrefixed(&list) {
+				ch.supportedVersions = readUint16List(list)
+			}
+		case extensionSupportedGroups:
+			var list cryptobyte.String
+			if ext.ReadUint16LengthPrefixed(&list) {
+				ch.groups = readUint16List(list)
+			}
+		case extensionPointFormats:
+			var list cryptobyte.String
+			if ext.ReadUint8LengthPrefixed(&list) {
+				ch.points = list
+			}
+		case extensionSignatureAlgorithms:
+			var list cryptobyte.String
+			if ext.ReadUint16LengthPrefixed(&list) {
+				ch.signatureAlgs = readUint16List(list)
+			}
+		case extensionSessionTicket:
+			ch.sessionTicket = !ext.Empty()
+		case extensionPreSharedKey:
+			ch.preSharedKey = true
+		}
+	}
+
+	return ch, true
+}
+
+// maxVersion returns the highest version offered by the client.
+func (ch *clientHello) maxVersion() uint16 {
+	v := ch.version
+
+	for _, sv := range ch.supportedVersions {
+		if !isGREASE(sv) && sv > v {
+			v = sv
+		}
+	}
+
+	return v
+}
+
+// ja3 returns the JA3 fingerprint for the ClientHello.
+func (ch *clientHello) ja3() string {
+	hello := &tlsx.ClientHelloBasic{
+		HandshakeVersion: tlsx.Version(ch.version),
+		AllExtensions:    ch.extensions,
+		SupportedGroups:  ch.groups,
+		SupportedPoints:  ch.points,
+	}
+
+	for _, c := range ch.cipherSuites {
+		hello.CipherSuites = append(hello.CipherSuites, tlsx.CipherSuite(c))
+	}
+
+	return ja3.DigestHex(hello)
+}
+
+// ja4 returns the JA4 fingerprint for the ClientHello sent over the given transport protocol.
+// The fingerprint consists of three parts separated by an underscore:
+// a readable prefix with the transport protocol, version, SNI presence, the number of cipher suites and extensions and the first ALPN value,
+// followed by truncated SHA256 hashes of the sorted cipher suites and of the sorted extensions and the signature algorithms.
+func (ch *clientHello) ja4(transport byte) string {
+	var (
+		ciphers    = hexValues(ch.cipherSuites)
+		extensions []string
+		numExt     int
+		sni        = "i"
+	)
+
+	for _, e := range ch.extensions {
+		if isGREASE(e) {
+			continue
+		}
+
+		numExt++
+
+		// SNI and ALPN are represented in the prefix already
+		if e != extensionServerName && e != extensionALPN {
+			extensions = append(extensions, fmt.Sprintf("%04x", e))
+		}
+	}
+
+	if ch.sni != "" {
+		sni = "d"
+	}
+
+	sort.Strings(ciphers)
+	sort.Strings(extensions)
+
+	ext := strings.Join(extensions, ",")
+	if len(ch.signatureAlgs) > 0 {
+		ext += "_" + strings.Join(hexValues(ch.signatureAlgs), ",")
+	}
+
+	var alpn string
+	if len(ch.alpns) > 0 {
+		alpn = ch.alpns[0]
+	}
+
+	return fmt.Sprintf("%c%s%s%02d%02d%s_%s_%s",
+		transport,
+		ja4Version(ch.maxVersion()),
+		sni,
+		limit(len(ciphers)),
+		limit(numExt),
+		ja4ALPN(alpn),
+		truncatedHash(strings.Join(ciphers, ",")),
+		truncatedHash(ext),
+	)
+}
+
+// ClientHelloInfo contains the properties and fingerprints of a ClientHello.
+type ClientHelloInfo struct {
+
+	// Version is the highest protocol version offered by the client
+	Version string
+
+	// SNI is the requested server name
+	SNI string
+
+	// ALPNs are the offered application protocols
+	ALPNs []string
+
+	// JA3 and JA4 fingerprints
+	JA3 string
+	JA4 string
+}
+
+// ParseClientHello parses a ClientHello handshake message, including the four byte handshake header.
+// The quic flag marks messages that were carried in QUIC CRYPTO frames, for the JA4 fingerprint.
+func ParseClientHello(msg []byte, quic bool) (*ClientHelloInfo, bool) {
+	if len(msg) < 4 || msg[0] != handshakeTypeClientHello {
+		return nil, false
+	}
+
+	length := int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3])
+	if len(msg) < 4+length {
+		return nil, false
+	}
+
+	ch, ok := parseClientHello(msg[4 : 4+length])
+	if !ok {
+		return nil, false
+	}
+
+	transport := byte(ja4TCP)
+	if quic {
+		transport = ja4QUIC
+	}
+
+	return &ClientHelloInfo{
+		Version: versionName(ch.maxVersion()),
+		SNI:     ch.sni,
+		ALPNs:   ch.alpns,
+		JA3:     ch.ja3(),
+		JA4:     ch.ja4(transport),
+	}, true
+}
+
+type serverHello struct {
+	version          uint16
+	random           []byte
+	sessionID        []byte
+	cipherSuite      uint16
+	extensions       []uint16
+	supportedVersion uint16
+	alpn             string
+	preSharedKey     bool
+}
+
+// parseServerHello parses the body of a ServerHello handshake message.
+func parseServerHello(data []byte) (*serverHello, bool) {
+	var (
+		sh          = &serverHello{}
+		s           = cryptobyte.String(data)
+		compression uint8
+	)
+
+	if !s.ReadUint16(&sh.version) ||
+		!s.ReadBytes(&sh.random, 32) ||
+		!s.ReadUint8LengthPrefixed((*cryptobyte.String)(&sh.sessionID)) ||
+		!s.ReadUint16(&sh.cipherSuite) ||
+		!s.ReadUint8(&compression) {
+		return nil, false
+	}
+
+	// no extensions
+	if s.Empty() {
+		return sh, true
+	}
+
+	var exts cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&exts) {
+		return nil, false
+	}
+
+	for !exts.Empty() {
+		var (
+			typ uint16
+			ext cryptobyte.String
+		)
+
+		if !exts.ReadUint16(&typ) || !exts.ReadUint16LengthPrefixed(&ext) {
+			return nil, false
+		}
+
+		sh.extensions = append(sh.extensions, typ)
+
+		switch typ {
+		case extensionSupportedVersions:
+			ext.ReadUint16(&sh.supportedVersion)
+		case extensionALPN:
+			if protos := readProtocolList(ext); len(protos) > 0 {
+				sh.alpn = protos[0]
+			}
+		case extensionPreSharedKey:
+			sh.preSharedKey = true
+		}
+	}
+
+	return sh, true
+}
+
+// isHelloRetryRequest checks if the message asks the client to send a new ClientHello.
+func (sh *serverHello) isHelloRetryRequest() bool {
+	return bytes.Equal(sh.random, helloRetryRequestRandom)
+}
+
+// negotiatedVersion returns the version that has been selected by the server.
+func (sh *serverHello) negotiatedVersion() uint16 {
+	if sh.supportedVersion != 0 {
+		return sh.supportedVersion
+	}
+
+	return sh.version
+}
+
+// ja3s returns the JA3S fingerprint for the ServerHello.
+func (sh *serverHello) ja3s() string {
+	return ja3.DigestHexJa3s(&tlsx.ServerHelloBasic{
+		Vers:        sh.version,
+		CipherSuite: sh.cipherSuite,
+		Extensions:  sh.extensions,
+	})
+}
+
+// ja4s returns the JA4S fingerprint for the ServerHello.
+// Other than for the client, the extensions are hashed in the order they appear in.
+func (sh *serverHello) ja4s() string {
+	return fmt.Sprintf("t%s%02d%s_%04x_%s",
+		ja4Version(sh.negotiatedVersion()),
+		limit(len(sh.extensions)),
+		ja4ALPN(sh.alpn),
+		sh.cipherSuite,
+		truncatedHash(strings.Join(hexValues(sh.extensions), ",")),
+	)
+}
+
+// readProtocolList reads the protocol names from an ALPN extension.
+func readProtocolList(ext cryptobyte.String) []string {
+	var (
+		list   cryptobyte.String
+		protos []string
+	)
+
+	if !ext.ReadUint16LengthPrefixed(&list) {
+		return nil
+	}
+
+	for !list.Empty() {
+		var proto cryptobyte.String
+		if !list.ReadUint8LengthPrefixed(&proto) {
+			break
+		}
+
+		protos = append(protos, string(proto))
+	}
+
+	return protos
+}
+
+func readUint16List(list cryptobyte.String) []uint16 {
+	var values []uint16
+
+	for !list.Empty() {
+		var v uint16
+		if !list.ReadUint16(&v) {
+			break
+		}
+
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// hexValues formats the values as four digit hex strings, GREASE values are skipped.
+func hexValues(values []uint16) []string {
+	var out []string
+
+	for _, v := range values {
+		if !isGREASE(v) {
+			out = append(out, fmt.Sprintf("%04x", v))
+		}
+	}
+
+	return out
+}
+
+func ja4Version(v uint16) string {
+	if s, ok := ja4Versions[v]; ok {
+		return s
+	}
+
+	return "00"
+}
+
+// ja4ALPN returns the first and last character of the ALPN value, or 00 if there is none.
+func ja4ALPN(alpn string) string {
+	if alpn == "" {
+		return "00"
+	}
+
+	return string(alpn[0]) + string(alpn[len(alpn)-1])
+}
+
+// limit caps the number of values to the two digits available in the fingerprint.
+func limit(n int) int {
+	if n > 99 {
+		return 99
+	}
+
+	return n
+}
+
+// truncatedHash returns the first 12 characters of the hex encoded SHA256 hash.
+func truncatedHash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+
+	sum := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(sum[:])[:12]
+}
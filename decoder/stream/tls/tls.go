@@ -0,0 +1,64 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tls
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var tlsLog = zap.NewNop()
+
+// Decoder for protocol analysis and writing audit records to disk.
+var Decoder = &decoder.StreamDecoder{
+	Type:        types.Type_NC_TLS,
+	Name:        "TLS",
+	Description: "Transport Layer Security sessions, including the handshake, certificate chain and alerts",
+	PostInit: func(sd *decoder.StreamDecoder) (err error) {
+		tlsLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"tls",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	CanDecode: func(client, server []byte) bool {
+		// the client must start with a handshake record containing a ClientHello
+		if len(client) < recordHeaderLen+1 || client[0] != recordTypeHandshake || client[1] != 3 || client[2] > 4 || client[5] != handshakeTypeClientHello {
+			return false
+		}
+
+		// the server responds with a handshake or an alert
+		if len(server) == 0 {
+			return true
+		}
+
+		return len(server) >= recordHeaderLen && (server[0] == recordTypeHandshake || server[0] == recordTypeAlert) && server[1] == 3
+	},
+	DeInit: func(sd *decoder.StreamDecoder) error {
+		return tlsLog.Sync()
+	},
+	Factory: &tlsReader{},
+	Typ:     core.TCP,
+}
+
+// ServerCertificateHandler is called with the leaf certificate presented by a server during a handshake,
+// e.g. to add it to the profile of the server address. It must be safe for concurrent use.
+var ServerCertificateHandler = func(ip string, cert *types.TLSCertificate) {}
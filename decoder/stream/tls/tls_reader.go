@@ -0,0 +1,408 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tls
+
+import (
+	"crypto/sha256"
+	gotls "crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/certificate"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// record content types.
+const (
+	recordTypeChangeCipherSpec uint8 = 20
+	recordTypeAlert            uint8 = 21
+	recordTypeHandshake        uint8 = 22
+	recordTypeApplicationData  uint8 = 23
+	recordTypeHeartbeat        uint8 = 24
+
+	recordHeaderLen = 5
+
+	// maximum size of a record, including the expansion for encryption.
+	maxRecordLen = 16384 + 2048
+)
+
+// handshake message types.
+const (
+	handshakeTypeClientHello       uint8 = 1
+	handshakeTypeServerHello       uint8 = 2
+	handshakeTypeNewSessionTicket  uint8 = 4
+	handshakeTypeCertificate       uint8 = 11
+	handshakeTypeServerKeyExchange uint8 = 12
+	handshakeTypeServerHelloDone   uint8 = 14
+
+	handshakeHeaderLen = 4
+
+	// limits the size of buffered handshake messages,
+	// certificate chains are usually well below.
+	maxHandshakeLen = 256 * 1024
+)
+
+// range of timestamps that can be represented in nanoseconds.
+var (
+	minTime = time.Unix(0, math.MinInt64)
+	maxTime = time.Unix(0, math.MaxInt64)
+)
+
+// tlsStream is the data sent into one direction of the connection.
+type tlsStream struct {
+	*core.StreamDirection
+
+	// handshake data that has not been processed yet
+	handshake []byte
+
+	// set after a ChangeCipherSpec message has been received
+	encrypted bool
+
+	fromServer bool
+}
+
+type tlsReader struct {
+	conversation *core.ConversationInfo
+
+	tls *types.TLS
+
+	clientHello *clientHello
+	serverHello *serverHello
+
+	// set when the server sent a message that is only part of a full handshake
+	fullHandshake bool
+}
+
+// New will instantiate a new TLS reader.
+func (h *tlsReader) New(conv *core.ConversationInfo) core.StreamDecoderInterface {
+	return &tlsReader{
+		conversation: conv,
+	}
+}
+
+// Decode parses the records sent into both directions.
+// Once a side starts encrypting, only the record types are evaluated.
+func (h *tlsReader) Decode() {
+	// prevent nil pointer access if decoder is not initialized
+	if Decoder.Writer == nil {
+		return
+	}
+
+	h.tls = &types.TLS{
+		Timestamp:   h.conversation.FirstClientPacket.UnixNano(),
+		ClientIP:    h.conversation.ClientIP,
+		ServerIP:    h.conversation.ServerIP,
+		ClientPort:  h.conversation.ClientPort,
+		ServerPort:  h.conversation.ServerPort,
+		CommunityID: h.conversation.CommunityID,
+	}
+
+	c, s := core.SplitDirections(h.conversation.Data)
+
+	var (
+		client = &tlsStream{StreamDirection: c}
+		server = &tlsStream{StreamDirection: s, fromServer: true}
+	)
+
+	h.readRecords(client)
+	h.readRecords(server)
+
+	if h.clientHello == nil {
+		return
+	}
+
+	h.tls.SNI = h.clientHello.sni
+	h.tls.ALPNs = h.clientHello.alpns
+	h.tls.SessionID = hex.EncodeToString(h.clientHello.sessionID)
+	h.tls.SessionTicket = h.clientHello.sessionTicket || h.clientHello.preSharedKey
+	h.tls.JA3 = h.clientHello.ja3()
+	h.tls.JA4 = h.clientHello.ja4(ja4TCP)
+
+	if sh := h.serverHello; sh != nil {
+		h.tls.Version = versionName(sh.negotiatedVersion())
+		h.tls.CipherSuite = gotls.CipherSuiteName(sh.cipherSuite)
+		h.tls.SelectedALPN = sh.alpn
+		h.tls.JA3S = sh.ja3s()
+		h.tls.JA4S = sh.ja4s()
+
+		if sh.negotiatedVersion() == versionTLS13 {
+			// resumption is signaled by accepting a pre shared key
+			h.tls.Resumed = sh.preSharedKey
+		} else {
+			// an abbreviated handshake continues with ChangeCipherSpec directly after the ServerHello
+			h.tls.Resumed = server.encrypted && !h.fullHandshake
+		}
+	}
+
+	h.writeCertificates()
+
+	// export metrics if configured
+	if decoderconfig.Instance.ExportMetrics {
+		h.tls.Inc()
+	}
+
+	// write record to disk
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(h.tls)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
+
+// readRecords processes the records sent into one direction.
+func (h *tlsReader) readRecords(s *tlsStream) {
+	data := s.Bytes()
+
+	for offset := 0; offset+recordHeaderLen <= len(data); {
+		var (
+			typ    = data[offset]
+			length = int(binary.BigEndian.Uint16(data[offset+3:]))
+			start  = offset + recordHeaderLen
+		)
+
+		// stop at garbage or a truncated record
+		if typ < recordTypeChangeCipherSpec || typ > recordTypeHeartbeat || data[offset+1] != 3 || length > maxRecordLen || start+length > len(data) {
+			tlsLog.Debug("stopped reading records",
+				zap.String("ident", h.conversation.Ident),
+				zap.Bool("server", s.fromServer),
+				zap.Int("offset", offset),
+				zap.Uint8("type", typ),
+			)
+
+			return
+		}
+
+		payload := data[start : start+length]
+
+		switch typ {
+		case recordTypeChangeCipherSpec:
+			s.encrypted = true
+		case recordTypeAlert:
+			h.handleAlert(s, payload, s.TimeAt(offset))
+		case recordTypeHandshake:
+			if !s.encrypted {
+				h.handleHandshake(s, payload)
+			}
+		case recordTypeApplicationData:
+			// with TLS 1.3 the encrypted handshake is sent as application data as well,
+			// once the client sends application data its handshake has been completed
+			if !s.fromServer {
+				h.tls.HandshakeComplete = true
+			}
+		}
+
+		offset = start + length
+	}
+}
+
+// handleAlert processes an alert record.
+func (h *tlsReader) handleAlert(s *tlsStream, payload []byte, ts time.Time) {
+	a := &types.TLSAlert{
+		Timestamp:  ts.UnixNano(),
+		FromServer: s.fromServer,
+		Encrypted:  s.encrypted,
+	}
+
+	if !s.encrypted && len(payload) >= 2 {
+		a.Level = int32(payload[0])
+		a.Description = int32(payload[1])
+	}
+
+	h.tls.Alerts = append(h.tls.Alerts, a)
+}
+
+// handleHandshake collects the handshake messages, which can be fragmented over multiple records.
+func (h *tlsReader) handleHandshake(s *tlsStream, payload []byte) {
+	s.handshake = append(s.handshake, payload...)
+
+	for len(s.handshake) >= handshakeHeaderLen {
+		var (
+			typ    = s.handshake[0]
+			length = int(s.handshake[1])<<16 | int(s.handshake[2])<<8 | int(s.handshake[3])
+		)
+
+		if length > maxHandshakeLen {
+			s.handshake = nil
+
+			return
+		}
+
+		// wait for more data
+		if len(s.handshake) < handshakeHeaderLen+length {
+			return
+		}
+
+		h.handleHandshakeMessage(s, typ, s.handshake[handshakeHeaderLen:handshakeHeaderLen+length])
+		s.handshake = s.handshake[handshakeHeaderLen+length:]
+	}
+}
+
+func (h *tlsReader) handleHandshakeMessage(s *tlsStream, typ uint8, msg []byte) {
+	switch typ {
+	case handshakeTypeClientHello:
+		// keep the first ClientHello, a second one is sent after a HelloRetryRequest
+		if s.fromServer || h.clientHello != nil {
+			return
+		}
+
+		if ch, ok := parseClientHello(msg); ok {
+			h.clientHello = ch
+		}
+	case handshakeTypeServerHello:
+		if !s.fromServer {
+			return
+		}
+
+		sh, ok := parseServerHello(msg)
+		if !ok || (sh.isHelloRetryRequest() && h.serverHello != nil) {
+			return
+		}
+
+		h.serverHello = sh
+
+		// with TLS 1.3 everything after the ServerHello is encrypted
+		if sh.negotiatedVersion() == versionTLS13 && !sh.isHelloRetryRequest() {
+			s.encrypted = true
+		}
+	case handshakeTypeCertificate:
+		if !s.fromServer {
+			return
+		}
+
+		h.fullHandshake = true
+		h.handleCertificates(msg)
+	case handshakeTypeServerKeyExchange, handshakeTypeServerHelloDone:
+		h.fullHandshake = true
+	case handshakeTypeNewSessionTicket:
+		tlsLog.Debug("new session ticket", zap.String("ident", h.conversation.Ident))
+	}
+}
+
+// handleCertificates parses the certificate chain sent by the server.
+func (h *tlsReader) handleCertificates(msg []byte) {
+	if len(msg) < 3 {
+		return
+	}
+
+	var (
+		length = int(msg[0])<<16 | int(msg[1])<<8 | int(msg[2])
+		list   = msg[3:]
+	)
+
+	if length < len(list) {
+		list = list[:length]
+	}
+
+	for len(list) >= 3 {
+		n := int(list[0])<<16 | int(list[1])<<8 | int(list[2])
+		if 3+n > len(list) {
+			return
+		}
+
+		cert := newCertificate(list[3 : 3+n])
+		if len(h.tls.Certificates) == 0 {
+			// the first certificate in the chain belongs to the server
+			ServerCertificateHandler(h.conversation.ServerIP, cert)
+		}
+
+		h.tls.Certificates = append(h.tls.Certificates, cert)
+		list = list[3+n:]
+	}
+}
+
+// writeCertificates writes an audit record for each certificate in the chain presented by the server.
+func (h *tlsReader) writeCertificates() {
+	for i, c := range h.tls.Certificates {
+		certificate.WriteCertificate(&types.Certificate{
+			Timestamp:          h.tls.Timestamp,
+			ClientIP:           h.tls.ClientIP,
+			ServerIP:           h.tls.ServerIP,
+			ClientPort:         h.tls.ClientPort,
+			ServerPort:         h.tls.ServerPort,
+			SNI:                h.tls.SNI,
+			Version:            h.tls.Version,
+			ChainIndex:         int32(i),
+			Fingerprint:        c.Fingerprint,
+			Subject:            c.Subject,
+			Issuer:             c.Issuer,
+			SerialNumber:       c.SerialNumber,
+			NotBefore:          c.NotBefore,
+			NotAfter:           c.NotAfter,
+			DNSNames:           c.DNSNames,
+			IPAddresses:        c.IPAddresses,
+			SignatureAlgorithm: c.SignatureAlgorithm,
+			PublicKeyAlgorithm: c.PublicKeyAlgorithm,
+			IsCA:               c.IsCA,
+			CommunityID:        h.tls.CommunityID,
+		})
+	}
+}
+
+// newCertificate converts the DER encoded certificate.
+// If the certificate cannot be parsed, only the fingerprint is set.
+func newCertificate(der []byte) *types.TLSCertificate {
+	sum := sha256.Sum256(der)
+
+	c := &types.TLSCertificate{
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tlsLog.Debug("failed to parse certificate", zap.Error(err), zap.String("fingerprint", c.Fingerprint))
+
+		return c
+	}
+
+	c.Subject = cert.Subject.String()
+	c.Issuer = cert.Issuer.String()
+	c.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+	c.NotBefore = unixNano(cert.NotBefore)
+	c.NotAfter = unixNano(cert.NotAfter)
+	c.DNSNames = cert.DNSNames
+	c.SignatureAlgorithm = cert.SignatureAlgorithm.String()
+	c.PublicKeyAlgorithm = cert.PublicKeyAlgorithm.String()
+	c.IsCA = cert.IsCA
+
+	for _, ip := range cert.IPAddresses {
+		c.IPAddresses = append(c.IPAddresses, ip.String())
+	}
+
+	return c
+}
+
+// unixNano returns the timestamp in nanoseconds,
+// dates that cannot be represented, such as the 9999-12-31 used for certificates without a well defined expiry, are capped.
+func unixNano(t time.Time) int64 {
+	if t.After(maxTime) {
+		return math.MaxInt64
+	}
+
+	if t.Before(minTime) {
+		return math.MinInt64
+	}
+
+	return t.UnixNano()
+}
@@ -0,0 +1,316 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/certificate"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/io/iotest"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// now returns a time within the validity period of the test certificate,
+// sessions for expired certificates are not resumed.
+func now() time.Time {
+	return ts.Add(time.Hour)
+}
+
+// recorder collects the data written by both sides of a connection as stream fragments.
+type recorder struct {
+	sync.Mutex
+	data core.DataFragments
+}
+
+type recordingConn struct {
+	net.Conn
+	r   *recorder
+	dir reassembly.TCPFlowDirection
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.r.Lock()
+	c.r.data = append(c.r.data, &core.StreamData{
+		RawData: append([]byte(nil), b...),
+		Dir:     c.dir,
+		CaptureInformation: gopacket.CaptureInfo{
+			Timestamp: ts.Add(time.Duration(len(c.r.data)) * time.Millisecond),
+		},
+	})
+	c.r.Unlock()
+
+	return c.Conn.Write(b)
+}
+
+func serverCertificate(t *testing.T) gotls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "netcap.test", Organization: []string{"Netcap"}},
+		DNSNames:     []string{"netcap.test", "www.netcap.test"},
+		IPAddresses:  []net.IP{net.ParseIP("192.168.1.10")},
+		NotBefore:    ts,
+		NotAfter:     ts.Add(365 * 24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gotls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// handshake performs a TLS handshake and returns the recorded conversation.
+func handshake(t *testing.T, client *gotls.Config, server *gotls.Config) *core.ConversationInfo {
+	t.Helper()
+
+	var (
+		r      = &recorder{}
+		c, s   = net.Pipe()
+		cc     = gotls.Client(&recordingConn{Conn: c, r: r, dir: reassembly.TCPDirClientToServer}, client)
+		sc     = gotls.Server(&recordingConn{Conn: s, r: r, dir: reassembly.TCPDirServerToClient}, server)
+		errs   = make(chan error, 1)
+		buffer = make([]byte, 5)
+	)
+
+	go func() {
+		if _, err := sc.Read(buffer); err != nil {
+			errs <- err
+
+			return
+		}
+
+		errs <- nil
+	}()
+
+	if _, err := cc.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// close the underlying connections, no close notify alerts are recorded
+	_ = c.Close()
+	_ = s.Close()
+
+	conv := streamtest.Conversation(ts, streamtest.Endpoints{
+		ClientIP:   "192.168.1.2",
+		ServerIP:   "192.168.1.10",
+		ClientPort: 49999,
+		ServerPort: 443,
+	})
+	conv.Data = r.data
+
+	return conv
+}
+
+func decode(t *testing.T, w *iotest.RecordWriter, conv *core.ConversationInfo) *types.TLS {
+	t.Helper()
+
+	if !Decoder.CanDecode(conv.Data[0].Raw(), conv.Data[1].Raw()) {
+		t.Fatal("expected decoder to match")
+	}
+
+	(&tlsReader{}).New(conv).Decode()
+
+	if len(w.Records) == 0 {
+		t.Fatal("expected a record")
+	}
+
+	return w.Records[len(w.Records)-1].(*types.TLS)
+}
+
+func TestTLS12Handshake(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	w := writers[0]
+	defer cleanup()
+
+	var (
+		cert  = serverCertificate(t)
+		cache = gotls.NewLRUClientSessionCache(1)
+		cConf = &gotls.Config{
+			ServerName:         "netcap.test",
+			NextProtos:         []string{"h2", "http/1.1"},
+			InsecureSkipVerify: true,
+			MaxVersion:         gotls.VersionTLS12,
+			ClientSessionCache: cache,
+			Time:               now,
+		}
+		sConf = &gotls.Config{
+			Certificates: []gotls.Certificate{cert},
+			NextProtos:   []string{"http/1.1"},
+			Time:         now,
+		}
+		// leaf certificates passed to the handler, by server address
+		serverCerts = make(map[string]*types.TLSCertificate)
+	)
+
+	ServerCertificateHandler = func(ip string, cert *types.TLSCertificate) {
+		serverCerts[ip] = cert
+	}
+
+	defer func() {
+		ServerCertificateHandler = func(ip string, cert *types.TLSCertificate) {}
+	}()
+
+	r := decode(t, w, handshake(t, cConf, sConf))
+
+	if r.Version != "TLS 1.2" || r.SNI != "netcap.test" || r.SelectedALPN != "http/1.1" || len(r.ALPNs) != 2 {
+		t.Fatal("unexpected handshake", r)
+	}
+
+	if r.Resumed || !r.HandshakeComplete || r.CipherSuite == "" || len(r.JA3) != 32 || len(r.JA3S) != 32 {
+		t.Fatal("unexpected session", r)
+	}
+
+	if !strings.HasPrefix(r.JA4, "t12d") || !strings.HasSuffix(strings.Split(r.JA4, "_")[0], "h2") || !strings.HasPrefix(r.JA4S, "t12") {
+		t.Fatal("unexpected JA4 fingerprints", r.JA4, r.JA4S)
+	}
+
+	if len(r.Certificates) != 1 {
+		t.Fatal("expected 1 certificate, got", len(r.Certificates))
+	}
+
+	c := r.Certificates[0]
+	if c.Subject != "CN=netcap.test,O=Netcap" || c.SerialNumber != "1092" || len(c.DNSNames) != 2 || c.IPAddresses[0] != "192.168.1.10" || c.NotBefore != ts.UnixNano() || len(c.Fingerprint) != 64 {
+		t.Fatal("unexpected certificate", c)
+	}
+
+	if sc := serverCerts["192.168.1.10"]; sc == nil || sc.Fingerprint != c.Fingerprint {
+		t.Fatal("expected certificate to be passed to the handler for the server")
+	}
+
+	// the second connection resumes the session
+	r = decode(t, w, handshake(t, cConf, sConf))
+
+	if !r.Resumed || len(r.Certificates) != 0 || !r.SessionTicket {
+		t.Fatal("expected resumed session", r)
+	}
+}
+
+func TestCertificateRecords(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	w := writers[0]
+	defer cleanup()
+
+	certificate.Decoder.Writer = w
+	defer func() {
+		certificate.Decoder.Writer = nil
+	}()
+
+	r := decode(t, w, handshake(t,
+		&gotls.Config{ServerName: "netcap.test", InsecureSkipVerify: true, MaxVersion: gotls.VersionTLS12},
+		&gotls.Config{Certificates: []gotls.Certificate{serverCertificate(t)}, Time: now},
+	))
+
+	// the certificate records are written before the TLS record
+	if len(w.Records) != 2 {
+		t.Fatal("expected 2 records, got", len(w.Records))
+	}
+
+	c, ok := w.Records[0].(*types.Certificate)
+	if !ok {
+		t.Fatal("expected certificate record, got", w.Records[0])
+	}
+
+	if c.ChainIndex != 0 || c.Fingerprint != r.Certificates[0].Fingerprint || c.SNI != "netcap.test" || c.Version != "TLS 1.2" || c.ServerIP != "192.168.1.10" || c.ServerPort != 443 {
+		t.Fatal("unexpected certificate record", c)
+	}
+
+	if !c.SelfSigned || c.Expired || c.Issuer != c.Subject || c.NotAfter != ts.Add(365*24*time.Hour).UnixNano() {
+		t.Fatal("unexpected certificate validity", c)
+	}
+}
+
+func TestTLS13Handshake(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	w := writers[0]
+	defer cleanup()
+
+	r := decode(t, w, handshake(t,
+		&gotls.Config{ServerName: "netcap.test", InsecureSkipVerify: true},
+		&gotls.Config{Certificates: []gotls.Certificate{serverCertificate(t)}},
+	))
+
+	if r.Version != "TLS 1.3" || !strings.HasPrefix(r.JA4, "t13d") || !r.HandshakeComplete || r.Resumed {
+		t.Fatal("unexpected handshake", r)
+	}
+
+	// the certificate is encrypted
+	if len(r.Certificates) != 0 {
+		t.Fatal("expected no certificates, got", len(r.Certificates))
+	}
+}
+
+func TestAlert(t *testing.T) {
+	writers, cleanup := streamtest.Setup(Decoder)
+	w := writers[0]
+	defer cleanup()
+
+	// the server does not support the protocol version offered by the client
+	r := &recorder{}
+	c, s := net.Pipe()
+
+	go func() {
+		sc := gotls.Server(&recordingConn{Conn: s, r: r, dir: reassembly.TCPDirServerToClient}, &gotls.Config{
+			Certificates: []gotls.Certificate{serverCertificate(t)},
+			MinVersion:   gotls.VersionTLS13,
+		})
+		_ = sc.Handshake()
+	}()
+
+	cc := gotls.Client(&recordingConn{Conn: c, r: r, dir: reassembly.TCPDirClientToServer}, &gotls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         gotls.VersionTLS12,
+	})
+	if err := cc.Handshake(); err == nil {
+		t.Fatal("expected handshake to fail")
+	}
+
+	_ = c.Close()
+	_ = s.Close()
+
+	res := decode(t, w, &core.ConversationInfo{Data: r.data, ServerIP: "10.0.0.1"})
+
+	if len(res.Alerts) == 0 || !res.Alerts[0].FromServer || res.Alerts[0].Level != 2 || res.Alerts[0].Description != 70 {
+		t.Fatal("expected protocol version alert", res.Alerts)
+	}
+}
@@ -41,12 +41,19 @@ type udpStream struct {
 	sync.Mutex
 	data    core.DataFragments
 	decoder core.StreamDecoderInterface
+
+	// number of payload bytes and capture timestamp of the last packet
+	size     int
+	lastSeen time.Time
 }
 
 // udpStreamPool holds a pool of UDP streams.
 type udpStreamPool struct {
 	sync.Mutex
 	streams map[uint64]*udpStream
+
+	// number of packets, used to check for idle streams in the configured interval
+	numPackets int64
 }
 
 func newUDPStreamPool() *udpStreamPool {
@@ -63,31 +70,71 @@ func (u *udpStreamPool) size() int {
 }
 
 // HandleUDP takes an UDP packet and tracks the data seen for the conversation.
+// Conversations that exceed the configured size or have been idle for too long
+// are removed from the pool and passed to the stream decoders right away.
 func (u *udpStreamPool) HandleUDP(packet gopacket.Packet, udpLayer gopacket.Layer) {
-	u.Lock()
-	if s, ok := u.streams[packet.TransportLayer().TransportFlow().FastHash()]; ok {
-		u.Unlock()
-
-		s.Lock()
-		s.data = append(s.data, &core.StreamData{
+	var (
+		key  = packet.TransportLayer().TransportFlow().FastHash()
+		ts   = packet.Metadata().Timestamp
+		data = &core.StreamData{
 			RawData:            udpLayer.LayerPayload(),
 			CaptureInformation: packet.Metadata().CaptureInfo,
 			Trans:              packet.TransportLayer().TransportFlow(),
 			Net:                packet.NetworkLayer().NetworkFlow(),
-		})
-		s.Unlock()
-	} else {
+		}
+		flush []*udpStream
+	)
+
+	u.Lock()
+
+	s, ok := u.streams[key]
+	if !ok {
 		// add new
-		str := new(udpStream)
-		str.data = append(str.data, &core.StreamData{
-			RawData:            udpLayer.LayerPayload(),
-			CaptureInformation: packet.Metadata().CaptureInfo,
-			Trans:              packet.TransportLayer().TransportFlow(),
-			Net:                packet.NetworkLayer().NetworkFlow(),
-		})
-		u.streams[packet.TransportLayer().TransportFlow().FastHash()] = str
-		u.Unlock()
+		s = new(udpStream)
+		u.streams[key] = s
+	}
+
+	s.Lock()
+	s.data = append(s.data, data)
+	s.size += len(data.RawData)
+
+	if ts.After(s.lastSeen) {
+		s.lastSeen = ts
 	}
+
+	// the next packet for this flow will start a new conversation
+	if decoderconfig.Instance.UDPMaxStreamSize > 0 && s.size > decoderconfig.Instance.UDPMaxStreamSize {
+		delete(u.streams, key)
+		flush = append(flush, s)
+	}
+	s.Unlock()
+
+	u.numPackets++
+
+	if decoderconfig.Instance.UDPIdleTimeout > 0 && decoderconfig.Instance.FlushEvery > 0 && u.numPackets%int64(decoderconfig.Instance.FlushEvery) == 0 {
+		flush = append(flush, u.removeIdle(ts.Add(-decoderconfig.Instance.UDPIdleTimeout))...)
+	}
+
+	u.Unlock()
+
+	for _, f := range flush {
+		processStream(f)
+	}
+}
+
+// removeIdle removes all streams that did not receive any data since the reference time from the pool and returns them.
+// The pool must be locked by the caller.
+func (u *udpStreamPool) removeIdle(ref time.Time) (idle []*udpStream) {
+	for key, s := range u.streams {
+		s.Lock()
+		if s.lastSeen.Before(ref) {
+			delete(u.streams, key)
+			idle = append(idle, s)
+		}
+		s.Unlock()
+	}
+
+	return idle
 }
 
 // saves the banner for a UDP service to the filesystem
@@ -129,7 +176,7 @@ func saveUDPServiceBanner(banner []byte, flowIdent string, serviceIdent string,
 		serv.Name = resolvers.LookupServiceByPort(dst, typeUDP)
 	}
 
-	service.MatchServiceProbes(serv, banner, flowIdent)
+	service.MatchServiceProbes(serv, banner, flowIdent, utils.CommunityIDFromFlows(net, transport))
 
 	// add new service
 	service.Store.Lock()
@@ -141,8 +188,7 @@ func saveUDPServiceBanner(banner []byte, flowIdent string, serviceIdent string,
 	streamutils.Stats.Unlock()
 }
 
-// TODO: ensure that only decoders of protocols are called that actually support being transported via UDP
-// TODO: specify transport protocols during decoder creation
+// decode runs the UDP stream decoders against the conversation.
 func (u *udpStream) decode() {
 	// choose the decoder to run against the data stream
 	var (
@@ -186,10 +232,11 @@ func (u *udpStream) decode() {
 		ServerIP:          u.data[0].Network().Dst().String(),
 		ClientPort:        utils.DecodePort(u.data[0].Transport().Src().Raw()),
 		ServerPort:        utils.DecodePort(u.data[0].Transport().Dst().Raw()),
+		CommunityID:       utils.CommunityIDFromFlows(u.data[0].Network(), u.data[0].Transport()),
 	}
 
 	// make a good first guess based on the destination port of the connection
-	if sd, exists := stream.DefaultStreamDecoders[utils.DecodePort(u.data[0].Transport().Dst().Raw())]; exists {
+	if sd, exists := stream.DefaultUDPStreamDecoders[utils.DecodePort(u.data[0].Transport().Dst().Raw())]; exists {
 		if sd.Transport() == core.UDP || sd.Transport() == core.All {
 			if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
 				u.decoder = sd.GetReaderFactory().New(conv)
@@ -201,7 +248,7 @@ func (u *udpStream) decode() {
 	// if no stream decoder for the port was found, or the stream decoder did not match
 	// try all available decoders and use the first one that matches
 	if !found {
-		for _, sd := range stream.DefaultStreamDecoders {
+		for _, sd := range stream.DefaultUDPStreamDecoders {
 			if sd.Transport() == core.UDP || sd.Transport() == core.All {
 				if sd.GetReaderFactory() != nil && sd.CanDecodeStream(cr, sr) {
 					u.decoder = sd.GetReaderFactory().New(conv)
@@ -52,8 +52,54 @@ func init() {
 	)
 }
 
+// live processes the UDP streams that are flushed while packets are still being collected.
+var live struct {
+	sync.Mutex
+	processor *udpStreamProcessor
+}
+
+// processStream passes a stream that has been removed from the pool during collection to the stream workers,
+// the workers are started when the first stream is flushed.
+func processStream(s *udpStream) {
+	live.Lock()
+	if live.processor == nil {
+		numWorkers := decoderconfig.Instance.NumStreamWorkers
+		if numWorkers < 1 {
+			numWorkers = 1
+		}
+
+		live.processor = new(udpStreamProcessor)
+		live.processor.initWorkers(decoderconfig.Instance.StreamBufferSize, numWorkers)
+	}
+	sp := live.processor
+	live.Unlock()
+
+	sp.handleStream(s)
+}
+
+// stopLiveProcessing waits until all streams that have been flushed during collection are processed
+// and stops the workers.
+func stopLiveProcessing() {
+	live.Lock()
+	sp := live.processor
+	live.processor = nil
+	live.Unlock()
+
+	if sp == nil {
+		return
+	}
+
+	sp.wg.Wait()
+
+	for _, w := range sp.workers {
+		w <- nil
+	}
+}
+
 // FlushUDPStreams will flush all collected UDP streams to disk.
 func FlushUDPStreams() {
+	stopLiveProcessing()
+
 	numTotal := Streams.size()
 
 	sp := new(udpStreamProcessor)
@@ -108,8 +154,9 @@ func (usp *udpStreamProcessor) handleStream(s *udpStream) {
 	//	return
 	//}
 
-	// send the packetInfo to the decoder routine
-	usp.workers[usp.next] <- s
+	// streams flushed during collection can be handled concurrently
+	usp.Lock()
+	w := usp.workers[usp.next]
 
 	// increment or reset next
 	if usp.numWorkers == usp.next+1 {
@@ -118,6 +165,10 @@ func (usp *udpStreamProcessor) handleStream(s *udpStream) {
 	} else {
 		usp.next++
 	}
+	usp.Unlock()
+
+	// send the packetInfo to the decoder routine
+	w <- s
 }
 
 // worker spawns a new worker goroutine
@@ -156,6 +207,9 @@ func (usp *udpStreamProcessor) streamWorker(wg *sync.WaitGroup) chan *udpStream
 				ident = utils.CreateFlowIdentFromLayerFlows(clientNetwork, clientTransport)
 			} else {
 				// skip empty conns
+				s.Unlock()
+				wg.Done()
+
 				continue
 			}
 
@@ -181,7 +235,7 @@ func (usp *udpStreamProcessor) streamWorker(wg *sync.WaitGroup) chan *udpStream
 			s.decode()
 
 			// save stream data
-			err := streamutils.SaveConversation("UDP", s.data, ident, firstPacket, clientTransport)
+			err := streamutils.SaveConversation("UDP", s.data, ident, firstPacket, clientNetwork, clientTransport)
 			if err != nil {
 				fmt.Println("failed to save UDP conversation:", err)
 			}
@@ -201,7 +255,8 @@ func (usp *udpStreamProcessor) streamWorker(wg *sync.WaitGroup) chan *udpStream
 			usp.Lock()
 			usp.numDone++
 
-			if !decoderconfig.Instance.Quiet {
+			// progress is only known when flushing the remaining streams at teardown
+			if !decoderconfig.Instance.Quiet && usp.numTotal > 0 {
 				utils.ClearLine()
 				fmt.Print("processing UDP streams... ", "(", usp.numDone, "/", usp.numTotal, ")")
 			}
@@ -0,0 +1,138 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package udp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/stream/service"
+)
+
+// newUDPPacket creates an UDP packet with the given payload sent from the source port to port 53.
+func newUDPPacket(t *testing.T, srcPort layers.UDPPort, payload []byte, ts time.Time) (gopacket.Packet, gopacket.Layer) {
+	t.Helper()
+
+	var (
+		ip = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolUDP,
+			SrcIP:    net.IP{10, 0, 0, 1},
+			DstIP:    net.IP{10, 0, 0, 2},
+		}
+		udp = &layers.UDP{
+			SrcPort: srcPort,
+			DstPort: 53,
+		}
+		buf = gopacket.NewSerializeBuffer()
+	)
+
+	if err := udp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, ip, udp, gopacket.Payload(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+	p.Metadata().Timestamp = ts
+
+	return p, p.Layer(layers.LayerTypeUDP)
+}
+
+func initTestConfig() {
+	decoderconfig.Instance = &decoderconfig.Config{
+		FlushEvery:       1,
+		UDPIdleTimeout:   time.Minute,
+		UDPMaxStreamSize: 10,
+		NumStreamWorkers: 1,
+		BannerSize:       512,
+		Quiet:            true,
+	}
+}
+
+func TestUDPStreamIdleTimeout(t *testing.T) {
+	initTestConfig()
+
+	var (
+		pool = newUDPStreamPool()
+		t0   = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	pool.HandleUDP(newUDPPacket(t, 40000, []byte("a"), t0))
+	pool.HandleUDP(newUDPPacket(t, 40001, []byte("b"), t0.Add(30*time.Second)))
+
+	if pool.size() != 2 {
+		t.Fatal("expected 2 streams, got", pool.size())
+	}
+
+	// the first stream did not receive any data for more than a minute
+	pool.HandleUDP(newUDPPacket(t, 40001, []byte("c"), t0.Add(90*time.Second)))
+
+	if pool.size() != 1 {
+		t.Fatal("expected 1 stream after idle timeout, got", pool.size())
+	}
+
+	stopLiveProcessing()
+
+	// the banner of the service is saved when the stream is processed
+	service.Store.Lock()
+	_, ok := service.Store.Items["10.0.0.2:53"]
+	service.Store.Unlock()
+
+	if !ok {
+		t.Fatal("expected flushed stream to be processed")
+	}
+}
+
+func TestUDPStreamMaxSize(t *testing.T) {
+	initTestConfig()
+
+	var (
+		pool = newUDPStreamPool()
+		t0   = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	pool.HandleUDP(newUDPPacket(t, 40002, []byte("123456"), t0))
+	pool.HandleUDP(newUDPPacket(t, 40002, []byte("7890"), t0))
+
+	// the stream contains exactly the maximum stream size
+	if pool.size() != 1 {
+		t.Fatal("expected 1 stream, got", pool.size())
+	}
+
+	// exceeds the maximum stream size
+	pool.HandleUDP(newUDPPacket(t, 40002, []byte("1"), t0.Add(time.Second)))
+
+	if pool.size() != 0 {
+		t.Fatal("expected stream to be flushed, got", pool.size())
+	}
+
+	// the next packet starts a new conversation
+	pool.HandleUDP(newUDPPacket(t, 40002, []byte("3"), t0.Add(2*time.Second)))
+
+	if pool.size() != 1 {
+		t.Fatal("expected 1 stream, got", pool.size())
+	}
+
+	stopLiveProcessing()
+}
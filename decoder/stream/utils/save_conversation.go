@@ -42,7 +42,7 @@ const (
 
 // SaveConversation will save TCP / UDP conversations to disk
 // this also invokes the harvesters on the conversation banner
-func SaveConversation(proto string, conversation core.DataFragments, ident string, firstPacket time.Time, transport gopacket.Flow) error {
+func SaveConversation(proto string, conversation core.DataFragments, ident string, firstPacket time.Time, network, transport gopacket.Flow) error {
 	// prevent processing zero bytes
 	if len(conversation) == 0 || conversation.Size() == 0 {
 		return nil
@@ -51,7 +51,7 @@ func SaveConversation(proto string, conversation core.DataFragments, ident strin
 	// fmt.Println("saving conv", conversation.size(), ident)
 
 	banner := createBannerFromConversation(conversation)
-	credentials.RunHarvesters(banner, transport, ident, firstPacket)
+	credentials.RunHarvesters(banner, network, transport, ident, firstPacket)
 
 	if !decoderconfig.Instance.SaveConns {
 		return nil
@@ -16,7 +16,6 @@ package utils
 import (
 	"bytes"
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -27,7 +26,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/dreadl0ck/cryptoutils"
 	gzip "github.com/klauspost/pgzip"
 	"go.uber.org/zap"
 
@@ -92,6 +90,10 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 		fileName = name
 	}
 
+	if file.Storage != nil {
+		return saveFileDeduplicated(conv, source, fileName, err, body, encoding, host, contentType)
+	}
+
 	// make sure root path exists
 	err = os.MkdirAll(root, defaults.DirectoryPermission)
 	if err != nil {
@@ -149,7 +151,7 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 	var (
 		r             io.Reader
 		length        int
-		hash          string
+		contents      []byte
 		cTypeDetected = trimEncoding(http.DetectContentType(body))
 	)
 
@@ -217,8 +219,8 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 		// TODO: refactor to avoid reading the file contents into memory again
 		body, err = ioutil.ReadFile(target)
 		if err == nil {
-			// set hash to value for decompressed content and update size
-			hash = hex.EncodeToString(cryptoutils.MD5Data(body))
+			// set hashes to values for decompressed content and update size
+			contents = body
 			length = len(body)
 
 			// update content type
@@ -242,7 +244,7 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 			}
 		}
 	} else {
-		hash = hex.EncodeToString(cryptoutils.MD5Data(body))
+		contents = body
 		length = len(body)
 	}
 
@@ -252,25 +254,130 @@ func SaveFile(conv *core.ConversationInfo, source, name string, err error, body
 		contentType = cType
 	}
 
-	// write file to disk
-	file.WriteFile(&types.File{
+	record := &types.File{
 		// TODO: use the actual timestamp when file has been transferred
 		Timestamp:           conv.FirstClientPacket.UnixNano(),
 		Name:                fileName,
 		Length:              int64(length),
-		Hash:                hash,
 		Location:            target,
 		Ident:               conv.Ident,
 		Source:              source,
 		ContentType:         contentType,
 		ContentTypeDetected: cTypeDetected,
 		// TODO: set the actual flow direction of the file, not the one of the connection
-		SrcIP:   conv.ClientIP,
-		DstIP:   conv.ServerIP,
-		SrcPort: conv.ServerPort,
-		DstPort: conv.ClientPort,
-		Host:    host,
-	})
+		SrcIP:       conv.ClientIP,
+		DstIP:       conv.ServerIP,
+		SrcPort:     conv.ServerPort,
+		DstPort:     conv.ClientPort,
+		Host:        host,
+		CommunityID: conv.CommunityID,
+	}
+
+	if contents != nil {
+		file.SetHashes(record, contents)
+		file.ScanContents(record, contents)
+	}
+
+	// write file to disk
+	file.WriteFile(record)
+
+	if contents != nil && decoderconfig.Instance.UnpackArchives {
+		unpackFile(record, contents)
+	}
 
 	return nil
 }
+
+// saveFileDeduplicated decodes the file contents and adds them to the content addressed file storage.
+func saveFileDeduplicated(conv *core.ConversationInfo, source, fileName string, errTransfer error, body []byte, encoding []string, host string, contentType string) error {
+	// objects are named after their hash, so incomplete transfers are marked in the name of the record and the manifest entry
+	if errTransfer != nil {
+		fileName = "incomplete-" + fileName
+	}
+
+	data, err := decodeFileContents(body, encoding)
+	if err != nil {
+		reassemblyLog.Error(
+			"failed to decode file contents",
+			zap.String("ident", conv.Ident),
+			zap.Strings("encoding", encoding),
+			zap.Error(err),
+		)
+
+		return err
+	}
+
+	// set the value for the provided content type to the value from the content type detection
+	// if none was provided
+	if contentType == "" {
+		contentType = trimEncoding(http.DetectContentType(body))
+	}
+
+	record := &types.File{
+		// TODO: use the actual timestamp when file has been transferred
+		Timestamp:           conv.FirstClientPacket.UnixNano(),
+		Name:                fileName,
+		Length:              int64(len(data)),
+		Ident:               conv.Ident,
+		Source:              source,
+		ContentType:         contentType,
+		ContentTypeDetected: trimEncoding(http.DetectContentType(data)),
+		// TODO: set the actual flow direction of the file, not the one of the connection
+		SrcIP:       conv.ClientIP,
+		DstIP:       conv.ServerIP,
+		SrcPort:     conv.ServerPort,
+		DstPort:     conv.ClientPort,
+		Host:        host,
+		CommunityID: conv.CommunityID,
+	}
+
+	file.SetHashes(record, data)
+
+	// the audit record is written without a location if the file could not be stored
+	err = file.Storage.Add(record, data)
+	if err != nil {
+		reassemblyLog.Error(
+			"failed to store file",
+			zap.String("ident", conv.Ident),
+			zap.String("sha256", record.SHA256),
+			zap.Error(err),
+		)
+	}
+
+	file.ScanContents(record, data)
+	file.WriteFile(record)
+
+	if decoderconfig.Instance.UnpackArchives {
+		unpackFile(record, data)
+	}
+
+	return nil
+}
+
+// decodeFileContents removes the content and transfer encoding from a file.
+func decodeFileContents(body []byte, encoding []string) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(body)
+
+	if len(encoding) > 0 {
+		switch encoding[0] {
+		case "gzip", "deflate":
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+
+			defer func() {
+				errClose := gr.Close()
+				if errClose != nil {
+					reassemblyLog.Error("failed to close gzip reader", zap.Error(errClose))
+				}
+			}()
+
+			r = gr
+		case "base64":
+			r = base64.NewDecoder(base64.StdEncoding, r)
+		}
+	}
+
+	return ioutil.ReadAll(r)
+}
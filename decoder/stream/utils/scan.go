@@ -0,0 +1,76 @@
+/*
+* NETCAP - Traffic Analysis Framework
+* Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+*
+* THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+* WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+* MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+* ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+* WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+* ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+* OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"bytes"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/reassembly"
+	"github.com/dreadl0ck/netcap/yara"
+)
+
+// ScanConversation scans the data of a conversation with the loaded YARA rules.
+// Both directions are scanned separately, so the offsets in the alerts refer to the data sent by one side.
+// Only the first yara.MaxConversationScanSize bytes of each direction are scanned.
+func ScanConversation(conv *core.ConversationInfo) {
+	if yara.Instance == nil || len(conv.Data) == 0 {
+		return
+	}
+
+	var client, server bytes.Buffer
+
+	for _, d := range conv.Data {
+		if d.Direction() == reassembly.TCPDirClientToServer {
+			appendLimited(&client, d.Raw())
+		} else {
+			appendLimited(&server, d.Raw())
+		}
+	}
+
+	if client.Len() > 0 {
+		yara.Instance.ScanAndAlert(client.Bytes(), &yara.Flow{
+			Timestamp:   conv.FirstClientPacket.UnixNano(),
+			SrcIP:       conv.ClientIP,
+			DstIP:       conv.ServerIP,
+			SrcPort:     conv.ClientPort,
+			DstPort:     conv.ServerPort,
+			Protocol:    "TCP",
+			CommunityID: conv.CommunityID,
+			Notes:       "ident: " + conv.Ident + ", client to server",
+		})
+	}
+
+	if server.Len() > 0 {
+		yara.Instance.ScanAndAlert(server.Bytes(), &yara.Flow{
+			Timestamp:   conv.FirstServerPacket.UnixNano(),
+			SrcIP:       conv.ServerIP,
+			DstIP:       conv.ClientIP,
+			SrcPort:     conv.ServerPort,
+			DstPort:     conv.ClientPort,
+			Protocol:    "TCP",
+			CommunityID: conv.CommunityID,
+			Notes:       "ident: " + conv.Ident + ", server to client",
+		})
+	}
+}
+
+// appendLimited appends data to the buffer until it holds yara.MaxConversationScanSize bytes.
+func appendLimited(buf *bytes.Buffer, data []byte) {
+	if n := yara.MaxConversationScanSize - buf.Len(); len(data) > n {
+		data = data[:n]
+	}
+
+	buf.Write(data)
+}
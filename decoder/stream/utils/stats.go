@@ -21,6 +21,8 @@ var Stats struct {
 	BiggestChunkPackets int64
 	OverlapBytes        int64
 	OverlapPackets      int64
+	TruncatedBytes      int64
+	TruncatedConns      int64
 	SavedTCPConnections int64
 	SavedUDPConnections int64
 	NumSoftware         int64
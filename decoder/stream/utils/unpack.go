@@ -0,0 +1,138 @@
+/*
+* NETCAP - Traffic Analysis Framework
+* Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+*
+* THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+* WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+* MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+* ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+* WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+* ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+* OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/stream/file"
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// unpackFile recursively extracts the members of archives and writes a file record for each member.
+// The records are linked to the archive they were extracted from via its SHA-256 hash,
+// the member contents are stored next to the archive, or in the deduplicated file storage.
+func unpackFile(parent *types.File, data []byte) {
+	if !file.IsArchive(data) {
+		return
+	}
+
+	u := file.NewUnpacker(file.UnpackConfig{
+		MaxSize:  decoderconfig.Instance.UnpackMaxSize,
+		MaxRatio: decoderconfig.Instance.UnpackMaxRatio,
+	})
+
+	unpackMembers(u, parent, data, 1)
+}
+
+func unpackMembers(u *file.Unpacker, parent *types.File, data []byte, depth int) {
+	if depth > decoderconfig.Instance.UnpackMaxDepth {
+		reassemblyLog.Info("not unpacking archive, maximum depth reached",
+			zap.String("name", parent.Name),
+			zap.String("sha256", parent.SHA256),
+			zap.Int("depth", depth),
+		)
+
+		return
+	}
+
+	members, err := u.Unpack(parent.Name, data)
+	if err != nil {
+		// members extracted before a limit was exceeded are still processed
+		reassemblyLog.Error("failed to unpack archive",
+			zap.String("name", parent.Name),
+			zap.String("sha256", parent.SHA256),
+			zap.Int("members", len(members)),
+			zap.Error(err),
+		)
+	}
+
+	for i, m := range members {
+		child := &types.File{
+			Timestamp:           parent.Timestamp,
+			Name:                m.Name,
+			Length:              int64(len(m.Data)),
+			Ident:               parent.Ident,
+			Source:              parent.Source,
+			ContentType:         parent.ContentType,
+			ContentTypeDetected: trimEncoding(http.DetectContentType(m.Data)),
+			SrcIP:               parent.SrcIP,
+			DstIP:               parent.DstIP,
+			SrcPort:             parent.SrcPort,
+			DstPort:             parent.DstPort,
+			Host:                parent.Host,
+			CommunityID:         parent.CommunityID,
+			ParentSHA256:        parent.SHA256,
+			Depth:               int32(depth),
+		}
+
+		file.SetHashes(child, m.Data)
+
+		err = storeMember(parent, child, i, m.Data)
+		if err != nil {
+			reassemblyLog.Error("failed to store archive member",
+				zap.String("name", child.Name),
+				zap.String("sha256", child.SHA256),
+				zap.Error(err),
+			)
+		}
+
+		file.ScanContents(child, m.Data)
+		file.WriteFile(child)
+
+		if file.IsArchive(m.Data) {
+			unpackMembers(u, child, m.Data, depth+1)
+		}
+	}
+}
+
+// storeMember writes the contents of an archive member and sets its location.
+// Without the deduplicated file storage, members are written to a directory next to the archive.
+// The member names are not trusted, only their base name prefixed with the index is used.
+func storeMember(parent, child *types.File, index int, data []byte) error {
+	if file.Storage != nil {
+		return file.Storage.Add(child, data)
+	}
+
+	if parent.Location == "" {
+		return nil
+	}
+
+	dir := parent.Location + "-unpacked"
+
+	err := os.MkdirAll(dir, defaults.DirectoryPermission)
+	if err != nil {
+		return err
+	}
+
+	location := filepath.Join(dir, strconv.Itoa(index)+"-"+path.Base(path.Clean("/"+filepath.ToSlash(child.Name))))
+
+	err = ioutil.WriteFile(location, data, defaults.FilePermission)
+	if err != nil {
+		return err
+	}
+
+	child.Location = location
+
+	return nil
+}
@@ -0,0 +1,319 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package vulnerability
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/umisama/go-cpe"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// CVE is a vulnerability from the NVD with the CPE names of the affected software.
+type CVE struct {
+	ID           string      `json:"id"`
+	Description  string      `json:"description"`
+	Severity     string      `json:"severity"`
+	V2Score      string      `json:"v2Score"`
+	AccessVector string      `json:"accessVector"`
+	Matches      []*CPEMatch `json:"matches"`
+}
+
+// CPEMatch describes the software affected by a vulnerability.
+// If any of the version bounds is set, all versions in the range are affected,
+// otherwise only the version from the CPE name. A version of * affects all versions.
+type CPEMatch struct {
+	Vendor                string `json:"vendor"`
+	Product               string `json:"product"`
+	Version               string `json:"version"`
+	VersionStartIncluding string `json:"versionStartIncluding,omitempty"`
+	VersionStartExcluding string `json:"versionStartExcluding,omitempty"`
+	VersionEndIncluding   string `json:"versionEndIncluding,omitempty"`
+	VersionEndExcluding   string `json:"versionEndExcluding,omitempty"`
+}
+
+// NewCPEMatch parses the CPE 2.3 name of an NVD configuration entry.
+func NewCPEMatch(m NVDCpeMatch) (*CPEMatch, error) {
+	item, err := cpe.NewItemFromFormattedString(m.Cpe23URI)
+	if err != nil {
+		return nil, err
+	}
+
+	version := item.Version().String()
+
+	// the update contains the patch level for some products, e.g. 7.6:p1 for OpenSSH
+	if u := item.Update(); version != "*" && version != "-" && !u.IsEmpty() && u.String() != "-" {
+		version += u.String()
+	}
+
+	return &CPEMatch{
+		Vendor:                strings.ToLower(item.Vendor().String()),
+		Product:               strings.ToLower(item.Product().String()),
+		Version:               version,
+		VersionStartIncluding: m.VersionStartIncluding,
+		VersionStartExcluding: m.VersionStartExcluding,
+		VersionEndIncluding:   m.VersionEndIncluding,
+		VersionEndExcluding:   m.VersionEndExcluding,
+	}, nil
+}
+
+// VulnerableCPEs collects the CPE matches that are marked vulnerable from the nodes of an NVD configuration.
+// Names that cannot be parsed are skipped.
+func VulnerableCPEs(nodes []NVDNode) []*CPEMatch {
+	var out []*CPEMatch
+
+	for _, n := range nodes {
+		out = append(out, VulnerableCPEs(n.Children)...)
+
+		for _, m := range n.CpeMatch {
+			if !m.Vulnerable {
+				continue
+			}
+
+			c, err := NewCPEMatch(m)
+			if err != nil {
+				continue
+			}
+
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// hasRange returns true if any of the version bounds is set.
+func (m *CPEMatch) hasRange() bool {
+	return m.VersionStartIncluding != "" ||
+		m.VersionStartExcluding != "" ||
+		m.VersionEndIncluding != "" ||
+		m.VersionEndExcluding != ""
+}
+
+// MatchesVersion checks whether the given version is affected.
+func (m *CPEMatch) MatchesVersion(version string) bool {
+	if m.hasRange() {
+		if m.VersionStartIncluding != "" && compareVersions(version, m.VersionStartIncluding) < 0 {
+			return false
+		}
+
+		if m.VersionStartExcluding != "" && compareVersions(version, m.VersionStartExcluding) <= 0 {
+			return false
+		}
+
+		if m.VersionEndIncluding != "" && compareVersions(version, m.VersionEndIncluding) > 0 {
+			return false
+		}
+
+		if m.VersionEndExcluding != "" && compareVersions(version, m.VersionEndExcluding) >= 0 {
+			return false
+		}
+
+		return true
+	}
+
+	switch m.Version {
+	case "*":
+		return true
+	case "-", "":
+		return false
+	}
+
+	return compareVersions(version, m.Version) == 0
+}
+
+// cpeName is the vendor and product of a CPE name.
+type cpeName struct {
+	vendor  string
+	product string
+}
+
+// productAliases maps product names observed on the network to CPE names,
+// for products whose CPE name differs from the name they advertise.
+var productAliases = map[string]cpeName{
+	"apache":        {vendor: "apache", product: "http_server"},
+	"microsoft_iis": {vendor: "microsoft", product: "internet_information_services"},
+	"vsftp":         {vendor: "beasts", product: "vsftpd"},
+}
+
+// normalizeCPEName converts a vendor or product name to the format used in CPE names.
+func normalizeCPEName(name string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// cpeDatabase holds the CVEs indexed by the affected product name.
+type cpeDatabase struct {
+	products map[string][]*cpeEntry
+}
+
+// cpeEntry associates a CPE match with its CVE.
+type cpeEntry struct {
+	match *CPEMatch
+	cve   *CVE
+}
+
+var (
+	cpeDB *cpeDatabase
+
+	// caches the lookup results for vendor, product and version.
+	cpeCache = struct {
+		sync.Mutex
+		items map[string][]*CVE
+	}{
+		items: make(map[string][]*CVE),
+	}
+)
+
+func newCPEDatabase(cves []*CVE) *cpeDatabase {
+	d := &cpeDatabase{
+		products: make(map[string][]*cpeEntry),
+	}
+
+	for _, c := range cves {
+		for _, m := range c.Matches {
+			d.products[m.Product] = append(d.products[m.Product], &cpeEntry{
+				match: m,
+				cve:   c,
+			})
+		}
+	}
+
+	return d
+}
+
+// WriteCPEDatabase writes the CVEs as gzipped JSON to the given path.
+func WriteCPEDatabase(path string, cves []*CVE) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := gzip.NewWriter(f)
+
+	err = json.NewEncoder(w).Encode(cves)
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// LoadCPEDatabase loads the CVEs written by WriteCPEDatabase,
+// once loaded, the vulnerability lookup uses the CPE names instead of the bleve index.
+func LoadCPEDatabase(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		errClose := f.Close()
+		if errClose != nil {
+			vulnLog.Error("failed to close CPE database", zap.Error(errClose))
+		}
+	}()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+
+	var cves []*CVE
+
+	err = json.NewDecoder(r).Decode(&cves)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(cves) == 0 {
+		return 0, errors.New("no CVEs in CPE database")
+	}
+
+	setCPEDatabase(cves)
+
+	return len(cves), nil
+}
+
+// setCPEDatabase replaces the CPE database and resets the cache.
+func setCPEDatabase(cves []*CVE) {
+	cpeCache.Lock()
+	cpeDB = newCPEDatabase(cves)
+	cpeCache.items = make(map[string][]*CVE)
+	cpeCache.Unlock()
+}
+
+// matchCPE returns the CVEs affecting the software.
+// Results are cached, since the same software is usually seen many times.
+func matchCPE(software *types.Software) []*CVE {
+	var (
+		vendor  = normalizeCPEName(software.Vendor)
+		product = normalizeCPEName(software.Product)
+		key     = vendor + ":" + product + ":" + software.Version
+	)
+
+	cpeCache.Lock()
+	defer cpeCache.Unlock()
+
+	if cves, ok := cpeCache.items[key]; ok {
+		return cves
+	}
+
+	if alias, ok := productAliases[product]; ok {
+		product = alias.product
+
+		if vendor == "" {
+			vendor = alias.vendor
+		}
+	}
+
+	var (
+		cves []*CVE
+		seen = make(map[string]struct{})
+	)
+
+	for _, e := range cpeDB.products[product] {
+		if vendor != "" && e.match.Vendor != vendor {
+			continue
+		}
+
+		if _, ok := seen[e.cve.ID]; ok {
+			continue
+		}
+
+		if e.match.MatchesVersion(software.Version) {
+			seen[e.cve.ID] = struct{}{}
+			cves = append(cves, e.cve)
+		}
+	}
+
+	cpeCache.items[key] = cves
+
+	return cves
+}
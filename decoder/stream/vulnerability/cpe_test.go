@@ -0,0 +1,242 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package vulnerability
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"7.6", "7.6.0", 0},
+		{"2.4.7", "2.4.38", -1},
+		{"2.4.38", "2.4.7", 1},
+		{"10.0", "9.9.9", 1},
+		{"1.0.1", "1.0.1a", -1},
+		{"1.0.1k", "1.0.1a", 1},
+		{"7.6p1", "7.6", 1},
+		{"7.6p1", "7.6p2", -1},
+		{"1.0rc1", "1.0", -1},
+		{"1.0-beta", "1.0-rc1", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-rc1", "1.0.1", -1},
+		{"007", "7", 0},
+		{"18.18363", "18.17763", 1},
+	}
+
+	for _, test := range tests {
+		if c := compareVersions(test.a, test.b); c != test.expected {
+			t.Fatal("compare", test.a, test.b, ": expected", test.expected, "got", c)
+		}
+	}
+}
+
+func TestNewCPEMatch(t *testing.T) {
+	m, err := NewCPEMatch(NVDCpeMatch{
+		Vulnerable: true,
+		Cpe23URI:   "cpe:2.3:a:openbsd:openssh:7.6:p1:*:*:*:*:*:*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Vendor != "openbsd" || m.Product != "openssh" || m.Version != "7.6p1" {
+		t.Fatal("unexpected cpe match", m)
+	}
+
+	if !m.MatchesVersion("7.6p1") || m.MatchesVersion("7.6p2") {
+		t.Fatal("exact version not matched")
+	}
+
+	_, err = NewCPEMatch(NVDCpeMatch{Cpe23URI: "cpe:/a:openbsd:openssh:7.6"})
+	if err == nil {
+		t.Fatal("expected error for CPE 2.2 URI")
+	}
+}
+
+func TestCPEMatchVersionRange(t *testing.T) {
+	m := &CPEMatch{
+		Vendor:                "apache",
+		Product:               "http_server",
+		Version:               "*",
+		VersionStartIncluding: "2.4.0",
+		VersionEndExcluding:   "2.4.39",
+	}
+
+	for version, expected := range map[string]bool{
+		"2.2.34": false,
+		"2.4.0":  true,
+		"2.4.7":  true,
+		"2.4.38": true,
+		"2.4.39": false,
+		"2.4.41": false,
+	} {
+		if m.MatchesVersion(version) != expected {
+			t.Fatal("version", version, "expected match", expected)
+		}
+	}
+
+	m = &CPEMatch{
+		Version:               "*",
+		VersionStartExcluding: "1.0",
+		VersionEndIncluding:   "1.2",
+	}
+
+	for version, expected := range map[string]bool{
+		"1.0":   false,
+		"1.0.1": true,
+		"1.2":   true,
+		"1.2.1": false,
+	} {
+		if m.MatchesVersion(version) != expected {
+			t.Fatal("version", version, "expected match", expected)
+		}
+	}
+}
+
+func TestVulnerableCPEs(t *testing.T) {
+	nodes := []NVDNode{
+		{
+			Operator: "AND",
+			Children: []NVDNode{
+				{
+					Operator: "OR",
+					CpeMatch: []NVDCpeMatch{
+						{Vulnerable: true, Cpe23URI: "cpe:2.3:a:nginx:nginx:*:*:*:*:*:*:*:*", VersionEndExcluding: "1.15.6"},
+					},
+				},
+				{
+					Operator: "OR",
+					CpeMatch: []NVDCpeMatch{
+						{Vulnerable: false, Cpe23URI: "cpe:2.3:o:linux:linux_kernel:-:*:*:*:*:*:*:*"},
+					},
+				},
+			},
+		},
+		{
+			Operator: "OR",
+			CpeMatch: []NVDCpeMatch{
+				{Vulnerable: true, Cpe23URI: "invalid"},
+			},
+		},
+	}
+
+	matches := VulnerableCPEs(nodes)
+	if len(matches) != 1 {
+		t.Fatal("expected 1 vulnerable cpe, got", len(matches))
+	}
+
+	if matches[0].Product != "nginx" || matches[0].VersionEndExcluding != "1.15.6" {
+		t.Fatal("unexpected cpe match", matches[0])
+	}
+}
+
+func TestMatchCPE(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-cpe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cves := []*CVE{
+		{
+			ID: "CVE-2019-0211",
+			Matches: []*CPEMatch{
+				{Vendor: "apache", Product: "http_server", Version: "*", VersionStartIncluding: "2.4.17", VersionEndIncluding: "2.4.38"},
+			},
+		},
+		{
+			ID: "CVE-2018-15473",
+			Matches: []*CPEMatch{
+				{Vendor: "openbsd", Product: "openssh", Version: "*", VersionEndIncluding: "7.7"},
+			},
+		},
+		{
+			ID: "CVE-2016-10009",
+			Matches: []*CPEMatch{
+				{Vendor: "openbsd", Product: "openssh", Version: "7.3"},
+			},
+		},
+		{
+			ID: "CVE-2000-0001",
+			Matches: []*CPEMatch{
+				{Vendor: "other", Product: "openssh", Version: "*"},
+			},
+		},
+	}
+
+	path := filepath.Join(dir, "nvd-cpe.json.gz")
+
+	err = WriteCPEDatabase(path, cves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num, err := LoadCPEDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cpeDB = nil
+	}()
+
+	if num != len(cves) {
+		t.Fatal("expected", len(cves), "CVEs, got", num)
+	}
+
+	tests := []struct {
+		software *types.Software
+		expected []string
+	}{
+		{&types.Software{Product: "Apache", Version: "2.4.38"}, []string{"CVE-2019-0211"}},
+		{&types.Software{Product: "Apache", Version: "2.4.39"}, nil},
+		{&types.Software{Product: "Apache", Version: "2.2.34"}, nil},
+		{&types.Software{Vendor: "OpenBSD", Product: "OpenSSH", Version: "7.6p1"}, []string{"CVE-2018-15473"}},
+		{&types.Software{Vendor: "OpenBSD", Product: "OpenSSH", Version: "7.3"}, []string{"CVE-2018-15473", "CVE-2016-10009"}},
+		{&types.Software{Product: "OpenSSH", Version: "7.9"}, []string{"CVE-2000-0001"}},
+	}
+
+	for _, test := range tests {
+		// the second iteration is served from the cache
+		for i := 0; i < 2; i++ {
+			res := matchCPE(test.software)
+			if len(res) != len(test.expected) {
+				t.Fatal(test.software.Product, test.software.Version, ": expected", test.expected, "got", len(res), "results")
+			}
+
+			for j, c := range res {
+				if c.ID != test.expected[j] {
+					t.Fatal(test.software.Product, test.software.Version, ": expected", test.expected[j], "got", c.ID)
+				}
+			}
+		}
+	}
+
+	cpeCache.Lock()
+	numCached := len(cpeCache.items)
+	cpeCache.Unlock()
+
+	if numCached != len(tests) {
+		t.Fatal("expected", len(tests), "cached results, got", numCached)
+	}
+}
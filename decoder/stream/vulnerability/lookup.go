@@ -20,10 +20,9 @@ import (
 	"go.uber.org/zap"
 )
 
-// VulnerabilitiesLookup searches for known vulnerabilities in the indexed bleve database
-// TODO:
-// - Make the threshold configurable on the commandline
-// - add caching layer to avoid repeating matching operations.
+// VulnerabilitiesLookup searches for known vulnerabilities of the software.
+// If the CPE database is loaded, the CPE names and version ranges from the NVD are matched,
+// otherwise the indexed bleve database is searched.
 func VulnerabilitiesLookup(software *types.Software) {
 
 	if software == nil {
@@ -33,6 +32,14 @@ func VulnerabilitiesLookup(software *types.Software) {
 		return
 	}
 
+	if cpeDB != nil {
+		for _, c := range matchCPE(software) {
+			writeVuln(software, c)
+		}
+
+		return
+	}
+
 	if db.VulnerabilitiesIndex == nil {
 		return
 	}
@@ -55,7 +62,7 @@ func VulnerabilitiesLookup(software *types.Software) {
 	for _, v := range searchResults.Hits {
 		if v.Score > ThresholdNVD {
 			doc, _ := db.VulnerabilitiesIndex.Document(v.ID)
-			writeVuln(software, doc)
+			writeVuln(software, cveFromDocument(doc))
 		}
 	}
 }
@@ -68,16 +68,8 @@ type NVDVulnerabilityItems struct {
 			} `json:"description"`
 		} `json:"cve"`
 		Configurations struct {
-			CVEDataVersion string `json:"CVE_data_version"`
-			Nodes          []struct {
-				Operator string `json:"operator"`
-				CpeMatch []struct {
-					Vulnerable            bool   `json:"vulnerable"`
-					Cpe23URI              string `json:"cpe23Uri"`
-					VersionEndExcluding   string `json:"versionEndExcluding"`
-					VersionStartIncluding string `json:"versionStartIncluding,omitempty"`
-				} `json:"cpe_match"`
-			} `json:"nodes"`
+			CVEDataVersion string    `json:"CVE_data_version"`
+			Nodes          []NVDNode `json:"nodes"`
 		} `json:"configurations"`
 		Impact struct {
 			BaseMetricV3 struct {
@@ -125,6 +117,24 @@ type NVDVulnerabilityItems struct {
 	} `json:"CVE_Items"`
 }
 
+// NVDNode is a node of an NVD configuration.
+// Nodes with the AND operator contain their CPE matches in the child nodes.
+type NVDNode struct {
+	Operator string        `json:"operator"`
+	Children []NVDNode     `json:"children,omitempty"`
+	CpeMatch []NVDCpeMatch `json:"cpe_match"`
+}
+
+// NVDCpeMatch is a CPE name in an NVD configuration, optionally restricted to a range of versions.
+type NVDCpeMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Cpe23URI              string `json:"cpe23Uri"`
+	VersionStartIncluding string `json:"versionStartIncluding,omitempty"`
+	VersionStartExcluding string `json:"versionStartExcluding,omitempty"`
+	VersionEndIncluding   string `json:"versionEndIncluding,omitempty"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
 func buildNVDQuery(vendor, software, version string) string {
 	var b strings.Builder
 
@@ -163,35 +173,43 @@ var vulnStore = vulnerabilityStore{
 	items: make(map[string]struct{}),
 }
 
-func writeVuln(software *types.Software, doc *document.Document) {
-	// use CVE as map index to deduplicate
-	id := string(doc.Fields[0].Value())
+// cveFromDocument converts a document from the bleve index into a CVE.
+func cveFromDocument(doc *document.Document) *CVE {
+	c := &CVE{
+		ID:          string(doc.Fields[0].Value()),
+		Description: strings.Trim(string(doc.Fields[1].Value()), "\""),
+		Severity:    string(doc.Fields[2].Value()),
+	}
 
+	if len(doc.Fields) > 3 {
+		c.V2Score = string(doc.Fields[3].Value())
+		c.AccessVector = string(doc.Fields[4].Value())
+	}
+
+	return c
+}
+
+func writeVuln(software *types.Software, c *CVE) {
+	// use CVE as map index to deduplicate
 	vulnStore.Lock()
-	if _, ok := vulnStore.items[id]; ok {
+	if _, ok := vulnStore.items[c.ID]; ok {
 		vulnStore.Unlock()
 		// exists, exit.
 		return
 	}
-	vulnStore.items[id] = struct{}{}
+	vulnStore.items[c.ID] = struct{}{}
 	vulnStore.Unlock()
 
-	var score, vector string
-	if len(doc.Fields) > 3 {
-		score = string(doc.Fields[3].Value())
-		vector = string(doc.Fields[4].Value())
-	}
-
 	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
 
 	if Decoder.Writer != nil { // during unit tests, the vulnerability decoder might not be initialized
 		err := Decoder.Writer.Write(&types.Vulnerability{
 			Timestamp:    software.Timestamp,
-			ID:           id,
-			Description:  strings.Trim(string(doc.Fields[1].Value()), "\""),
-			Severity:     string(doc.Fields[2].Value()),
-			V2Score:      score,
-			AccessVector: vector,
+			ID:           c.ID,
+			Description:  c.Description,
+			Severity:     c.Severity,
+			V2Score:      c.V2Score,
+			AccessVector: c.AccessVector,
 			Software:     software,
 		})
 		if err != nil {
@@ -0,0 +1,175 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package vulnerability
+
+import (
+	"strings"
+	"unicode"
+)
+
+// pre-release identifiers, ordered from lowest to highest precedence.
+// they sort before the release they belong to, e.g. 1.0rc1 < 1.0.
+var preReleases = map[string]int{
+	"dev":   1,
+	"alpha": 2,
+	"beta":  3,
+	"pre":   4,
+	"rc":    5,
+}
+
+// versionToken is a numeric or alphabetic part of a version string.
+type versionToken struct {
+	value   string
+	numeric bool
+}
+
+// tokenizeVersion splits a version string into runs of digits and letters.
+// All other characters are treated as separators, so 7.6p1 results in [7 6 p 1].
+func tokenizeVersion(version string) []versionToken {
+	var (
+		tokens []versionToken
+		start  = -1
+	)
+
+	isNum := func(r rune) bool {
+		return r >= '0' && r <= '9'
+	}
+
+	runes := []rune(strings.ToLower(version))
+	for i, r := range runes {
+		if !isNum(r) && !unicode.IsLetter(r) {
+			if start >= 0 {
+				tokens = append(tokens, newVersionToken(string(runes[start:i])))
+				start = -1
+			}
+
+			continue
+		}
+
+		if start >= 0 && isNum(r) != isNum(runes[start]) {
+			tokens = append(tokens, newVersionToken(string(runes[start:i])))
+			start = -1
+		}
+
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, newVersionToken(string(runes[start:])))
+	}
+
+	return tokens
+}
+
+func newVersionToken(value string) versionToken {
+	if value[0] >= '0' && value[0] <= '9' {
+		// strip leading zeros, the numbers are compared by length first
+		value = strings.TrimLeft(value, "0")
+		if value == "" {
+			value = "0"
+		}
+
+		return versionToken{value: value, numeric: true}
+	}
+
+	return versionToken{value: value}
+}
+
+// compareVersions compares two version strings.
+// The result will be 0 if a == b, -1 if a < b and +1 if a > b.
+// Numeric parts are compared by value, missing numeric parts are treated as zero,
+// so 7.6 and 7.6.0 are equal. Pre-release identifiers sort before the release,
+// all other alphabetic suffixes after it, so 1.0.1 < 1.0.1a and 1.0rc1 < 1.0.
+func compareVersions(a, b string) int {
+	var (
+		ta = tokenizeVersion(a)
+		tb = tokenizeVersion(b)
+	)
+
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		var c int
+
+		switch {
+		case i >= len(ta):
+			c = -compareMissingToken(tb[i])
+		case i >= len(tb):
+			c = compareMissingToken(ta[i])
+		default:
+			c = compareTokens(ta[i], tb[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareMissingToken compares a version that has no token at a position,
+// with a version that has the token t at the same position.
+// The result is from the point of view of the version containing the token.
+func compareMissingToken(t versionToken) int {
+	if t.numeric {
+		return compareTokens(t, versionToken{value: "0", numeric: true})
+	}
+
+	if _, ok := preReleases[t.value]; ok {
+		return -1
+	}
+
+	return 1
+}
+
+func compareTokens(a, b versionToken) int {
+	switch {
+	case a.numeric && b.numeric:
+		if len(a.value) != len(b.value) {
+			return sign(len(a.value) - len(b.value))
+		}
+
+		return strings.Compare(a.value, b.value)
+	case a.numeric:
+		return 1
+	case b.numeric:
+		return -1
+	}
+
+	pa, okA := preReleases[a.value]
+	pb, okB := preReleases[b.value]
+
+	switch {
+	case okA && okB:
+		return sign(pa - pb)
+	case okA:
+		return -1
+	case okB:
+		return 1
+	}
+
+	return strings.Compare(a.value, b.value)
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,372 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// opcodes, see RFC 6455 section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var opcodeNames = map[byte]string{
+	opContinuation: "continuation",
+	opText:         "text",
+	opBinary:       "binary",
+	opClose:        "close",
+	opPing:         "ping",
+	opPong:         "pong",
+}
+
+const (
+	// maximum payload size of control frames, see RFC 6455 section 5.5.
+	maxControlPayload = 125
+
+	// maximum number of payload bytes that are collected for a single message.
+	maxMessageSize = 1024 * 1024
+
+	// number of payload bytes that are kept in the audit record.
+	maxPreview = 256
+
+	// size of the LZ77 window used by permessage-deflate, see RFC 7692 section 7.1.2.
+	maxWindowSize = 32 * 1024
+)
+
+var (
+	errIncomplete      = errors.New("incomplete frame")
+	errReservedOpcode  = errors.New("reserved opcode")
+	errInvalidControl  = errors.New("invalid control frame")
+	errUnexpectedFrame = errors.New("unexpected continuation frame")
+)
+
+// deflateTail is appended to compressed messages, since it is removed by the sender, see RFC 7692 section 7.2.2.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// frame is a single WebSocket frame with an unmasked payload.
+type frame struct {
+	fin     bool
+	rsv1    bool
+	masked  bool
+	opcode  byte
+	payload []byte
+}
+
+func (f *frame) isControl() bool {
+	return f.opcode&0x8 != 0
+}
+
+// readFrame parses the frame at the start of data and returns the number of bytes consumed.
+func readFrame(data []byte) (*frame, int, error) {
+	if len(data) < 2 {
+		return nil, 0, errIncomplete
+	}
+
+	var (
+		f = &frame{
+			fin:    data[0]&0x80 != 0,
+			rsv1:   data[0]&0x40 != 0,
+			opcode: data[0] & 0x0f,
+			masked: data[1]&0x80 != 0,
+		}
+		length = uint64(data[1] & 0x7f)
+		n      = 2
+	)
+
+	if _, ok := opcodeNames[f.opcode]; !ok {
+		return nil, 0, errReservedOpcode
+	}
+
+	switch length {
+	case 126:
+		if len(data) < n+2 {
+			return nil, 0, errIncomplete
+		}
+
+		length = uint64(binary.BigEndian.Uint16(data[n:]))
+		n += 2
+	case 127:
+		if len(data) < n+8 {
+			return nil, 0, errIncomplete
+		}
+
+		length = binary.BigEndian.Uint64(data[n:])
+		n += 8
+	}
+
+	if f.isControl() && (!f.fin || length > maxControlPayload) {
+		return nil, 0, errInvalidControl
+	}
+
+	var key []byte
+
+	if f.masked {
+		if len(data) < n+4 {
+			return nil, 0, errIncomplete
+		}
+
+		key = data[n : n+4]
+		n += 4
+	}
+
+	if length > uint64(len(data)-n) {
+		return nil, 0, errIncomplete
+	}
+
+	f.payload = make([]byte, length)
+	copy(f.payload, data[n:])
+
+	for i := range key {
+		for j := i; j < len(f.payload); j += len(key) {
+			f.payload[j] ^= key[i]
+		}
+	}
+
+	return f, n + int(length), nil
+}
+
+// message is a data message assembled from one or more frames.
+type message struct {
+	timestamp  time.Time
+	opcode     byte
+	compressed bool
+	masked     bool
+	numFrames  int32
+	length     int64
+	payload    bytes.Buffer
+}
+
+func (m *message) add(f *frame) {
+	m.numFrames++
+	m.length += int64(len(f.payload))
+
+	if n := maxMessageSize - m.payload.Len(); n > 0 {
+		if n > len(f.payload) {
+			n = len(f.payload)
+		}
+
+		m.payload.Write(f.payload[:n])
+	}
+}
+
+// reader decodes the frames sent into one direction of a session.
+type reader struct {
+	session    *Session
+	fromServer bool
+
+	// window contains the most recent uncompressed data, if context takeover is used
+	window []byte
+}
+
+// Decode parses the frames sent into one direction of a session and writes an audit record for each message.
+// The timeAt func returns the capture timestamp for an offset into the data.
+// Decoding stops at the first malformed or truncated frame, a message that has not been completed at this point is still written.
+func Decode(s *Session, fromServer bool, data []byte, timeAt func(offset int) time.Time) {
+	var (
+		r      = &reader{session: s, fromServer: fromServer}
+		msg    *message
+		offset int
+	)
+
+	for offset < len(data) {
+		f, n, err := readFrame(data[offset:])
+		if err == nil && f.opcode == opContinuation && msg == nil {
+			err = errUnexpectedFrame
+		}
+
+		if err != nil {
+			if !errors.Is(err, errIncomplete) {
+				wsLog.Debug("failed to read WebSocket frame",
+					zap.String("ident", s.Conversation.Ident),
+					zap.Bool("server", fromServer),
+					zap.Int("offset", offset),
+					zap.Error(err),
+				)
+			}
+
+			break
+		}
+
+		ts := timeAt(offset)
+		offset += n
+
+		if f.isControl() {
+			// control frames can be injected in the middle of a fragmented message
+			c := &message{
+				timestamp: ts,
+				opcode:    f.opcode,
+				masked:    f.masked,
+			}
+			c.add(f)
+			r.write(c)
+
+			if f.opcode == opClose {
+				break
+			}
+
+			continue
+		}
+
+		if f.opcode != opContinuation {
+			if msg != nil {
+				// the previous message has not been finished
+				r.write(msg)
+			}
+
+			msg = &message{
+				timestamp:  ts,
+				opcode:     f.opcode,
+				compressed: f.rsv1 && s.Deflate,
+				masked:     f.masked,
+			}
+		}
+
+		msg.add(f)
+
+		if f.fin {
+			r.write(msg)
+			msg = nil
+		}
+	}
+
+	if msg != nil {
+		r.write(msg)
+	}
+}
+
+// inflate decompresses a message payload, see RFC 7692 section 7.2.2.
+func (r *reader) inflate(m *message) ([]byte, error) {
+	var (
+		in = io.MultiReader(bytes.NewReader(m.payload.Bytes()), bytes.NewReader(deflateTail))
+		fr = flate.NewReaderDict(in, r.window)
+	)
+
+	out, err := ioutil.ReadAll(io.LimitReader(fr, maxMessageSize))
+	// the stream is not terminated with a final block
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	noContextTakeover := r.session.ClientNoContextTakeover
+	if r.fromServer {
+		noContextTakeover = r.session.ServerNoContextTakeover
+	}
+
+	if noContextTakeover {
+		r.window = nil
+	} else {
+		r.window = append(r.window, out...)
+		if len(r.window) > maxWindowSize {
+			r.window = r.window[len(r.window)-maxWindowSize:]
+		}
+	}
+
+	return out, nil
+}
+
+// write creates the audit record for a message.
+func (r *reader) write(m *message) {
+	var (
+		s       = r.session
+		payload = m.payload.Bytes()
+		length  = m.length
+	)
+
+	if m.compressed {
+		out, err := r.inflate(m)
+		if err != nil {
+			wsLog.Debug("failed to inflate WebSocket message",
+				zap.String("ident", s.Conversation.Ident),
+				zap.Bool("server", r.fromServer),
+				zap.Error(err),
+			)
+		} else {
+			payload = out
+			length = int64(len(out))
+		}
+	}
+
+	ws := &types.WebSocket{
+		Timestamp:   m.timestamp.UnixNano(),
+		ClientIP:    s.Conversation.ClientIP,
+		ServerIP:    s.Conversation.ServerIP,
+		ClientPort:  s.Conversation.ClientPort,
+		ServerPort:  s.Conversation.ServerPort,
+		Host:        s.Host,
+		URL:         s.URL,
+		Protocol:    s.Protocol,
+		FromServer:  r.fromServer,
+		Opcode:      opcodeNames[m.opcode],
+		Length:      length,
+		NumFrames:   m.numFrames,
+		Compressed:  m.compressed,
+		Masked:      m.masked,
+		CommunityID: s.Conversation.CommunityID,
+	}
+
+	switch m.opcode {
+	case opText:
+		ws.Payload = preview(payload)
+	case opClose:
+		// the payload starts with the status code, followed by an optional reason, see RFC 6455 section 5.5.1.
+		if len(payload) >= 2 {
+			ws.CloseCode = int32(binary.BigEndian.Uint16(payload))
+			ws.Payload = preview(payload[2:])
+		}
+	}
+
+	writeMessage(ws)
+}
+
+// preview returns the start of a text payload with line breaks and commas replaced,
+// so it can be displayed in a single CSV field.
+func preview(payload []byte) string {
+	if len(payload) > maxPreview {
+		payload = payload[:maxPreview]
+
+		// do not cut a multi byte character
+		for i := 1; i < utf8.UTFMax && !utf8.Valid(payload); i++ {
+			payload = payload[:len(payload)-1]
+		}
+	}
+
+	if !utf8.Valid(payload) {
+		return "invalid UTF-8 (" + strconv.Itoa(len(payload)) + " bytes)"
+	}
+
+	return strings.NewReplacer(
+		",", "(comma)",
+		"\r", " ",
+		"\n", " ",
+	).Replace(string(payload))
+}
@@ -0,0 +1,116 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package websocket decodes the messages of WebSocket sessions, which are established with a HTTP upgrade.
+package websocket
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/decoder"
+	decoderconfig "github.com/dreadl0ck/netcap/decoder/config"
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/utils"
+	logging "github.com/dreadl0ck/netcap/logger"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var wsLog = zap.NewNop()
+
+// Decoder for writing WebSocket message audit records to disk.
+// The messages are decoded by the HTTP stream decoder, once a connection has been upgraded.
+var Decoder = &decoder.AbstractDecoder{
+	Type:        types.Type_NC_WebSocket,
+	Name:        "WebSocket",
+	Description: "WebSocket messages exchanged after a HTTP connection has been upgraded",
+	PostInit: func(d *decoder.AbstractDecoder) (err error) {
+		wsLog, _, err = logging.InitZapLogger(
+			decoderconfig.Instance.Out,
+			"websocket",
+			decoderconfig.Instance.Debug,
+		)
+
+		return err
+	},
+	DeInit: func(d *decoder.AbstractDecoder) error {
+		return wsLog.Sync()
+	},
+}
+
+const extensionDeflate = "permessage-deflate"
+
+// Session describes a WebSocket connection and the parameters negotiated in the opening handshake.
+type Session struct {
+	Conversation *core.ConversationInfo
+
+	// Host and URL of the upgrade request
+	Host string
+	URL  string
+
+	// Protocol is the subprotocol selected by the server
+	Protocol string
+
+	// Deflate is set if the permessage-deflate extension has been negotiated,
+	// the compression context is reset after every message for a peer that does not use context takeover.
+	Deflate                 bool
+	ClientNoContextTakeover bool
+	ServerNoContextTakeover bool
+}
+
+// NewSession creates a session for the conversation,
+// the extensions are taken from the Sec-WebSocket-Extensions header of the server response.
+func NewSession(conv *core.ConversationInfo, host, url, protocol, extensions string) *Session {
+	s := &Session{
+		Conversation: conv,
+		Host:         host,
+		URL:          url,
+		Protocol:     protocol,
+	}
+
+	for _, ext := range strings.Split(extensions, ",") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != extensionDeflate {
+			continue
+		}
+
+		s.Deflate = true
+
+		for _, p := range params[1:] {
+			switch strings.TrimSpace(p) {
+			case "client_no_context_takeover":
+				s.ClientNoContextTakeover = true
+			case "server_no_context_takeover":
+				s.ServerNoContextTakeover = true
+			}
+		}
+	}
+
+	return s
+}
+
+// writeMessage writes the audit record for a message.
+func writeMessage(ws *types.WebSocket) {
+	if decoderconfig.Instance.ExportMetrics {
+		ws.Inc()
+	}
+
+	atomic.AddInt64(&Decoder.NumRecordsWritten, 1)
+
+	err := Decoder.Writer.Write(ws)
+	if err != nil {
+		utils.ErrorMap.Inc(err.Error())
+	}
+}
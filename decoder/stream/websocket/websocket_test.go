@@ -0,0 +1,253 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/decoder/core"
+	"github.com/dreadl0ck/netcap/decoder/stream/streamtest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ts = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// encodeFrame creates a frame, the payload is masked if a key is provided.
+func encodeFrame(fin, rsv1 bool, opcode byte, key []byte, payload []byte) []byte {
+	var b0 = opcode
+	if fin {
+		b0 |= 0x80
+	}
+
+	if rsv1 {
+		b0 |= 0x40
+	}
+
+	var (
+		out  = []byte{b0}
+		mask byte
+	)
+
+	if key != nil {
+		mask = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		out = append(out, mask|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		out = append(out, mask|126, 0, 0)
+		binary.BigEndian.PutUint16(out[2:], uint16(len(payload)))
+	default:
+		out = append(out, mask|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(out[2:], uint64(len(payload)))
+	}
+
+	out = append(out, key...)
+
+	for i, c := range payload {
+		if key != nil {
+			c ^= key[i%4]
+		}
+
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// deflater compresses messages with a shared context.
+type deflater struct {
+	buf bytes.Buffer
+	w   *flate.Writer
+}
+
+func (d *deflater) compress(t *testing.T, data string) []byte {
+	t.Helper()
+
+	if d.w == nil {
+		var err error
+
+		d.w, err = flate.NewWriter(&d.buf, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.buf.Reset()
+
+	if _, err := d.w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.TrimSuffix(d.buf.Bytes(), deflateTail)
+}
+
+func newTestSession(extensions string) *Session {
+	return NewSession(&core.ConversationInfo{
+		Ident:       "192.168.1.2:49999->192.168.1.10:80",
+		ClientIP:    "192.168.1.2",
+		ServerIP:    "192.168.1.10",
+		ClientPort:  49999,
+		ServerPort:  80,
+		CommunityID: "1:abc",
+	}, "example.com", "/chat", "chat", extensions)
+}
+
+func decodeRecords(t *testing.T, s *Session, fromServer bool, data []byte) []*types.WebSocket {
+	t.Helper()
+
+	writers, cleanup := streamtest.Setup(Decoder)
+	defer cleanup()
+
+	w := writers[0]
+
+	Decode(s, fromServer, data, func(offset int) time.Time {
+		return ts.Add(time.Duration(offset) * time.Millisecond)
+	})
+
+	records := make([]*types.WebSocket, len(w.Records))
+	for i, r := range w.Records {
+		records[i] = r.(*types.WebSocket)
+	}
+
+	return records
+}
+
+func TestNewSession(t *testing.T) {
+	s := newTestSession("x-webkit-deflate-frame, permessage-deflate; client_max_window_bits=15; server_no_context_takeover")
+	if !s.Deflate || s.ClientNoContextTakeover || !s.ServerNoContextTakeover {
+		t.Fatal("unexpected extensions", s)
+	}
+
+	s = newTestSession("")
+	if s.Deflate {
+		t.Fatal("unexpected deflate extension")
+	}
+}
+
+func TestDecodeClient(t *testing.T) {
+	var (
+		key  = []byte{0x37, 0xfa, 0x21, 0x3d}
+		data []byte
+		long = strings.Repeat("a", 300)
+	)
+
+	// a masked text message
+	data = append(data, encodeFrame(true, false, opText, key, []byte("hello, world\n"))...)
+
+	// a fragmented binary message with a ping in between
+	data = append(data, encodeFrame(false, false, opBinary, key, []byte{1, 2, 3})...)
+	data = append(data, encodeFrame(true, false, opPing, key, []byte("ping"))...)
+	data = append(data, encodeFrame(false, false, opContinuation, key, make([]byte, 200))...)
+	data = append(data, encodeFrame(true, false, opContinuation, key, make([]byte, 70000))...)
+
+	// a text message exceeding the preview length
+	data = append(data, encodeFrame(true, false, opText, key, []byte(long))...)
+
+	// a normal closure, followed by data that is ignored
+	closure := append([]byte{0x03, 0xe8}, "bye"...)
+	data = append(data, encodeFrame(true, false, opClose, key, closure)...)
+	data = append(data, encodeFrame(true, false, opText, key, []byte("ignored"))...)
+
+	records := decodeRecords(t, newTestSession(""), false, data)
+	if len(records) != 5 {
+		t.Fatal("expected 5 records, got", len(records))
+	}
+
+	text := records[0]
+	if text.Opcode != "text" || text.Payload != "hello(comma) world " || text.Length != 13 || !text.Masked || text.FromServer || text.NumFrames != 1 {
+		t.Fatal("unexpected text message", text)
+	}
+
+	if text.Host != "example.com" || text.URL != "/chat" || text.Protocol != "chat" || text.ClientPort != 49999 || text.CommunityID != "1:abc" || text.Timestamp != ts.UnixNano() {
+		t.Fatal("unexpected session information", text)
+	}
+
+	if ping := records[1]; ping.Opcode != "ping" || ping.Length != 4 || ping.Payload != "" {
+		t.Fatal("unexpected ping", ping)
+	}
+
+	if bin := records[2]; bin.Opcode != "binary" || bin.Length != 70203 || bin.NumFrames != 3 || bin.Payload != "" {
+		t.Fatal("unexpected binary message", bin)
+	}
+
+	if text := records[3]; text.Payload != long[:maxPreview] || text.Length != 300 {
+		t.Fatal("unexpected long text message", text.Length, len(text.Payload))
+	}
+
+	if c := records[4]; c.Opcode != "close" || c.CloseCode != 1000 || c.Payload != "bye" {
+		t.Fatal("unexpected close frame", c)
+	}
+}
+
+func TestDecodeCompressed(t *testing.T) {
+	var (
+		d    = &deflater{}
+		data []byte
+		msg  = `{"type":"update","value":"` + strings.Repeat("x", 100) + `"}`
+	)
+
+	// the second message references the first one
+	data = append(data, encodeFrame(true, true, opText, nil, d.compress(t, msg))...)
+	data = append(data, encodeFrame(true, true, opText, nil, d.compress(t, msg))...)
+
+	// a compressed message split into two frames, only the first frame has the RSV1 bit set
+	c := d.compress(t, "fragmented")
+	data = append(data, encodeFrame(false, true, opText, nil, c[:3])...)
+	data = append(data, encodeFrame(true, false, opContinuation, nil, c[3:])...)
+
+	records := decodeRecords(t, newTestSession("permessage-deflate"), true, data)
+	if len(records) != 3 {
+		t.Fatal("expected 3 records, got", len(records))
+	}
+
+	for _, r := range records[:2] {
+		if !r.Compressed || r.Masked || !r.FromServer || r.Length != int64(len(msg)) || r.Payload != strings.Replace(msg, ",", "(comma)", -1) {
+			t.Fatal("unexpected compressed message", r)
+		}
+	}
+
+	if r := records[2]; r.Payload != "fragmented" || r.NumFrames != 2 {
+		t.Fatal("unexpected fragmented compressed message", r)
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	var data []byte
+
+	data = append(data, encodeFrame(false, false, opText, nil, []byte("partial "))...)
+	data = append(data, encodeFrame(true, false, opContinuation, nil, []byte("message"))[:5]...)
+
+	records := decodeRecords(t, newTestSession(""), true, data)
+	if len(records) != 1 || records[0].Payload != "partial " || records[0].NumFrames != 1 {
+		t.Fatal("unexpected records", records)
+	}
+
+	// invalid control frame
+	data = encodeFrame(false, false, opPing, nil, nil)
+
+	if records = decodeRecords(t, newTestSession(""), true, data); len(records) != 0 {
+		t.Fatal("unexpected records", records)
+	}
+}
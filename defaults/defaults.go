@@ -67,6 +67,27 @@ const (
 	// CloseInactiveTimeout Close inactive streams after.
 	CloseInactiveTimeout = 24 * time.Hour
 
+	// UDPIdleTimeout Flush UDP conversations that did not receive any data after.
+	UDPIdleTimeout = 1 * time.Minute
+
+	// UDPMaxStreamSize Flush UDP conversations once they contain more bytes than this.
+	UDPMaxStreamSize = 1024 * 1024 * 1 // 1 MB
+
+	// ReassemblyBudget Maximum number of bytes buffered for TCP reassembly and conversation data, in MB.
+	ReassemblyBudget = 0 // unlimited
+
+	// ReassemblyConnMax Maximum number of bytes buffered for a single TCP connection, in MB.
+	ReassemblyConnMax = 0 // unlimited
+
+	// UnpackMaxDepth Maximum nesting depth when unpacking archives from extracted files.
+	UnpackMaxDepth = 3
+
+	// UnpackMaxSize Maximum number of bytes unpacked from a single extracted file, in MB.
+	UnpackMaxSize = 100
+
+	// UnpackMaxRatio Maximum ratio between the unpacked and the compressed size of an archive member.
+	UnpackMaxRatio = 100
+
 	// AllowMissingInit TCP State Machine.
 	AllowMissingInit = true
 
@@ -102,4 +123,13 @@ const (
 
 	// NetcapTypePrefix holds the prefix for the protobuf types
 	NetcapTypePrefix = "NC_"
+
+	// AlertKey holds the alert fields used to identify duplicate alerts.
+	AlertKey = "Name,SrcIP,DstIP,Protocol"
+
+	// AlertFlushInterval controls how often deduplicated alerts are written.
+	AlertFlushInterval = 10 * time.Second
+
+	// AlertSuppressionWindow is the minimum time between writing the same alert again.
+	AlertSuppressionWindow = 1 * time.Minute
 )
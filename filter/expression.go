@@ -0,0 +1,323 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// ErrSyntax is returned for malformed filter expressions.
+var ErrSyntax = errors.New("syntax error")
+
+// node is an element of a parsed filter expression.
+type node interface {
+	match(record types.AuditRecord) bool
+}
+
+type (
+	// and matches if both expressions match.
+	and struct {
+		left, right node
+	}
+
+	// or matches if one of the expressions matches.
+	or struct {
+		left, right node
+	}
+
+	// not inverts the result of the expression.
+	not struct {
+		expr node
+	}
+
+	// comparison compares the value of a field, it never matches if the record does not have the field.
+	comparison struct {
+		field     string
+		predicate func(v reflect.Value) bool
+	}
+)
+
+func (n *and) match(record types.AuditRecord) bool {
+	return n.left.match(record) && n.right.match(record)
+}
+
+func (n *or) match(record types.AuditRecord) bool {
+	return n.left.match(record) || n.right.match(record)
+}
+
+func (n *not) match(record types.AuditRecord) bool {
+	return !n.expr.match(record)
+}
+
+func (n *comparison) match(record types.AuditRecord) bool {
+	v, ok := FieldValue(record, n.field)
+
+	return ok && n.predicate(v)
+}
+
+// token is a lexical element of a filter expression.
+type token struct {
+	value  string
+	quoted bool
+	pos    int
+}
+
+// symbols that are split from words, longest first.
+var symbols = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "(", ")", "!", "<", ">", "="}
+
+// tokenize splits the expression into words, quoted strings and symbols.
+// Quoted strings can use single or double quotes, a backslash escapes the quote character.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var (
+				b     strings.Builder
+				start = i
+				end   = -1
+			)
+
+			for j := i + 1; j < len(expr); j++ {
+				if expr[j] == '\\' && j+1 < len(expr) && (expr[j+1] == c || expr[j+1] == '\\') {
+					j++
+					b.WriteByte(expr[j])
+
+					continue
+				}
+
+				if expr[j] == c {
+					end = j
+
+					break
+				}
+
+				b.WriteByte(expr[j])
+			}
+
+			if end == -1 {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrSyntax, start)
+			}
+
+			tokens = append(tokens, token{value: b.String(), quoted: true, pos: start})
+			i = end + 1
+		default:
+			if s := symbolAt(expr, i); s != "" {
+				tokens = append(tokens, token{value: s, pos: i})
+				i += len(s)
+
+				continue
+			}
+
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\n\r\"'", rune(expr[i])) && symbolAt(expr, i) == "" {
+				i++
+			}
+
+			tokens = append(tokens, token{value: expr[start:i], pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+// symbolAt returns the symbol at the given position, or an empty string.
+func symbolAt(expr string, i int) string {
+	for _, s := range symbols {
+		if strings.HasPrefix(expr[i:], s) {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// parser is a recursive descent parser for filter expressions:
+//
+//	expr       = and { ( "||" | "or" ) and }
+//	and        = unary { ( "&&" | "and" ) unary }
+//	unary      = ( "!" | "not" ) unary | "(" expr ")" | comparison
+//	comparison = field operator value
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses the filter expression.
+func parse(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, t.value, t.pos)
+	}
+
+	return n, nil
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+// next consumes the next token.
+func (p *parser) next() (token, error) {
+	t, ok := p.peek()
+	if !ok {
+		return t, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+	}
+
+	p.pos++
+
+	return t, nil
+}
+
+// accept consumes the next token if it is one of the given symbols or keywords.
+func (p *parser) accept(values ...string) bool {
+	t, ok := p.peek()
+	if !ok || t.quoted {
+		return false
+	}
+
+	for _, v := range values {
+		if strings.EqualFold(t.value, v) {
+			p.pos++
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||", "or") {
+		right, errRight := p.parseAnd()
+		if errRight != nil {
+			return nil, errRight
+		}
+
+		left = &or{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&", "and") {
+		right, errRight := p.parseUnary()
+		if errRight != nil {
+			return nil, errRight
+		}
+
+		left = &and{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!", "not") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &not{expr: n}, nil
+	}
+
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(")") {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrSyntax)
+		}
+
+		return n, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if field.quoted || symbolAt(field.value, 0) != "" {
+		return nil, fmt.Errorf("%w: expected field name at position %d, got %q", ErrSyntax, field.pos, field.value)
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if !value.quoted && (value.value == "(" || value.value == ")") {
+		return nil, fmt.Errorf("%w: expected value at position %d, got %q", ErrSyntax, value.pos, value.value)
+	}
+
+	if op.quoted {
+		return nil, fmt.Errorf("%w: expected operator at position %d, got %q", ErrSyntax, op.pos, op.value)
+	}
+
+	predicate, err := Predicate(strings.ToLower(op.value), value.value)
+	if err != nil {
+		return nil, fmt.Errorf("field %s at position %d: %w", field.value, field.pos, err)
+	}
+
+	return &comparison{field: field.value, predicate: predicate}, nil
+}
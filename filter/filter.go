@@ -0,0 +1,131 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// ErrInvalidTime is returned for time bounds that cannot be parsed.
+var ErrInvalidTime = errors.New("invalid time")
+
+// Filter selects audit records by an expression and a time range.
+type Filter struct {
+	// Since excludes records before the given time, if set
+	Since time.Time
+
+	// Until excludes records at or after the given time, if set
+	Until time.Time
+
+	expr node
+}
+
+// New parses the expression and returns a filter for it.
+// An empty expression matches all records within the time range.
+//
+// Expressions compare fields of an audit record, and can be combined with && (and), || (or), ! (not) and parentheses:
+//
+//	SrcIP in-subnet 10.0.0.0/8 && (DstPort == 443 || ServerName =~ ".*\.example\.com")
+//
+// Nested fields are separated by a dot, e.g. Context.SrcIP or RequestHeader.User-Agent.
+func New(expression string, since, until time.Time) (*Filter, error) {
+	f := &Filter{
+		Since: since,
+		Until: until,
+	}
+
+	if strings.TrimSpace(expression) != "" {
+		n, err := parse(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		f.expr = n
+	}
+
+	return f, nil
+}
+
+// Match returns true if the record is within the time range and matches the expression.
+// A nil filter matches all records.
+func (f *Filter) Match(record types.AuditRecord) bool {
+	if f == nil {
+		return true
+	}
+
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		t := time.Unix(0, record.Time())
+
+		if !f.Since.IsZero() && t.Before(f.Since) {
+			return false
+		}
+
+		if !f.Until.IsZero() && !t.Before(f.Until) {
+			return false
+		}
+	}
+
+	return f.expr == nil || f.expr.match(record)
+}
+
+// layouts for absolute times, without a time zone the location passed to ParseTime is used.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses a time bound. Supported are RFC3339 timestamps,
+// dates with an optional time of day (e.g. 2020-06-01 or 2020-06-01 14:30),
+// unix timestamps in seconds (e.g. 1590969600 or 1590969600.5),
+// and durations relative to now (e.g. 2h for two hours ago).
+// An empty string returns the zero time.
+func ParseTime(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	// numbers without a unit are unix timestamps, e.g. 0 is the epoch and not a duration of zero
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec := int64(f)
+
+		return time.Unix(sec, int64((f-float64(sec))*float64(time.Second))), nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			d = -d
+		}
+
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+}
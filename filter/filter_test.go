@@ -0,0 +1,147 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	ts = time.Date(2020, 6, 1, 14, 30, 0, 0, time.UTC)
+
+	record = &types.HTTP{
+		Timestamp:  ts.UnixNano(),
+		Method:     "GET",
+		Host:       "www.example.com",
+		URL:        "/login?user=admin",
+		StatusCode: 404,
+		SrcIP:      "10.0.1.5",
+		DstIP:      "93.184.216.34",
+		RequestHeader: map[string]string{
+			"User-Agent": "curl/7.64.1",
+		},
+	}
+)
+
+func TestFilterMatch(t *testing.T) {
+	for expr, expected := range map[string]bool{
+		``:                                       true,
+		`Method == GET`:                          true,
+		`Method = "POST"`:                        false,
+		`Method != POST`:                         true,
+		`StatusCode >= 400 && StatusCode < 500`:  true,
+		`StatusCode > 404`:                       false,
+		`SrcIP in-subnet 10.0.0.0/8`:             true,
+		`DstIP in-subnet 10.0.0.0/8`:             false,
+		`Host =~ '.*\.example\.com'`:             true,
+		`Host regex "^example"`:                  false,
+		`URL contains admin`:                     true,
+		`Method in "GET, HEAD"`:                  true,
+		`RequestHeader.User-Agent contains curl`: true,
+		`RequestHeader.Accept == "*/*"`:          false,
+		`RequestHeader.Accept != "*/*"`:          false,
+		`Unknown == 1`:                           false,
+		`!(Method == GET)`:                       false,
+		`not Method == POST`:                     true,
+		`Method == POST || StatusCode == 404`:    true,
+		`Method == POST or StatusCode == 404 and SrcIP == 10.0.0.1`:   false,
+		`(Method == POST OR StatusCode == 404) AND SrcIP == 10.0.1.5`: true,
+		`URL == "/login?user=admin"`:                                  true,
+	} {
+		f, err := New(expr, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(expr, err)
+		}
+
+		if f.Match(record) != expected {
+			t.Fatal("expected", expected, "for", expr)
+		}
+	}
+}
+
+func TestFilterTimeRange(t *testing.T) {
+	for _, c := range []struct {
+		since, until time.Time
+		expected     bool
+	}{
+		{ts.Add(-time.Minute), ts.Add(time.Minute), true},
+		{ts, time.Time{}, true},
+		{time.Time{}, ts, false},
+		{ts.Add(time.Second), time.Time{}, false},
+	} {
+		f, err := New("Method == GET", c.since, c.until)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Match(record) != c.expected {
+			t.Fatal("expected", c.expected, "for", c.since, c.until)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for expr, expected := range map[string]error{
+		`Method ==`:        ErrSyntax,
+		`(Method == GET`:   ErrSyntax,
+		`Method == GET)`:   ErrSyntax,
+		`Method == "GET`:   ErrSyntax,
+		`Method == GET &&`: ErrSyntax,
+		`== GET`:           ErrSyntax,
+		`Method like GET`:  ErrInvalidOperator,
+		`Method "==" GET`:  ErrSyntax,
+	} {
+		if _, err := New(expr, time.Time{}, time.Time{}); !errors.Is(err, expected) {
+			t.Fatal("expected", expected, "for", expr, "got", err)
+		}
+	}
+
+	if _, err := New(`SrcIP in-subnet 10.0.0.0`, time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected an error for an invalid subnet")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2020, 6, 1, 16, 0, 0, 0, time.UTC)
+
+	for s, expected := range map[string]time.Time{
+		"":                          {},
+		"2020-06-01T14:30:00Z":      ts,
+		"2020-06-01T16:30:00+02:00": ts,
+		"2020-06-01 14:30":          ts,
+		"2020-06-01T14:30:00":       ts,
+		"2020-06-01":                time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		"1591021800":                ts,
+		"1591021800.5":              ts.Add(500 * time.Millisecond),
+		"0":                         time.Unix(0, 0),
+		"90m":                       ts,
+	} {
+		parsed, err := ParseTime(s, now, time.UTC)
+		if err != nil {
+			t.Fatal(s, err)
+		}
+
+		if !parsed.Equal(expected) {
+			t.Fatal("expected", expected, "for", s, "got", parsed)
+		}
+	}
+
+	if _, err := ParseTime("yesterday", now, time.UTC); !errors.Is(err, ErrInvalidTime) {
+		t.Fatal("expected an error, got", err)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// supported comparison operators.
+const (
+	opEqual        = "=="
+	opNotEqual     = "!="
+	opLess         = "<"
+	opLessEqual    = "<="
+	opGreater      = ">"
+	opGreaterEqual = ">="
+	opContains     = "contains"
+	opIn           = "in"
+	opRegex        = "regex"
+	opMatch        = "=~"
+	opInSubnet     = "in-subnet"
+)
+
+// ErrInvalidOperator is returned for unknown comparison operators.
+var ErrInvalidOperator = errors.New("invalid operator")
+
+// Predicate returns a function that compares a field value against the value with the given operator.
+// Equality is checked numerically if both the field and the value are numbers,
+// and by comparing the string representation otherwise.
+func Predicate(op, value string) (func(v reflect.Value) bool, error) {
+	num, isNum := parseFloat(value)
+
+	// numeric returns a predicate for numeric comparisons.
+	numeric := func(cmp func(a, b float64) bool) func(v reflect.Value) bool {
+		return func(v reflect.Value) bool {
+			if !isNum {
+				return false
+			}
+
+			f, ok := ToFloat(v)
+
+			return ok && cmp(f, num)
+		}
+	}
+
+	// equal compares numerically if possible.
+	equal := func(v reflect.Value) bool {
+		if isNum {
+			if f, ok := ToFloat(v); ok {
+				return f == num
+			}
+		}
+
+		return ToString(v) == value
+	}
+
+	switch op {
+	case opEqual, "=", "":
+		return equal, nil
+	case opNotEqual:
+		return func(v reflect.Value) bool {
+			return !equal(v)
+		}, nil
+	case opLess:
+		return numeric(func(a, b float64) bool { return a < b }), nil
+	case opLessEqual:
+		return numeric(func(a, b float64) bool { return a <= b }), nil
+	case opGreater:
+		return numeric(func(a, b float64) bool { return a > b }), nil
+	case opGreaterEqual:
+		return numeric(func(a, b float64) bool { return a >= b }), nil
+	case opContains:
+		return func(v reflect.Value) bool {
+			return strings.Contains(ToString(v), value)
+		}, nil
+	case opIn:
+		list := parseList(value)
+
+		return func(v reflect.Value) bool {
+			_, ok := list[ToString(v)]
+
+			return ok
+		}, nil
+	case opRegex, opMatch:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(v reflect.Value) bool {
+			return re.MatchString(ToString(v))
+		}, nil
+	case opInSubnet:
+		_, subnet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(v reflect.Value) bool {
+			ip := net.ParseIP(ToString(v))
+
+			return ip != nil && subnet.Contains(ip)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOperator, op)
+	}
+}
+
+// parseList parses a comma separated list of values into a set.
+func parseList(s string) map[string]struct{} {
+	m := make(map[string]struct{})
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			m[v] = struct{}{}
+		}
+	}
+
+	return m
+}
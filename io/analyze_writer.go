@@ -0,0 +1,52 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// analyzeWriter passes each audit record to the registered anomaly detectors,
+// before handing it to the underlying writer.
+type analyzeWriter struct {
+	AuditRecordWriter
+}
+
+// newAnalyzeWriter wraps the writer.
+func newAnalyzeWriter(w AuditRecordWriter) *analyzeWriter {
+	return &analyzeWriter{
+		AuditRecordWriter: w,
+	}
+}
+
+// Write analyzes and writes the audit record.
+func (w *analyzeWriter) Write(msg proto.Message) error {
+	// analyze before writing, since some writers modify the record, e.g. the timestamp for JSON
+	if r, ok := msg.(types.AuditRecord); ok {
+		r.Analyze()
+	}
+
+	return w.AuditRecordWriter.Write(msg)
+}
+
+// GetChan returns the channel of the underlying writer, if it is a channel writer.
+func (w *analyzeWriter) GetChan() <-chan []byte {
+	if cw, ok := w.AuditRecordWriter.(ChannelAuditRecordWriter); ok {
+		return cw.GetChan()
+	}
+
+	return nil
+}
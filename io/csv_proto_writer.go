@@ -25,9 +25,8 @@ type csvProtoWriter struct {
 	w io.Writer
 
 	// config
-	encode  bool
-	analyze bool
-	label   bool
+	encode bool
+	label  bool
 
 	// avoid allocations by reusing these variables
 	//values []string
@@ -91,15 +90,8 @@ func (w *csvProtoWriter) writeRecord(msg proto.Message) (int, error) {
 	// 	  - less memory usage but every write blocks until worker is done
 	// 2) avoid lock during processing and only lock for write, but alloc temp variables for record, values, out etc
 	//    - likely better, since invoking analyzers and / or encoding takes time..
+	// audit records are passed to the anomaly detectors by the analyzeWriter, see NewAuditRecordWriter.
 	if record, ok := msg.(types.AuditRecord); ok {
-
-		// pass audit record to analyzer
-		if w.analyze {
-			// TODO: change Encode() signature so that it returns a []float64 vector
-			// and calculate it only once, passing it to analyze when configured.
-			record.Analyze()
-		}
-
 		var (
 			values []string
 			out    []byte
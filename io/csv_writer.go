@@ -31,6 +31,7 @@ import (
 type csvWriter struct {
 	bWriter   *bufio.Writer
 	gWriter   *pgzip.Writer
+	cWriter   *countingWriter
 	csvWriter *csvProtoWriter
 
 	file *os.File
@@ -65,9 +66,11 @@ func newCSVWriter(wc *WriterConfig) *csvWriter {
 				panic(errGzipWriter)
 			}
 
-			w.csvWriter = newCSVProtoWriter(w.gWriter, wc.Encode, wc.Label)
+			w.cWriter = &countingWriter{w: w.gWriter}
+			w.csvWriter = newCSVProtoWriter(w.cWriter, wc.Encode, wc.Label)
 		} else {
-			w.csvWriter = newCSVProtoWriter(w.bWriter, wc.Encode, wc.Label)
+			w.cWriter = &countingWriter{w: w.bWriter}
+			w.csvWriter = newCSVProtoWriter(w.cWriter, wc.Encode, wc.Label)
 		}
 	} else {
 		if wc.Compress {
@@ -76,9 +79,11 @@ func newCSVWriter(wc *WriterConfig) *csvWriter {
 			if errGzipWriter != nil {
 				panic(errGzipWriter)
 			}
-			w.csvWriter = newCSVProtoWriter(w.gWriter, wc.Encode, wc.Label)
+			w.cWriter = &countingWriter{w: w.gWriter}
+			w.csvWriter = newCSVProtoWriter(w.cWriter, wc.Encode, wc.Label)
 		} else {
-			w.csvWriter = newCSVProtoWriter(w.file, wc.Encode, wc.Label)
+			w.cWriter = &countingWriter{w: w.file}
+			w.csvWriter = newCSVProtoWriter(w.cWriter, wc.Encode, wc.Label)
 		}
 	}
 
@@ -110,6 +115,11 @@ func (w *csvWriter) WriteHeader(t types.Type) error {
 	return err
 }
 
+// written returns the number of bytes written, before buffering and compression.
+func (w *csvWriter) written() int64 {
+	return w.cWriter.n
+}
+
 // Close flushes and closes the writer and the associated file handles.
 func (w *csvWriter) Close(numRecords int64) (name string, size int64) {
 
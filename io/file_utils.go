@@ -16,6 +16,7 @@ package io
 import (
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"log"
 	"net"
 	"os"
@@ -131,6 +132,19 @@ func removeEmptyNewlineDelimitedFile(name string) (size int64) {
 	return 0
 }
 
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
 // createFile is a wrapper to create new audit record file.
 func createFile(name, ext string) *os.File {
 	f, err := os.OpenFile(name+ext, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaults.FilePermission)
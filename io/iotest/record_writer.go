@@ -0,0 +1,50 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package iotest provides audit record writers for testing decoders and writers.
+package iotest
+
+import (
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// RecordWriter keeps the audit records written to it in memory.
+// It is not safe for concurrent use.
+type RecordWriter struct {
+	Header  types.Type
+	Records []proto.Message
+	Closed  bool
+}
+
+// Write appends the record.
+func (w *RecordWriter) Write(msg proto.Message) error {
+	w.Records = append(w.Records, msg)
+
+	return nil
+}
+
+// WriteHeader stores the type of the records.
+func (w *RecordWriter) WriteHeader(t types.Type) error {
+	w.Header = t
+
+	return nil
+}
+
+// Close marks the writer as closed and returns the number of records.
+func (w *RecordWriter) Close(int64) (string, int64) {
+	w.Closed = true
+
+	return "records", int64(len(w.Records))
+}
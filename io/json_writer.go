@@ -39,6 +39,7 @@ type jsonWriter struct {
 	mu      sync.Mutex
 	bWriter *bufio.Writer
 	gWriter *pgzip.Writer
+	cWriter *countingWriter
 	dWriter *delimited.Writer
 	jWriter *jsonProtoWriter
 
@@ -74,9 +75,11 @@ func newJSONWriter(wc *WriterConfig) *jsonWriter {
 				panic(errGzipWriter)
 			}
 
-			w.jWriter = newJSONProtoWriter(w.gWriter)
+			w.cWriter = &countingWriter{w: w.gWriter}
+			w.jWriter = newJSONProtoWriter(w.cWriter)
 		} else {
-			w.jWriter = newJSONProtoWriter(w.bWriter)
+			w.cWriter = &countingWriter{w: w.bWriter}
+			w.jWriter = newJSONProtoWriter(w.cWriter)
 		}
 	} else {
 		if wc.Compress {
@@ -85,9 +88,11 @@ func newJSONWriter(wc *WriterConfig) *jsonWriter {
 			if errGzipWriter != nil {
 				panic(errGzipWriter)
 			}
-			w.jWriter = newJSONProtoWriter(w.gWriter)
+			w.cWriter = &countingWriter{w: w.gWriter}
+			w.jWriter = newJSONProtoWriter(w.cWriter)
 		} else {
-			w.jWriter = newJSONProtoWriter(w.file)
+			w.cWriter = &countingWriter{w: w.file}
+			w.jWriter = newJSONProtoWriter(w.cWriter)
 		}
 	}
 
@@ -123,6 +128,11 @@ func (w *jsonWriter) WriteHeader(t types.Type) error {
 	return err
 }
 
+// written returns the number of bytes written, before buffering and compression.
+func (w *jsonWriter) written() int64 {
+	return w.cWriter.n
+}
+
 // Close flushes and closes the writer and the associated file handles.
 func (w *jsonWriter) Close(numRecords int64) (name string, size int64) {
 	w.mu.Lock()
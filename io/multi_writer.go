@@ -0,0 +1,237 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// number of pending operations buffered for each writer of a multiWriter.
+const multiWriterQueueSize = 10000
+
+// maximum time to wait for a lossy writer with a full queue, before records are dropped for it.
+var multiWriterDropTimeout = time.Second
+
+// multiWriter passes each audit record to several writers.
+// Every writer is fed by its own goroutine, so errors of one writer do not stop the others:
+// they are logged and counted per writer.
+// Writers block the multiWriter once their queue is full, so no records are lost for local files.
+// Writers marked with Lossy, for example an unreachable elastic endpoint,
+// drop records instead if their queue stays full for longer than the drop timeout.
+type multiWriter struct {
+	sinks []*sink
+}
+
+// sink is a single writer of a multiWriter.
+type sink struct {
+	AuditRecordWriter
+
+	name  string
+	lossy bool
+	queue chan func(w AuditRecordWriter) error
+	done  chan struct{}
+
+	// set once a lossy writer timed out, records are dropped without waiting until its queue has room again
+	tripped int32
+
+	numErrors  int64
+	numDropped int64
+}
+
+// lossyWriter marks a writer that may drop records.
+type lossyWriter struct {
+	AuditRecordWriter
+}
+
+// Lossy marks a writer passed to NewMultiWriter, for which records are dropped
+// if it does not keep up, e.g. a remote endpoint that is slow or unreachable.
+// All other writers slow down the multiWriter instead.
+func Lossy(w AuditRecordWriter) AuditRecordWriter {
+	return &lossyWriter{AuditRecordWriter: w}
+}
+
+// NewMultiWriter returns a writer that writes each audit record to all of the passed writers.
+// Each writer receives its own copy of the record, since some writers modify records, e.g. the timestamp for JSON.
+// A single writer is returned as is.
+func NewMultiWriter(writers ...AuditRecordWriter) AuditRecordWriter {
+	if len(writers) == 1 {
+		if l, ok := writers[0].(*lossyWriter); ok {
+			return l.AuditRecordWriter
+		}
+
+		return writers[0]
+	}
+
+	m := &multiWriter{}
+
+	for _, w := range writers {
+		s := &sink{
+			AuditRecordWriter: w,
+			queue:             make(chan func(w AuditRecordWriter) error, multiWriterQueueSize),
+			done:              make(chan struct{}),
+		}
+
+		if l, ok := w.(*lossyWriter); ok {
+			s.AuditRecordWriter = l.AuditRecordWriter
+			s.lossy = true
+		}
+
+		s.name = fmt.Sprintf("%T", s.AuditRecordWriter)
+
+		go s.run()
+
+		m.sinks = append(m.sinks, s)
+	}
+
+	return m
+}
+
+// run executes the queued operations until the queue is closed.
+func (s *sink) run() {
+	defer close(s.done)
+
+	for op := range s.queue {
+		if err := op(s.AuditRecordWriter); err != nil {
+			if atomic.AddInt64(&s.numErrors, 1) == 1 {
+				ioLog.Error("multi writer: write failed", zap.String("writer", s.name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// enqueue adds the operation to the queue of the sink.
+// If the queue is full, it blocks until there is room again,
+// or drops the operation for lossy writers that do not keep up within the drop timeout.
+func (s *sink) enqueue(op func(w AuditRecordWriter) error) {
+	if !s.lossy {
+		s.queue <- op
+
+		return
+	}
+
+	select {
+	case s.queue <- op:
+		atomic.StoreInt32(&s.tripped, 0)
+
+		return
+	default:
+	}
+
+	if atomic.LoadInt32(&s.tripped) == 0 {
+		timer := time.NewTimer(multiWriterDropTimeout)
+		defer timer.Stop()
+
+		select {
+		case s.queue <- op:
+			return
+		case <-timer.C:
+			atomic.StoreInt32(&s.tripped, 1)
+		}
+	}
+
+	if atomic.AddInt64(&s.numDropped, 1) == 1 {
+		ioLog.Warn("multi writer: queue is full, dropping audit records", zap.String("writer", s.name))
+	}
+}
+
+// Write passes a copy of the audit record to all writers.
+func (m *multiWriter) Write(msg proto.Message) error {
+	for _, s := range m.sinks {
+		c := proto.Clone(msg)
+
+		s.enqueue(func(w AuditRecordWriter) error {
+			return w.Write(c)
+		})
+	}
+
+	return nil
+}
+
+// WriteHeader writes the header for all writers.
+// The header is queued as well, to make sure it is written before the first record.
+func (m *multiWriter) WriteHeader(t types.Type) error {
+	for _, s := range m.sinks {
+		s.enqueue(func(w AuditRecordWriter) error {
+			return w.WriteHeader(t)
+		})
+	}
+
+	return nil
+}
+
+// Close waits until all queued records have been written, and closes the writers.
+// The name of the first writer that produced a file and the combined size is returned.
+func (m *multiWriter) Close(numRecords int64) (name string, size int64) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+
+	names := make([]string, len(m.sinks))
+
+	for i, s := range m.sinks {
+		wg.Add(1)
+
+		go func(i int, s *sink) {
+			defer wg.Done()
+
+			close(s.queue)
+			<-s.done
+
+			n, sz := s.AuditRecordWriter.Close(numRecords)
+			names[i] = n
+
+			mu.Lock()
+			size += sz
+			mu.Unlock()
+
+			if errs, dropped := atomic.LoadInt64(&s.numErrors), atomic.LoadInt64(&s.numDropped); errs > 0 || dropped > 0 {
+				ioLog.Warn("multi writer: not all audit records have been written",
+					zap.String("writer", s.name),
+					zap.Int64("errors", errs),
+					zap.Int64("dropped", dropped),
+				)
+			}
+		}(i, s)
+	}
+
+	wg.Wait()
+
+	for _, n := range names {
+		if n != "" {
+			return n, size
+		}
+	}
+
+	return "", size
+}
+
+// GetChan returns the channel of the first channel writer.
+func (m *multiWriter) GetChan() <-chan []byte {
+	for _, s := range m.sinks {
+		if cw, ok := s.AuditRecordWriter.(ChannelAuditRecordWriter); ok {
+			return cw.GetChan()
+		}
+	}
+
+	return nil
+}
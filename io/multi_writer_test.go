@@ -0,0 +1,139 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/dreadl0ck/netcap/io/iotest"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var errSinkFailed = errors.New("sink failed")
+
+// failingWriter fails all operations, and blocks writing until unblocked.
+type failingWriter struct {
+	block chan struct{}
+}
+
+func (w *failingWriter) Write(proto.Message) error {
+	<-w.block
+
+	return errSinkFailed
+}
+
+func (w *failingWriter) WriteHeader(types.Type) error {
+	return errSinkFailed
+}
+
+func (w *failingWriter) Close(int64) (string, int64) {
+	return "", 0
+}
+
+// slowWriter pauses regularly, so it falls behind the producer.
+type slowWriter struct {
+	iotest.RecordWriter
+}
+
+func (w *slowWriter) Write(msg proto.Message) error {
+	if len(w.Records)%1000 == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return w.RecordWriter.Write(msg)
+}
+
+func TestMultiWriter(t *testing.T) {
+	var (
+		records = &iotest.RecordWriter{}
+		failing = &failingWriter{block: make(chan struct{})}
+		w       = NewMultiWriter(Lossy(failing), records).(*multiWriter)
+		num     = multiWriterQueueSize + 10
+	)
+
+	multiWriterDropTimeout = 10 * time.Millisecond
+
+	defer func() {
+		multiWriterDropTimeout = time.Second
+	}()
+
+	if err := w.WriteHeader(types.Type_NC_TCP); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failing writer blocks, so its queue fills up and records are dropped for it, while the other writer keeps writing
+	for i := 0; i < num; i++ {
+		if err := w.Write(tcps[i%len(tcps)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(failing.block)
+
+	name, size := w.Close(int64(num))
+	if name != "records" || size != int64(num) {
+		t.Fatal("unexpected result from close", name, size)
+	}
+
+	if !records.Closed || records.Header != types.Type_NC_TCP || len(records.Records) != num {
+		t.Fatal("expected", num, "records, got", len(records.Records))
+	}
+
+	// each writer receives a copy of the record
+	if r := records.Records[0]; r == tcps[0] || !proto.Equal(r, tcps[0]) {
+		t.Fatal("unexpected record", r)
+	}
+
+	if s := w.sinks[0]; s.numDropped == 0 || s.numErrors == 0 {
+		t.Fatal("expected dropped records and errors for the failing writer", s.numDropped, s.numErrors)
+	}
+}
+
+func TestMultiWriterBackpressure(t *testing.T) {
+	var (
+		slow    = &slowWriter{}
+		records = &iotest.RecordWriter{}
+		w       = NewMultiWriter(slow, records)
+		num     = 2 * multiWriterQueueSize
+	)
+
+	// writers that are not lossy never drop records, even if they are slower than the producer
+	for i := 0; i < num; i++ {
+		if err := w.Write(tcps[i%len(tcps)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.Close(int64(num))
+
+	if len(slow.Records) != num || len(records.Records) != num {
+		t.Fatal("expected", num, "records, got", len(slow.Records), len(records.Records))
+	}
+}
+
+func TestNewMultiWriterSingle(t *testing.T) {
+	records := &iotest.RecordWriter{}
+
+	if w := NewMultiWriter(records); w != records {
+		t.Fatal("expected the writer to be returned as is")
+	}
+
+	if w := NewMultiWriter(Lossy(records)); w != records {
+		t.Fatal("expected the lossy writer to be unwrapped")
+	}
+}
@@ -151,6 +151,22 @@ func InitRecord(typ types.Type) (record proto.Message) {
 		record = new(types.Diameter)
 	case types.Type_NC_POP3:
 		record = new(types.POP3)
+	case types.Type_NC_FTP:
+		record = new(types.FTP)
+	case types.Type_NC_IMAP:
+		record = new(types.IMAP)
+	case types.Type_NC_TLS:
+		record = new(types.TLS)
+	case types.Type_NC_QUIC:
+		record = new(types.QUIC)
+	case types.Type_NC_SMB:
+		record = new(types.SMB)
+	case types.Type_NC_Kerberos:
+		record = new(types.Kerberos)
+	case types.Type_NC_Certificate:
+		record = new(types.Certificate)
+	case types.Type_NC_WebSocket:
+		record = new(types.WebSocket)
 	case types.Type_NC_TLSServerHello:
 		record = new(types.TLSServerHello)
 	case types.Type_NC_Software:
@@ -36,6 +36,7 @@ type protoWriter struct {
 
 	bWriter *bufio.Writer
 	gWriter *pgzip.Writer
+	cWriter *countingWriter
 	dWriter *delimited.Writer
 	pWriter *delimitedProtoWriter
 
@@ -72,10 +73,12 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 			// experiment: buffer -> pgzip
 			w.bWriter = bufio.NewWriterSize(w.gWriter, wc.MemBufferSize)
 			// experiment: delimited -> buffer
-			w.dWriter = delimited.NewWriter(w.bWriter)
+			w.cWriter = &countingWriter{w: w.bWriter}
+			w.dWriter = delimited.NewWriter(w.cWriter)
 		} else {
 			w.bWriter = bufio.NewWriterSize(w.file, wc.MemBufferSize)
-			w.dWriter = delimited.NewWriter(w.bWriter)
+			w.cWriter = &countingWriter{w: w.bWriter}
+			w.dWriter = delimited.NewWriter(w.cWriter)
 		}
 	} else {
 		if w.wc.Compress {
@@ -84,9 +87,11 @@ func newProtoWriter(wc *WriterConfig) *protoWriter {
 			if errGzipWriter != nil {
 				panic(errGzipWriter)
 			}
-			w.dWriter = delimited.NewWriter(w.gWriter)
+			w.cWriter = &countingWriter{w: w.gWriter}
+			w.dWriter = delimited.NewWriter(w.cWriter)
 		} else {
-			w.dWriter = delimited.NewWriter(w.file)
+			w.cWriter = &countingWriter{w: w.file}
+			w.dWriter = delimited.NewWriter(w.cWriter)
 		}
 	}
 
@@ -117,6 +122,11 @@ func (w *protoWriter) WriteHeader(t types.Type) error {
 	return w.pWriter.putProto(NewHeader(t, w.wc.Source, w.wc.Version, w.wc.IncludesPayloads, w.wc.StartTime))
 }
 
+// written returns the number of bytes written, before buffering and compression.
+func (w *protoWriter) written() int64 {
+	return w.cWriter.n
+}
+
 // Close flushes and closes the writer and the associated file handles.
 func (w *protoWriter) Close(numRecords int64) (name string, size int64) {
 	w.mu.Lock()
@@ -0,0 +1,411 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// RotationConfig configures the rotation of audit record files.
+type RotationConfig struct {
+	// RotateInterval starts a new file for each interval, the files are aligned to multiples of the interval in UTC
+	RotateInterval time.Duration
+
+	// RotateSize starts a new file once the given number of bytes has been written to the current one, before compression
+	RotateSize int64
+
+	// RotateHook is executed for each finished file, the file path is appended as the last argument.
+	// If it starts with unix: a JSON notification is sent to the unix socket at the given path instead
+	RotateHook string
+
+	// RetainAge removes rotated files that are older
+	RetainAge time.Duration
+
+	// RetainSize removes the oldest rotated files, once all rotated files of a type have more than the given number of bytes
+	RetainSize int64
+}
+
+// enabled returns true if files should be rotated.
+func (c RotationConfig) enabled() bool {
+	return c.RotateInterval > 0 || c.RotateSize > 0
+}
+
+var (
+	errRotateHookNoSocket  = errors.New("rotation hook: missing unix socket path")
+	errRotateHookNoCommand = errors.New("rotation hook: missing command")
+)
+
+// Validate returns an error if the rotation hook can not be run.
+func (c RotationConfig) Validate() error {
+	if c.RotateHook == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(c.RotateHook, hookPrefixUnix) {
+		if strings.TrimPrefix(c.RotateHook, hookPrefixUnix) == "" {
+			return errRotateHookNoSocket
+		}
+
+		return nil
+	}
+
+	if len(strings.Fields(c.RotateHook)) == 0 {
+		return errRotateHookNoCommand
+	}
+
+	return nil
+}
+
+// prefix for rotation hooks that notify a unix socket.
+const hookPrefixUnix = "unix:"
+
+// sizeCounter is implemented by the file writers, to check the size of the current file without flushing the buffers.
+type sizeCounter interface {
+	written() int64
+}
+
+// rotationNotification is sent to the unix socket hook for each finished file.
+type rotationNotification struct {
+	File    string `json:"file"`
+	Type    string `json:"type"`
+	Records int64  `json:"records"`
+	Size    int64  `json:"size"`
+}
+
+// rotatingWriter writes audit records to a series of files,
+// a new file is started for each interval or once the size limit has been reached.
+// Each file starts with its own header.
+type rotatingWriter struct {
+	mu sync.Mutex
+
+	wc     *WriterConfig
+	ext    string
+	create func(wc *WriterConfig) AuditRecordWriter
+
+	// currently open file
+	current    AuditRecordWriter
+	path       string
+	numRecords int64
+
+	// header type, set once WriteHeader has been called
+	header    types.Type
+	hasHeader bool
+
+	// name and combined size of all finished files
+	lastName  string
+	totalSize int64
+
+	stop   chan struct{}
+	closed bool
+	hooks  sync.WaitGroup
+}
+
+// newRotatingWriter returns the file based writer created by create,
+// wrapped for rotation if enabled in the config. The extension of the created files is used to find rotated files.
+func newRotatingWriter(wc *WriterConfig, ext string, create func(wc *WriterConfig) AuditRecordWriter) AuditRecordWriter {
+	if !wc.RotationConfig.enabled() {
+		return create(wc)
+	}
+
+	if wc.Compress {
+		ext += ".gz"
+	}
+
+	w := &rotatingWriter{
+		wc:     wc,
+		ext:    ext,
+		create: create,
+		stop:   make(chan struct{}),
+	}
+
+	w.open(time.Now())
+
+	if wc.RotateInterval > 0 {
+		go w.rotateEveryInterval()
+	}
+
+	return w
+}
+
+// rotateEveryInterval rotates the file at the end of each interval,
+// so files are finished even if no records are written.
+func (w *rotatingWriter) rotateEveryInterval() {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(w.wc.RotateInterval).Add(w.wc.RotateInterval).Sub(now))
+
+		select {
+		case <-w.stop:
+			timer.Stop()
+
+			return
+		case t := <-timer.C:
+			w.mu.Lock()
+			if !w.closed {
+				w.rotate(t)
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Write writes the record to the current file, and rotates it once the size limit has been reached.
+func (w *rotatingWriter) Write(msg proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.current.Write(msg)
+	w.numRecords++
+
+	if c, ok := w.current.(sizeCounter); ok && w.wc.RotateSize > 0 && c.written() >= w.wc.RotateSize {
+		w.rotate(time.Now())
+	}
+
+	return err
+}
+
+// WriteHeader writes the header to the current file, and to each file that is created later on.
+func (w *rotatingWriter) WriteHeader(t types.Type) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.header = t
+	w.hasHeader = true
+
+	return w.current.WriteHeader(t)
+}
+
+// Close closes the current file, and returns the name of the last file and the size of all files.
+func (w *rotatingWriter) Close(_ int64) (name string, size int64) {
+	if w.wc.RotateInterval > 0 {
+		close(w.stop)
+	}
+
+	w.mu.Lock()
+	w.finish()
+	w.closed = true
+	w.mu.Unlock()
+
+	// wait for the hooks to complete, before the program exits
+	w.hooks.Wait()
+
+	return w.lastName, w.totalSize
+}
+
+// rotate finishes the current file and opens a new one.
+// CAUTION: the writer needs to be locked when calling this.
+func (w *rotatingWriter) rotate(t time.Time) {
+	w.finish()
+	w.open(t)
+
+	if w.hasHeader {
+		if err := w.current.WriteHeader(w.header); err != nil {
+			ioLog.Error("failed to write header to rotated file", zap.String("path", w.path), zap.Error(err))
+		}
+	}
+}
+
+// open creates the writer for a new file.
+// CAUTION: the writer needs to be locked when calling this.
+func (w *rotatingWriter) open(t time.Time) {
+	c := *w.wc
+	c.Name = w.fileName(t.UTC())
+	c.StartTime = t
+
+	w.current = w.create(&c)
+	w.path = filepath.Join(c.Out, c.Name) + w.ext
+	w.numRecords = 0
+
+	w.applyRetention()
+}
+
+// finish closes the current file, and runs the hook for it.
+// CAUTION: the writer needs to be locked when calling this.
+func (w *rotatingWriter) finish() {
+	name, size := w.current.Close(w.numRecords)
+
+	ioLog.Info("finished audit record file",
+		zap.String("path", w.path),
+		zap.Int64("records", w.numRecords),
+		zap.Int64("size", size),
+	)
+
+	// empty files are removed on close
+	if w.numRecords == 0 {
+		return
+	}
+
+	w.lastName = name
+	w.totalSize += size
+
+	if w.wc.RotateHook != "" {
+		w.hooks.Add(1)
+
+		go w.runHook(rotationNotification{
+			File:    w.path,
+			Type:    w.wc.Type.String(),
+			Records: w.numRecords,
+			Size:    size,
+		})
+	}
+}
+
+// fileName returns a name for a file created at t, that is not in use yet.
+// The timestamp has the precision of the rotation interval, e.g. Connection-2026-10-18T14 for hourly rotation.
+func (w *rotatingWriter) fileName(t time.Time) string {
+	layout := "2006-01-02T15-04-05"
+
+	switch i := w.wc.RotateInterval; {
+	case i == 0:
+	case i%(24*time.Hour) == 0:
+		layout = "2006-01-02"
+	case i%time.Hour == 0:
+		layout = "2006-01-02T15"
+	case i%time.Minute == 0:
+		layout = "2006-01-02T15-04"
+	}
+
+	var (
+		base = w.wc.Name + "-" + t.Format(layout)
+		name = base
+	)
+
+	// files rotated by size within the same interval get a sequence number
+	for n := 1; ; n++ {
+		if _, err := os.Stat(filepath.Join(w.wc.Out, name) + w.ext); os.IsNotExist(err) {
+			return name
+		}
+
+		name = base + "_" + strconv.Itoa(n)
+	}
+}
+
+// runHook executes the command or sends the notification to the unix socket configured as hook.
+func (w *rotatingWriter) runHook(n rotationNotification) {
+	defer w.hooks.Done()
+
+	if strings.HasPrefix(w.wc.RotateHook, hookPrefixUnix) {
+		path := strings.TrimPrefix(w.wc.RotateHook, hookPrefixUnix)
+
+		conn, err := net.DialTimeout(networkTypeUnix, path, time.Second)
+		if err != nil {
+			ioLog.Error("failed to connect to rotation hook socket", zap.String("socket", path), zap.Error(err))
+
+			return
+		}
+
+		defer conn.Close()
+
+		if err = json.NewEncoder(conn).Encode(n); err != nil {
+			ioLog.Error("failed to send rotation notification", zap.String("socket", path), zap.Error(err))
+		}
+
+		return
+	}
+
+	// the hook is validated when the configuration is loaded, see RotationConfig.Validate
+	args := strings.Fields(w.wc.RotateHook)
+	if len(args) == 0 {
+		ioLog.Error("rotation hook has no command", zap.String("hook", w.wc.RotateHook))
+
+		return
+	}
+
+	out, err := exec.Command(args[0], append(args[1:], n.File)...).CombinedOutput()
+	if err != nil {
+		ioLog.Error("rotation hook failed",
+			zap.String("hook", w.wc.RotateHook),
+			zap.String("file", n.File),
+			zap.ByteString("output", out),
+			zap.Error(err),
+		)
+	}
+}
+
+// applyRetention removes rotated files of the same type that are older than the maximum age,
+// and the oldest files once the total size exceeds the maximum size. The current file is never removed.
+// CAUTION: the writer needs to be locked when calling this.
+func (w *rotatingWriter) applyRetention() {
+	if w.wc.RetainAge == 0 && w.wc.RetainSize == 0 {
+		return
+	}
+
+	files, err := w.rotatedFiles()
+	if err != nil {
+		ioLog.Error("failed to list rotated files", zap.Error(err))
+
+		return
+	}
+
+	// newest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	var total int64
+
+	for _, f := range files {
+		path := filepath.Join(w.wc.Out, f.Name())
+		if path == w.path {
+			continue
+		}
+
+		total += f.Size()
+
+		if (w.wc.RetainAge > 0 && time.Since(f.ModTime()) > w.wc.RetainAge) ||
+			(w.wc.RetainSize > 0 && total > w.wc.RetainSize) {
+			ioLog.Info("removing rotated file", zap.String("path", path))
+
+			if err = os.Remove(path); err != nil {
+				ioLog.Error("failed to remove rotated file", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}
+}
+
+// rotatedFiles returns the files created for the type of the writer, which begin with the name, a dash and a timestamp.
+func (w *rotatingWriter) rotatedFiles() ([]os.FileInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(w.wc.Out, w.wc.Name) + "-[0-9]*" + w.ext)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(matches))
+
+	for _, m := range matches {
+		s, errStat := os.Stat(m)
+		if errStat != nil {
+			continue
+		}
+
+		files = append(files, s)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,255 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package io
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/defaults"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	// collect the notifications sent by the rotation hook
+	socket := filepath.Join(dir, "hook.sock")
+
+	l, err := net.Listen(networkTypeUnix, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	notifications := make(chan rotationNotification)
+
+	go func() {
+		for {
+			conn, errAccept := l.Accept()
+			if errAccept != nil {
+				return
+			}
+
+			var n rotationNotification
+			if errDecode := json.NewDecoder(bufio.NewReader(conn)).Decode(&n); errDecode == nil {
+				notifications <- n
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	w := newProtoFileWriter(&WriterConfig{
+		Proto:         true,
+		Name:          "TCP",
+		Type:          types.Type_NC_TCP,
+		Out:           dir,
+		Buffer:        true,
+		MemBufferSize: defaults.BufferSize,
+		RotationConfig: RotationConfig{
+			// every record exceeds the size limit, while it is still buffered
+			RotateSize: 1,
+			RotateHook: hookPrefixUnix + socket,
+		},
+	})
+
+	if err = w.WriteHeader(types.Type_NC_TCP); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+
+	for _, tcp := range tcps {
+		if err = w.Write(tcp); err != nil {
+			t.Fatal(err)
+		}
+
+		n := <-notifications
+		if n.Type != "NC_TCP" || n.Records != 1 || n.Size == 0 {
+			t.Fatal("unexpected notification", n)
+		}
+
+		files = append(files, n.File)
+	}
+
+	// the last file does not contain records and is removed
+	if _, size := w.Close(int64(len(tcps))); size == 0 {
+		t.Fatal("no bytes written")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "TCP-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != len(tcps) {
+		t.Fatal("expected", len(tcps), "files, got", matches)
+	}
+
+	// files created within the same second get a sequence number
+	base := filepath.Base(files[0])
+	if len(base) != len("TCP-2006-01-02T15-04-05.ncap") || filepath.Ext(base) != defaults.FileExtension {
+		t.Fatal("unexpected file name", base)
+	}
+
+	for i, f := range files {
+		r, errOpen := Open(f, defaults.BufferSize)
+		if errOpen != nil {
+			t.Fatal(errOpen)
+		}
+
+		header, errHeader := r.ReadHeader()
+		if errHeader != nil || header.Type != types.Type_NC_TCP {
+			t.Fatal("unexpected header", header, errHeader)
+		}
+
+		tcp := &types.TCP{}
+		if err = r.Next(tcp); err != nil || tcp.SeqNum != tcps[i].SeqNum {
+			t.Fatal("unexpected record", tcp, err)
+		}
+
+		if err = r.Next(tcp); !errors.Is(err, io.EOF) {
+			t.Fatal("expected a single record, got", err)
+		}
+
+		_ = r.Close()
+	}
+}
+
+func TestRotationConfigValidate(t *testing.T) {
+	for hook, expected := range map[string]error{
+		"":                      nil,
+		"gzip -9":               nil,
+		"unix:/run/netcap.sock": nil,
+		" \t ":                  errRotateHookNoCommand,
+		hookPrefixUnix:          errRotateHookNoSocket,
+	} {
+		if err := (RotationConfig{RotateHook: hook}).Validate(); !errors.Is(err, expected) {
+			t.Fatal("expected", expected, "for hook", hook, "got", err)
+		}
+	}
+}
+
+func TestRotationFileName(t *testing.T) {
+	ts := time.Date(2026, 10, 18, 14, 30, 15, 0, time.UTC)
+
+	for interval, expected := range map[time.Duration]string{
+		0:               "Connection-2026-10-18T14-30-15",
+		24 * time.Hour:  "Connection-2026-10-18",
+		time.Hour:       "Connection-2026-10-18T14",
+		6 * time.Hour:   "Connection-2026-10-18T14",
+		5 * time.Minute: "Connection-2026-10-18T14-30",
+		time.Second:     "Connection-2026-10-18T14-30-15",
+	} {
+		w := &rotatingWriter{
+			wc: &WriterConfig{
+				Name: "Connection",
+				Out:  os.TempDir(),
+				RotationConfig: RotationConfig{
+					RotateInterval: interval,
+				},
+			},
+			ext: ".test",
+		}
+
+		if name := w.fileName(ts); name != expected {
+			t.Fatal("expected", expected, "for interval", interval, "got", name)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+
+	for i, name := range []string{
+		"TCP-2026-10-18T10.ncap.gz",
+		"TCP-2026-10-18T11.ncap.gz",
+		"TCP-2026-10-18T12.ncap.gz",
+		"TCP-2026-10-18T13.ncap.gz",
+		// not affected: different type and extension
+		"TCPX-2026-10-18T10.ncap.gz",
+		"TCP-2026-10-18T10.csv.gz",
+	} {
+		path := filepath.Join(dir, name)
+
+		if err = ioutil.WriteFile(path, make([]byte, 100), defaults.FilePermission); err != nil {
+			t.Fatal(err)
+		}
+
+		mod := now.Add(time.Duration(i-4) * time.Hour)
+		if err = os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &rotatingWriter{
+		wc: &WriterConfig{
+			Name: "TCP",
+			Out:  dir,
+			RotationConfig: RotationConfig{
+				RetainAge:  210 * time.Minute,
+				RetainSize: 150,
+			},
+		},
+		ext:  defaults.FileExtensionCompressed,
+		path: filepath.Join(dir, "TCP-2026-10-18T13.ncap.gz"),
+	}
+
+	w.applyRetention()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file at 10 is too old, the one at 11 exceeds the size limit, the current file is kept
+	expected := []string{
+		filepath.Join(dir, "TCP-2026-10-18T10.csv.gz"),
+		filepath.Join(dir, "TCP-2026-10-18T12.ncap.gz"),
+		filepath.Join(dir, "TCP-2026-10-18T13.ncap.gz"),
+		filepath.Join(dir, "TCPX-2026-10-18T10.ncap.gz"),
+	}
+
+	if len(files) != len(expected) {
+		t.Fatal("expected", expected, "got", files)
+	}
+
+	for i := range files {
+		if files[i] != expected[i] {
+			t.Fatal("expected", expected, "got", files)
+		}
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/dreadl0ck/netcap"
+	"github.com/dreadl0ck/netcap/filter"
 	"github.com/dreadl0ck/netcap/types"
 	"github.com/dreadl0ck/netcap/utils"
 )
@@ -95,6 +96,9 @@ type DumpConfig struct {
 	Path          string
 	Separator     string
 	Selection     string
+	Filter        string
+	Since         time.Time
+	Until         time.Time
 	MemBufferSize int
 	JSON          bool
 	Table         bool
@@ -136,6 +140,11 @@ func Dump(w *os.File, c DumpConfig) error {
 		return errFileHeader
 	}
 
+	f, errFilter := filter.New(c.Filter, c.Since, c.Until)
+	if errFilter != nil {
+		return fmt.Errorf("invalid filter: %w", errFilter)
+	}
+
 	var (
 		record = InitRecord(header.Type)
 		// rows for table print
@@ -170,9 +179,14 @@ func Dump(w *os.File, c DumpConfig) error {
 		} else if err != nil {
 			return fmt.Errorf("failed to read next audit record: %w", err)
 		}
-		count++
 
 		if p, ok := record.(types.AuditRecord); ok {
+			// skip records that do not match the filter
+			if !f.Match(p) {
+				continue
+			}
+
+			count++
 
 			// JSON
 			if c.JSON {
@@ -17,6 +17,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/gogo/protobuf/proto"
 
+	"github.com/dreadl0ck/netcap/defaults"
 	"github.com/dreadl0ck/netcap/types"
 )
 
@@ -35,16 +36,33 @@ type ChannelAuditRecordWriter interface {
 }
 
 // NewAuditRecordWriter will return a new writer for netcap audit records.
+// If anomaly detectors are registered, the audit records will be passed to them before being written.
 func NewAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
+	w := newAuditRecordWriter(wc)
+
+	// alerts are not analyzed, to avoid feedback loops between detectors
+	if types.HasAnomalyDetectors() && wc.Type != types.Type_NC_Alert {
+		return newAnalyzeWriter(w)
+	}
+
+	return w
+}
+
+// newAuditRecordWriter returns the writer for the output format selected in the config.
+func newAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
+	if wc.FanOut {
+		return newFanOutWriter(wc)
+	}
+
 	switch {
 	case wc.UnixSocket:
 		return newUnixSocketWriter(wc)
 	case wc.CSV:
-		return newCSVWriter(wc)
+		return newCSVFileWriter(wc)
 	case wc.Chan:
 		return newChanWriter(wc)
 	case wc.JSON:
-		return newJSONWriter(wc)
+		return newJSONFileWriter(wc)
 	case wc.Null:
 		return newNullWriter(wc)
 	case wc.Elastic:
@@ -52,7 +70,7 @@ func NewAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
 
 	// proto is the default, so this option should be checked last to allow overwriting it
 	case wc.Proto:
-		return newProtoWriter(wc)
+		return newProtoFileWriter(wc)
 	default:
 		spew.Dump(wc)
 		panic("invalid WriterConfig")
@@ -60,3 +78,46 @@ func NewAuditRecordWriter(wc *WriterConfig) AuditRecordWriter {
 
 	return nil //nolint:govet // stop complaining that this is unreachable
 }
+
+// newFanOutWriter returns a writer for all output formats enabled in the config.
+func newFanOutWriter(wc *WriterConfig) AuditRecordWriter {
+	var writers []AuditRecordWriter
+
+	// each writer gets its own copy of the config
+	add := func(enabled bool, create func(wc *WriterConfig) AuditRecordWriter) {
+		if enabled {
+			c := *wc
+			writers = append(writers, create(&c))
+		}
+	}
+
+	add(wc.UnixSocket, func(c *WriterConfig) AuditRecordWriter { return newUnixSocketWriter(c) })
+	add(wc.CSV, newCSVFileWriter)
+	add(wc.Chan, func(c *WriterConfig) AuditRecordWriter { return newChanWriter(c) })
+	add(wc.JSON, newJSONFileWriter)
+	add(wc.Null, func(c *WriterConfig) AuditRecordWriter { return newNullWriter(c) })
+	add(wc.Elastic, func(c *WriterConfig) AuditRecordWriter { return Lossy(newElasticWriter(c)) })
+	add(wc.Proto, newProtoFileWriter)
+
+	if len(writers) == 0 {
+		spew.Dump(wc)
+		panic("invalid WriterConfig")
+	}
+
+	return NewMultiWriter(writers...)
+}
+
+// newCSVFileWriter returns a CSV writer, which rotates files if configured.
+func newCSVFileWriter(wc *WriterConfig) AuditRecordWriter {
+	return newRotatingWriter(wc, ".csv", func(c *WriterConfig) AuditRecordWriter { return newCSVWriter(c) })
+}
+
+// newJSONFileWriter returns a JSON writer, which rotates files if configured.
+func newJSONFileWriter(wc *WriterConfig) AuditRecordWriter {
+	return newRotatingWriter(wc, ".json", func(c *WriterConfig) AuditRecordWriter { return newJSONWriter(c) })
+}
+
+// newProtoFileWriter returns a protobuf writer, which rotates files if configured.
+func newProtoFileWriter(wc *WriterConfig) AuditRecordWriter {
+	return newRotatingWriter(wc, defaults.FileExtension, func(c *WriterConfig) AuditRecordWriter { return newProtoWriter(c) })
+}
@@ -50,6 +50,13 @@ type WriterConfig struct {
 	// The Null writer will write nothing to disk and discard all data.
 	Null bool
 
+	// RotationConfig configures the rotation of files written by the CSV, JSON and protobuf writers
+	RotationConfig
+
+	// FanOut writes the audit records to all enabled writer types,
+	// instead of only the first one in the order of precedence.
+	FanOut bool
+
 	// Netcap header information
 	Name          string
 	Type          types.Type
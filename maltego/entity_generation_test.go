@@ -114,6 +114,7 @@ var maltegoEntities = []maltego.EntityCoreInfo{
 	{"Connection", "compare_arrows", "A bidirectional network connection", "", []*maltego.PropertyField{maltego.NewStringField("path", "path to the audit records on disk")}},
 	{"TLSClientHello", "call_made", "A TLS Client", "", []*maltego.PropertyField{maltego.NewStringField("path", "path to the audit records on disk")}},
 	{"TLSServerHello", "call_received", "A TLS Server", "", []*maltego.PropertyField{maltego.NewStringField("path", "path to the audit records on disk")}},
+	{"Certificate", "verified_user", "A X.509 certificate presented by a TLS server", "", []*maltego.PropertyField{maltego.NewStringField("path", "path to the audit records on disk"), maltego.NewStringField("fingerprint", "SHA256 fingerprint of the certificate")}},
 }
 
 // generate all entities and pack as archive
@@ -108,6 +108,9 @@ var transforms = []*maltego.TransformCoreInfo{
 	{"ToIPProfilesForSoftware", "netcap.Software", "Show all ip hosts for the selected software"},
 	{"ToJA3Hashes", "netcap.TLSClientHelloAuditRecords", "Show all discovered ja3 client hashes"},
 	{"ToJA3SHashes", "netcap.TLSServerHelloAuditRecords", "Show all discovered ja3 server hashes"},
+	{"ToCertificates", "netcap.IPAddr", "Show the TLS certificates presented by the selected server"},
+	{"ToHostsForCertificate", "netcap.Certificate", "Show all servers that presented the selected certificate"},
+	{"ToExpiredCertificates", "netcap.CertificateAuditRecords", "Show all expired certificates presented by servers"},
 	{"ToSMTPCommandTypes", "netcap.SMTPAuditRecords", "Show all SMTP command types"},
 	{"ToDNSOpCodes", "netcap.DNSAuditRecords", "Show all DNS op codes"},
 
@@ -42,6 +42,10 @@ type assemblerOptions struct {
 	// particular connection, the smallest sequence number will be flushed, along
 	// with any contiguous data.  If <= 0, this is ignored.
 	MaxBufferedPagesPerConnection int
+	// BufferLimitReached is consulted before out-of-order data is buffered,
+	// if it returns true the assembler degrades to flushing the connection,
+	// as if MaxBufferedPagesTotal has been reached.  If nil, this is ignored.
+	BufferLimitReached func() bool
 }
 
 // Assembler handles reassembling TCP streams.  It is not safe for
@@ -559,7 +563,8 @@ func (a *Assembler) handleBytes(bytes []byte, seq Sequence, half *halfconnection
 		a.checkOverlap(half, true, ac)
 
 		if (a.MaxBufferedPagesPerConnection > 0 && half.pages >= a.MaxBufferedPagesPerConnection) ||
-			(a.MaxBufferedPagesTotal > 0 && a.pc.used >= a.MaxBufferedPagesTotal) {
+			(a.MaxBufferedPagesTotal > 0 && a.pc.used >= a.MaxBufferedPagesTotal) ||
+			(a.BufferLimitReached != nil && a.BufferLimitReached()) {
 			if Debug {
 				log.Printf("hit max buffer size: %+v, %v, %v", a.assemblerOptions, half.pages, a.pc.used)
 			}
@@ -8,9 +8,19 @@ package reassembly
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 )
 
+// usedPages is the number of pages in use by all page caches.
+var usedPages int64
+
+// BufferedBytes returns the number of bytes in the pages used by all assemblers
+// to buffer out-of-order data.
+func BufferedBytes() int64 {
+	return atomic.LoadInt64(&usedPages) * pageBytes
+}
+
 /*
  * pageCache
  */
@@ -90,6 +100,7 @@ func (c *pageCache) next(ts time.Time) (p *page) {
 	p.seen = ts
 	p.bytes = p.buf[:0]
 	c.used++
+	atomic.AddInt64(&usedPages, 1)
 	if Debug {
 		log.Printf("allocator returns %s\n", p)
 	}
@@ -105,6 +116,7 @@ func (c *pageCache) next(ts time.Time) (p *page) {
 // replace replaces a page into the pageCache.
 func (c *pageCache) replace(p *page) {
 	c.used--
+	atomic.AddInt64(&usedPages, -1)
 	if Debug {
 		log.Printf("replacing %s\n", p)
 	}
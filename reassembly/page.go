@@ -10,6 +10,9 @@ import (
 
 const pageBytes = 1900
 
+// PageSize is the number of bytes that can be stored in a page.
+const PageSize = pageBytes
+
 /* page: implements a byteContainer */
 
 // page is used to store TCP data we're not ready for yet (out-of-order
@@ -324,6 +324,70 @@ func TestMaxPerSkip(t *testing.T) {
 	})
 }
 
+func TestBufferLimitReached(t *testing.T) {
+	fact := &testFactory{}
+	a := NewAssembler(NewStreamPool(fact))
+
+	used := BufferedBytes()
+	a.BufferLimitReached = func() bool {
+		// the page for the current segment has already been allocated when this is called
+		return BufferedBytes()-used > PageSize
+	}
+
+	for i, testSeq := range []testSequence{
+		{
+			in: layers.TCP{
+				SrcPort:   1,
+				DstPort:   2,
+				Seq:       1000,
+				SYN:       true,
+				BaseLayer: layers.BaseLayer{Payload: []byte{1, 2, 3}},
+			},
+			want: []Reassembly{
+				{
+					Start: true,
+					Bytes: []byte{1, 2, 3},
+				},
+			},
+		},
+		{
+			in: layers.TCP{
+				SrcPort:   1,
+				DstPort:   2,
+				Seq:       1007,
+				BaseLayer: layers.BaseLayer{Payload: []byte{3, 2, 3}},
+			},
+			want: []Reassembly{},
+		},
+		{
+			// the second page exceeds the limit, so the connection is flushed instead of buffering more data
+			in: layers.TCP{
+				SrcPort:   1,
+				DstPort:   2,
+				Seq:       1010,
+				BaseLayer: layers.BaseLayer{Payload: []byte{4, 2, 3}},
+			},
+			want: []Reassembly{
+				{
+					Skip:  3,
+					Bytes: []byte{3, 2, 3, 4, 2, 3},
+				},
+			},
+		},
+	} {
+		fact.reassembly = []Reassembly{}
+		a.assemble(netFlow, &testSeq.in)
+
+		if !reflect.DeepEqual(fact.reassembly, testSeq.want) {
+			t.Fatalf("test %v:\nwant: %v\n got: %v\n", i, testSeq.want, fact.reassembly)
+		}
+	}
+
+	if n := BufferedBytes() - used; n != 0 {
+		t.Fatal("expected all pages to be released, got", n, "bytes")
+	}
+}
+
 func TestReorderFast(t *testing.T) {
 	test(t, []testSequence{
 		{
@@ -0,0 +1,184 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dreadl0ck/netcap/filter"
+	"github.com/dreadl0ck/netcap/resolvers"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+// operators that are evaluated by the rule engine,
+// all other operators are provided by the filter package.
+const (
+	opTimeOfDay = "time-of-day"
+	opCountry   = "country"
+)
+
+var (
+	errMissingField = errors.New("condition has no field")
+	errInvalidValue = errors.New("invalid value")
+)
+
+// Condition is a single predicate that is evaluated against a field of an audit record.
+type Condition struct {
+
+	// Field name of the audit record, nested fields can be addressed with a dot, e.g. Context.SrcIP
+	Field string `yaml:"field"`
+
+	// Op is the comparison operator
+	Op string `yaml:"op"`
+
+	// Value to compare against
+	Value string `yaml:"value"`
+
+	// Not negates the result of the condition
+	Not bool `yaml:"not"`
+
+	// compiled values
+	list      map[string]struct{}
+	startMin  int
+	endMin    int
+	location  *time.Location
+	predicate func(v reflect.Value) bool
+}
+
+// compile parses the value of the condition according to the operator.
+func (c *Condition) compile(loc *time.Location) error {
+	if c.Field == "" {
+		return errMissingField
+	}
+
+	c.location = loc
+
+	switch c.Op {
+	case opTimeOfDay:
+		var err error
+
+		c.startMin, c.endMin, err = parseTimeRange(c.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Field, err)
+		}
+
+		c.predicate = c.timeOfDay
+	case opCountry:
+		// evaluated in match, since unknown locations must not match when the condition is negated
+		c.list = parseList(strings.ToUpper(c.Value))
+	default:
+		var err error
+
+		c.predicate, err = filter.Predicate(c.Op, c.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Field, err)
+		}
+	}
+
+	return nil
+}
+
+// match evaluates the condition against the audit record.
+// A condition never matches if the record does not have the requested field,
+// or if the location of an address is unknown for the country operator, even if it is negated.
+func (c *Condition) match(record types.AuditRecord) bool {
+	v, ok := filter.FieldValue(record, c.Field)
+	if !ok {
+		return false
+	}
+
+	if c.Op == opCountry {
+		code := countryCode(v)
+		if code == "" {
+			return false
+		}
+
+		_, ok = c.list[code]
+
+		return ok != c.Not
+	}
+
+	return c.predicate(v) != c.Not
+}
+
+// countryCode returns the ISO code of the country an address geolocates to,
+// or an empty string if the address is unknown or no GeoLite database has been loaded.
+func countryCode(v reflect.Value) string {
+	geo, _ := resolvers.LookupGeolocation(filter.ToString(v))
+
+	// geolocation is formatted as: ISO code (City), the code is missing for unknown countries
+	if geo == "" || strings.HasPrefix(geo, " ") {
+		return ""
+	}
+
+	return strings.Fields(geo)[0]
+}
+
+// timeOfDay checks whether a timestamp field is within the configured time range.
+// Ranges that span midnight, e.g. 22:00-06:00, are supported.
+func (c *Condition) timeOfDay(v reflect.Value) bool {
+	f, ok := filter.ToFloat(v)
+	if !ok {
+		return false
+	}
+
+	var (
+		t   = time.Unix(0, int64(f)).In(c.location)
+		min = t.Hour()*60 + t.Minute()
+	)
+
+	if c.startMin <= c.endMin {
+		return min >= c.startMin && min < c.endMin
+	}
+
+	return min >= c.startMin || min < c.endMin
+}
+
+// parseTimeRange parses a time range in the format 08:00-18:00
+// and returns the start and end as minutes of the day.
+func parseTimeRange(s string) (start, end int, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%w: expected time range like 08:00-18:00, got %q", errInvalidValue, s)
+	}
+
+	startTime, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	endTime, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return startTime.Hour()*60 + startTime.Minute(), endTime.Hour()*60 + endTime.Minute(), nil
+}
+
+// parseList parses a comma separated list of values into a set.
+func parseList(s string) map[string]struct{} {
+	m := make(map[string]struct{})
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			m[v] = struct{}{}
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,177 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package rule
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/filter"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var ruleLog = zap.NewNop()
+
+// SetLogger sets the logger for the rule package.
+func SetLogger(l *zap.Logger) {
+	ruleLog = l
+}
+
+// Engine evaluates a set of rules against audit records
+// and implements the types.AnomalyDetector interface.
+type Engine struct {
+
+	// rules mapped to the audit record type they apply to
+	rules map[types.Type][]*Rule
+
+	// rules that apply to all audit records
+	all []*Rule
+}
+
+// Load reads the YAML rule configuration at path and returns a new Engine.
+func Load(path string) (*Engine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses a YAML rule configuration and returns a new Engine.
+func Parse(data []byte) (*Engine, error) {
+	var conf = new(Config)
+
+	err := yaml.UnmarshalStrict(data, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEngine(conf.Rules...)
+}
+
+// NewEngine compiles the given rules and returns a new Engine.
+func NewEngine(rules ...*Rule) (*Engine, error) {
+	e := &Engine{
+		rules: make(map[types.Type][]*Rule),
+	}
+
+	for _, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+
+		if r.ApplyToAllTypes {
+			e.all = append(e.all, r)
+		} else {
+			e.rules[r.Typ] = append(e.rules[r.Typ], r)
+		}
+	}
+
+	return e, nil
+}
+
+// NumRules returns the number of loaded rules.
+func (e *Engine) NumRules() int {
+	n := len(e.all)
+	for _, r := range e.rules {
+		n += len(r)
+	}
+
+	return n
+}
+
+// Name returns the name of the detector.
+func (e *Engine) Name() string {
+	return "Rules"
+}
+
+// Types returns the audit record types that rules have been loaded for.
+// If a rule applies to all audit records, nil is returned.
+func (e *Engine) Types() []types.Type {
+	if len(e.all) > 0 {
+		return nil
+	}
+
+	typs := make([]types.Type, 0, len(e.rules))
+	for t := range e.rules {
+		typs = append(typs, t)
+	}
+
+	return typs
+}
+
+// Analyze evaluates all rules that apply to the type of the audit record.
+func (e *Engine) Analyze(record types.AuditRecord) {
+	for _, r := range e.rules[record.NetcapType()] {
+		e.apply(r, record)
+	}
+
+	for _, r := range e.all {
+		e.apply(r, record)
+	}
+}
+
+func (e *Engine) apply(r *Rule, record types.AuditRecord) {
+	ok, err := r.Apply(record)
+	if err != nil {
+		ruleLog.Error("failed to execute rule action",
+			zap.String("rule", r.Name),
+			zap.Error(err),
+		)
+
+		return
+	}
+
+	if ok {
+		ruleLog.Debug("rule matched",
+			zap.String("rule", r.Name),
+			zap.String("type", record.NetcapType().String()),
+		)
+	}
+}
+
+// writeAlert is the default action for rules and emits an alert.
+func writeAlert(r *Rule, record types.AuditRecord) error {
+	return alert.Emit(&types.Alert{
+		Timestamp:   record.Time(),
+		Name:        r.Name,
+		Description: r.Description,
+		SrcIP:       lookupString(record, record.Src(), "SrcIP", "ClientIP"),
+		SrcPort:     lookupString(record, "", "SrcPort"),
+		DstIP:       lookupString(record, record.Dst(), "DstIP", "ServerIP"),
+		DstPort:     lookupString(record, "", "DstPort"),
+		MITRE:       r.MITRE,
+		Protocol:    strings.TrimPrefix(record.NetcapType().String(), "NC_"),
+		CommunityID: lookupString(record, "", "CommunityID"),
+	})
+}
+
+// lookupString returns the value of the first named field that is present and not empty,
+// or the default value if none is found.
+func lookupString(record types.AuditRecord, def string, names ...string) string {
+	for _, n := range names {
+		if v, ok := filter.FieldValue(record, n); ok {
+			if s := filter.ToString(v); s != "" && s != "0" {
+				return s
+			}
+		}
+	}
+
+	return def
+}
@@ -11,13 +11,17 @@
  * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
  */
 
+// Package rule implements a simple rule engine, that evaluates user defined rules against audit records
+// and emits alerts when a rule applies.
 package rule
 
 import (
-	"github.com/dreadl0ck/netcap/types"
-	"net"
-	"regexp"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/dreadl0ck/netcap/types"
 )
 
 // Suspicious activity / normal usage violation
@@ -35,60 +39,153 @@ import (
 //   - HTTP host is IP address and not domain
 //   - shell commands in URL params
 
+var (
+	errMissingName = errors.New("rule has no name")
+	errInvalidType = errors.New("invalid audit record type")
+	errNoCondition = errors.New("rule has no conditions")
+)
+
 // Config holds all rules.
 type Config struct {
-	Rules []*Rule
+	Rules []*Rule `yaml:"rules"`
 }
 
 // Action to execute when the rule applies.
-type Action func() error
+type Action func(r *Rule, record types.AuditRecord) error
 
 // Operation to compare values
-type Operation func() bool
+// returns true if the audit record matches.
+type Operation func(record types.AuditRecord) bool
 
 // Rule models a generic detection rule, that will be executed based on the provided information.
 // Simple rules could be created as a YAML configuration,
 // while more complex ones should be written in Go in order to implement a custom Action.
 type Rule struct {
 
+	// Name of the rule, will be used as name for the generated alerts
+	Name string `yaml:"name"`
+
+	// Description text for the event
+	Description string `yaml:"description"`
+
+	// MITRE ATT&CK technique associated with the rule
+	MITRE string `yaml:"mitre"`
+
+	// Audit record type name for which the rule shall be applied, e.g. HTTP or Connection
+	// an empty string or a '*' will apply the rule to all audit records
+	Type string `yaml:"type"`
+
 	// Audit record type for which the rule shall be applied
-	Typ types.Type
+	Typ types.Type `yaml:"-"`
 
 	// or apply to all audit records
-	ApplyToAllTypes bool
+	ApplyToAllTypes bool `yaml:"-"`
+
+	// Timezone used to evaluate time of day conditions, defaults to UTC
+	Timezone string `yaml:"timezone"`
 
-	// todo: make timezone configurable!
 	// fire if record has a timestamp in a given interval
-	StartAt time.Time
-	EndAt   time.Time
+	StartAt time.Time `yaml:"startAt"`
+	EndAt   time.Time `yaml:"endAt"`
 
-	// Description text for the event
-	Description string
+	// Conditions that must all be met for the rule to apply
+	Conditions []*Condition `yaml:"conditions"`
 
 	// Logic to execute
-	Action Action
+	Action Action `yaml:"-"`
 
 	// Comparison Operations
 	// ==, <, >, >=, <= etc
-	Operation Operation
+	// compiled from the conditions
+	Operation Operation `yaml:"-"`
 
-	// num bytes
-	ingressBytes int
-	egressBytes  int
+	location *time.Location
+}
+
+// compile validates the rule and compiles its conditions into a single operation.
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return errMissingName
+	}
+
+	if len(r.Conditions) == 0 {
+		return fmt.Errorf("%s: %w", r.Name, errNoCondition)
+	}
+
+	name := strings.TrimPrefix(r.Type, "NC_")
+	if name == "" || name == "*" {
+		r.ApplyToAllTypes = true
+	} else {
+		t, ok := types.Type_value["NC_"+name]
+		if !ok {
+			return fmt.Errorf("%s: %w: %s", r.Name, errInvalidType, r.Type)
+		}
+
+		r.Typ = types.Type(t)
+	}
+
+	r.location = time.UTC
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+
+		r.location = loc
+	}
+
+	for _, c := range r.Conditions {
+		if err := c.compile(r.location); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+	}
+
+	r.Operation = func(record types.AuditRecord) bool {
+		for _, c := range r.Conditions {
+			if !c.match(record) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if r.Action == nil {
+		r.Action = writeAlert
+	}
+
+	return nil
+}
 
-	// ip network information
-	homeSubnet net.IPNet
-	homeMask   net.IPMask
+// Apply checks whether the rule matches the audit record
+// and executes the action if it does.
+func (r *Rule) Apply(record types.AuditRecord) (bool, error) {
+	if !r.ApplyToAllTypes && record.NetcapType() != r.Typ {
+		return false, nil
+	}
+
+	if !r.inInterval(record.Time()) {
+		return false, nil
+	}
+
+	if !r.Operation(record) {
+		return false, nil
+	}
+
+	return true, r.Action(r, record)
+}
 
-	// Port number
-	Port int
+// inInterval checks whether the timestamp is within the configured interval of the rule.
+func (r *Rule) inInterval(ts int64) bool {
+	t := time.Unix(0, ts)
 
-	// IP address
-	IP net.IP
+	if !r.StartAt.IsZero() && t.Before(r.StartAt) {
+		return false
+	}
 
-	// MAC address
-	MAC string
+	if !r.EndAt.IsZero() && t.After(r.EndAt) {
+		return false
+	}
 
-	// Regular expression to match against packet contents or stream banners
-	Regex regexp.Regexp
+	return true
 }
@@ -0,0 +1,165 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/types"
+)
+
+func TestLoadExampleRules(t *testing.T) {
+	e, err := Load("../configs/rules.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.NumRules() != 5 {
+		t.Fatal("expected 5 rules, got", e.NumRules())
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	var fired []string
+
+	e, err := Parse([]byte(`
+rules:
+  - name: http-host-is-ip
+    type: HTTP
+    conditions:
+      - field: Host
+        op: regex
+        value: '^\d{1,3}(\.\d{1,3}){3}(:\d+)?$'
+  - name: curl
+    type: HTTP
+    conditions:
+      - field: RequestHeader.User-Agent
+        op: contains
+        value: curl
+  - name: internal-night
+    type: Connection
+    conditions:
+      - field: SrcIP
+        op: in-subnet
+        value: 10.0.0.0/8
+      - field: TimestampFirst
+        op: time-of-day
+        value: 22:00-06:00
+  - name: large
+    type: '*'
+    conditions:
+      - field: TotalSize
+        op: '>='
+        value: 1000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rules := range e.rules {
+		for _, r := range rules {
+			r.Action = func(r *Rule, record types.AuditRecord) error {
+				fired = append(fired, r.Name)
+
+				return nil
+			}
+		}
+	}
+
+	for _, r := range e.all {
+		r.Action = func(r *Rule, record types.AuditRecord) error {
+			fired = append(fired, r.Name)
+
+			return nil
+		}
+	}
+
+	e.Analyze(&types.HTTP{
+		Host:          "192.168.1.1:8080",
+		RequestHeader: map[string]string{"User-Agent": "curl/7.64.1"},
+	})
+	e.Analyze(&types.HTTP{
+		Host: "example.com",
+	})
+	e.Analyze(&types.Connection{
+		SrcIP:          "10.1.2.3",
+		TimestampFirst: time.Date(2020, 1, 1, 23, 30, 0, 0, time.UTC).UnixNano(),
+		TotalSize:      1500,
+	})
+	e.Analyze(&types.Connection{
+		SrcIP:          "10.1.2.3",
+		TimestampFirst: time.Date(2020, 1, 1, 12, 30, 0, 0, time.UTC).UnixNano(),
+	})
+
+	expected := []string{"http-host-is-ip", "curl", "internal-night", "large"}
+	if len(fired) != len(expected) {
+		t.Fatal("expected", expected, "got", fired)
+	}
+
+	for i, name := range expected {
+		if fired[i] != name {
+			t.Fatal("expected", expected, "got", fired)
+		}
+	}
+}
+
+func TestCountryUnknownLocation(t *testing.T) {
+	e, err := Parse([]byte(`
+rules:
+  - name: unexpected-country
+    type: Connection
+    conditions:
+      - field: DstIP
+        op: country
+        value: DE
+        not: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fired bool
+
+	for _, r := range e.rules[types.Type_NC_Connection] {
+		r.Action = func(*Rule, types.AuditRecord) error {
+			fired = true
+
+			return nil
+		}
+	}
+
+	// no GeoLite database is loaded, so the location is unknown
+	e.Analyze(&types.Connection{DstIP: "8.8.8.8"})
+
+	if fired {
+		t.Fatal("expected no match for an unknown location")
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, conf := range []string{
+		"rules: [{name: a, type: Invalid, conditions: [{field: Host, op: '=='}]}]",
+		"rules: [{name: a, type: HTTP, conditions: [{field: Host, op: '~'}]}]",
+		"rules: [{name: a, type: HTTP, conditions: [{field: Host, op: regex, value: '('}]}]",
+		"rules: [{name: a, type: HTTP, conditions: [{field: SrcIP, op: in-subnet, value: '10.0.0.0'}]}]",
+		"rules: [{name: a, type: HTTP, conditions: [{field: Timestamp, op: time-of-day, value: '08:00'}]}]",
+		"rules: [{name: a, type: HTTP}]",
+		"rules: [{type: HTTP, conditions: [{field: Host}]}]",
+	} {
+		if _, err := Parse([]byte(conf)); err == nil {
+			t.Fatal("expected an error for", conf)
+		}
+	}
+}
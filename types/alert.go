@@ -24,6 +24,8 @@ import (
 const (
 	fieldMITRE        = "MITRE"        // string
 	fieldIPReputation = "IPReputation" // string
+	fieldLastSeen     = "LastSeen"     // int64
+	fieldCount        = "Count"        // int64
 )
 
 var fieldsAlert = []string{
@@ -36,6 +38,9 @@ var fieldsAlert = []string{
 	fieldDstPort,
 	fieldMITRE,
 	fieldIPReputation,
+	fieldLastSeen,
+	fieldCount,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -55,6 +60,9 @@ func (a *Alert) CSVRecord() []string {
 		a.DstPort,
 		a.MITRE,
 		a.IPReputation,
+		formatTimestamp(a.LastSeen),
+		formatInt64(a.Count),
+		a.CommunityID, // string
 	})
 }
 
@@ -67,6 +75,7 @@ func (a *Alert) Time() int64 {
 func (a *Alert) JSON() (string, error) {
 	// convert unix timestamp from nano to millisecond precision for elastic
 	a.Timestamp /= int64(time.Millisecond)
+	a.LastSeen /= int64(time.Millisecond)
 
 	return jsonMarshaler.MarshalToString(a)
 }
@@ -76,12 +85,14 @@ var aMetric = prometheus.NewCounterVec(
 		Name: strings.ToLower(Type_NC_Alert.String()),
 		Help: Type_NC_Alert.String() + " audit records",
 	},
-	fieldsAlert[1:],
+	// exclude the timestamps and counter
+	fieldsAlert[1:len(fieldsAlert)-2],
 )
 
 // Inc increments the metrics for the audit record.
 func (a *Alert) Inc() {
-	aMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+	r := a.CSVRecord()
+	aMetric.WithLabelValues(r[1 : len(r)-2]...).Inc()
 }
 
 // SetPacketContext sets the associated packet context for the audit record.
@@ -113,11 +124,16 @@ func (a *Alert) Encode() []string {
 		aEncoder.String(fieldDstPort, a.DstPort),
 		aEncoder.String(fieldMITRE, a.MITRE),
 		aEncoder.String(fieldIPReputation, a.IPReputation),
+		aEncoder.Int64(fieldLastSeen, a.LastSeen),
+		aEncoder.Int64(fieldCount, a.Count),
+		aEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *Alert) Analyze() {}
+func (a *Alert) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *Alert) NetcapType() Type {
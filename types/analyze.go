@@ -0,0 +1,93 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import "sync"
+
+// AnomalyDetector is the interface for analyzers that inspect audit records at runtime.
+// Implementations are invoked concurrently from multiple decoders and must be safe for concurrent use.
+type AnomalyDetector interface {
+
+	// Name returns the name of the detector
+	Name() string
+
+	// Analyze processes a single audit record
+	// findings should be reported by the detector itself, e.g. by emitting an alert.
+	Analyze(record AuditRecord)
+}
+
+// detectors holds all registered anomaly detectors.
+var detectors = struct {
+	sync.RWMutex
+
+	// detectors mapped to the audit record types they were registered for
+	byType map[Type][]AnomalyDetector
+
+	// detectors that process all audit records
+	all []AnomalyDetector
+}{
+	byType: make(map[Type][]AnomalyDetector),
+}
+
+// RegisterAnomalyDetector registers a detector for the given audit record types.
+// If no types are provided, the detector will receive all audit records.
+func RegisterAnomalyDetector(d AnomalyDetector, typs ...Type) {
+	detectors.Lock()
+	defer detectors.Unlock()
+
+	if len(typs) == 0 {
+		detectors.all = append(detectors.all, d)
+
+		return
+	}
+
+	for _, t := range typs {
+		detectors.byType[t] = append(detectors.byType[t], d)
+	}
+}
+
+// ResetAnomalyDetectors removes all registered detectors.
+func ResetAnomalyDetectors() {
+	detectors.Lock()
+	defer detectors.Unlock()
+
+	detectors.byType = make(map[Type][]AnomalyDetector)
+	detectors.all = nil
+}
+
+// HasAnomalyDetectors returns true if at least one detector is registered.
+func HasAnomalyDetectors() bool {
+	detectors.RLock()
+	defer detectors.RUnlock()
+
+	return len(detectors.all) > 0 || len(detectors.byType) > 0
+}
+
+// analyze dispatches the audit record to all detectors registered for its type.
+func analyze(r AuditRecord) {
+	detectors.RLock()
+	var (
+		typed = detectors.byType[r.NetcapType()]
+		all   = detectors.all
+	)
+	detectors.RUnlock()
+
+	for _, d := range typed {
+		d.Analyze(r)
+	}
+
+	for _, d := range all {
+		d.Analyze(r)
+	}
+}
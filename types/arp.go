@@ -127,7 +127,9 @@ func (arp *ARP) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (arp *ARP) Analyze() {}
+func (arp *ARP) Analyze() {
+	analyze(arp)
+}
 
 // NetcapType returns the type of the current audit record
 func (arp *ARP) NetcapType() Type {
@@ -74,9 +74,8 @@ type AuditRecord interface {
 	// and return the result as CSV.
 	Encode() []string
 
-	// Analyze will feed this audit record to an analyzer.
+	// Analyze will feed this audit record to the AnomalyDetectors registered for its type.
 	// This could either be a static rule based analyzer, or one that is based on a more complex Anomaly Detector (statistical or ML).
-	// TODO: define AnomalyDetector interface
 	Analyze()
 
 	// NetcapType returns the audit record type
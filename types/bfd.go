@@ -174,6 +174,7 @@ func (b *BFD) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (b *BFD) Analyze() {
+	analyze(b)
 }
 
 // NetcapType returns the type of the current audit record
@@ -0,0 +1,177 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldChainIndex         = "ChainIndex"
+	fieldFingerprint        = "Fingerprint"
+	fieldIssuer             = "Issuer"
+	fieldSerialNumber       = "SerialNumber"
+	fieldNotBefore          = "NotBefore"
+	fieldNotAfter           = "NotAfter"
+	fieldSignatureAlgorithm = "SignatureAlgorithm"
+	fieldPublicKeyAlgorithm = "PublicKeyAlgorithm"
+	fieldIsCA               = "IsCA"
+	fieldSelfSigned         = "SelfSigned"
+	fieldExpired            = "Expired"
+)
+
+var fieldsCertificate = []string{
+	fieldTimestamp,
+	fieldClientIP,           // string
+	fieldServerIP,           // string
+	fieldClientPort,         // int32
+	fieldServerPort,         // int32
+	fieldSNI,                // string
+	fieldVersion,            // string
+	fieldChainIndex,         // int32
+	fieldFingerprint,        // string
+	fieldSubject,            // string
+	fieldIssuer,             // string
+	fieldSerialNumber,       // string
+	fieldNotBefore,          // int64
+	fieldNotAfter,           // int64
+	fieldDNSNames,           // []string
+	fieldIPAddresses,        // []string
+	fieldSignatureAlgorithm, // string
+	fieldPublicKeyAlgorithm, // string
+	fieldIsCA,               // bool
+	fieldSelfSigned,         // bool
+	fieldExpired,            // bool
+	fieldCommunityID,        // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *Certificate) CSVHeader() []string {
+	return filter(fieldsCertificate)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *Certificate) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                       // string
+		a.ServerIP,                       // string
+		formatInt32(a.ClientPort),        // int32
+		formatInt32(a.ServerPort),        // int32
+		a.SNI,                            // string
+		a.Version,                        // string
+		formatInt32(a.ChainIndex),        // int32
+		a.Fingerprint,                    // string
+		a.Subject,                        // string
+		a.Issuer,                         // string
+		a.SerialNumber,                   // string
+		formatTimestamp(a.NotBefore),     // int64
+		formatTimestamp(a.NotAfter),      // int64
+		join(a.DNSNames...),              // []string
+		join(a.IPAddresses...),           // []string
+		a.SignatureAlgorithm,             // string
+		a.PublicKeyAlgorithm,             // string
+		strconv.FormatBool(a.IsCA),       // bool
+		strconv.FormatBool(a.SelfSigned), // bool
+		strconv.FormatBool(a.Expired),    // bool
+		a.CommunityID,                    // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *Certificate) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *Certificate) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+	a.NotBefore /= int64(time.Millisecond)
+	a.NotAfter /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var certificateMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Certificate.String()),
+		Help: Type_NC_Certificate.String() + " audit records",
+	},
+	fieldsCertificate[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *Certificate) Inc() {
+	certificateMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *Certificate) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *Certificate) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *Certificate) Dst() string {
+	return a.ServerIP
+}
+
+var certificateEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *Certificate) Encode() []string {
+	return filter([]string{
+		certificateEncoder.Int64(fieldTimestamp, a.Timestamp),
+		certificateEncoder.String(fieldClientIP, a.ClientIP),                     // string
+		certificateEncoder.String(fieldServerIP, a.ServerIP),                     // string
+		certificateEncoder.Int32(fieldClientPort, a.ClientPort),                  // int32
+		certificateEncoder.Int32(fieldServerPort, a.ServerPort),                  // int32
+		certificateEncoder.String(fieldSNI, a.SNI),                               // string
+		certificateEncoder.String(fieldVersion, a.Version),                       // string
+		certificateEncoder.Int32(fieldChainIndex, a.ChainIndex),                  // int32
+		certificateEncoder.String(fieldFingerprint, a.Fingerprint),               // string
+		certificateEncoder.String(fieldSubject, a.Subject),                       // string
+		certificateEncoder.String(fieldIssuer, a.Issuer),                         // string
+		certificateEncoder.String(fieldSerialNumber, a.SerialNumber),             // string
+		certificateEncoder.Int64(fieldNotBefore, a.NotBefore),                    // int64
+		certificateEncoder.Int64(fieldNotAfter, a.NotAfter),                      // int64
+		certificateEncoder.String(fieldDNSNames, join(a.DNSNames...)),            // []string
+		certificateEncoder.String(fieldIPAddresses, join(a.IPAddresses...)),      // []string
+		certificateEncoder.String(fieldSignatureAlgorithm, a.SignatureAlgorithm), // string
+		certificateEncoder.String(fieldPublicKeyAlgorithm, a.PublicKeyAlgorithm), // string
+		certificateEncoder.Bool(a.IsCA),                                          // bool
+		certificateEncoder.Bool(a.SelfSigned),                                    // bool
+		certificateEncoder.Bool(a.Expired),                                       // bool
+		certificateEncoder.String(fieldCommunityID, a.CommunityID),               // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *Certificate) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *Certificate) NetcapType() Type {
+	return Type_NC_Certificate
+}
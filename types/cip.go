@@ -48,6 +48,7 @@ var fieldsCIP = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -77,6 +78,7 @@ func (c *CIP) CSVRecord() []string {
 		c.DstIP,
 		formatInt32(c.SrcPort),
 		formatInt32(c.DstPort),
+		c.CommunityID, // string
 	})
 }
 
@@ -112,6 +114,7 @@ func (c *CIP) SetPacketContext(ctx *PacketContext) {
 	c.DstIP = ctx.DstIP
 	c.SrcPort = ctx.SrcPort
 	c.DstPort = ctx.DstPort
+	c.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -149,12 +152,13 @@ func (c *CIP) Encode() []string {
 		cipEncoder.String(fieldDstIP, c.DstIP),
 		cipEncoder.Int32(fieldSrcPort, c.SrcPort),
 		cipEncoder.Int32(fieldDstPort, c.DstPort),
+		cipEncoder.String(fieldCommunityID, c.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (c *CIP) Analyze() {
-
+	analyze(c)
 }
 
 // NetcapType returns the type of the current audit record
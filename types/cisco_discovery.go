@@ -129,6 +129,7 @@ func (cd *CiscoDiscovery) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (cd *CiscoDiscovery) Analyze() {
+	analyze(cd)
 }
 
 // NetcapType returns the type of the current audit record
@@ -394,7 +394,7 @@ func (a *CiscoDiscoveryInfo) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *CiscoDiscoveryInfo) Analyze() {
-
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
@@ -46,6 +46,8 @@ const (
 	fieldNumCWRFlags         = "NumCWRFlags"
 	fieldNumNSFlags          = "NumNSFlags"
 	fieldMeanWindowSize      = "MeanWindowSize"
+	fieldCommunityID         = "CommunityID"
+	fieldCommunityIDs        = "CommunityIDs"
 )
 
 var fieldsConnection = []string{
@@ -78,6 +80,7 @@ var fieldsConnection = []string{
 	fieldNumCWRFlags,
 	fieldNumNSFlags,
 	fieldMeanWindowSize,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -117,6 +120,7 @@ func (c *Connection) CSVRecord() []string {
 		formatInt32(c.NumCWRFlags),
 		formatInt32(c.NumNSFlags),
 		formatInt32(c.MeanWindowSize),
+		c.CommunityID, // string
 	})
 }
 
@@ -260,12 +264,13 @@ func (c *Connection) Encode() []string {
 		connectionEncoder.Int32(fieldNumCWRFlags, c.NumCWRFlags),
 		connectionEncoder.Int32(fieldNumNSFlags, c.NumNSFlags),
 		connectionEncoder.Int32(fieldMeanWindowSize, c.MeanWindowSize),
+		connectionEncoder.String(fieldCommunityID, c.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (c *Connection) Analyze() {
-
+	analyze(c)
 }
 
 // NetcapType returns the type of the current audit record
@@ -31,11 +31,12 @@ const (
 
 var fieldsCredentials = []string{
 	fieldTimestamp,
-	fieldService,  // string
-	fieldFlow,     // string
-	fieldUser,     // string
-	fieldPassword, // string
-	fieldNotes,    // string
+	fieldService,     // string
+	fieldFlow,        // string
+	fieldUser,        // string
+	fieldPassword,    // string
+	fieldNotes,       // string
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -52,6 +53,7 @@ func (c *Credentials) CSVRecord() []string {
 		c.User,
 		c.Password,
 		c.Notes,
+		c.CommunityID, // string
 	})
 }
 
@@ -107,11 +109,13 @@ func (c *Credentials) Encode() []string {
 		credentialsEncoder.String(fieldUser, c.User),
 		credentialsEncoder.String(fieldPassword, c.Password),
 		credentialsEncoder.String(fieldNotes, c.Notes),
+		credentialsEncoder.String(fieldCommunityID, c.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (c *Credentials) Analyze() {
+	analyze(c)
 }
 
 // NetcapType returns the type of the current audit record
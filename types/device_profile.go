@@ -101,6 +101,7 @@ func (d *DeviceProfile) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *DeviceProfile) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
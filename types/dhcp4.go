@@ -60,6 +60,7 @@ var fieldsDHCPv4 = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -95,6 +96,7 @@ func (d *DHCPv4) CSVRecord() []string {
 		d.DstIP,
 		formatInt32(d.SrcPort),
 		formatInt32(d.DstPort),
+		d.CommunityID, // string
 	})
 }
 
@@ -182,6 +184,7 @@ func (d *DHCPv4) SetPacketContext(ctx *PacketContext) {
 	d.DstIP = ctx.DstIP
 	d.SrcPort = ctx.SrcPort
 	d.DstPort = ctx.DstPort
+	d.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -226,11 +229,13 @@ func (d *DHCPv4) Encode() []string {
 		dhcp4Encoder.String(fieldDstIP, d.DstIP),
 		dhcp4Encoder.Int32(fieldSrcPort, d.SrcPort),
 		dhcp4Encoder.Int32(fieldDstPort, d.DstPort),
+		dhcp4Encoder.String(fieldCommunityID, d.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *DHCPv4) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
@@ -42,6 +42,7 @@ var fieldsDHCPv6 = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -68,6 +69,7 @@ func (d *DHCPv6) CSVRecord() []string {
 		d.DstIP,
 		formatInt32(d.SrcPort),
 		formatInt32(d.DstPort),
+		d.CommunityID, // string
 	})
 }
 
@@ -139,6 +141,7 @@ func (d *DHCPv6) SetPacketContext(ctx *PacketContext) {
 	d.DstIP = ctx.DstIP
 	d.SrcPort = ctx.SrcPort
 	d.DstPort = ctx.DstPort
+	d.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -172,11 +175,13 @@ func (d *DHCPv6) Encode() []string {
 		dhcp4Encoder.String(fieldDstIP, d.DstIP),
 		dhcp4Encoder.Int32(fieldSrcPort, d.SrcPort),
 		dhcp4Encoder.Int32(fieldDstPort, d.DstPort),
+		dhcp6Encoder.String(fieldCommunityID, d.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *DHCPv6) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
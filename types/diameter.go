@@ -44,6 +44,7 @@ var fieldsDiameter = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -73,6 +74,7 @@ func (d *Diameter) CSVRecord() []string {
 		d.DstIP,
 		formatInt32(d.SrcPort),
 		formatInt32(d.DstPort),
+		d.CommunityID, // string
 	})
 }
 
@@ -108,6 +110,7 @@ func (d *Diameter) SetPacketContext(ctx *PacketContext) {
 	d.DstIP = ctx.DstIP
 	d.SrcPort = ctx.SrcPort
 	d.DstPort = ctx.DstPort
+	d.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -139,11 +142,13 @@ func (d *Diameter) Encode() []string {
 		diameterEncoder.String(fieldDstIP, d.DstIP),
 		diameterEncoder.Int32(fieldSrcPort, d.SrcPort),
 		diameterEncoder.Int32(fieldDstPort, d.DstPort),
+		diameterEncoder.String(fieldCommunityID, d.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *Diameter) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
@@ -66,6 +66,7 @@ var fieldsDNS = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -117,6 +118,7 @@ func (d *DNS) CSVRecord() []string {
 		d.DstIP,
 		formatInt32(d.SrcPort),
 		formatInt32(d.DstPort),
+		d.CommunityID, // string
 	})
 }
 
@@ -246,6 +248,7 @@ func (d *DNS) SetPacketContext(ctx *PacketContext) {
 	d.DstIP = ctx.DstIP
 	d.SrcPort = ctx.SrcPort
 	d.DstPort = ctx.DstPort
+	d.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -305,11 +308,13 @@ func (d *DNS) Encode() []string {
 		dnsEncoder.String(fieldDstIP, d.DstIP),
 		dnsEncoder.Int32(fieldSrcPort, d.SrcPort),
 		dnsEncoder.Int32(fieldDstPort, d.DstPort),
+		dnsEncoder.String(fieldCommunityID, d.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *DNS) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
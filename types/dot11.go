@@ -256,6 +256,7 @@ func (d *Dot11) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *Dot11) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
@@ -108,6 +108,7 @@ func (d *Dot1Q) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (d *Dot1Q) Analyze() {
+	analyze(d)
 }
 
 // NetcapType returns the type of the current audit record
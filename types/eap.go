@@ -110,6 +110,7 @@ func (a *EAP) Encode() []string {
 
 // Analyze will invoke the configured analyzer(s) for the audit record and return a score.
 func (a *EAP) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
@@ -96,7 +96,9 @@ func (a *EAPOL) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *EAPOL) Analyze() {}
+func (a *EAPOL) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *EAPOL) NetcapType() Type {
@@ -174,7 +174,9 @@ func (a *EAPOLKey) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *EAPOLKey) Analyze() {}
+func (a *EAPOLKey) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *EAPOLKey) NetcapType() Type {
@@ -42,6 +42,7 @@ var fieldsENIP = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -65,6 +66,7 @@ func (en *ENIP) CSVRecord() []string {
 		en.DstIP,
 		formatInt32(en.SrcPort),
 		formatInt32(en.DstPort),
+		en.CommunityID, // string
 	})
 }
 
@@ -100,6 +102,7 @@ func (en *ENIP) SetPacketContext(ctx *PacketContext) {
 	en.DstIP = ctx.DstIP
 	en.SrcPort = ctx.SrcPort
 	en.DstPort = ctx.DstPort
+	en.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -130,11 +133,14 @@ func (en *ENIP) Encode() []string {
 		enipEncoder.String(fieldDstIP, en.DstIP),
 		enipEncoder.Int32(fieldSrcPort, en.SrcPort),
 		enipEncoder.Int32(fieldDstPort, en.DstPort),
+		enipEncoder.String(fieldCommunityID, en.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (en *ENIP) Analyze() {}
+func (en *ENIP) Analyze() {
+	analyze(en)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *ENIP) NetcapType() Type {
@@ -92,7 +92,9 @@ func (i *EthernetCTP) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *EthernetCTP) Analyze() {}
+func (i *EthernetCTP) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *EthernetCTP) NetcapType() Type {
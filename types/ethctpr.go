@@ -100,7 +100,9 @@ func (ectpr *EthernetCTPReply) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (ectpr *EthernetCTPReply) Analyze() {}
+func (ectpr *EthernetCTPReply) Analyze() {
+	analyze(ectpr)
+}
 
 // NetcapType returns the type of the current audit record
 func (ectpr *EthernetCTPReply) NetcapType() Type {
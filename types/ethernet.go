@@ -146,7 +146,9 @@ func (eth *Ethernet) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (eth *Ethernet) Analyze() {}
+func (eth *Ethernet) Analyze() {
+	analyze(eth)
+}
 
 // NetcapType returns the type of the current audit record
 func (eth *Ethernet) NetcapType() Type {
@@ -108,7 +108,9 @@ func (a *Exploit) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *Exploit) Analyze() {}
+func (a *Exploit) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *Exploit) NetcapType() Type {
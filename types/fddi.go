@@ -102,7 +102,9 @@ func (a *FDDI) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *FDDI) Analyze() {}
+func (a *FDDI) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *FDDI) NetcapType() Type {
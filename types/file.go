@@ -27,6 +27,11 @@ const (
 	fieldIdent       = "Ident"
 	fieldSource      = "Source"
 	fieldContentType = "ContentType"
+	fieldSHA1        = "SHA1"
+	fieldSHA256      = "SHA256"
+	fieldSSDEEP      = "SSDEEP"
+	fieldParent      = "ParentSHA256"
+	fieldDepth       = "Depth"
 )
 
 var fieldsFile = []string{
@@ -42,6 +47,12 @@ var fieldsFile = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
+	fieldSHA1,        // string
+	fieldSHA256,      // string
+	fieldSSDEEP,      // string
+	fieldParent,      // string
+	fieldDepth,       // int32
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -64,6 +75,12 @@ func (a *File) CSVRecord() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.CommunityID, // string
+		a.SHA1,        // string
+		a.SHA256,      // string
+		a.SSDEEP,      // string
+		a.ParentSHA256,
+		formatInt32(a.Depth),
 	})
 }
 
@@ -154,11 +171,19 @@ func (a *File) Encode() []string {
 		fileEncoder.String(fieldDstIP, a.DstIP),
 		fileEncoder.Int32(fieldSrcPort, a.SrcPort),
 		fileEncoder.Int32(fieldDstPort, a.DstPort),
+		fileEncoder.String(fieldCommunityID, a.CommunityID), // string
+		fileEncoder.String(fieldSHA1, a.SHA1),               // string
+		fileEncoder.String(fieldSHA256, a.SHA256),           // string
+		fileEncoder.String(fieldSSDEEP, a.SSDEEP),           // string
+		fileEncoder.String(fieldParent, a.ParentSHA256),     // string
+		fileEncoder.Int32(fieldDepth, a.Depth),              // int32
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *File) Analyze() {}
+func (a *File) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *File) NetcapType() Type {
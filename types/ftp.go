@@ -0,0 +1,143 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldClientPort   = "ClientPort"
+	fieldServerPort   = "ServerPort"
+	fieldNumCommands  = "NumCommands"
+	fieldNumTransfers = "NumTransfers"
+)
+
+var fieldsFTP = []string{
+	fieldTimestamp,
+	fieldClientIP,     // string
+	fieldServerIP,     // string
+	fieldClientPort,   // int32
+	fieldServerPort,   // int32
+	fieldBanner,       // string
+	fieldUser,         // string
+	fieldPass,         // string
+	fieldNumCommands,  // []*FTPCommand
+	fieldNumTransfers, // []*FTPTransfer
+	fieldCommunityID,  // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *FTP) CSVHeader() []string {
+	return filter(fieldsFTP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *FTP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                     // string
+		a.ServerIP,                     // string
+		formatInt32(a.ClientPort),      // int32
+		formatInt32(a.ServerPort),      // int32
+		a.Banner,                       // string
+		a.User,                         // string
+		a.Pass,                         // string
+		strconv.Itoa(len(a.Commands)),  // []*FTPCommand
+		strconv.Itoa(len(a.Transfers)), // []*FTPTransfer
+		a.CommunityID,                  // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *FTP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *FTP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	for _, c := range a.Commands {
+		c.Timestamp /= int64(time.Millisecond)
+	}
+
+	for _, t := range a.Transfers {
+		t.Timestamp /= int64(time.Millisecond)
+	}
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var ftpMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_FTP.String()),
+		Help: Type_NC_FTP.String() + " audit records",
+	},
+	fieldsFTP[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *FTP) Inc() {
+	ftpMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *FTP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *FTP) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *FTP) Dst() string {
+	return a.ServerIP
+}
+
+var ftpEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *FTP) Encode() []string {
+	return filter([]string{
+		ftpEncoder.Int64(fieldTimestamp, a.Timestamp),
+		ftpEncoder.String(fieldClientIP, a.ClientIP),        // string
+		ftpEncoder.String(fieldServerIP, a.ServerIP),        // string
+		ftpEncoder.Int32(fieldClientPort, a.ClientPort),     // int32
+		ftpEncoder.Int32(fieldServerPort, a.ServerPort),     // int32
+		ftpEncoder.String(fieldBanner, a.Banner),            // string
+		ftpEncoder.String(fieldUser, a.User),                // string
+		ftpEncoder.String(fieldPass, a.Pass),                // string
+		ftpEncoder.Int(fieldNumCommands, len(a.Commands)),   // []*FTPCommand
+		ftpEncoder.Int(fieldNumTransfers, len(a.Transfers)), // []*FTPTransfer
+		ftpEncoder.String(fieldCommunityID, a.CommunityID),  // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *FTP) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *FTP) NetcapType() Type {
+	return Type_NC_FTP
+}
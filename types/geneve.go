@@ -144,7 +144,9 @@ func (i *Geneve) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *Geneve) Analyze() {}
+func (i *Geneve) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *Geneve) NetcapType() Type {
@@ -60,6 +60,7 @@ var fieldsGRE = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -89,6 +90,7 @@ func (a *GRE) CSVRecord() []string {
 		a.Routing.getString(),                   // *GRERouting
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -144,6 +146,7 @@ func (a *GRE) Inc() {
 func (a *GRE) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -180,11 +183,14 @@ func (a *GRE) Encode() []string {
 		greEncoder.String(fieldRouting, a.Routing.getString()),      // *GRERouting
 		dhcp4Encoder.String(fieldSrcIP, a.SrcIP),
 		dhcp4Encoder.String(fieldDstIP, a.DstIP),
+		greEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *GRE) Analyze() {}
+func (a *GRE) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *GRE) NetcapType() Type {
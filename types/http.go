@@ -35,6 +35,11 @@ const (
 	fieldStatusCode         = "StatusCode"
 	fieldReqContentEncoding = "ReqContentEncoding"
 	fieldResContentEncoding = "ResContentEncoding"
+	fieldStreamID           = "StreamID"
+	fieldGRPCService        = "GRPCService"
+	fieldGRPCMethod         = "GRPCMethod"
+	fieldGRPCStatus         = "GRPCStatus"
+	fieldGRPCMessage        = "GRPCMessage"
 )
 
 var fieldsHTTP = []string{
@@ -56,6 +61,12 @@ var fieldsHTTP = []string{
 	fieldReqContentEncoding,
 	fieldResContentEncoding,
 	fieldServerName,
+	fieldCommunityID, // string
+	fieldStreamID,    // uint32
+	fieldGRPCService, // string
+	fieldGRPCMethod,  // string
+	fieldGRPCStatus,  // string
+	fieldGRPCMessage, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -92,6 +103,12 @@ func (h *HTTP) CSVRecord() []string {
 		h.ReqContentEncoding,
 		h.ResContentEncoding,
 		h.ServerName,
+		h.CommunityID,            // string
+		formatUint32(h.StreamID), // uint32
+		h.GRPCService,            // string
+		h.GRPCMethod,             // string
+		h.GRPCStatus,             // string
+		h.GRPCMessage,            // string
 	})
 }
 
@@ -186,11 +203,19 @@ func (h *HTTP) Encode() []string {
 		httpEncoder.String(fieldReqContentEncoding, h.ReqContentEncoding),
 		httpEncoder.String(fieldResContentEncoding, h.ResContentEncoding),
 		httpEncoder.String(fieldServerName, h.ServerName),
+		httpEncoder.String(fieldCommunityID, h.CommunityID), // string
+		httpEncoder.Uint32(fieldStreamID, h.StreamID),       // uint32
+		httpEncoder.String(fieldGRPCService, h.GRPCService), // string
+		httpEncoder.String(fieldGRPCMethod, h.GRPCMethod),   // string
+		httpEncoder.String(fieldGRPCStatus, h.GRPCStatus),   // string
+		httpEncoder.String(fieldGRPCMessage, h.GRPCMessage), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (h *HTTP) Analyze() {}
+func (h *HTTP) Analyze() {
+	analyze(h)
+}
 
 // NetcapType returns the type of the current audit record
 func (h *HTTP) NetcapType() Type {
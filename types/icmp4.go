@@ -33,6 +33,7 @@ var fieldsICMPv4 = []string{
 	fieldSeq,      // int32
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -50,6 +51,7 @@ func (i *ICMPv4) CSVRecord() []string {
 		formatInt32(i.Seq),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -83,6 +85,7 @@ func (i *ICMPv4) Inc() {
 func (i *ICMPv4) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -107,11 +110,14 @@ func (i *ICMPv4) Encode() []string {
 		icmp4Encoder.Int32(fieldSeq, i.Seq),
 		icmp4Encoder.String(fieldSrcIP, i.SrcIP),
 		icmp4Encoder.String(fieldDstIP, i.DstIP),
+		icmp4Encoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv4) Analyze() {}
+func (i *ICMPv4) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv4) NetcapType() Type {
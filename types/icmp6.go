@@ -27,6 +27,7 @@ var fieldsICMPv6 = []string{
 	fieldChecksum, // int32
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -42,6 +43,7 @@ func (i *ICMPv6) CSVRecord() []string {
 		formatInt32(i.Checksum),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -75,6 +77,7 @@ func (i *ICMPv6) Inc() {
 func (i *ICMPv6) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -97,11 +100,14 @@ func (i *ICMPv6) Encode() []string {
 		icmp6Encoder.Int32(fieldChecksum, i.Checksum),
 		icmp6Encoder.String(fieldSrcIP, i.SrcIP),
 		icmp6Encoder.String(fieldDstIP, i.DstIP),
+		icmp6Encoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6) Analyze() {}
+func (i *ICMPv6) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6) NetcapType() Type {
@@ -32,6 +32,7 @@ var fieldsICMPv6Echo = []string{
 	fieldSeqNumber,  //  int32
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -47,6 +48,7 @@ func (i *ICMPv6Echo) CSVRecord() []string {
 		formatInt32(i.SeqNumber),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -80,6 +82,7 @@ func (i *ICMPv6Echo) Inc() {
 func (i *ICMPv6Echo) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -102,11 +105,14 @@ func (i *ICMPv6Echo) Encode() []string {
 		icmp6eEncoder.Int32(fieldSeqNumber, i.SeqNumber),
 		icmp6eEncoder.String(fieldSrcIP, i.SrcIP),
 		icmp6eEncoder.String(fieldDstIP, i.DstIP),
+		icmp6eEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6Echo) Analyze() {}
+func (i *ICMPv6Echo) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6Echo) NetcapType() Type {
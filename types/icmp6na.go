@@ -32,6 +32,7 @@ var fieldsICMPv6NeighborAdvertisement = []string{
 	fieldOptions,       // []*ICMPv6Option
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -53,6 +54,7 @@ func (i *ICMPv6NeighborAdvertisement) CSVRecord() []string {
 		strings.Join(opts, ""),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -86,6 +88,7 @@ func (i *ICMPv6NeighborAdvertisement) Inc() {
 func (i *ICMPv6NeighborAdvertisement) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -113,11 +116,14 @@ func (i *ICMPv6NeighborAdvertisement) Encode() []string {
 		icmp6naEncoder.String(fieldOptions, strings.Join(opts, "")),
 		icmp6naEncoder.String(fieldSrcIP, i.SrcIP),
 		icmp6naEncoder.String(fieldDstIP, i.DstIP),
+		icmp6naEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6NeighborAdvertisement) Analyze() {}
+func (i *ICMPv6NeighborAdvertisement) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6NeighborAdvertisement) NetcapType() Type {
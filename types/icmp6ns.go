@@ -27,6 +27,7 @@ var fieldsICMPv6NeighborSolicitation = []string{
 	fieldOptions,       // []*ICMPv6Option
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -47,6 +48,7 @@ func (i *ICMPv6NeighborSolicitation) CSVRecord() []string {
 		strings.Join(opts, ""),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -80,6 +82,7 @@ func (i *ICMPv6NeighborSolicitation) Inc() {
 func (i *ICMPv6NeighborSolicitation) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -106,11 +109,14 @@ func (i *ICMPv6NeighborSolicitation) Encode() []string {
 		icmp6nsEncoder.String(fieldOptions, strings.Join(opts, "")),
 		icmp6nsEncoder.String(fieldSrcIP, i.SrcIP),
 		icmp6nsEncoder.String(fieldDstIP, i.DstIP),
+		icmp6nsEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6NeighborSolicitation) Analyze() {}
+func (i *ICMPv6NeighborSolicitation) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6NeighborSolicitation) NetcapType() Type {
@@ -38,6 +38,7 @@ var fieldsICMPv6RouterAdvertisement = []string{
 	fieldOptions,        //  []*ICMPv6Option
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -62,6 +63,7 @@ func (i *ICMPv6RouterAdvertisement) CSVRecord() []string {
 		strings.Join(opts, ""),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -95,6 +97,7 @@ func (i *ICMPv6RouterAdvertisement) Inc() {
 func (i *ICMPv6RouterAdvertisement) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -125,11 +128,14 @@ func (i *ICMPv6RouterAdvertisement) Encode() []string {
 		icmp6raEncoder.String(fieldOptions, strings.Join(opts, "")),
 		icmp6raEncoder.String(fieldSrcIP, i.SrcIP),
 		icmp6raEncoder.String(fieldDstIP, i.DstIP),
+		icmp6raEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6RouterAdvertisement) Analyze() {}
+func (i *ICMPv6RouterAdvertisement) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6RouterAdvertisement) NetcapType() Type {
@@ -27,6 +27,7 @@ var fieldsICMPv6RouterSolicitation = []string{
 	fieldOptions,
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -46,6 +47,7 @@ func (i *ICMPv6RouterSolicitation) CSVRecord() []string {
 		strings.Join(opts, ""),
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -90,6 +92,7 @@ func (i *ICMPv6RouterSolicitation) Inc() {
 func (i *ICMPv6RouterSolicitation) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -115,11 +118,14 @@ func (i *ICMPv6RouterSolicitation) Encode() []string {
 		icmp6rsEncoder.String(fieldOptions, strings.Join(opts, "")),
 		icmp6rsEncoder.String(fieldSrcIP, i.SrcIP),
 		icmp6rsEncoder.String(fieldDstIP, i.DstIP),
+		icmp6rsEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *ICMPv6RouterSolicitation) Analyze() {}
+func (i *ICMPv6RouterSolicitation) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *ICMPv6RouterSolicitation) NetcapType() Type {
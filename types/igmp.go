@@ -50,6 +50,7 @@ var fieldsIGMP = []string{
 	fieldVersion,                 // int32
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -80,6 +81,7 @@ func (i *IGMP) CSVRecord() []string {
 		formatInt32(i.Version),                        // int32
 		i.SrcIP,
 		i.DstIP,
+		i.CommunityID, // string
 	})
 }
 
@@ -130,6 +132,7 @@ func (i *IGMP) Inc() {
 func (i *IGMP) SetPacketContext(ctx *PacketContext) {
 	i.SrcIP = ctx.SrcIP
 	i.DstIP = ctx.DstIP
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -166,11 +169,14 @@ func (i *IGMP) Encode() []string {
 		igmpEncoder.Int32(fieldVersion, i.Version),                           // int32
 		igmpEncoder.String(fieldSrcIP, i.SrcIP),
 		igmpEncoder.String(fieldDstIP, i.DstIP),
+		igmpEncoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *IGMP) Analyze() {}
+func (i *IGMP) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *IGMP) NetcapType() Type {
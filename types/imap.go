@@ -0,0 +1,139 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldMailboxes = "Mailboxes"
+)
+
+var fieldsIMAP = []string{
+	fieldTimestamp,
+	fieldClientIP,    // string
+	fieldServerIP,    // string
+	fieldClientPort,  // int32
+	fieldServerPort,  // int32
+	fieldBanner,      // string
+	fieldUser,        // string
+	fieldPass,        // string
+	fieldMailboxes,   // []string
+	fieldNumCommands, // []*IMAPCommand
+	fieldNumMails,    // []string
+	fieldCommunityID, // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *IMAP) CSVHeader() []string {
+	return filter(fieldsIMAP)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *IMAP) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                    // string
+		a.ServerIP,                    // string
+		formatInt32(a.ClientPort),     // int32
+		formatInt32(a.ServerPort),     // int32
+		a.Banner,                      // string
+		a.User,                        // string
+		a.Pass,                        // string
+		join(a.Mailboxes...),          // []string
+		strconv.Itoa(len(a.Commands)), // []*IMAPCommand
+		strconv.Itoa(len(a.MailIDs)),  // []string
+		a.CommunityID,                 // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *IMAP) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *IMAP) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	for _, c := range a.Commands {
+		c.Timestamp /= int64(time.Millisecond)
+	}
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var imapMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_IMAP.String()),
+		Help: Type_NC_IMAP.String() + " audit records",
+	},
+	fieldsIMAP[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *IMAP) Inc() {
+	imapMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *IMAP) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *IMAP) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *IMAP) Dst() string {
+	return a.ServerIP
+}
+
+var imapEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *IMAP) Encode() []string {
+	return filter([]string{
+		imapEncoder.Int64(fieldTimestamp, a.Timestamp),
+		imapEncoder.String(fieldClientIP, a.ClientIP),            // string
+		imapEncoder.String(fieldServerIP, a.ServerIP),            // string
+		imapEncoder.Int32(fieldClientPort, a.ClientPort),         // int32
+		imapEncoder.Int32(fieldServerPort, a.ServerPort),         // int32
+		imapEncoder.String(fieldBanner, a.Banner),                // string
+		imapEncoder.String(fieldUser, a.User),                    // string
+		imapEncoder.String(fieldPass, a.Pass),                    // string
+		imapEncoder.String(fieldMailboxes, join(a.Mailboxes...)), // []string
+		imapEncoder.Int(fieldNumCommands, len(a.Commands)),       // []*IMAPCommand
+		imapEncoder.Int(fieldNumMails, len(a.MailIDs)),           // []string
+		imapEncoder.String(fieldCommunityID, a.CommunityID),      // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *IMAP) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *IMAP) NetcapType() Type {
+	return Type_NC_IMAP
+}
@@ -48,6 +48,7 @@ var fieldsIPv4 = []string{
 	//fieldOptions,        // []*IPv4Option
 	fieldPayloadEntropy, // float64
 	fieldPayloadSize,    // int32
+	fieldCommunityID,    // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -79,6 +80,7 @@ func (i *IPv4) CSVRecord() []string {
 		//strings.Join(opts, ""),        // []*IPv4Option
 		strconv.FormatFloat(i.PayloadEntropy, 'f', 6, 64), // float64
 		formatInt32(i.PayloadSize),                        // int32
+		i.CommunityID,                                     // string
 	})
 }
 
@@ -177,6 +179,7 @@ func (i *IPv4) Inc() {
 func (i *IPv4) SetPacketContext(ctx *PacketContext) {
 	i.SrcPort = ctx.SrcPort
 	i.DstPort = ctx.DstPort
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -214,11 +217,14 @@ func (i *IPv4) Encode() []string {
 		//ipv4Encoder.String(fieldOptions, strings.Join(opts, "")),   // []*IPv4Option
 		ipv4Encoder.Float64(fieldPayloadEntropy, i.PayloadEntropy), // float64
 		ipv4Encoder.Int32(fieldPayloadSize, i.PayloadSize),         // int32
+		ipv4Encoder.String(fieldCommunityID, i.CommunityID),        // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *IPv4) Analyze() {}
+func (i *IPv4) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *IPv4) NetcapType() Type {
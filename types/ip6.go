@@ -42,6 +42,7 @@ var fieldsIPv6 = []string{
 	fieldPayloadEntropy, // float64
 	fieldPayloadSize,    // int32
 	//fieldHopByHop,       // *IPv6HopByHop
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -64,6 +65,7 @@ func (i *IPv6) CSVRecord() []string {
 		strconv.FormatFloat(i.PayloadEntropy, 'f', 6, 64), // float64
 		formatInt32(i.PayloadSize),                        // int32
 		//hop,                                               // *IPv6HopByHop
+		i.CommunityID, // string
 	})
 }
 
@@ -150,6 +152,7 @@ func (i *IPv6) Inc() {
 func (i *IPv6) SetPacketContext(ctx *PacketContext) {
 	i.SrcPort = ctx.SrcPort
 	i.DstPort = ctx.DstPort
+	i.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -180,11 +183,14 @@ func (i *IPv6) Encode() []string {
 		ipv6Encoder.Int32(fieldPayloadSize, i.PayloadSize),         // int32
 		// TODO: flatten
 		//hop,                                               // *IPv6HopByHop
+		ipv6Encoder.String(fieldCommunityID, i.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (i *IPv6) Analyze() {}
+func (i *IPv6) Analyze() {
+	analyze(i)
+}
 
 // NetcapType returns the type of the current audit record
 func (i *IPv6) NetcapType() Type {
@@ -25,8 +25,9 @@ import (
 var fieldsIPv6HopByHop = []string{
 	fieldTimestamp,
 	fieldOptions,
-	fieldSrcIP, // string
-	fieldDstIP, // string
+	fieldSrcIP,       // string
+	fieldDstIP,       // string
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -46,6 +47,7 @@ func (l *IPv6HopByHop) CSVRecord() []string {
 		strings.Join(opts, ""),
 		l.SrcIP,
 		l.DstIP,
+		l.CommunityID, // string
 	})
 }
 
@@ -95,6 +97,7 @@ func (l *IPv6HopByHop) Inc() {
 func (l *IPv6HopByHop) SetPacketContext(ctx *PacketContext) {
 	l.SrcIP = ctx.SrcIP
 	l.DstIP = ctx.DstIP
+	l.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -121,11 +124,14 @@ func (l *IPv6HopByHop) Encode() []string {
 		ip6hopEncoder.String(fieldOptions, strings.Join(opts, "")),
 		ip6hopEncoder.String(fieldSrcIP, l.SrcIP),
 		ip6hopEncoder.String(fieldDstIP, l.DstIP),
+		ip6hopEncoder.String(fieldCommunityID, l.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (l *IPv6HopByHop) Analyze() {}
+func (l *IPv6HopByHop) Analyze() {
+	analyze(l)
+}
 
 // NetcapType returns the type of the current audit record
 func (l *IPv6HopByHop) NetcapType() Type {
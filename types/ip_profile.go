@@ -117,7 +117,9 @@ func (d *IPProfile) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (d *IPProfile) Analyze() {}
+func (d *IPProfile) Analyze() {
+	analyze(d)
+}
 
 // NetcapType returns the type of the current audit record
 func (d *IPProfile) NetcapType() Type {
@@ -32,8 +32,9 @@ var fieldsIPSecAH = []string{
 	fieldReserved,
 	fieldSPI,
 	fieldSeq,
-	fieldSrcIP, // string
-	fieldDstIP, // string
+	fieldSrcIP,       // string
+	fieldDstIP,       // string
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -50,6 +51,7 @@ func (a *IPSecAH) CSVRecord() []string {
 		formatInt32(a.Seq),
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -83,6 +85,7 @@ func (a *IPSecAH) Inc() {
 func (a *IPSecAH) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -106,11 +109,14 @@ func (a *IPSecAH) Encode() []string {
 		ipsecahEncoder.Int32(fieldSeq, a.Seq),
 		ipsecahEncoder.String(fieldSrcIP, a.SrcIP),
 		ipsecahEncoder.String(fieldDstIP, a.DstIP),
+		ipsecahEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *IPSecAH) Analyze() {}
+func (a *IPSecAH) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *IPSecAH) NetcapType() Type {
@@ -28,8 +28,9 @@ var fieldsIPSecESP = []string{
 	fieldSPI,
 	fieldSeq,
 	fieldLenEncrypted,
-	fieldSrcIP, // string
-	fieldDstIP, // string
+	fieldSrcIP,       // string
+	fieldDstIP,       // string
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -46,6 +47,7 @@ func (a *IPSecESP) CSVRecord() []string {
 		formatInt32(a.LenEncrypted),
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -79,6 +81,7 @@ func (a *IPSecESP) Inc() {
 func (a *IPSecESP) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -102,11 +105,14 @@ func (a *IPSecESP) Encode() []string {
 		ipsecespEncoder.Int32(fieldLenEncrypted, a.LenEncrypted),
 		ipsecespEncoder.String(fieldSrcIP, a.SrcIP),
 		ipsecespEncoder.String(fieldDstIP, a.DstIP),
+		ipsecespEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *IPSecESP) Analyze() {}
+func (a *IPSecESP) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *IPSecESP) NetcapType() Type {
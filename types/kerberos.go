@@ -0,0 +1,180 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldResponseType         = "ResponseType"
+	fieldClientName           = "ClientName"
+	fieldRealm                = "Realm"
+	fieldServiceName          = "ServiceName"
+	fieldEncryptionTypes      = "EncryptionTypes"
+	fieldTicketEncryptionType = "TicketEncryptionType"
+	fieldReplyEncryptionType  = "ReplyEncryptionType"
+	fieldErrorCode            = "ErrorCode"
+	fieldErrorName            = "ErrorName"
+	fieldTill                 = "Till"
+	fieldRenewTill            = "RenewTill"
+	fieldPreAuthenticated     = "PreAuthenticated"
+	fieldNoPreAuthRequired    = "NoPreAuthRequired"
+	fieldWeakEncryption       = "WeakEncryption"
+)
+
+var fieldsKerberos = []string{
+	fieldTimestamp,
+	fieldClientIP,             // string
+	fieldServerIP,             // string
+	fieldClientPort,           // int32
+	fieldServerPort,           // int32
+	fieldTransportProto,       // string
+	fieldRequestType,          // string
+	fieldResponseType,         // string
+	fieldClientName,           // string
+	fieldRealm,                // string
+	fieldServiceName,          // string
+	fieldEncryptionTypes,      // []string
+	fieldTicketEncryptionType, // string
+	fieldReplyEncryptionType,  // string
+	fieldErrorCode,            // int32
+	fieldErrorName,            // string
+	fieldTill,                 // int64
+	fieldRenewTill,            // int64
+	fieldPreAuthenticated,     // bool
+	fieldNoPreAuthRequired,    // bool
+	fieldWeakEncryption,       // bool
+	fieldCommunityID,          // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *Kerberos) CSVHeader() []string {
+	return filter(fieldsKerberos)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *Kerberos) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                              // string
+		a.ServerIP,                              // string
+		formatInt32(a.ClientPort),               // int32
+		formatInt32(a.ServerPort),               // int32
+		a.TransportProto,                        // string
+		a.RequestType,                           // string
+		a.ResponseType,                          // string
+		a.ClientName,                            // string
+		a.Realm,                                 // string
+		a.ServiceName,                           // string
+		join(a.EncryptionTypes...),              // []string
+		a.TicketEncryptionType,                  // string
+		a.ReplyEncryptionType,                   // string
+		formatInt32(a.ErrorCode),                // int32
+		a.ErrorName,                             // string
+		formatTimestamp(a.Till),                 // int64
+		formatTimestamp(a.RenewTill),            // int64
+		strconv.FormatBool(a.PreAuthenticated),  // bool
+		strconv.FormatBool(a.NoPreAuthRequired), // bool
+		strconv.FormatBool(a.WeakEncryption),    // bool
+		a.CommunityID,                           // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *Kerberos) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *Kerberos) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+	a.Till /= int64(time.Millisecond)
+	a.RenewTill /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var kerberosMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_Kerberos.String()),
+		Help: Type_NC_Kerberos.String() + " audit records",
+	},
+	fieldsKerberos[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *Kerberos) Inc() {
+	kerberosMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *Kerberos) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *Kerberos) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *Kerberos) Dst() string {
+	return a.ServerIP
+}
+
+var kerberosEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *Kerberos) Encode() []string {
+	return filter([]string{
+		kerberosEncoder.Int64(fieldTimestamp, a.Timestamp),
+		kerberosEncoder.String(fieldClientIP, a.ClientIP),                         // string
+		kerberosEncoder.String(fieldServerIP, a.ServerIP),                         // string
+		kerberosEncoder.Int32(fieldClientPort, a.ClientPort),                      // int32
+		kerberosEncoder.Int32(fieldServerPort, a.ServerPort),                      // int32
+		kerberosEncoder.String(fieldTransportProto, a.TransportProto),             // string
+		kerberosEncoder.String(fieldRequestType, a.RequestType),                   // string
+		kerberosEncoder.String(fieldResponseType, a.ResponseType),                 // string
+		kerberosEncoder.String(fieldClientName, a.ClientName),                     // string
+		kerberosEncoder.String(fieldRealm, a.Realm),                               // string
+		kerberosEncoder.String(fieldServiceName, a.ServiceName),                   // string
+		kerberosEncoder.String(fieldEncryptionTypes, join(a.EncryptionTypes...)),  // []string
+		kerberosEncoder.String(fieldTicketEncryptionType, a.TicketEncryptionType), // string
+		kerberosEncoder.String(fieldReplyEncryptionType, a.ReplyEncryptionType),   // string
+		kerberosEncoder.Int32(fieldErrorCode, a.ErrorCode),                        // int32
+		kerberosEncoder.String(fieldErrorName, a.ErrorName),                       // string
+		kerberosEncoder.Int64(fieldTill, a.Till),                                  // int64
+		kerberosEncoder.Int64(fieldRenewTill, a.RenewTill),                        // int64
+		kerberosEncoder.Bool(a.PreAuthenticated),                                  // bool
+		kerberosEncoder.Bool(a.NoPreAuthRequired),                                 // bool
+		kerberosEncoder.Bool(a.WeakEncryption),                                    // bool
+		kerberosEncoder.String(fieldCommunityID, a.CommunityID),                   // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *Kerberos) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *Kerberos) NetcapType() Type {
+	return Type_NC_Kerberos
+}
@@ -43,6 +43,7 @@ var fieldsLCM = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -66,6 +67,7 @@ func (a *LCM) CSVRecord() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.CommunityID, // string
 	})
 }
 
@@ -101,6 +103,7 @@ func (a *LCM) SetPacketContext(ctx *PacketContext) {
 	a.DstIP = ctx.DstIP
 	a.SrcPort = ctx.SrcPort
 	a.DstPort = ctx.DstPort
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -131,11 +134,14 @@ func (a *LCM) Encode() []string {
 		lcmEncoder.String(fieldDstIP, a.DstIP),
 		lcmEncoder.Int32(fieldSrcPort, a.SrcPort),
 		lcmEncoder.Int32(fieldDstPort, a.DstPort),
+		lcmEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *LCM) Analyze() {}
+func (a *LCM) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *LCM) NetcapType() Type {
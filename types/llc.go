@@ -111,7 +111,9 @@ func (l *LLC) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (l *LLC) Analyze() {}
+func (l *LLC) Analyze() {
+	analyze(l)
+}
 
 // NetcapType returns the type of the current audit record
 func (l *LLC) NetcapType() Type {
@@ -118,7 +118,9 @@ func (l *LinkLayerDiscovery) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (l *LinkLayerDiscovery) Analyze() {}
+func (l *LinkLayerDiscovery) Analyze() {
+	analyze(l)
+}
 
 // NetcapType returns the type of the current audit record
 func (l *LinkLayerDiscovery) NetcapType() Type {
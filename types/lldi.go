@@ -210,7 +210,9 @@ func (l *LinkLayerDiscoveryInfo) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (l *LinkLayerDiscoveryInfo) Analyze() {}
+func (l *LinkLayerDiscoveryInfo) Analyze() {
+	analyze(l)
+}
 
 // NetcapType returns the type of the current audit record
 func (l *LinkLayerDiscoveryInfo) NetcapType() Type {
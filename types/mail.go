@@ -146,7 +146,9 @@ func (d *Mail) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (d *Mail) Analyze() {}
+func (d *Mail) Analyze() {
+	analyze(d)
+}
 
 // NetcapType returns the type of the current audit record
 func (d *Mail) NetcapType() Type {
@@ -57,6 +57,14 @@ var Metrics = []prometheus.Collector{
 	cipMetric,
 	lcmMetric,
 	pop3Metric,
+	ftpMetric,
+	imapMetric,
+	tlsMetric,
+	quicMetric,
+	smbMetric,
+	kerberosMetric,
+	certificateMetric,
+	webSocketMetric,
 	connectionsMetric,
 	connTotalSize,
 	connAppPayloadSize,
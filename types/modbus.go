@@ -44,6 +44,7 @@ var fieldsModbus = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -66,6 +67,7 @@ func (a *Modbus) CSVRecord() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.CommunityID, // string
 	})
 }
 
@@ -101,6 +103,7 @@ func (a *Modbus) SetPacketContext(ctx *PacketContext) {
 	a.DstIP = ctx.DstIP
 	a.SrcPort = ctx.SrcPort
 	a.DstPort = ctx.DstPort
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -130,11 +133,14 @@ func (a *Modbus) Encode() []string {
 		modbusEncoder.String(fieldDstIP, a.DstIP),
 		modbusEncoder.Int32(fieldSrcPort, a.SrcPort),
 		modbusEncoder.Int32(fieldDstPort, a.DstPort),
+		modbusEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *Modbus) Analyze() {}
+func (a *Modbus) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *Modbus) NetcapType() Type {
@@ -104,7 +104,9 @@ func (a *MPLS) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *MPLS) Analyze() {}
+func (a *MPLS) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *MPLS) NetcapType() Type {
@@ -114,7 +114,9 @@ func (a *NortelDiscovery) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *NortelDiscovery) Analyze() {}
+func (a *NortelDiscovery) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *NortelDiscovery) NetcapType() Type {
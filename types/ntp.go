@@ -58,6 +58,7 @@ var fieldsNTP = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -87,6 +88,7 @@ func (n *NTP) CSVRecord() []string {
 		n.DstIP,
 		formatInt32(n.SrcPort),
 		formatInt32(n.DstPort),
+		n.CommunityID, // string
 	})
 }
 
@@ -123,6 +125,7 @@ func (n *NTP) SetPacketContext(ctx *PacketContext) {
 	n.DstIP = ctx.DstIP
 	n.SrcPort = ctx.SrcPort
 	n.DstPort = ctx.DstPort
+	n.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -159,11 +162,14 @@ func (n *NTP) Encode() []string {
 		ntpEncoder.String(fieldDstIP, n.DstIP),
 		ntpEncoder.Int32(fieldSrcPort, n.SrcPort),
 		ntpEncoder.Int32(fieldDstPort, n.DstPort),
+		ntpEncoder.String(fieldCommunityID, n.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (n *NTP) Analyze() {}
+func (n *NTP) Analyze() {
+	analyze(n)
+}
 
 // NetcapType returns the type of the current audit record
 func (n *NTP) NetcapType() Type {
@@ -52,6 +52,7 @@ var fieldsOSPFv2 = []string{
 	fieldHelloV2,        // *HelloPkgV2
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -89,6 +90,7 @@ func (a *OSPFv2) CSVRecord() []string {
 		toString(a.HelloV2),           // *HelloPkgV2
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -478,6 +480,7 @@ func (a *OSPFv2) JSON() (string, error) {
 func (a *OSPFv2) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -522,11 +525,14 @@ func (a *OSPFv2) Encode() []string {
 		ospf2Encoder.String(fieldHelloV2, toString(a.HelloV2)), // *HelloPkgV2
 		ospf2Encoder.String(fieldSrcIP, a.SrcIP),
 		ospf2Encoder.String(fieldDstIP, a.DstIP),
+		ospf2Encoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *OSPFv2) Analyze() {}
+func (a *OSPFv2) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (n *OSPFv2) NetcapType() Type {
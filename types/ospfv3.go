@@ -43,6 +43,7 @@ var fieldsOSPFv3 = []string{
 	fieldLSAs,         // []*LSAheader
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -80,6 +81,7 @@ func (a *OSPFv3) CSVRecord() []string {
 		join(lsas...),               // []*LSAheader
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -137,6 +139,7 @@ func (a *OSPFv3) JSON() (string, error) {
 func (a *OSPFv3) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -182,11 +185,14 @@ func (a *OSPFv3) Encode() []string {
 		ospf3Encoder.String(fieldLSAs, join(lsas...)),         // []*LSAheader
 		ospf2Encoder.String(fieldSrcIP, a.SrcIP),
 		ospf2Encoder.String(fieldDstIP, a.DstIP),
+		ospf3Encoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *OSPFv3) Analyze() {}
+func (a *OSPFv3) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *OSPFv3) NetcapType() Type {
@@ -32,12 +32,13 @@ const (
 
 var fieldsPOP3 = []string{
 	fieldTimestamp,
-	fieldClient,    // string
-	fieldServer,    // string
-	fieldAuthToken, // string
-	fieldUser,      // string
-	fieldPass,      // string
-	fieldNumMails,  // []*Mail
+	fieldClient,      // string
+	fieldServer,      // string
+	fieldAuthToken,   // string
+	fieldUser,        // string
+	fieldPass,        // string
+	fieldNumMails,    // []*Mail
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -55,6 +56,7 @@ func (a *POP3) CSVRecord() []string {
 		a.User,                       // string
 		a.Pass,                       // string
 		strconv.Itoa(len(a.MailIDs)), // []*Mail
+		a.CommunityID,                // string
 	})
 }
 
@@ -103,17 +105,20 @@ var pop3Encoder = encoder.NewValueEncoder()
 func (a *POP3) Encode() []string {
 	return filter([]string{
 		pop3Encoder.Int64(fieldTimestamp, a.Timestamp),
-		pop3Encoder.String(fieldClientIP, a.ClientIP),   // string
-		pop3Encoder.String(fieldServerIP, a.ServerIP),   // string
-		pop3Encoder.String(fieldAuthToken, a.AuthToken), // string
-		pop3Encoder.String(fieldUser, a.User),           // string
-		pop3Encoder.String(fieldPass, a.Pass),           // string
-		pop3Encoder.Int(fieldNumMails, len(a.MailIDs)),  // []*Mail
+		pop3Encoder.String(fieldClientIP, a.ClientIP),       // string
+		pop3Encoder.String(fieldServerIP, a.ServerIP),       // string
+		pop3Encoder.String(fieldAuthToken, a.AuthToken),     // string
+		pop3Encoder.String(fieldUser, a.User),               // string
+		pop3Encoder.String(fieldPass, a.Pass),               // string
+		pop3Encoder.Int(fieldNumMails, len(a.MailIDs)),      // []*Mail
+		pop3Encoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *POP3) Analyze() {}
+func (a *POP3) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *POP3) NetcapType() Type {
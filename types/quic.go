@@ -0,0 +1,149 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldDCID        = "DCID"
+	fieldSCID        = "SCID"
+	fieldTokenLength = "TokenLength"
+	fieldTLSVersion  = "TLSVersion"
+)
+
+var fieldsQUIC = []string{
+	fieldTimestamp,
+	fieldSrcIP,       // string
+	fieldDstIP,       // string
+	fieldSrcPort,     // int32
+	fieldDstPort,     // int32
+	fieldVersion,     // string
+	fieldDCID,        // string
+	fieldSCID,        // string
+	fieldTokenLength, // int32
+	fieldSNI,         // string
+	fieldALPNs,       // []string
+	fieldTLSVersion,  // string
+	fieldJA3,         // string
+	fieldJA4,         // string
+	fieldNumPackets,  // int32
+	fieldCommunityID, // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *QUIC) CSVHeader() []string {
+	return filter(fieldsQUIC)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *QUIC) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.SrcIP,                    // string
+		a.DstIP,                    // string
+		formatInt32(a.SrcPort),     // int32
+		formatInt32(a.DstPort),     // int32
+		a.Version,                  // string
+		a.DCID,                     // string
+		a.SCID,                     // string
+		formatInt32(a.TokenLength), // int32
+		a.SNI,                      // string
+		join(a.ALPNs...),           // []string
+		a.TLSVersion,               // string
+		a.JA3,                      // string
+		a.JA4,                      // string
+		formatInt32(a.NumPackets),  // int32
+		a.CommunityID,              // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *QUIC) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *QUIC) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var quicMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_QUIC.String()),
+		Help: Type_NC_QUIC.String() + " audit records",
+	},
+	fieldsQUIC[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *QUIC) Inc() {
+	quicMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *QUIC) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *QUIC) Src() string {
+	return a.SrcIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *QUIC) Dst() string {
+	return a.DstIP
+}
+
+var quicEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *QUIC) Encode() []string {
+	return filter([]string{
+		quicEncoder.Int64(fieldTimestamp, a.Timestamp),
+		quicEncoder.String(fieldSrcIP, a.SrcIP),             // string
+		quicEncoder.String(fieldDstIP, a.DstIP),             // string
+		quicEncoder.Int32(fieldSrcPort, a.SrcPort),          // int32
+		quicEncoder.Int32(fieldDstPort, a.DstPort),          // int32
+		quicEncoder.String(fieldVersion, a.Version),         // string
+		quicEncoder.String(fieldDCID, a.DCID),               // string
+		quicEncoder.String(fieldSCID, a.SCID),               // string
+		quicEncoder.Int32(fieldTokenLength, a.TokenLength),  // int32
+		quicEncoder.String(fieldSNI, a.SNI),                 // string
+		quicEncoder.String(fieldALPNs, join(a.ALPNs...)),    // []string
+		quicEncoder.String(fieldTLSVersion, a.TLSVersion),   // string
+		quicEncoder.String(fieldJA3, a.JA3),                 // string
+		quicEncoder.String(fieldJA4, a.JA4),                 // string
+		quicEncoder.Int32(fieldNumPackets, a.NumPackets),    // int32
+		quicEncoder.String(fieldCommunityID, a.CommunityID), // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *QUIC) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *QUIC) NetcapType() Type {
+	return Type_NC_QUIC
+}
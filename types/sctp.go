@@ -32,6 +32,7 @@ var fieldsSCTP = []string{
 	fieldChecksum,
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -49,6 +50,7 @@ func (s *SCTP) CSVRecord() []string {
 		strconv.FormatUint(uint64(s.Checksum), 10),
 		s.SrcIP,
 		s.DstIP,
+		s.CommunityID, // string
 	})
 }
 
@@ -82,6 +84,7 @@ func (s *SCTP) Inc() {
 func (s *SCTP) SetPacketContext(ctx *PacketContext) {
 	s.SrcIP = ctx.SrcIP
 	s.DstIP = ctx.DstIP
+	s.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -106,11 +109,14 @@ func (s *SCTP) Encode() []string {
 		sctpEncoder.Uint32(fieldChecksum, s.Checksum),
 		sctpEncoder.String(fieldSrcIP, s.SrcIP),
 		sctpEncoder.String(fieldDstIP, s.DstIP),
+		sctpEncoder.String(fieldCommunityID, s.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (s *SCTP) Analyze() {}
+func (s *SCTP) Analyze() {
+	analyze(s)
+}
 
 // NetcapType returns the type of the current audit record
 func (s *SCTP) NetcapType() Type {
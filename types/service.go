@@ -172,7 +172,9 @@ func (a *Service) Encode() []string {
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (a *Service) Analyze() {}
+func (a *Service) Analyze() {
+	analyze(a)
+}
 
 // NetcapType returns the type of the current audit record
 func (a *Service) NetcapType() Type {
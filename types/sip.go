@@ -40,6 +40,7 @@ var fieldsSIP = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -61,6 +62,7 @@ func (s *SIP) CSVRecord() []string {
 		s.DstIP,
 		formatInt32(s.SrcPort),
 		formatInt32(s.DstPort),
+		s.CommunityID, // string
 	})
 }
 
@@ -96,6 +98,7 @@ func (s *SIP) SetPacketContext(ctx *PacketContext) {
 	s.DstIP = ctx.DstIP
 	s.SrcPort = ctx.SrcPort
 	s.DstPort = ctx.DstPort
+	s.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -124,11 +127,14 @@ func (s *SIP) Encode() []string {
 		sipEncoder.String(fieldDstIP, s.DstIP),
 		sipEncoder.Int32(fieldSrcPort, s.SrcPort),
 		sipEncoder.Int32(fieldDstPort, s.DstPort),
+		sipEncoder.String(fieldCommunityID, s.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
-func (s *SIP) Analyze() {}
+func (s *SIP) Analyze() {
+	analyze(s)
+}
 
 // NetcapType returns the type of the current audit record
 func (s *SIP) NetcapType() Type {
@@ -0,0 +1,150 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldDialect     = "Dialect"
+	fieldDomain      = "Domain"
+	fieldWorkstation = "Workstation"
+	fieldTrees       = "Trees"
+	fieldNumFiles    = "NumFiles"
+	fieldEncrypted   = "Encrypted"
+)
+
+var fieldsSMB = []string{
+	fieldTimestamp,
+	fieldClientIP,    // string
+	fieldServerIP,    // string
+	fieldClientPort,  // int32
+	fieldServerPort,  // int32
+	fieldDialect,     // string
+	fieldUser,        // string
+	fieldDomain,      // string
+	fieldWorkstation, // string
+	fieldTrees,       // []string
+	fieldNumFiles,    // []*SMBFile
+	fieldNumCommands, // int32
+	fieldEncrypted,   // bool
+	fieldCommunityID, // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *SMB) CSVHeader() []string {
+	return filter(fieldsSMB)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *SMB) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                      // string
+		a.ServerIP,                      // string
+		formatInt32(a.ClientPort),       // int32
+		formatInt32(a.ServerPort),       // int32
+		a.Dialect,                       // string
+		a.User,                          // string
+		a.Domain,                        // string
+		a.Workstation,                   // string
+		join(a.Trees...),                // []string
+		strconv.Itoa(len(a.Files)),      // []*SMBFile
+		formatInt32(a.NumCommands),      // int32
+		strconv.FormatBool(a.Encrypted), // bool
+		a.CommunityID,                   // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *SMB) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *SMB) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	for _, f := range a.Files {
+		f.Timestamp /= int64(time.Millisecond)
+	}
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var smbMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_SMB.String()),
+		Help: Type_NC_SMB.String() + " audit records",
+	},
+	fieldsSMB[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *SMB) Inc() {
+	smbMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *SMB) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *SMB) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *SMB) Dst() string {
+	return a.ServerIP
+}
+
+var smbEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *SMB) Encode() []string {
+	return filter([]string{
+		smbEncoder.Int64(fieldTimestamp, a.Timestamp),
+		smbEncoder.String(fieldClientIP, a.ClientIP),       // string
+		smbEncoder.String(fieldServerIP, a.ServerIP),       // string
+		smbEncoder.Int32(fieldClientPort, a.ClientPort),    // int32
+		smbEncoder.Int32(fieldServerPort, a.ServerPort),    // int32
+		smbEncoder.String(fieldDialect, a.Dialect),         // string
+		smbEncoder.String(fieldUser, a.User),               // string
+		smbEncoder.String(fieldDomain, a.Domain),           // string
+		smbEncoder.String(fieldWorkstation, a.Workstation), // string
+		smbEncoder.String(fieldTrees, join(a.Trees...)),    // []string
+		smbEncoder.Int(fieldNumFiles, len(a.Files)),        // []*SMBFile
+		smbEncoder.Int32(fieldNumCommands, a.NumCommands),  // int32
+		smbEncoder.Bool(a.Encrypted),                       // bool
+		smbEncoder.String(fieldCommunityID, a.CommunityID), // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *SMB) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *SMB) NetcapType() Type {
+	return Type_NC_SMB
+}
@@ -38,6 +38,7 @@ var fieldsSMTP = []string{
 	fieldDstIP,
 	fieldSrcPort,
 	fieldDstPort,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -57,6 +58,7 @@ func (a *SMTP) CSVRecord() []string {
 		a.DstIP,
 		formatInt32(a.SrcPort),
 		formatInt32(a.DstPort),
+		a.CommunityID, // string
 	})
 }
 
@@ -112,6 +114,7 @@ func (a *SMTP) SetPacketContext(ctx *PacketContext) {
 	a.DstIP = ctx.DstIP
 	a.SrcPort = ctx.SrcPort
 	a.DstPort = ctx.DstPort
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -138,11 +141,13 @@ func (a *SMTP) Encode() []string {
 		smtpEncoder.String(fieldDstIP, a.DstIP),
 		smtpEncoder.Int32(fieldSrcPort, a.SrcPort),
 		smtpEncoder.Int32(fieldDstPort, a.DstPort),
+		smtpEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *SMTP) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
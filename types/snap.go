@@ -97,6 +97,7 @@ func (s *SNAP) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (s *SNAP) Analyze() {
+	analyze(s)
 }
 
 // NetcapType returns the type of the current audit record
@@ -42,6 +42,7 @@ var fieldsSoftware = []string{
 	fieldFlows,
 	fieldSourceData,
 	fieldNotes,
+	fieldCommunityIDs, // []string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -63,6 +64,7 @@ func (a *Software) CSVRecord() []string {
 		join(a.Flows...),
 		a.SourceData,
 		a.Notes,
+		join(a.CommunityIDs...), // []string
 	})
 }
 
@@ -145,11 +147,13 @@ func (a *Software) Encode() []string {
 		softwareEncoder.String(fieldFlows, join(a.Flows...)),
 		softwareEncoder.String(fieldSourceData, a.SourceData),
 		softwareEncoder.String(fieldNotes, a.Notes),
+		softwareEncoder.String(fieldCommunityIDs, join(a.CommunityIDs...)), // []string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *Software) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
@@ -28,6 +28,7 @@ var fieldsSSH = []string{
 	fieldHASSH,
 	fieldFlow,
 	fieldNotes,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -42,6 +43,7 @@ func (a *SSH) CSVRecord() []string {
 		a.HASSH,
 		a.Flow,
 		a.Notes,
+		a.CommunityID, // string
 	})
 }
 
@@ -107,12 +109,13 @@ func (a *SSH) Encode() []string {
 		sshEncoder.String(fieldHASSH, a.HASSH),
 		sshEncoder.String(fieldFlow, a.Flow),
 		sshEncoder.String(fieldNotes, a.Notes),
+		sshEncoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *SSH) Analyze() {
-
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
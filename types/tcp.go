@@ -65,6 +65,7 @@ var fieldsTCP = []string{
 	fieldPayloadSize,
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -98,6 +99,7 @@ func (t *TCP) CSVRecord() []string {
 		formatInt32(t.PayloadSize),                        // int32
 		t.SrcIP,
 		t.DstIP,
+		t.CommunityID, // string
 	})
 }
 
@@ -211,6 +213,7 @@ func (t *TCP) Inc() {
 func (t *TCP) SetPacketContext(ctx *PacketContext) {
 	t.SrcIP = ctx.SrcIP
 	t.DstIP = ctx.DstIP
+	t.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -251,11 +254,13 @@ func (t *TCP) Encode() []string {
 		tcpEncoder.Int32(fieldPayloadSize, t.PayloadSize),         // int32
 		tcpEncoder.Int64(fieldSrcIP, ipToInt64(t.SrcIP)),
 		tcpEncoder.Int64(fieldDstIP, ipToInt64(t.DstIP)),
+		tcpEncoder.String(fieldCommunityID, t.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (t *TCP) Analyze() {
+	analyze(t)
 }
 
 // NetcapType returns the type of the current audit record
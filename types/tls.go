@@ -0,0 +1,180 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldSelectedALPN      = "SelectedALPN"
+	fieldSessionTicket     = "SessionTicket"
+	fieldResumed           = "Resumed"
+	fieldJA3               = "JA3"
+	fieldJA3S              = "JA3S"
+	fieldJA4               = "JA4"
+	fieldJA4S              = "JA4S"
+	fieldNumCertificates   = "NumCertificates"
+	fieldNumAlerts         = "NumAlerts"
+	fieldHandshakeComplete = "HandshakeComplete"
+)
+
+var fieldsTLS = []string{
+	fieldTimestamp,
+	fieldClientIP,          // string
+	fieldServerIP,          // string
+	fieldClientPort,        // int32
+	fieldServerPort,        // int32
+	fieldVersion,           // string
+	fieldSNI,               // string
+	fieldALPNs,             // []string
+	fieldSelectedALPN,      // string
+	fieldCipherSuite,       // string
+	fieldSessionID,         // string
+	fieldSessionTicket,     // bool
+	fieldResumed,           // bool
+	fieldJA3,               // string
+	fieldJA3S,              // string
+	fieldJA4,               // string
+	fieldJA4S,              // string
+	fieldNumCertificates,   // []*TLSCertificate
+	fieldNumAlerts,         // []*TLSAlert
+	fieldHandshakeComplete, // bool
+	fieldCommunityID,       // string
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *TLS) CSVHeader() []string {
+	return filter(fieldsTLS)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *TLS) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                              // string
+		a.ServerIP,                              // string
+		formatInt32(a.ClientPort),               // int32
+		formatInt32(a.ServerPort),               // int32
+		a.Version,                               // string
+		a.SNI,                                   // string
+		join(a.ALPNs...),                        // []string
+		a.SelectedALPN,                          // string
+		a.CipherSuite,                           // string
+		a.SessionID,                             // string
+		strconv.FormatBool(a.SessionTicket),     // bool
+		strconv.FormatBool(a.Resumed),           // bool
+		a.JA3,                                   // string
+		a.JA3S,                                  // string
+		a.JA4,                                   // string
+		a.JA4S,                                  // string
+		strconv.Itoa(len(a.Certificates)),       // []*TLSCertificate
+		strconv.Itoa(len(a.Alerts)),             // []*TLSAlert
+		strconv.FormatBool(a.HandshakeComplete), // bool
+		a.CommunityID,                           // string
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *TLS) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *TLS) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	for _, c := range a.Certificates {
+		c.NotBefore /= int64(time.Millisecond)
+		c.NotAfter /= int64(time.Millisecond)
+	}
+
+	for _, al := range a.Alerts {
+		al.Timestamp /= int64(time.Millisecond)
+	}
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var tlsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_TLS.String()),
+		Help: Type_NC_TLS.String() + " audit records",
+	},
+	fieldsTLS[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *TLS) Inc() {
+	tlsMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *TLS) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *TLS) Src() string {
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *TLS) Dst() string {
+	return a.ServerIP
+}
+
+var tlsEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *TLS) Encode() []string {
+	return filter([]string{
+		tlsEncoder.Int64(fieldTimestamp, a.Timestamp),
+		tlsEncoder.String(fieldClientIP, a.ClientIP),              // string
+		tlsEncoder.String(fieldServerIP, a.ServerIP),              // string
+		tlsEncoder.Int32(fieldClientPort, a.ClientPort),           // int32
+		tlsEncoder.Int32(fieldServerPort, a.ServerPort),           // int32
+		tlsEncoder.String(fieldVersion, a.Version),                // string
+		tlsEncoder.String(fieldSNI, a.SNI),                        // string
+		tlsEncoder.String(fieldALPNs, join(a.ALPNs...)),           // []string
+		tlsEncoder.String(fieldSelectedALPN, a.SelectedALPN),      // string
+		tlsEncoder.String(fieldCipherSuite, a.CipherSuite),        // string
+		tlsEncoder.String(fieldSessionID, a.SessionID),            // string
+		tlsEncoder.Bool(a.SessionTicket),                          // bool
+		tlsEncoder.Bool(a.Resumed),                                // bool
+		tlsEncoder.String(fieldJA3, a.JA3),                        // string
+		tlsEncoder.String(fieldJA3S, a.JA3S),                      // string
+		tlsEncoder.String(fieldJA4, a.JA4),                        // string
+		tlsEncoder.String(fieldJA4S, a.JA4S),                      // string
+		tlsEncoder.Int(fieldNumCertificates, len(a.Certificates)), // []*TLSCertificate
+		tlsEncoder.Int(fieldNumAlerts, len(a.Alerts)),             // []*TLSAlert
+		tlsEncoder.Bool(a.HandshakeComplete),                      // bool
+		tlsEncoder.String(fieldCommunityID, a.CommunityID),        // string
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *TLS) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *TLS) NetcapType() Type {
+	return Type_NC_TLS
+}
@@ -182,6 +182,7 @@ func (t *TLSClientHello) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (t *TLSClientHello) Analyze() {
+	analyze(t)
 }
 
 // NetcapType returns the type of the current audit record
@@ -186,6 +186,7 @@ func (t *TLSServerHello) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (t *TLSServerHello) Analyze() {
+	analyze(t)
 }
 
 // NetcapType returns the type of the current audit record
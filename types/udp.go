@@ -33,6 +33,7 @@ var fieldsUDP = []string{
 	//fieldPayload,
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -52,6 +53,7 @@ func (u *UDP) CSVRecord() []string {
 		formatInt32(u.PayloadSize),                        // int32
 		u.SrcIP,
 		u.DstIP,
+		u.CommunityID, // string
 	})
 }
 
@@ -119,6 +121,7 @@ func (u *UDP) Inc() {
 func (u *UDP) SetPacketContext(ctx *PacketContext) {
 	u.SrcIP = ctx.SrcIP
 	u.DstIP = ctx.DstIP
+	u.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -145,12 +148,13 @@ func (u *UDP) Encode() []string {
 		udpEncoder.Int32(fieldPayloadSize, u.PayloadSize),         // int32
 		udpEncoder.String(fieldSrcIP, u.SrcIP),
 		udpEncoder.String(fieldDstIP, u.DstIP),
+		udpEncoder.String(fieldCommunityID, u.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (u *UDP) Analyze() {
-
+	analyze(u)
 }
 
 // NetcapType returns the type of the current audit record
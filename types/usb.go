@@ -164,6 +164,7 @@ func (u *USB) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (u *USB) Analyze() {
+	analyze(u)
 }
 
 // NetcapType returns the type of the current audit record
@@ -110,6 +110,7 @@ func (a *USBRequestBlockSetup) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *USBRequestBlockSetup) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
@@ -42,6 +42,7 @@ var fieldsVRRPv2 = []string{
 	fieldIPAddresses,  // []string
 	fieldSrcIP,
 	fieldDstIP,
+	fieldCommunityID, // string
 }
 
 // CSVHeader returns the CSV header for the audit record.
@@ -64,6 +65,7 @@ func (a *VRRPv2) CSVRecord() []string {
 		join(a.IPAddress...),        // []string
 		a.SrcIP,
 		a.DstIP,
+		a.CommunityID, // string
 	})
 }
 
@@ -97,6 +99,7 @@ func (a *VRRPv2) Inc() {
 func (a *VRRPv2) SetPacketContext(ctx *PacketContext) {
 	a.SrcIP = ctx.SrcIP
 	a.DstIP = ctx.DstIP
+	a.CommunityID = ctx.CommunityID
 }
 
 // Src returns the source address of the audit record.
@@ -126,11 +129,13 @@ func (a *VRRPv2) Encode() []string {
 		vrrpv2Encoder.String(fieldIPAddresses, join(a.IPAddress...)), // []string
 		vrrpv2Encoder.String(fieldSrcIP, a.SrcIP),
 		vrrpv2Encoder.String(fieldDstIP, a.DstIP),
+		vrrpv2Encoder.String(fieldCommunityID, a.CommunityID), // string
 	})
 }
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *VRRPv2) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
@@ -120,6 +120,7 @@ func (a *Vulnerability) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *Vulnerability) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
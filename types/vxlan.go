@@ -115,6 +115,7 @@ func (a *VXLAN) Encode() []string {
 
 // Analyze will invoke the configured analyzer for the audit record and return a score.
 func (a *VXLAN) Analyze() {
+	analyze(a)
 }
 
 // NetcapType returns the type of the current audit record
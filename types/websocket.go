@@ -0,0 +1,163 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dreadl0ck/netcap/encoder"
+)
+
+const (
+	fieldFromServer = "FromServer"
+	fieldOpcode     = "Opcode"
+	fieldNumFrames  = "NumFrames"
+	fieldCompressed = "Compressed"
+	fieldMasked     = "Masked"
+	fieldCloseCode  = "CloseCode"
+)
+
+var fieldsWebSocket = []string{
+	fieldTimestamp,
+	fieldClientIP,   // string
+	fieldServerIP,   // string
+	fieldClientPort, // int32
+	fieldServerPort, // int32
+	fieldHost,       // string
+	fieldURL,        // string
+	fieldProtocol,   // string
+	fieldFromServer, // bool
+	fieldOpcode,     // string
+	fieldLength,     // int64
+	fieldNumFrames,  // int32
+	fieldCompressed, // bool
+	fieldMasked,     // bool
+	fieldCloseCode,  // int32
+	fieldPayload,    // string
+	fieldCommunityID,
+}
+
+// CSVHeader returns the CSV header for the audit record.
+func (a *WebSocket) CSVHeader() []string {
+	return filter(fieldsWebSocket)
+}
+
+// CSVRecord returns the CSV record for the audit record.
+func (a *WebSocket) CSVRecord() []string {
+	return filter([]string{
+		formatTimestamp(a.Timestamp),
+		a.ClientIP,                       // string
+		a.ServerIP,                       // string
+		formatInt32(a.ClientPort),        // int32
+		formatInt32(a.ServerPort),        // int32
+		a.Host,                           // string
+		a.URL,                            // string
+		a.Protocol,                       // string
+		strconv.FormatBool(a.FromServer), // bool
+		a.Opcode,                         // string
+		formatInt64(a.Length),            // int64
+		formatInt32(a.NumFrames),         // int32
+		strconv.FormatBool(a.Compressed), // bool
+		strconv.FormatBool(a.Masked),     // bool
+		formatInt32(a.CloseCode),         // int32
+		a.Payload,                        // string
+		a.CommunityID,
+	})
+}
+
+// Time returns the timestamp associated with the audit record.
+func (a *WebSocket) Time() int64 {
+	return a.Timestamp
+}
+
+// JSON returns the JSON representation of the audit record.
+func (a *WebSocket) JSON() (string, error) {
+	// convert unix timestamp from nano to millisecond precision for elastic
+	a.Timestamp /= int64(time.Millisecond)
+
+	return jsonMarshaler.MarshalToString(a)
+}
+
+var webSocketMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: strings.ToLower(Type_NC_WebSocket.String()),
+		Help: Type_NC_WebSocket.String() + " audit records",
+	},
+	fieldsWebSocket[1:],
+)
+
+// Inc increments the metrics for the audit record.
+func (a *WebSocket) Inc() {
+	webSocketMetric.WithLabelValues(a.CSVRecord()[1:]...).Inc()
+}
+
+// SetPacketContext sets the associated packet context for the audit record.
+func (a *WebSocket) SetPacketContext(*PacketContext) {}
+
+// Src returns the source address of the audit record.
+func (a *WebSocket) Src() string {
+	if a.FromServer {
+		return a.ServerIP
+	}
+
+	return a.ClientIP
+}
+
+// Dst returns the destination address of the audit record.
+func (a *WebSocket) Dst() string {
+	if a.FromServer {
+		return a.ClientIP
+	}
+
+	return a.ServerIP
+}
+
+var webSocketEncoder = encoder.NewValueEncoder()
+
+// Encode will encode categorical values and normalize according to configuration
+func (a *WebSocket) Encode() []string {
+	return filter([]string{
+		webSocketEncoder.Int64(fieldTimestamp, a.Timestamp),
+		webSocketEncoder.String(fieldClientIP, a.ClientIP),    // string
+		webSocketEncoder.String(fieldServerIP, a.ServerIP),    // string
+		webSocketEncoder.Int32(fieldClientPort, a.ClientPort), // int32
+		webSocketEncoder.Int32(fieldServerPort, a.ServerPort), // int32
+		webSocketEncoder.String(fieldHost, a.Host),            // string
+		webSocketEncoder.String(fieldURL, a.URL),              // string
+		webSocketEncoder.String(fieldProtocol, a.Protocol),    // string
+		webSocketEncoder.Bool(a.FromServer),                   // bool
+		webSocketEncoder.String(fieldOpcode, a.Opcode),        // string
+		webSocketEncoder.Int64(fieldLength, a.Length),         // int64
+		webSocketEncoder.Int32(fieldNumFrames, a.NumFrames),   // int32
+		webSocketEncoder.Bool(a.Compressed),                   // bool
+		webSocketEncoder.Bool(a.Masked),                       // bool
+		webSocketEncoder.Int32(fieldCloseCode, a.CloseCode),   // int32
+		webSocketEncoder.String(fieldPayload, a.Payload),      // string
+		webSocketEncoder.String(fieldCommunityID, a.CommunityID),
+	})
+}
+
+// Analyze will invoke the configured analyzer for the audit record and return a score.
+func (a *WebSocket) Analyze() {
+	analyze(a)
+}
+
+// NetcapType returns the type of the current audit record
+func (a *WebSocket) NetcapType() Type {
+	return Type_NC_WebSocket
+}
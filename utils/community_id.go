@@ -0,0 +1,199 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+/*
+ * Community ID flow hashing, see https://github.com/corelight/community-id-spec
+ */
+
+const (
+	communityIDVersion = "1:"
+
+	// CommunityIDSeed is the seed for the hash, it must match the seed used by other tools to correlate flows.
+	CommunityIDSeed uint16 = 0
+
+	protoICMPv4 = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+	protoSCTP   = 132
+)
+
+// ICMP message types and the type of the corresponding reply or request.
+// Messages without a counterpart are one way, and their endpoints are not ordered.
+var (
+	icmpv4Counterparts = map[uint8]uint8{
+		layers.ICMPv4TypeEchoRequest:         layers.ICMPv4TypeEchoReply,
+		layers.ICMPv4TypeEchoReply:           layers.ICMPv4TypeEchoRequest,
+		layers.ICMPv4TypeTimestampRequest:    layers.ICMPv4TypeTimestampReply,
+		layers.ICMPv4TypeTimestampReply:      layers.ICMPv4TypeTimestampRequest,
+		layers.ICMPv4TypeInfoRequest:         layers.ICMPv4TypeInfoReply,
+		layers.ICMPv4TypeInfoReply:           layers.ICMPv4TypeInfoRequest,
+		layers.ICMPv4TypeRouterSolicitation:  layers.ICMPv4TypeRouterAdvertisement,
+		layers.ICMPv4TypeRouterAdvertisement: layers.ICMPv4TypeRouterSolicitation,
+		layers.ICMPv4TypeAddressMaskRequest:  layers.ICMPv4TypeAddressMaskReply,
+		layers.ICMPv4TypeAddressMaskReply:    layers.ICMPv4TypeAddressMaskRequest,
+	}
+	icmpv6Counterparts = map[uint8]uint8{
+		layers.ICMPv6TypeEchoRequest:                         layers.ICMPv6TypeEchoReply,
+		layers.ICMPv6TypeEchoReply:                           layers.ICMPv6TypeEchoRequest,
+		layers.ICMPv6TypeMLDv1MulticastListenerQueryMessage:  layers.ICMPv6TypeMLDv1MulticastListenerReportMessage,
+		layers.ICMPv6TypeMLDv1MulticastListenerReportMessage: layers.ICMPv6TypeMLDv1MulticastListenerQueryMessage,
+		layers.ICMPv6TypeRouterSolicitation:                  layers.ICMPv6TypeRouterAdvertisement,
+		layers.ICMPv6TypeRouterAdvertisement:                 layers.ICMPv6TypeRouterSolicitation,
+		layers.ICMPv6TypeNeighborSolicitation:                layers.ICMPv6TypeNeighborAdvertisement,
+		layers.ICMPv6TypeNeighborAdvertisement:               layers.ICMPv6TypeNeighborSolicitation,
+		// ICMP node information query and response
+		139: 140,
+		140: 139,
+		// home agent address discovery request and reply
+		144: 145,
+		145: 144,
+	}
+)
+
+// CommunityID computes the Community ID of a flow.
+// The ID is the same for both directions of a flow, if the protocol does not use ports they are ignored.
+func CommunityID(srcIP, dstIP net.IP, proto uint8, srcPort, dstPort uint16) string {
+	// use the 4 byte representation for IPv4 addresses
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		srcIP, dstIP = src4, dst4
+	}
+
+	if len(srcIP) == 0 || len(srcIP) != len(dstIP) {
+		return ""
+	}
+
+	oneWay := false
+
+	switch proto {
+	case protoICMPv4:
+		dstPort, oneWay = icmpPorts(icmpv4Counterparts, srcPort, dstPort)
+	case protoICMPv6:
+		dstPort, oneWay = icmpPorts(icmpv6Counterparts, srcPort, dstPort)
+	}
+
+	// order the endpoints, so that both directions produce the same hash
+	if !oneWay {
+		if c := bytes.Compare(srcIP, dstIP); c > 0 || (c == 0 && srcPort > dstPort) {
+			srcIP, dstIP = dstIP, srcIP
+			srcPort, dstPort = dstPort, srcPort
+		}
+	}
+
+	var (
+		h   = sha1.New()
+		buf = make([]byte, 2)
+	)
+
+	binary.BigEndian.PutUint16(buf, CommunityIDSeed)
+	h.Write(buf)
+	h.Write(srcIP)
+	h.Write(dstIP)
+	h.Write([]byte{proto, 0})
+
+	switch proto {
+	case protoICMPv4, protoTCP, protoUDP, protoICMPv6, protoSCTP:
+		binary.BigEndian.PutUint16(buf, srcPort)
+		h.Write(buf)
+		binary.BigEndian.PutUint16(buf, dstPort)
+		h.Write(buf)
+	}
+
+	return communityIDVersion + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// icmpPorts returns the value used as destination port for an ICMP message,
+// the source port is the ICMP type and the destination port the ICMP code.
+func icmpPorts(counterparts map[uint8]uint8, typ, code uint16) (port uint16, oneWay bool) {
+	if c, ok := counterparts[uint8(typ)]; ok {
+		return uint16(c), false
+	}
+
+	return code, true
+}
+
+// CommunityIDFromFlows computes the Community ID for a TCP, UDP or SCTP flow.
+func CommunityIDFromFlows(network, transport gopacket.Flow) string {
+	var proto uint8
+
+	switch transport.EndpointType() {
+	case layers.EndpointTCPPort:
+		proto = protoTCP
+	case layers.EndpointUDPPort:
+		proto = protoUDP
+	case layers.EndpointSCTPPort:
+		proto = protoSCTP
+	default:
+		return ""
+	}
+
+	src, dst := transport.Endpoints()
+
+	return CommunityID(
+		network.Src().Raw(),
+		network.Dst().Raw(),
+		proto,
+		binary.BigEndian.Uint16(src.Raw()),
+		binary.BigEndian.Uint16(dst.Raw()),
+	)
+}
+
+// CommunityIDFromPacket computes the Community ID for the flow of a packet.
+// An empty string is returned for packets without an IP layer.
+func CommunityIDFromPacket(p gopacket.Packet) string {
+	var (
+		proto            uint8
+		srcPort, dstPort uint16
+	)
+
+	switch nl := p.NetworkLayer().(type) {
+	case *layers.IPv4:
+		proto = uint8(nl.Protocol)
+	case *layers.IPv6:
+		proto = uint8(nl.NextHeader)
+	default:
+		return ""
+	}
+
+	switch l := p.TransportLayer().(type) {
+	case *layers.TCP:
+		proto, srcPort, dstPort = protoTCP, uint16(l.SrcPort), uint16(l.DstPort)
+	case *layers.UDP:
+		proto, srcPort, dstPort = protoUDP, uint16(l.SrcPort), uint16(l.DstPort)
+	case *layers.SCTP:
+		proto, srcPort, dstPort = protoSCTP, uint16(l.SrcPort), uint16(l.DstPort)
+	default:
+		if l, ok := p.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); ok {
+			proto, srcPort, dstPort = protoICMPv4, uint16(l.TypeCode.Type()), uint16(l.TypeCode.Code())
+		} else if l, ok := p.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); ok {
+			proto, srcPort, dstPort = protoICMPv6, uint16(l.TypeCode.Type()), uint16(l.TypeCode.Code())
+		}
+	}
+
+	flow := p.NetworkLayer().NetworkFlow()
+
+	return CommunityID(flow.Src().Raw(), flow.Dst().Raw(), proto, srcPort, dstPort)
+}
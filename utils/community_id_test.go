@@ -0,0 +1,85 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dreadl0ck/gopacket"
+	"github.com/dreadl0ck/gopacket/layers"
+)
+
+func TestCommunityID(t *testing.T) {
+	tests := []struct {
+		name             string
+		src, dst         string
+		proto            uint8
+		srcPort, dstPort uint16
+		expected         string
+	}{
+		{"tcp", "128.232.110.120", "66.35.250.204", protoTCP, 34855, 80, "1:LQU9qZlK+B5F3KDmev6m5PMibrg="},
+		{"tcp reverse", "66.35.250.204", "128.232.110.120", protoTCP, 80, 34855, "1:LQU9qZlK+B5F3KDmev6m5PMibrg="},
+		{"udp", "192.168.1.52", "8.8.8.8", protoUDP, 54585, 53, "1:d/FP5EW3wiY1vCndhwleRRKHowQ="},
+		{"tcp ipv6", "fe80::200:86ff:fe05:80da", "fe80::260:97ff:fe07:69ea", protoTCP, 1020, 22, "1:RjhEHTGuRTRfdnFcTNMvajQgNRY="},
+		{"icmp echo request", "192.168.0.89", "192.168.0.1", protoICMPv4, 8, 0, "1:X0snYXpgwiv9TZtqg64sgzUn6Dk="},
+		{"icmp echo reply", "192.168.0.1", "192.168.0.89", protoICMPv4, 0, 0, "1:X0snYXpgwiv9TZtqg64sgzUn6Dk="},
+		{"icmp unreachable", "192.168.0.1", "192.168.0.89", protoICMPv4, 3, 1, "1:o3zT92uTgnRWv/YrDVeXcWumpC4="},
+		{"icmp unreachable reverse", "192.168.0.89", "192.168.0.1", protoICMPv4, 3, 1, "1:LXCfhMUFbYXe+CEMrQuZCbiLY3o="},
+		{"gre", "10.0.0.1", "10.0.0.2", 47, 0, 0, "1:+KlEHDT0vJgzs/eNmzHq0aSpRYw="},
+	}
+
+	for _, test := range tests {
+		id := CommunityID(net.ParseIP(test.src), net.ParseIP(test.dst), test.proto, test.srcPort, test.dstPort)
+		if id != test.expected {
+			t.Fatal(test.name, ": expected", test.expected, "got", id)
+		}
+	}
+}
+
+func TestCommunityIDFromPacket(t *testing.T) {
+	var (
+		ip = &layers.IPv4{
+			Version:  4,
+			TTL:      64,
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    net.IP{128, 232, 110, 120},
+			DstIP:    net.IP{66, 35, 250, 204},
+		}
+		tcp = &layers.TCP{
+			SrcPort: 34855,
+			DstPort: 80,
+			SYN:     true,
+		}
+		buf = gopacket.NewSerializeBuffer()
+	)
+
+	if err := tcp.SetNetworkLayerForChecksum(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, ip, tcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+	if id := CommunityIDFromPacket(p); id != "1:LQU9qZlK+B5F3KDmev6m5PMibrg=" {
+		t.Fatal("unexpected community id from packet:", id)
+	}
+
+	if id := CommunityIDFromFlows(p.NetworkLayer().NetworkFlow(), p.TransportLayer().TransportFlow()); id != "1:LQU9qZlK+B5F3KDmev6m5PMibrg=" {
+		t.Fatal("unexpected community id from flows:", id)
+	}
+}
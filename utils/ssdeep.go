@@ -0,0 +1,274 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+ * ssdeep context triggered piecewise hashing, see https://ssdeep-project.github.io/ssdeep
+ */
+
+const (
+	ssdeepRollingWindow = 7
+	ssdeepMinBlockSize  = 3
+	ssdeepSpamSumLength = 64
+	ssdeepHashPrime     = 0x01000193
+	ssdeepHashInit      = 0x28021967
+	ssdeepB64           = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+var errInvalidSSDEEP = errors.New("invalid ssdeep hash")
+
+// ssdeepRollingHash is the rolling hash over the last bytes of the input,
+// that is used to determine the boundaries of the hashed pieces.
+type ssdeepRollingHash struct {
+	window     [ssdeepRollingWindow]byte
+	h1, h2, h3 uint32
+	n          uint32
+}
+
+func (r *ssdeepRollingHash) update(c byte) {
+	r.h2 -= r.h1
+	r.h2 += ssdeepRollingWindow * uint32(c)
+
+	r.h1 += uint32(c)
+	r.h1 -= uint32(r.window[r.n%ssdeepRollingWindow])
+
+	r.window[r.n%ssdeepRollingWindow] = c
+	r.n++
+
+	r.h3 <<= 5
+	r.h3 ^= uint32(c)
+}
+
+func (r *ssdeepRollingHash) sum() uint32 {
+	return r.h1 + r.h2 + r.h3
+}
+
+// SSDEEP computes the ssdeep fuzzy hash of the data.
+// The hash has the format blocksize:hash:hash, and can be compared with SSDEEPCompare.
+func SSDEEP(data []byte) string {
+	blockSize := uint32(ssdeepMinBlockSize)
+	for blockSize*ssdeepSpamSumLength < uint32(len(data)) {
+		blockSize *= 2
+	}
+
+	for {
+		var (
+			roll    ssdeepRollingHash
+			h1      uint32 = ssdeepHashInit
+			h2      uint32 = ssdeepHashInit
+			digest1        = make([]byte, 0, ssdeepSpamSumLength)
+			digest2        = make([]byte, 0, ssdeepSpamSumLength/2)
+		)
+
+		for _, c := range data {
+			roll.update(c)
+
+			h1 = (h1 * ssdeepHashPrime) ^ uint32(c)
+			h2 = (h2 * ssdeepHashPrime) ^ uint32(c)
+
+			rh := roll.sum()
+
+			// once a digest is full, the remaining data is hashed into its last character
+			if rh%blockSize == blockSize-1 && len(digest1) < ssdeepSpamSumLength-1 {
+				digest1 = append(digest1, ssdeepB64[h1%64])
+				h1 = ssdeepHashInit
+			}
+
+			if rh%(blockSize*2) == blockSize*2-1 && len(digest2) < ssdeepSpamSumLength/2-1 {
+				digest2 = append(digest2, ssdeepB64[h2%64])
+				h2 = ssdeepHashInit
+			}
+		}
+
+		// retry with a smaller block size if the digest is too short
+		if blockSize > ssdeepMinBlockSize && len(digest1) < ssdeepSpamSumLength/2 {
+			blockSize /= 2
+
+			continue
+		}
+
+		if roll.sum() != 0 {
+			digest1 = append(digest1, ssdeepB64[h1%64])
+			digest2 = append(digest2, ssdeepB64[h2%64])
+		}
+
+		return strconv.FormatUint(uint64(blockSize), 10) + ":" + string(digest1) + ":" + string(digest2)
+	}
+}
+
+// SSDEEPCompare returns the similarity of two ssdeep hashes,
+// as a score from 0 (no similarity) to 100 (identical).
+func SSDEEPCompare(a, b string) (int, error) {
+	bs1, a1, a2, err := parseSSDEEP(a)
+	if err != nil {
+		return 0, err
+	}
+
+	bs2, b1, b2, err := parseSSDEEP(b)
+	if err != nil {
+		return 0, err
+	}
+
+	// only hashes with the same or an adjacent block size can be compared
+	if bs1 != bs2 && bs1 != bs2*2 && bs2 != bs1*2 {
+		return 0, nil
+	}
+
+	a1, a2 = ssdeepEliminateSequences(a1), ssdeepEliminateSequences(a2)
+	b1, b2 = ssdeepEliminateSequences(b1), ssdeepEliminateSequences(b2)
+
+	if bs1 == bs2 && a1 == b1 {
+		return 100, nil
+	}
+
+	switch {
+	case bs1 == bs2:
+		s1 := ssdeepScoreStrings(a1, b1, bs1)
+		s2 := ssdeepScoreStrings(a2, b2, bs1*2)
+
+		if s1 > s2 {
+			return s1, nil
+		}
+
+		return s2, nil
+	case bs1 == bs2*2:
+		return ssdeepScoreStrings(a1, b2, bs1), nil
+	default:
+		return ssdeepScoreStrings(a2, b1, bs2), nil
+	}
+}
+
+func parseSSDEEP(hash string) (blockSize int, digest1, digest2 string, err error) {
+	parts := strings.SplitN(hash, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", "", errInvalidSSDEEP
+	}
+
+	blockSize, err = strconv.Atoi(parts[0])
+	if err != nil || blockSize <= 0 {
+		return 0, "", "", errInvalidSSDEEP
+	}
+
+	// strip an optional file name in quotes, as written by the ssdeep tool
+	digest2 = parts[2]
+	if i := strings.IndexByte(digest2, ','); i >= 0 {
+		digest2 = digest2[:i]
+	}
+
+	return blockSize, parts[1], digest2, nil
+}
+
+// ssdeepEliminateSequences shortens runs of more than three identical characters,
+// they carry little information and would otherwise bias the score.
+func ssdeepEliminateSequences(s string) string {
+	if len(s) <= 3 {
+		return s
+	}
+
+	out := []byte(s[:3])
+
+	for i := 3; i < len(s); i++ {
+		if s[i] != s[i-1] || s[i] != s[i-2] || s[i] != s[i-3] {
+			out = append(out, s[i])
+		}
+	}
+
+	return string(out)
+}
+
+// ssdeepHasCommonSubstring checks whether the strings share a substring with the size of the rolling window.
+func ssdeepHasCommonSubstring(a, b string) bool {
+	if len(a) < ssdeepRollingWindow || len(b) < ssdeepRollingWindow {
+		return false
+	}
+
+	for i := 0; i <= len(a)-ssdeepRollingWindow; i++ {
+		if strings.Contains(b, a[i:i+ssdeepRollingWindow]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ssdeepEditDistance computes the edit distance, a substitution is counted as a removal and an insertion.
+func ssdeepEditDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := prev[j-1]
+			if a[i-1] != b[j-1] {
+				cost += 2
+			}
+
+			if prev[j]+1 < cost {
+				cost = prev[j] + 1
+			}
+
+			if cur[j-1]+1 < cost {
+				cost = cur[j-1] + 1
+			}
+
+			cur[j] = cost
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func ssdeepScoreStrings(a, b string, blockSize int) int {
+	if !ssdeepHasCommonSubstring(a, b) {
+		return 0
+	}
+
+	// scale the edit distance to the length of the strings, and convert it to a score
+	score := ssdeepEditDistance(a, b) * ssdeepSpamSumLength / (len(a) + len(b))
+	score = 100 * score / ssdeepSpamSumLength
+
+	if score >= 100 {
+		return 0
+	}
+
+	score = 100 - score
+
+	// do not overstate the similarity of small block sizes
+	if blockSize < (99+ssdeepRollingWindow)/ssdeepRollingWindow*ssdeepMinBlockSize {
+		minLen := len(a)
+		if len(b) < minLen {
+			minLen = len(b)
+		}
+
+		if limit := blockSize / ssdeepMinBlockSize * minLen; score > limit {
+			score = limit
+		}
+	}
+
+	return score
+}
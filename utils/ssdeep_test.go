@@ -0,0 +1,103 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+
+	return data
+}
+
+func TestSSDEEP(t *testing.T) {
+	if h := SSDEEP(nil); h != "3::" {
+		t.Fatal("unexpected hash for empty input:", h)
+	}
+
+	data := randomData(1, 64*1024)
+
+	h := SSDEEP(data)
+	if h != SSDEEP(data) {
+		t.Fatal("hash is not deterministic")
+	}
+
+	parts := strings.Split(h, ":")
+	if len(parts) != 3 {
+		t.Fatal("invalid hash format:", h)
+	}
+
+	if len(parts[1]) < ssdeepSpamSumLength/2 || len(parts[1]) > ssdeepSpamSumLength || len(parts[2]) > ssdeepSpamSumLength/2 {
+		t.Fatal("unexpected digest length:", h)
+	}
+}
+
+func TestSSDEEPCompare(t *testing.T) {
+	var (
+		data     = randomData(1, 64*1024)
+		modified = append([]byte{}, data...)
+		other    = randomData(2, 64*1024)
+	)
+
+	// change a small part of the data
+	copy(modified[32*1024:], randomData(3, 1024))
+
+	score, err := SSDEEPCompare(SSDEEP(data), SSDEEP(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 100 {
+		t.Fatal("expected score 100 for identical data, got", score)
+	}
+
+	score, err = SSDEEPCompare(SSDEEP(data), SSDEEP(modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score < 80 || score == 100 {
+		t.Fatal("expected high score for similar data, got", score)
+	}
+
+	score, err = SSDEEPCompare(SSDEEP(data), SSDEEP(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 0 {
+		t.Fatal("expected score 0 for unrelated data, got", score)
+	}
+
+	// block sizes that are too far apart can not be compared
+	score, err = SSDEEPCompare(SSDEEP(data), SSDEEP(data[:1024]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 0 {
+		t.Fatal("expected score 0 for different block sizes, got", score)
+	}
+
+	if _, err = SSDEEPCompare("invalid", SSDEEP(data)); err == nil {
+		t.Fatal("expected error for invalid hash")
+	}
+}
+
+func TestSSDEEPEliminateSequences(t *testing.T) {
+	if s := ssdeepEliminateSequences("AAAAAABCCCCD"); s != "AAABCCCD" {
+		t.Fatal("unexpected result:", s)
+	}
+}
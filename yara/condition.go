@@ -0,0 +1,279 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package yara
+
+import "encoding/binary"
+
+// scanContext holds the data that is scanned and caches the results for each rule.
+type scanContext struct {
+	data      []byte
+	lowerData []byte
+
+	// string matches by rule and string identifier
+	matches map[*Rule]map[string][]int
+
+	// condition results by rule, for rules that are referenced by other rules
+	results map[*Rule]bool
+}
+
+func newScanContext(data []byte) *scanContext {
+	return &scanContext{
+		data:    data,
+		matches: make(map[*Rule]map[string][]int),
+		results: make(map[*Rule]bool),
+	}
+}
+
+// lower returns the data in lower case, it is only computed if a rule uses nocase strings.
+func (ctx *scanContext) lower() []byte {
+	if ctx.lowerData == nil {
+		ctx.lowerData = toLower(ctx.data)
+	}
+
+	return ctx.lowerData
+}
+
+// stringMatches returns the offsets of the matches for all strings of the rule.
+func (ctx *scanContext) stringMatches(r *Rule) map[string][]int {
+	if m, ok := ctx.matches[r]; ok {
+		return m
+	}
+
+	m := make(map[string][]int, len(r.Strings))
+	for _, s := range r.Strings {
+		if offsets := s.find(ctx); len(offsets) > 0 {
+			m[s.ID] = offsets
+		}
+	}
+
+	ctx.matches[r] = m
+
+	return m
+}
+
+// evalRule evaluates the condition of a rule.
+func (ctx *scanContext) evalRule(r *Rule) bool {
+	if res, ok := ctx.results[r]; ok {
+		return res
+	}
+
+	res := r.condition.eval(&ruleContext{
+		scan:    ctx,
+		rule:    r,
+		matches: ctx.stringMatches(r),
+	}) != 0
+
+	ctx.results[r] = res
+
+	return res
+}
+
+// ruleContext is used to evaluate the condition of a single rule.
+type ruleContext struct {
+	scan    *scanContext
+	rule    *Rule
+	matches map[string][]int
+}
+
+// node is an element of a condition.
+// Boolean expressions evaluate to 1 if they are true and 0 otherwise.
+type node interface {
+	eval(ctx *ruleContext) int64
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+type numberNode int64
+
+func (n numberNode) eval(*ruleContext) int64 {
+	return int64(n)
+}
+
+type filesizeNode struct{}
+
+func (filesizeNode) eval(ctx *ruleContext) int64 {
+	return int64(len(ctx.scan.data))
+}
+
+// stringNode is true if the string matched at least once.
+type stringNode string
+
+func (n stringNode) eval(ctx *ruleContext) int64 {
+	return boolToInt(len(ctx.matches[string(n)]) > 0)
+}
+
+// countNode is the number of matches of a string.
+type countNode string
+
+func (n countNode) eval(ctx *ruleContext) int64 {
+	return int64(len(ctx.matches[string(n)]))
+}
+
+// atNode is true if the string matched at the given offset.
+type atNode struct {
+	id     string
+	offset node
+}
+
+func (n *atNode) eval(ctx *ruleContext) int64 {
+	offset := n.offset.eval(ctx)
+
+	for _, o := range ctx.matches[n.id] {
+		if int64(o) == offset {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// inNode is true if the string matched in the given range of offsets.
+type inNode struct {
+	id     string
+	lo, hi node
+}
+
+func (n *inNode) eval(ctx *ruleContext) int64 {
+	lo, hi := n.lo.eval(ctx), n.hi.eval(ctx)
+
+	for _, o := range ctx.matches[n.id] {
+		if int64(o) >= lo && int64(o) <= hi {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// quantifiers for the of operator.
+const (
+	quantAny = -1
+	quantAll = -2
+)
+
+// ofNode is true if at least the given number of strings from the set matched.
+type ofNode struct {
+	quantifier node
+	ids        []string
+}
+
+func (n *ofNode) eval(ctx *ruleContext) int64 {
+	var num int64
+
+	for _, id := range n.ids {
+		if len(ctx.matches[id]) > 0 {
+			num++
+		}
+	}
+
+	switch q := n.quantifier.eval(ctx); q {
+	case quantAny:
+		return boolToInt(num > 0)
+	case quantAll:
+		return boolToInt(num == int64(len(n.ids)))
+	case 0:
+		// none of
+		return boolToInt(num == 0)
+	default:
+		return boolToInt(num >= q)
+	}
+}
+
+// intNode reads an integer from the data, it evaluates to zero if the offset is out of bounds.
+type intNode struct {
+	size      int
+	bigEndian bool
+	offset    node
+}
+
+func (n *intNode) eval(ctx *ruleContext) int64 {
+	offset := n.offset.eval(ctx)
+	data := ctx.scan.data
+
+	if offset < 0 || offset+int64(n.size) > int64(len(data)) {
+		return 0
+	}
+
+	b := data[offset : offset+int64(n.size)]
+
+	switch {
+	case n.size == 1:
+		return int64(b[0])
+	case n.size == 2 && n.bigEndian:
+		return int64(binary.BigEndian.Uint16(b))
+	case n.size == 2:
+		return int64(binary.LittleEndian.Uint16(b))
+	case n.bigEndian:
+		return int64(binary.BigEndian.Uint32(b))
+	default:
+		return int64(binary.LittleEndian.Uint32(b))
+	}
+}
+
+// ruleNode evaluates another rule.
+type ruleNode struct {
+	rule *Rule
+}
+
+func (n *ruleNode) eval(ctx *ruleContext) int64 {
+	return boolToInt(ctx.scan.evalRule(n.rule))
+}
+
+type notNode struct {
+	n node
+}
+
+func (n *notNode) eval(ctx *ruleContext) int64 {
+	return boolToInt(n.n.eval(ctx) == 0)
+}
+
+// binaryNode is a logical operator or a comparison.
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) eval(ctx *ruleContext) int64 {
+	switch n.op {
+	case "and":
+		return boolToInt(n.l.eval(ctx) != 0 && n.r.eval(ctx) != 0)
+	case "or":
+		return boolToInt(n.l.eval(ctx) != 0 || n.r.eval(ctx) != 0)
+	}
+
+	l, r := n.l.eval(ctx), n.r.eval(ctx)
+
+	switch n.op {
+	case "==":
+		return boolToInt(l == r)
+	case "!=":
+		return boolToInt(l != r)
+	case "<":
+		return boolToInt(l < r)
+	case "<=":
+		return boolToInt(l <= r)
+	case ">":
+		return boolToInt(l > r)
+	case ">=":
+		return boolToInt(l >= r)
+	}
+
+	return 0
+}
@@ -0,0 +1,977 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package yara
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parser is a recursive descent parser for the supported subset of the YARA rule language.
+type parser struct {
+	src  string
+	pos  int
+	file string
+
+	// rules that have been parsed so far, they can be referenced in conditions
+	rules map[string]*Rule
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.src[:p.pos], "\n")
+
+	return fmt.Errorf("%s:%d: %s", p.file, line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])):
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.src)
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			if i := strings.Index(p.src[p.pos+2:], "*/"); i >= 0 {
+				p.pos += i + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next character after whitespace and comments, or zero at the end of the input.
+func (p *parser) peek() byte {
+	p.skipSpace()
+
+	if p.pos >= len(p.src) {
+		return 0
+	}
+
+	return p.src[p.pos]
+}
+
+// accept consumes the token if it is next.
+// Keywords are only accepted if they are not the prefix of a longer identifier.
+func (p *parser) accept(tok string) bool {
+	p.skipSpace()
+
+	if !strings.HasPrefix(p.src[p.pos:], tok) {
+		return false
+	}
+
+	end := p.pos + len(tok)
+	if isIdentChar(tok[len(tok)-1]) && end < len(p.src) && isIdentChar(p.src[end]) {
+		return false
+	}
+
+	p.pos = end
+
+	return true
+}
+
+func (p *parser) expect(tok string) error {
+	if !p.accept(tok) {
+		return p.errorf("expected %q", tok)
+	}
+
+	return nil
+}
+
+func isIdentChar(c byte) bool {
+	return isAlnum(c) || c == '_'
+}
+
+// ident reads an identifier, it returns an empty string if there is none.
+func (p *parser) ident() string {
+	p.skipSpace()
+
+	start := p.pos
+	for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+// parseFile parses all rules in the input.
+func (p *parser) parseFile() ([]*Rule, error) {
+	var rules []*Rule
+
+	for p.peek() != 0 {
+		if p.accept("import") || p.accept("include") {
+			if _, err := p.quoted(); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		r, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (p *parser) parseRule() (*Rule, error) {
+	r := &Rule{
+		Meta: make(map[string]string),
+	}
+
+	for {
+		if p.accept("private") {
+			r.Private = true
+
+			continue
+		}
+
+		if p.accept("global") {
+			return nil, p.errorf("global rules are not supported")
+		}
+
+		break
+	}
+
+	if err := p.expect("rule"); err != nil {
+		return nil, err
+	}
+
+	r.Name = p.ident()
+	if r.Name == "" {
+		return nil, p.errorf("expected rule name")
+	}
+
+	if _, exists := p.rules[r.Name]; exists {
+		return nil, p.errorf("duplicate rule %q", r.Name)
+	}
+
+	if p.accept(":") {
+		for p.peek() != '{' {
+			tag := p.ident()
+			if tag == "" {
+				return nil, p.errorf("expected tag")
+			}
+
+			r.Tags = append(r.Tags, tag)
+		}
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	if p.accept("meta") {
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if err := p.parseMeta(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("strings") {
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if err := p.parseStrings(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("condition"); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseExpr(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.condition = cond
+
+	if err = p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	p.rules[r.Name] = r
+
+	return r, nil
+}
+
+func (p *parser) parseMeta(r *Rule) error {
+	for p.peek() != '$' && p.peek() != 0 {
+		start := p.pos
+
+		key := p.ident()
+		if key == "strings" || key == "condition" || key == "" {
+			p.pos = start
+
+			return nil
+		}
+
+		if err := p.expect("="); err != nil {
+			return err
+		}
+
+		switch c := p.peek(); {
+		case c == '"':
+			v, err := p.quoted()
+			if err != nil {
+				return err
+			}
+
+			r.Meta[key] = v
+		case c == '-' || c >= '0' && c <= '9':
+			start = p.pos
+			p.pos++
+
+			for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+				p.pos++
+			}
+
+			r.Meta[key] = p.src[start:p.pos]
+		default:
+			v := p.ident()
+			if v != "true" && v != "false" {
+				return p.errorf("invalid value for meta %q", key)
+			}
+
+			r.Meta[key] = v
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) parseStrings(r *Rule) error {
+	for p.peek() == '$' {
+		p.pos++
+
+		s := &String{
+			ID: "$" + p.ident(),
+		}
+
+		if s.ID == "$" {
+			return p.errorf("anonymous strings are not supported")
+		}
+
+		for _, existing := range r.Strings {
+			if existing.ID == s.ID {
+				return p.errorf("duplicate string %q", s.ID)
+			}
+		}
+
+		if err := p.expect("="); err != nil {
+			return err
+		}
+
+		var (
+			text  string
+			err   error
+			flags string
+		)
+
+		switch p.peek() {
+		case '"':
+			s.kind = stringText
+			text, err = p.quoted()
+		case '{':
+			s.kind = stringHex
+			s.hex, err = p.parseHexString()
+			s.atoms = prepareHex(s.hex)
+		case '/':
+			s.kind = stringRegex
+			text, flags, err = p.regex()
+		default:
+			err = p.errorf("expected string, hex string or regular expression for %s", s.ID)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = p.parseModifiers(s); err != nil {
+			return err
+		}
+
+		switch s.kind {
+		case stringText:
+			if text == "" {
+				return p.errorf("empty string %s", s.ID)
+			}
+
+			data := []byte(text)
+			if s.nocase {
+				data = toLower(data)
+			}
+
+			if s.ascii || !s.wide {
+				s.variants = append(s.variants, variant{data: data, width: 1})
+			}
+
+			if s.wide {
+				s.variants = append(s.variants, variant{data: toWide(data), width: 2})
+			}
+		case stringRegex:
+			if s.wide {
+				return p.errorf("wide regular expressions are not supported")
+			}
+
+			var prefix string
+			if s.nocase || strings.Contains(flags, "i") {
+				prefix += "i"
+			}
+
+			if strings.Contains(flags, "s") {
+				prefix += "s"
+			}
+
+			if prefix != "" {
+				text = "(?" + prefix + ")" + text
+			}
+
+			s.re, err = regexp.Compile(text)
+			if err != nil {
+				return p.errorf("invalid regular expression %s: %s", s.ID, err)
+			}
+		case stringHex:
+			if s.nocase || s.wide || s.ascii || s.fullword {
+				return p.errorf("modifiers are not allowed for hex string %s", s.ID)
+			}
+		}
+
+		r.Strings = append(r.Strings, s)
+	}
+
+	return nil
+}
+
+func (p *parser) parseModifiers(s *String) error {
+	for {
+		start := p.pos
+
+		switch p.ident() {
+		case "nocase":
+			s.nocase = true
+		case "wide":
+			s.wide = true
+		case "ascii":
+			s.ascii = true
+		case "fullword":
+			s.fullword = true
+		case "private":
+		case "xor", "base64", "base64wide":
+			return p.errorf("unsupported string modifier for %s", s.ID)
+		default:
+			p.pos = start
+
+			return nil
+		}
+	}
+}
+
+// quoted reads a string literal and resolves the escape sequences.
+func (p *parser) quoted() (string, error) {
+	if p.peek() != '"' {
+		return "", p.errorf("expected string literal")
+	}
+
+	p.pos++
+
+	var b strings.Builder
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\n':
+			return "", p.errorf("unterminated string literal")
+		case '\\':
+			if p.pos >= len(p.src) {
+				return "", p.errorf("unterminated string literal")
+			}
+
+			e := p.src[p.pos]
+			p.pos++
+
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(e)
+			case 'x':
+				if p.pos+2 > len(p.src) {
+					return "", p.errorf("invalid escape sequence")
+				}
+
+				v, err := strconv.ParseUint(p.src[p.pos:p.pos+2], 16, 8)
+				if err != nil {
+					return "", p.errorf("invalid escape sequence")
+				}
+
+				b.WriteByte(byte(v))
+				p.pos += 2
+			default:
+				return "", p.errorf("invalid escape sequence \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string literal")
+}
+
+// regex reads a regular expression literal and its flags.
+func (p *parser) regex() (expr, flags string, err error) {
+	p.pos++
+
+	var b strings.Builder
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+
+		switch c {
+		case '/':
+			start := p.pos
+			for p.pos < len(p.src) && (p.src[p.pos] == 'i' || p.src[p.pos] == 's') {
+				p.pos++
+			}
+
+			return b.String(), p.src[start:p.pos], nil
+		case '\n':
+			return "", "", p.errorf("unterminated regular expression")
+		case '\\':
+			if p.pos < len(p.src) && p.src[p.pos] == '/' {
+				b.WriteByte('/')
+				p.pos++
+
+				continue
+			}
+
+			b.WriteByte(c)
+
+			if p.pos < len(p.src) {
+				b.WriteByte(p.src[p.pos])
+				p.pos++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", "", p.errorf("unterminated regular expression")
+}
+
+// parseHexString parses a hex string with wildcards, jumps and alternatives.
+func (p *parser) parseHexString() ([]hexToken, error) {
+	p.pos++
+
+	tokens, end, err := p.parseHexTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	if end != '}' {
+		return nil, p.errorf("unexpected %q in hex string", end)
+	}
+
+	if len(tokens) == 0 {
+		return nil, p.errorf("empty hex string")
+	}
+
+	if tokens[0].kind == hexJump || tokens[len(tokens)-1].kind == hexJump {
+		return nil, p.errorf("hex strings must not start or end with a jump")
+	}
+
+	return tokens, nil
+}
+
+// parseHexTokens reads tokens until a closing brace or parenthesis, or an alternative separator,
+// and returns the character that ended the sequence.
+func (p *parser) parseHexTokens() ([]hexToken, byte, error) {
+	var tokens []hexToken
+
+	for {
+		c := p.peek()
+		if c == 0 {
+			return nil, 0, p.errorf("unterminated hex string")
+		}
+
+		switch {
+		case c == '}' || c == ')' || c == '|':
+			p.pos++
+
+			return tokens, c, nil
+		case c == '[':
+			t, err := p.parseHexJump()
+			if err != nil {
+				return nil, 0, err
+			}
+
+			tokens = append(tokens, t)
+		case c == '(':
+			p.pos++
+
+			t := hexToken{kind: hexAlt}
+
+			for {
+				alt, end, err := p.parseHexTokens()
+				if err != nil {
+					return nil, 0, err
+				}
+
+				if len(alt) == 0 {
+					return nil, 0, p.errorf("empty alternative in hex string")
+				}
+
+				t.alts = append(t.alts, alt)
+
+				if end == ')' {
+					break
+				}
+
+				if end != '|' {
+					return nil, 0, p.errorf("unterminated alternative in hex string")
+				}
+			}
+
+			tokens = append(tokens, t)
+		default:
+			if p.pos+2 > len(p.src) {
+				return nil, 0, p.errorf("unterminated hex string")
+			}
+
+			t := hexToken{kind: hexByte}
+
+			for i := 0; i < 2; i++ {
+				t.value <<= 4
+				t.mask <<= 4
+
+				d := p.src[p.pos+i]
+				if d == '?' {
+					continue
+				}
+
+				v, err := strconv.ParseUint(string(d), 16, 8)
+				if err != nil {
+					return nil, 0, p.errorf("invalid character %q in hex string", d)
+				}
+
+				t.value |= byte(v)
+				t.mask |= 0xf
+			}
+
+			p.pos += 2
+
+			tokens = append(tokens, t)
+		}
+	}
+}
+
+// parseHexJump parses a jump: [n], [n-m], [n-] or [-].
+func (p *parser) parseHexJump() (hexToken, error) {
+	p.pos++
+
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 {
+		return hexToken{}, p.errorf("unterminated jump in hex string")
+	}
+
+	var (
+		spec = strings.ReplaceAll(p.src[p.pos:p.pos+end], " ", "")
+		t    = hexToken{kind: hexJump, max: maxHexJump}
+		err  error
+	)
+
+	p.pos += end + 1
+
+	parseBound := func(s string) (int, error) {
+		n, errAtoi := strconv.Atoi(s)
+		if errAtoi != nil || n < 0 {
+			return 0, p.errorf("invalid jump [%s] in hex string", spec)
+		}
+
+		if n > maxHexJump {
+			return 0, p.errorf("jump [%s] in hex string exceeds the maximum of %d bytes", spec, maxHexJump)
+		}
+
+		return n, nil
+	}
+
+	i := strings.IndexByte(spec, '-')
+	if i < 0 {
+		if t.min, err = parseBound(spec); err != nil {
+			return t, err
+		}
+
+		t.max = t.min
+
+		return t, nil
+	}
+
+	if i > 0 {
+		if t.min, err = parseBound(spec[:i]); err != nil {
+			return t, err
+		}
+	}
+
+	if i < len(spec)-1 {
+		if t.max, err = parseBound(spec[i+1:]); err != nil {
+			return t, err
+		}
+
+		if t.max < t.min {
+			return t, p.errorf("invalid jump [%s] in hex string", spec)
+		}
+	}
+
+	return t, nil
+}
+
+// parseExpr parses a boolean expression with or as the operator with the lowest precedence.
+func (p *parser) parseExpr(r *Rule) (node, error) {
+	l, err := p.parseAnd(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("or") {
+		right, errRight := p.parseAnd(r)
+		if errRight != nil {
+			return nil, errRight
+		}
+
+		l = &binaryNode{op: "or", l: l, r: right}
+	}
+
+	return l, nil
+}
+
+func (p *parser) parseAnd(r *Rule) (node, error) {
+	l, err := p.parseNot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("and") {
+		right, errRight := p.parseNot(r)
+		if errRight != nil {
+			return nil, errRight
+		}
+
+		l = &binaryNode{op: "and", l: l, r: right}
+	}
+
+	return l, nil
+}
+
+func (p *parser) parseNot(r *Rule) (node, error) {
+	if p.accept("not") {
+		n, err := p.parseNot(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{n: n}, nil
+	}
+
+	return p.parseComparison(r)
+}
+
+// comparison operators, two character operators must be checked first.
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *parser) parseComparison(r *Rule) (node, error) {
+	l, err := p.parsePrimary(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range comparisonOperators {
+		if p.accept(op) {
+			right, errRight := p.parsePrimary(r)
+			if errRight != nil {
+				return nil, errRight
+			}
+
+			return &binaryNode{op: op, l: l, r: right}, nil
+		}
+	}
+
+	return l, nil
+}
+
+// integer functions that read from the data, mapped to the size and endianness.
+var intFunctions = map[string]struct {
+	size      int
+	bigEndian bool
+}{
+	"uint8":    {1, false},
+	"uint16":   {2, false},
+	"uint32":   {4, false},
+	"uint16be": {2, true},
+	"uint32be": {4, true},
+}
+
+func (p *parser) parsePrimary(r *Rule) (node, error) {
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+
+		n, err := p.parseExpr(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return n, p.expect(")")
+	case c == '$':
+		p.pos++
+
+		id := "$" + p.ident()
+		if !hasString(r, id) {
+			return nil, p.errorf("undefined string %s", id)
+		}
+
+		if p.accept("at") {
+			offset, err := p.parsePrimary(r)
+			if err != nil {
+				return nil, err
+			}
+
+			return &atNode{id: id, offset: offset}, nil
+		}
+
+		if p.accept("in") {
+			lo, hi, err := p.parseRange(r)
+			if err != nil {
+				return nil, err
+			}
+
+			return &inNode{id: id, lo: lo, hi: hi}, nil
+		}
+
+		return stringNode(id), nil
+	case c == '#':
+		p.pos++
+
+		id := "$" + p.ident()
+		if !hasString(r, id) {
+			return nil, p.errorf("undefined string %s", id)
+		}
+
+		return countNode(id), nil
+	case c >= '0' && c <= '9':
+		n, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.accept("of") {
+			return p.parseOf(r, n)
+		}
+
+		return n, nil
+	}
+
+	start := p.pos
+	name := p.ident()
+
+	switch name {
+	case "":
+		return nil, p.errorf("unexpected character in condition")
+	case "true":
+		return numberNode(1), nil
+	case "false":
+		return numberNode(0), nil
+	case "filesize":
+		return filesizeNode{}, nil
+	case "any", "all", "none":
+		if err := p.expect("of"); err != nil {
+			return nil, err
+		}
+
+		q := map[string]numberNode{"any": quantAny, "all": quantAll, "none": 0}[name]
+
+		return p.parseOf(r, q)
+	}
+
+	if f, ok := intFunctions[name]; ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		offset, err := p.parseExpr(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return &intNode{size: f.size, bigEndian: f.bigEndian, offset: offset}, p.expect(")")
+	}
+
+	if ref, ok := p.rules[name]; ok {
+		return &ruleNode{rule: ref}, nil
+	}
+
+	p.pos = start
+
+	return nil, p.errorf("unknown identifier %q in condition", name)
+}
+
+// parseRange parses a range of offsets: (lo..hi).
+func (p *parser) parseRange(r *Rule) (lo, hi node, err error) {
+	if err = p.expect("("); err != nil {
+		return nil, nil, err
+	}
+
+	if lo, err = p.parsePrimary(r); err != nil {
+		return nil, nil, err
+	}
+
+	if err = p.expect(".."); err != nil {
+		return nil, nil, err
+	}
+
+	if hi, err = p.parsePrimary(r); err != nil {
+		return nil, nil, err
+	}
+
+	return lo, hi, p.expect(")")
+}
+
+// parseOf parses the string set after the of keyword: them, or a list of strings with optional wildcards.
+func (p *parser) parseOf(r *Rule, quantifier node) (node, error) {
+	n := &ofNode{quantifier: quantifier}
+
+	if p.accept("them") {
+		for _, s := range r.Strings {
+			n.ids = append(n.ids, s.ID)
+		}
+	} else {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+
+		for {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+
+			id := "$" + p.ident()
+
+			if p.pos < len(p.src) && p.src[p.pos] == '*' {
+				p.pos++
+
+				var found bool
+				for _, s := range r.Strings {
+					if strings.HasPrefix(s.ID, id) {
+						n.ids = append(n.ids, s.ID)
+						found = true
+					}
+				}
+
+				if !found {
+					return nil, p.errorf("no strings match %s*", id)
+				}
+			} else {
+				if !hasString(r, id) {
+					return nil, p.errorf("undefined string %s", id)
+				}
+
+				n.ids = append(n.ids, id)
+			}
+
+			if p.accept(")") {
+				break
+			}
+
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(n.ids) == 0 {
+		return nil, p.errorf("empty string set")
+	}
+
+	return n, nil
+}
+
+// number parses a decimal or hexadecimal integer, with an optional KB or MB suffix.
+func (p *parser) number() (numberNode, error) {
+	p.skipSpace()
+
+	start := p.pos
+	for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+		p.pos++
+	}
+
+	var (
+		s                = p.src[start:p.pos]
+		multiplier int64 = 1
+	)
+
+	switch {
+	case strings.HasSuffix(s, "KB"):
+		s, multiplier = strings.TrimSuffix(s, "KB"), 1024
+	case strings.HasSuffix(s, "MB"):
+		s, multiplier = strings.TrimSuffix(s, "MB"), 1024*1024
+	}
+
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", p.src[start:p.pos])
+	}
+
+	return numberNode(v * multiplier), nil
+}
+
+func hasString(r *Rule, id string) bool {
+	for _, s := range r.Strings {
+		if s.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,412 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package yara
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// maxMatchesPerString limits the number of offsets recorded for a single string.
+const maxMatchesPerString = 1000
+
+const (
+	// maxHexJump is the maximum length of a jump in a hex string, unbounded jumps are limited to it.
+	maxHexJump = 0x7fff
+
+	// hexStepsPerByte limits the work for matching a hex string, relative to the size of the scanned data.
+	// Matching stops once the budget is exhausted, so pathological rules and data can not stall the scan.
+	hexStepsPerByte = 16
+	minHexSteps     = 1 << 16
+)
+
+// kinds of strings.
+const (
+	stringText = iota
+	stringHex
+	stringRegex
+)
+
+// Rule is a compiled rule.
+type Rule struct {
+	Name string
+	Tags []string
+	Meta map[string]string
+
+	// private rules can be referenced by other rules, but do not generate matches
+	Private bool
+
+	Strings []*String
+
+	condition node
+}
+
+// String is a pattern from the strings section of a rule.
+type String struct {
+	ID string
+
+	kind int
+
+	// text strings: the byte sequences to search for, lower case if nocase is set
+	variants []variant
+
+	// hex strings, and the runs of fixed bytes that must occur in the data for a match
+	hex   []hexToken
+	atoms [][]byte
+
+	// regular expressions
+	re *regexp.Regexp
+
+	nocase   bool
+	wide     bool
+	ascii    bool
+	fullword bool
+}
+
+// variant is an encoding of a text string.
+type variant struct {
+	data []byte
+
+	// distance between characters, 2 for wide strings
+	width int
+}
+
+// kinds of hex string tokens.
+const (
+	hexByte = iota
+	hexJump
+	hexAlt
+)
+
+// hexToken is a byte with a wildcard mask, a jump or a set of alternatives.
+type hexToken struct {
+	kind int
+
+	value byte
+	mask  byte
+
+	// jump range
+	min int
+	max int
+
+	// fixed bytes following a jump, only positions where they occur are tried
+	next []byte
+
+	alts [][]hexToken
+}
+
+// find returns the offsets of all matches in the data.
+func (s *String) find(ctx *scanContext) []int {
+	switch s.kind {
+	case stringText:
+		return s.findText(ctx)
+	case stringHex:
+		return s.findHex(ctx.data)
+	case stringRegex:
+		return s.findRegex(ctx.data)
+	}
+
+	return nil
+}
+
+func (s *String) findText(ctx *scanContext) []int {
+	var (
+		out  []int
+		data = ctx.data
+	)
+
+	if s.nocase {
+		data = ctx.lower()
+	}
+
+	for _, v := range s.variants {
+		for off := 0; off < len(data) && len(out) < maxMatchesPerString; {
+			i := bytes.Index(data[off:], v.data)
+			if i < 0 {
+				break
+			}
+
+			pos := off + i
+			if !s.fullword || isFullword(data, pos, len(v.data), v.width) {
+				out = append(out, pos)
+			}
+
+			off = pos + 1
+		}
+	}
+
+	// ascii and wide matches are collected separately
+	if len(s.variants) > 1 {
+		sort.Ints(out)
+	}
+
+	return out
+}
+
+func (s *String) findHex(data []byte) []int {
+	// all fixed byte runs must be present
+	for _, atom := range s.atoms {
+		if !bytes.Contains(data, atom) {
+			return nil
+		}
+	}
+
+	var (
+		out  []int
+		m    = newHexMatcher(data)
+		lead = leadingBytes(s.hex)
+	)
+
+	for pos := 0; pos < len(data) && len(out) < maxMatchesPerString; pos++ {
+		// skip ahead to the next candidate if the string starts with fixed bytes
+		if len(lead) > 0 {
+			i := bytes.Index(data[pos:], lead)
+			if i < 0 {
+				break
+			}
+
+			pos += i
+		}
+
+		if m.match(s.hex, pos, matchAny) {
+			out = append(out, pos)
+		}
+
+		if m.steps <= 0 {
+			yaraLog.Warn("hex string exceeded the matching budget, matches may be missing",
+				zap.String("string", s.ID),
+				zap.Int("size", len(data)),
+			)
+
+			break
+		}
+	}
+
+	return out
+}
+
+func (s *String) findRegex(data []byte) []int {
+	var out []int
+
+	for _, loc := range s.re.FindAllIndex(data, maxMatchesPerString) {
+		if !s.fullword || isFullword(data, loc[0], loc[1]-loc[0], 1) {
+			out = append(out, loc[0])
+		}
+	}
+
+	return out
+}
+
+func matchAny(int) bool {
+	return true
+}
+
+// hexMatcher matches hex strings against the data, within a budget of steps.
+type hexMatcher struct {
+	data  []byte
+	steps int
+
+	// last search for the fixed bytes following a jump
+	hits map[*hexToken]atomHit
+}
+
+// atomHit is the first occurrence of the fixed bytes at or after from, -1 if there is none.
+type atomHit struct {
+	from int
+	pos  int
+}
+
+func newHexMatcher(data []byte) *hexMatcher {
+	return &hexMatcher{
+		data:  data,
+		steps: hexStepsPerByte*len(data) + minHexSteps,
+		hits:  make(map[*hexToken]atomHit),
+	}
+}
+
+// match matches the tokens at pos and calls k with the end of the match.
+// Jumps and alternatives are backtracked if k does not accept the end position.
+func (m *hexMatcher) match(tokens []hexToken, pos int, k func(end int) bool) bool {
+	m.steps--
+	if m.steps <= 0 {
+		return false
+	}
+
+	if len(tokens) == 0 {
+		return k(pos)
+	}
+
+	t, rest := &tokens[0], tokens[1:]
+
+	switch t.kind {
+	case hexByte:
+		if pos >= len(m.data) || m.data[pos]&t.mask != t.value {
+			return false
+		}
+
+		return m.match(rest, pos+1, k)
+	case hexJump:
+		max := pos + t.max
+		if max > len(m.data) {
+			max = len(m.data)
+		}
+
+		for n := pos + t.min; n <= max; n++ {
+			// only try the positions where the following fixed bytes occur
+			if len(t.next) > 0 {
+				if n = m.nextHit(t, n); n < 0 || n > max {
+					return false
+				}
+			}
+
+			if m.match(rest, n, k) {
+				return true
+			}
+
+			if m.steps <= 0 {
+				return false
+			}
+		}
+	case hexAlt:
+		for _, alt := range t.alts {
+			if m.match(alt, pos, func(end int) bool {
+				return m.match(rest, end, k)
+			}) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// nextHit returns the position of the next occurrence of the fixed bytes following the jump, at or after pos.
+// The last result is cached, since the same ranges are searched again for subsequent candidates.
+func (m *hexMatcher) nextHit(t *hexToken, pos int) int {
+	if h, ok := m.hits[t]; ok && h.from <= pos && (h.pos < 0 || h.pos >= pos) {
+		return h.pos
+	}
+
+	h := atomHit{from: pos, pos: -1}
+
+	i := bytes.Index(m.data[pos:], t.next)
+	if i >= 0 {
+		h.pos = pos + i
+		m.steps -= i / hexStepsPerByte
+	} else {
+		m.steps -= (len(m.data) - pos) / hexStepsPerByte
+	}
+
+	m.hits[t] = h
+
+	return h.pos
+}
+
+// leadingBytes returns the fixed bytes at the start of the tokens.
+func leadingBytes(tokens []hexToken) []byte {
+	var out []byte
+
+	for _, t := range tokens {
+		if t.kind != hexByte || t.mask != 0xff {
+			break
+		}
+
+		out = append(out, t.value)
+	}
+
+	return out
+}
+
+// prepareHex collects the runs of fixed bytes that must occur for a match,
+// and sets the fixed bytes following each jump, including jumps in alternatives.
+func prepareHex(tokens []hexToken) (atoms [][]byte) {
+	var run []byte
+
+	for i := range tokens {
+		t := &tokens[i]
+
+		switch t.kind {
+		case hexByte:
+			if t.mask == 0xff {
+				run = append(run, t.value)
+
+				continue
+			}
+		case hexJump:
+			t.next = leadingBytes(tokens[i+1:])
+		case hexAlt:
+			for _, alt := range t.alts {
+				prepareHex(alt)
+			}
+		}
+
+		if len(run) > 0 {
+			atoms = append(atoms, run)
+			run = nil
+		}
+	}
+
+	if len(run) > 0 {
+		atoms = append(atoms, run)
+	}
+
+	return atoms
+}
+
+// isFullword checks that the match is not preceded or followed by an alphanumeric character.
+func isFullword(data []byte, pos, length, width int) bool {
+	if pos-width >= 0 && isAlnum(data[pos-width]) {
+		return false
+	}
+
+	if end := pos + length; end < len(data) && isAlnum(data[end]) {
+		return false
+	}
+
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// toLower converts ASCII letters to lower case, all other bytes are preserved,
+// so the offsets in the result are the same as in the input.
+func toLower(data []byte) []byte {
+	out := make([]byte, len(data))
+
+	for i, c := range data {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		out[i] = c
+	}
+
+	return out
+}
+
+// toWide interleaves the bytes with zeros, like UTF-16LE encoded ASCII text.
+func toWide(data []byte) []byte {
+	out := make([]byte, 0, len(data)*2)
+
+	for _, c := range data {
+		out = append(out, c, 0)
+	}
+
+	return out
+}
@@ -0,0 +1,258 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package yara implements scanning data with rules in a subset of the YARA rule language.
+//
+// Supported are text strings with the nocase, wide, ascii and fullword modifiers,
+// hex strings with wildcards, jumps and alternatives, and regular expressions in RE2 syntax.
+// Conditions can combine strings with and, or and not, count matches (#a), check offsets ($a at 0, $a in (0..100)),
+// use quantifiers (any of them, 2 of ($a*)), compare the filesize, read integers (uint16(0) == 0x5A4D)
+// and reference other rules.
+package yara
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+var (
+	yaraLog = zap.NewNop()
+
+	// Instance is the scanner used at runtime, it is nil if no rules have been loaded.
+	Instance *Scanner
+
+	// MaxScanSize limits the number of bytes that are scanned, data exceeding it is truncated.
+	MaxScanSize = 10 * 1024 * 1024
+
+	// MaxConversationScanSize limits the number of bytes that are scanned for each direction of a conversation.
+	// Conversations are scanned while decoding the stream, so the limit is much lower than for extracted files.
+	MaxConversationScanSize = 1024 * 1024
+)
+
+// SetLogger sets the logger for the yara package.
+func SetLogger(l *zap.Logger) {
+	yaraLog = l
+}
+
+// Scanner matches data against a set of rules.
+type Scanner struct {
+	rules []*Rule
+}
+
+// Match is a rule that matched the scanned data.
+type Match struct {
+	Rule    *Rule
+	Strings []*StringMatch
+}
+
+// StringMatch contains the offsets where a string of a rule matched.
+type StringMatch struct {
+	ID      string
+	Offsets []int
+}
+
+// Load reads the rules from a file, or from all files with a .yar or .yara extension in a directory.
+// Rules can reference rules from files that have been loaded before them, files are loaded in lexical order.
+func Load(path string) (*Scanner, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+
+	if info.IsDir() {
+		entries, errRead := ioutil.ReadDir(path)
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		files = files[:0]
+
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yar" || ext == ".yara") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var (
+		s = new(Scanner)
+		p = &parser{rules: make(map[string]*Rule)}
+	)
+
+	for _, f := range files {
+		data, errRead := ioutil.ReadFile(f)
+		if errRead != nil {
+			return nil, errRead
+		}
+
+		err = s.add(p, f, string(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Parse compiles the rules in the source.
+func Parse(src string) (*Scanner, error) {
+	s := new(Scanner)
+
+	err := s.add(&parser{rules: make(map[string]*Rule)}, "rules", src)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Scanner) add(p *parser, file, src string) error {
+	p.file = file
+	p.src = src
+	p.pos = 0
+
+	rules, err := p.parseFile()
+	if err != nil {
+		return err
+	}
+
+	s.rules = append(s.rules, rules...)
+
+	return nil
+}
+
+// NumRules returns the number of loaded rules.
+func (s *Scanner) NumRules() int {
+	return len(s.rules)
+}
+
+// Scan returns the matches of all rules that are not private.
+func (s *Scanner) Scan(data []byte) []*Match {
+	if len(data) > MaxScanSize {
+		data = data[:MaxScanSize]
+	}
+
+	var (
+		ctx     = newScanContext(data)
+		matches []*Match
+	)
+
+	for _, r := range s.rules {
+		if r.Private || !ctx.evalRule(r) {
+			continue
+		}
+
+		m := &Match{Rule: r}
+
+		for _, str := range r.Strings {
+			if offsets := ctx.matches[r][str.ID]; len(offsets) > 0 {
+				m.Strings = append(m.Strings, &StringMatch{ID: str.ID, Offsets: offsets})
+			}
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches
+}
+
+// Flow describes where the scanned data has been observed.
+type Flow struct {
+	Timestamp   int64
+	SrcIP       string
+	DstIP       string
+	SrcPort     int32
+	DstPort     int32
+	Protocol    string
+	CommunityID string
+
+	// additional information about the scanned data, e.g. the location of an extracted file
+	Notes string
+}
+
+// ScanAndAlert scans the data and emits an alert for each matching rule.
+// The description of a rule is taken from the description meta field, and the MITRE technique from the mitre field.
+func (s *Scanner) ScanAndAlert(data []byte, flow *Flow) []*Match {
+	matches := s.Scan(data)
+
+	for _, m := range matches {
+		description := m.Rule.Meta["description"]
+		if description == "" {
+			description = "YARA rule " + m.Rule.Name + " matched"
+		}
+
+		notes := FormatOffsets(m)
+		if len(m.Rule.Tags) > 0 {
+			notes += ", tags: " + strings.Join(m.Rule.Tags, " ")
+		}
+
+		if flow.Notes != "" {
+			notes += ", " + flow.Notes
+		}
+
+		err := alert.Emit(&types.Alert{
+			Timestamp:   flow.Timestamp,
+			Name:        m.Rule.Name,
+			Description: description,
+			SrcIP:       flow.SrcIP,
+			SrcPort:     strconv.Itoa(int(flow.SrcPort)),
+			DstIP:       flow.DstIP,
+			DstPort:     strconv.Itoa(int(flow.DstPort)),
+			MITRE:       m.Rule.Meta["mitre"],
+			Protocol:    flow.Protocol,
+			Notes:       notes,
+			CommunityID: flow.CommunityID,
+		})
+		if err != nil {
+			yaraLog.Error("failed to emit alert", zap.String("rule", m.Rule.Name), zap.Error(err))
+		}
+	}
+
+	return matches
+}
+
+// maxFormattedOffsets limits the number of offsets included for each string by FormatOffsets.
+const maxFormattedOffsets = 10
+
+// FormatOffsets returns the matched strings and their offsets, e.g. $a@0x0,0x1f $b@0x40.
+func FormatOffsets(m *Match) string {
+	parts := make([]string, 0, len(m.Strings))
+
+	for _, s := range m.Strings {
+		offsets := make([]string, 0, maxFormattedOffsets)
+
+		for i, o := range s.Offsets {
+			if i == maxFormattedOffsets {
+				offsets = append(offsets, "...")
+
+				break
+			}
+
+			offsets = append(offsets, "0x"+strconv.FormatInt(int64(o), 16))
+		}
+
+		parts = append(parts, s.ID+"@"+strings.Join(offsets, ","))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,315 @@
+/*
+ * NETCAP - Traffic Analysis Framework
+ * Copyright (c) 2017-2020 Philipp Mieden <dreadl0ck [at] protonmail [dot] ch>
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package yara
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreadl0ck/netcap/alert"
+	"github.com/dreadl0ck/netcap/types"
+)
+
+const testRules = `
+import "pe"
+
+/*
+ * test rules
+ */
+private rule MZ {
+	condition:
+		uint16(0) == 0x5A4D
+}
+
+rule Webshell : php backdoor {
+	meta:
+		description = "PHP webshell"
+		mitre = "T1505.003"
+		score = 80
+	strings:
+		$eval = "eval(" nocase
+		$post = "$_POST" fullword
+		$b64  = /base64_decode\s*\(/
+	condition:
+		$eval and ($post or $b64)
+}
+
+rule Dropper {
+	strings:
+		$hex  = { 4D 5A ?? 00 [2-4] 50 45 ( 00 00 | 4C 01 ) }
+		$w    = "cmd.exe" wide ascii
+	condition:
+		MZ and $hex at 0 and #w >= 2 and filesize < 1KB
+}
+
+rule AnyOf {
+	strings:
+		$a1 = "alpha"
+		$a2 = "beta"
+		$c  = "gamma"
+	condition:
+		2 of ($a*) and not $c
+}
+
+rule Range {
+	strings:
+		$x = "marker"
+		$y = "other"
+	condition:
+		$x in (0..10) and none of ($y)
+		and any of them
+}
+`
+
+func scanNames(t *testing.T, s *Scanner, data []byte) map[string]*Match {
+	t.Helper()
+
+	out := make(map[string]*Match)
+	for _, m := range s.Scan(data) {
+		out[m.Rule.Name] = m
+	}
+
+	return out
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.NumRules() != 5 {
+		t.Fatal("expected 5 rules, got", s.NumRules())
+	}
+
+	r := s.rules[1]
+	if r.Name != "Webshell" || len(r.Tags) != 2 || r.Meta["description"] != "PHP webshell" || r.Meta["score"] != "80" {
+		t.Fatal("unexpected rule", r.Name, r.Tags, r.Meta)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		`rule a { condition: $x }`,
+		`rule a { strings: $x = "x" condition: $x and }`,
+		`rule a { strings: $x = { 4D [2] } condition: $x }`,
+		`rule a { strings: $x = { 4D 5G } condition: $x }`,
+		`rule a { strings: $x = "x" xor condition: $x }`,
+		`rule a { condition: true } rule a { condition: true }`,
+		`rule a { condition: b }`,
+		`rule a { strings: $x = "x" condition: $x`,
+		`rule a { strings: $x = { 4D [0-40000] 5A } condition: $x }`,
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatal("expected error for", src)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	s, err := Parse(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// webshell
+	m := scanNames(t, s, []byte("<?php EVAL(base64_decode ($x)); ?>"))
+	if len(m) != 1 || m["Webshell"] == nil {
+		t.Fatal("expected Webshell match, got", m)
+	}
+
+	if FormatOffsets(m["Webshell"]) != "$eval@0x6 $b64@0xb" {
+		t.Fatal("unexpected offsets", FormatOffsets(m["Webshell"]))
+	}
+
+	// fullword prevents matching $_POSTED
+	m = scanNames(t, s, []byte("eval($_POSTED)"))
+	if len(m) != 0 {
+		t.Fatal("expected no match, got", m)
+	}
+
+	// dropper with hex pattern, jump, alternative and wide string
+	data := []byte("MZ\x90\x00\x01\x02\x03PEL\x01 cmd.exe c\x00m\x00d\x00.\x00e\x00x\x00e\x00")
+
+	m = scanNames(t, s, data)
+	if len(m) != 1 || m["Dropper"] == nil {
+		t.Fatal("expected Dropper match, got", m)
+	}
+
+	if FormatOffsets(m["Dropper"]) != "$hex@0x0 $w@0xc,0x14" {
+		t.Fatal("unexpected offsets", FormatOffsets(m["Dropper"]))
+	}
+
+	// the private rule MZ is not reported
+	m = scanNames(t, s, []byte("MZ"))
+	if len(m) != 0 {
+		t.Fatal("expected no match, got", m)
+	}
+
+	// quantifiers
+	m = scanNames(t, s, []byte("alpha beta"))
+	if m["AnyOf"] == nil {
+		t.Fatal("expected AnyOf match")
+	}
+
+	m = scanNames(t, s, []byte("alpha beta gamma"))
+	if m["AnyOf"] != nil {
+		t.Fatal("unexpected AnyOf match")
+	}
+
+	// offset range
+	m = scanNames(t, s, []byte("....marker"))
+	if m["Range"] == nil {
+		t.Fatal("expected Range match")
+	}
+
+	m = scanNames(t, s, []byte("............marker"))
+	if m["Range"] != nil {
+		t.Fatal("unexpected Range match")
+	}
+}
+
+func TestHexAlternativesBacktracking(t *testing.T) {
+	s, err := Parse(`rule h { strings: $h = { 41 ( 42 | 42 43 ) 44 [0-2] 45 } condition: #h == 2 }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second alternative only matches after backtracking from the first one
+	if len(s.Scan([]byte("ABDE ABCDxE"))) != 1 {
+		t.Fatal("expected match")
+	}
+}
+
+func TestHexJumps(t *testing.T) {
+	s, err := Parse(`rule h { strings: $h = { 4D 5A [-] 50 45 } condition: $h }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := append([]byte("MZ"), bytes.Repeat([]byte{0}, maxHexJump)...)
+	if len(s.Scan(append(data, "PE"...))) != 1 {
+		t.Fatal("expected match")
+	}
+
+	// unbounded jumps are limited like in YARA
+	if len(s.Scan(append(data, "xPE"...))) != 0 {
+		t.Fatal("expected no match")
+	}
+}
+
+func TestHexPathological(t *testing.T) {
+	// the fixed bytes at the end only occur before the candidates
+	for src, data := range map[string][]byte{
+		`rule h { strings: $h = { 4D 5A [-] 4D 5A [-] 50 45 } condition: $h }`: append([]byte("PE"), bytes.Repeat([]byte("MZ"), 128*1024)...),
+		`rule h { strings: $h = { 4D [-] 4? 5A } condition: $h }`:              append([]byte("Z"), bytes.Repeat([]byte("M"), 256*1024)...),
+	} {
+		s, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		if len(s.Scan(data)) != 0 {
+			t.Fatal("expected no match for", src)
+		}
+
+		if d := time.Since(start); d > 2*time.Second {
+			t.Fatal("scan took", d, "for", src)
+		}
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netcap-yara")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.yar":      `private rule Base { strings: $a = "netcap" condition: $a }`,
+		"b.yara":     `rule Derived { condition: Base and filesize > 6 }`,
+		"readme.txt": `not a rule`,
+	}
+
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.NumRules() != 2 {
+		t.Fatal("expected 2 rules, got", s.NumRules())
+	}
+
+	if len(s.Scan([]byte("netcap"))) != 0 || len(s.Scan([]byte("netcap!"))) != 1 {
+		t.Fatal("unexpected scan result")
+	}
+}
+
+func TestScanAndAlert(t *testing.T) {
+	var alerts []*types.Alert
+
+	alert.Instance = alert.NewManager(alert.Config{
+		Write: func(a *types.Alert) error {
+			alerts = append(alerts, a)
+
+			return nil
+		},
+	})
+	defer func() {
+		alert.Instance = nil
+	}()
+
+	s, err := Parse(testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.ScanAndAlert([]byte("eval($_POST['x'])"), &Flow{
+		Timestamp:   1,
+		SrcIP:       "192.168.1.2",
+		DstIP:       "10.0.0.1",
+		SrcPort:     49152,
+		DstPort:     80,
+		Protocol:    "TCP",
+		CommunityID: "1:abc",
+		Notes:       "client to server",
+	})
+
+	alert.Instance.Flush()
+
+	if len(alerts) != 1 {
+		t.Fatal("expected 1 alert, got", len(alerts))
+	}
+
+	a := alerts[0]
+	if a.Name != "Webshell" || a.Description != "PHP webshell" || a.MITRE != "T1505.003" || a.DstPort != "80" || a.CommunityID != "1:abc" {
+		t.Fatal("unexpected alert", a)
+	}
+
+	if a.Notes != "$eval@0x0 $post@0x5, tags: php backdoor, client to server" {
+		t.Fatal("unexpected notes", a.Notes)
+	}
+}